@@ -17,19 +17,32 @@ limitations under the License.
 package baseimage
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"k8s.io/kubeadm/kinder/pkg/build/base"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	kindbase "sigs.k8s.io/kind/pkg/build/base"
+	"sigs.k8s.io/kind/pkg/util"
 )
 
 type flagpole struct {
-	Source string
-	Image  string
-	CRI    string
+	Source       string
+	Image        string
+	CRI          string
+	Arch         string
+	GoCmd        string
+	Builder      string
+	BuildArgs    []string
+	KeepBuildDir bool
+	Labels       []string
+	DryRun       bool
+	ExportPath   string
+	BuildKit     bool
+	ExtraFiles   []string
 }
 
 // NewCommand returns a new cobra.Command for building the base image
@@ -58,7 +71,57 @@ func NewCommand() *cobra.Command {
 	cmd.Flags().StringVar(
 		&flags.CRI, "cri",
 		"containerd",
-		"container runtime to be added to the image. Use one of [docker, containerd]",
+		"container runtime to be added to the image. Use one of [docker, containerd, crio]",
+	)
+	cmd.Flags().StringVar(
+		&flags.Arch, "arch",
+		util.GetArch(),
+		"architecture of the resulting image (only supported for the docker and crio container runtimes)",
+	)
+	cmd.Flags().StringVar(
+		&flags.GoCmd, "go-cmd",
+		"go",
+		"path to the go toolchain used for building the entrypoint binary (only supported for the docker and crio container runtimes)",
+	)
+	cmd.Flags().StringVar(
+		&flags.Builder, "builder",
+		base.DockerBuilder,
+		fmt.Sprintf("tool used for building the image. Use one of [%s, %s] (only supported for the docker and crio container runtimes)", base.DockerBuilder, base.PodmanBuilder),
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.BuildArgs, "build-arg",
+		nil,
+		"build-time variables in the KEY=VALUE format (only supported for the docker and crio container runtimes)",
+	)
+	cmd.Flags().BoolVar(
+		&flags.KeepBuildDir, "keep-build-dir",
+		false,
+		"preserve the build dir for debugging when the build fails (only supported for the docker and crio container runtimes)",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.Labels, "label",
+		nil,
+		"labels to be added to the image in the KEY=VALUE format (only supported for the docker and crio container runtimes)",
+	)
+	cmd.Flags().BoolVar(
+		&flags.DryRun, "dry-run",
+		false,
+		"only prints build commands, without executing them (only supported for the docker and crio container runtimes)",
+	)
+	cmd.Flags().StringVar(
+		&flags.ExportPath, "export",
+		"",
+		"path to a tarball where to export the resulting image (only supported for the docker and crio container runtimes)",
+	)
+	cmd.Flags().BoolVar(
+		&flags.BuildKit, "buildkit",
+		false,
+		"build the image using BuildKit with plain progress output (only supported for the docker and crio container runtimes)",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.ExtraFiles, "extra-file",
+		nil,
+		"extra files to be added to the build context in the DEST=SRC format, with DEST relative to the build context (only supported for the docker and crio container runtimes)",
 	)
 	return cmd
 }
@@ -75,17 +138,57 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 			return errors.Wrap(err, "build failed")
 		}
 		return nil
-	case "docker":
+	case base.DockerCRI, base.CRIOCRI:
+		buildArgs, err := parseKeyValues("build arg", flags.BuildArgs)
+		if err != nil {
+			return err
+		}
+		labels, err := parseKeyValues("label", flags.Labels)
+		if err != nil {
+			return err
+		}
+		extraFiles, err := parseKeyValues("extra file", flags.ExtraFiles)
+		if err != nil {
+			return err
+		}
+
 		// Use build base image from kinder
 		ctx := base.NewBuildContext(
+			base.WithCRI(strings.ToLower(flags.CRI)),
 			base.WithImage(flags.Image),
 			base.WithSourceDir(flags.Source),
+			base.WithArch(flags.Arch),
+			base.WithGoCmd(flags.GoCmd),
+			base.WithBuilder(flags.Builder),
+			base.WithBuildArgs(buildArgs),
+			base.WithKeepBuildDir(flags.KeepBuildDir),
+			base.WithLabels(labels),
+			base.WithDryRun(flags.DryRun),
+			base.WithExportPath(flags.ExportPath),
+			base.WithBuildKit(flags.BuildKit),
+			base.WithExtraFiles(extraFiles),
 		)
 		if err := ctx.Build(); err != nil {
 			return errors.Wrap(err, "build failed")
 		}
+		if r := ctx.Result(); r.Digest != "" {
+			log.Infof("Built image %s with digest %s", r.Image, r.Digest)
+		}
 		return nil
 	default:
-		return errors.Errorf("%s container runtime is not supported. Use one of [docker, containerd]", flags.CRI)
+		return errors.Errorf("%s container runtime is not supported. Use one of [docker, containerd, crio]", flags.CRI)
+	}
+}
+
+// parseKeyValues converts a list of KEY=VALUE strings into a map
+func parseKeyValues(kind string, values []string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, v := range values {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid %s %q. Use the KEY=VALUE format", kind, v)
+		}
+		m[parts[0]] = parts[1]
 	}
+	return m, nil
 }
@@ -20,6 +20,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/cmd/kinder/build/baseimage"
+	"k8s.io/kubeadm/kinder/cmd/kinder/build/nodeimage"
 	"k8s.io/kubeadm/kinder/cmd/kinder/build/nodevariant"
 )
 
@@ -34,6 +35,7 @@ func NewCommand() *cobra.Command {
 	}
 	// add subcommands
 	cmd.AddCommand(baseimage.NewCommand())
+	cmd.AddCommand(nodeimage.NewCommand())
 	cmd.AddCommand(nodevariant.NewCommand())
 	return cmd
 }
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeimage
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/build/node"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	kindnode "sigs.k8s.io/kind/pkg/build/node"
+)
+
+type flagpole struct {
+	BuildType  string
+	Image      string
+	BaseImage  string
+	KubeRoot   string
+	OS         string
+	Version    string
+	Containerd string
+}
+
+// NewCommand returns a new cobra.Command for building the node image
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "node-image",
+		Short: "build the node image",
+		Long:  "build the node image which contains kubernetes build artifacts and other kind requirements, starting from a base image",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+	cmd.Flags().StringVar(
+		&flags.BuildType, "type",
+		kindnode.DefaultMode,
+		"build type, one of [bazel, docker, apt]",
+	)
+	cmd.Flags().StringVar(
+		&flags.Image, "image",
+		constants.DefaultNodeImage,
+		"name:tag of the resulting image to be built",
+	)
+	cmd.Flags().StringVar(
+		&flags.KubeRoot, "kube-root",
+		"",
+		"path to the Kubernetes source directory (if empty, the path is autodetected)",
+	)
+	cmd.Flags().StringVar(
+		&flags.BaseImage, "base-image",
+		"",
+		fmt.Sprintf("name:tag of the base image to use for the build, e.g. an image built with kinder build base-image; the image is pulled if it does not exist locally. Defaults to %s for linux and %s for windows", constants.DefaultBaseImage, node.DefaultWindowsBaseImage),
+	)
+	cmd.Flags().StringVar(
+		&flags.OS, "os",
+		node.LinuxOS,
+		fmt.Sprintf("OS of the node image, one of [%s, %s]; building %s node images requires a docker daemon running Windows containers", node.LinuxOS, node.WindowsOS, node.WindowsOS),
+	)
+	cmd.Flags().StringVar(
+		&flags.Version, "kubernetes-version",
+		"",
+		"release version or release label of the Kubernetes binaries to be added to the node image (only supported for windows)",
+	)
+	cmd.Flags().StringVar(
+		&flags.Containerd, "containerd-version",
+		"",
+		"replace the containerd binaries bundled in the base image with the given containerd release version, e.g. v1.4.3 (only supported for linux)",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	ctx, err := node.NewContext(
+		node.WithMode(flags.BuildType),
+		node.WithImage(flags.Image),
+		node.WithBaseImage(flags.BaseImage),
+		node.WithKubeRoot(flags.KubeRoot),
+		node.WithOS(flags.OS),
+		node.WithKubernetesVersion(flags.Version),
+		node.WithContainerdVersion(flags.Containerd),
+	)
+	if err != nil {
+		return errors.Wrap(err, "error creating build context")
+	}
+	if err := ctx.Build(); err != nil {
+		return errors.Wrap(err, "error building node image")
+	}
+	return nil
+}
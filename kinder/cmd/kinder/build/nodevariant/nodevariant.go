@@ -17,22 +17,34 @@ limitations under the License.
 package nodevariant
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/pkg/build/alter"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/extract"
+	"k8s.io/kubeadm/kinder/pkg/metrics"
+	"sigs.k8s.io/kind/pkg/util"
 )
 
 type flagpole struct {
-	Image            string
-	BaseImage        string
-	InitArtifacts    string
-	ImageTars        []string
-	ImageNamePrefix  string
-	UpgradeArtifacts string
-	Kubeadm          string
-	Kubelet          string
+	Image             string
+	BaseImage         string
+	InitArtifacts     string
+	ImageTars         []string
+	ExtraImages       []string
+	ImageNamePrefix   string
+	UpgradeArtifacts  string
+	Kubeadm           string
+	Kubelet           string
+	Arch              string
+	SkipChecksum      bool
+	CacheDir          string
+	CacheTTL          time.Duration
+	NoCache           bool
+	ContainerdVersion string
 }
 
 // NewCommand returns a new cobra.Command for building the node image
@@ -61,13 +73,18 @@ func NewCommand() *cobra.Command {
 	cmd.Flags().StringVar(
 		&flags.InitArtifacts, "with-init-artifacts",
 		"",
-		"version/build-label/path to a folder with Kubernetes binaries & image tarballs to be used for the kubeadm init workflow",
+		"version/build-label/path to a folder with Kubernetes binaries & image tarballs or to a Kubernetes source tree to be used for the kubeadm init workflow",
 	)
 	cmd.Flags().StringSliceVar(
 		&flags.ImageTars, "with-images",
 		nil,
 		"version/build-label/path to images tar or folder with images tars to be added to the images",
 	)
+	cmd.Flags().StringSliceVar(
+		&flags.ExtraImages, "with-extra-images",
+		nil,
+		"images to be pulled from a registry and added to the image",
+	)
 	cmd.Flags().StringVar(
 		&flags.ImageNamePrefix, "image-name-prefix",
 		"",
@@ -88,6 +105,36 @@ func NewCommand() *cobra.Command {
 		"",
 		"override the kubeadm binary existing in the image with the given version/build-label/file or folder containing the kubelet binary",
 	)
+	cmd.Flags().StringVar(
+		&flags.Arch, "arch",
+		util.GetArch(),
+		"architecture of the node image; artifacts downloaded from a version/build-label or built from a Kubernetes source tree target this value, and binaries added to the image are validated against it",
+	)
+	cmd.Flags().BoolVar(
+		&flags.SkipChecksum, "skip-checksum",
+		false,
+		"skip checksum verification of artifacts downloaded from a version/build-label and of the containerd release set with --containerd-version",
+	)
+	cmd.Flags().StringVar(
+		&flags.CacheDir, "cache-dir",
+		extract.DefaultCacheDir(),
+		"folder where artifacts downloaded from a version/build-label are cached",
+	)
+	cmd.Flags().DurationVar(
+		&flags.CacheTTL, "cache-ttl",
+		extract.DefaultCacheTTL,
+		"time after which cached artifacts are downloaded again; 0 means cached artifacts never expire",
+	)
+	cmd.Flags().BoolVar(
+		&flags.NoCache, "no-cache",
+		false,
+		"bypass the cache of downloaded artifacts",
+	)
+	cmd.Flags().StringVar(
+		&flags.ContainerdVersion, "containerd-version",
+		"",
+		"replace the containerd binaries existing in the image with the given containerd release version, e.g. v1.4.3",
+	)
 	return cmd
 }
 
@@ -96,20 +143,29 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 		// base build options
 		alter.WithBaseImage(flags.BaseImage),
 		alter.WithImage(flags.Image),
+		alter.WithArch(flags.Arch),
+		alter.WithSkipChecksum(flags.SkipChecksum),
+		alter.WithCacheDir(flags.CacheDir),
+		alter.WithCacheTTL(flags.CacheTTL),
+		alter.WithNoCache(flags.NoCache),
 		// bits to be added to the image
 		alter.WithInitArtifacts(flags.InitArtifacts),
 		alter.WithKubeadm(flags.Kubeadm),
 		alter.WithKubelet(flags.Kubelet),
 		alter.WithImageTars(flags.ImageTars),
+		alter.WithExtraImages(flags.ExtraImages),
 		alter.WithUpgradeArtifacts(flags.UpgradeArtifacts),
+		alter.WithContainerdVersion(flags.ContainerdVersion),
 		// bits options
 		alter.WithImageNamePrefix(flags.ImageNamePrefix),
 	)
 	if err != nil {
 		return errors.Wrap(err, "error creating alter context")
 	}
+	start := time.Now()
 	if err := ctx.Alter(); err != nil {
 		return errors.Wrap(err, "error altering node image")
 	}
+	metrics.Since(metrics.BuildSeconds, start, "image", flags.Image)
 	return nil
 }
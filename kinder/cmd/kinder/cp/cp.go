@@ -25,7 +25,8 @@ import (
 )
 
 type flagpole struct {
-	Name string
+	Name    string
+	Archive bool
 }
 
 // NewCommand returns a new cobra.Command for exec
@@ -34,7 +35,8 @@ func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Args: cobra.ExactArgs(2),
 		Use: "cp [flags] [NODE_NAME|NODE_SELECTOR:]SRC_PATH DEST_PATH |-\n" +
-			"  kinder cp [flags] SRC_PATH [NODE_NAME|NODE_SELECTOR:]DEST_PATH\n\n" +
+			"  kinder cp [flags] SRC_PATH [NODE_NAME|NODE_SELECTOR:]DEST_PATH\n" +
+			"  kinder cp [flags] NODE_NAME|NODE_SELECTOR:SRC_PATH NODE_NAME|NODE_SELECTOR:DEST_PATH\n\n" +
 			"Args:\n" +
 			"  NODE_NAME is the container name without the cluster name prefix\n" +
 			"  NODE_SELECTOR can be one of:\n" +
@@ -44,8 +46,8 @@ func NewCommand() *cobra.Command {
 			"    @cpN 	the secondary master nodes \n" +
 			"    @w* 	all the worker nodes\n" +
 			"    @lb 	the external load balancer\n" +
-			"    @etcd 	the external etcd",
-		Short: "Copy files/folders between a node and the local filesystem",
+			"    @etcd 	the external etcd members",
+		Short: "Copy files/folders between nodes and the local filesystem",
 		Long:  "kinder cp is a \"topology aware\" wrapper on docker cp",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runE(flags, cmd, args)
@@ -56,6 +58,11 @@ func NewCommand() *cobra.Command {
 		constants.DefaultClusterName,
 		"cluster name",
 	)
+	cmd.Flags().BoolVarP(
+		&flags.Archive, "archive", "a",
+		false,
+		"archive mode (copy all uid/gid information)",
+	)
 	return cmd
 }
 
@@ -67,7 +74,7 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 	}
 
 	// execute the copy action on selected target nodes
-	err = o.CopyFile(args[0], args[1])
+	err = o.CopyFile(args[0], args[1], flags.Archive)
 	if err != nil {
 		return errors.Wrap(err, "failed to copy files")
 	}
@@ -17,18 +17,28 @@ limitations under the License.
 package cluster
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 )
 
 const (
-	controlPlaneNodesFlagName = "control-plane-nodes"
-	workerNodesFlagName       = "worker-nodes"
+	controlPlaneNodesFlagName   = "control-plane-nodes"
+	workerNodesFlagName         = "worker-nodes"
+	externalEtcdMembersFlagName = "external-etcd-members"
 )
 
+// flagAliases defines shorter aliases for the flags controlling the cluster topology
+var flagAliases = map[string]string{
+	"control-planes": controlPlaneNodesFlagName,
+	"workers":        workerNodesFlagName,
+}
+
 type flagpole struct {
 	Name                 string
 	ImageName            string
@@ -36,8 +46,27 @@ type flagpole struct {
 	ControlPlanes        int
 	Retain               bool
 	ExternalEtcd         bool
+	ExternalEtcdMembers  int
 	ExternalLoadBalancer bool
+	APIServerPort        int32
 	Volumes              []string
+	Wait                 time.Duration
+	PollInterval         time.Duration
+	MaxRetries           int
+	Idempotent           bool
+	PodSubnet            string
+	ServiceSubnet        string
+	IPFamily             string
+	RegistryMirrors      []string
+	NodeCPU              string
+	NodeMemory           string
+	CgroupDriver         string
+	APIServerArgs        []string
+	ControllerArgs       []string
+	SchedulerArgs        []string
+	PostCreateHook       string
+	Nodes                []string
+	Config               string
 }
 
 // NewCommand returns a new cobra.Command for cluster creation
@@ -82,16 +111,119 @@ func NewCommand() *cobra.Command {
 		"external-etcd", false,
 		"create an external etcd container and setup kubeadm for using it",
 	)
+	cmd.Flags().IntVar(
+		&flags.ExternalEtcdMembers,
+		externalEtcdMembersFlagName, 1,
+		"number of members of the external etcd cluster (implies --external-etcd if greater than 1)",
+	)
 	cmd.Flags().BoolVar(
 		&flags.ExternalLoadBalancer,
 		"external-load-balancer", false,
 		"add an external load balancer to the cluster (implicit if number of control-plane nodes>1)",
 	)
+	cmd.Flags().Int32Var(
+		&flags.APIServerPort,
+		"api-server-port", 0,
+		"the port on the host where the API server is exposed, that is the port of the external load balancer, if any, or of the first control-plane node; if not set, a free port is used",
+	)
 	cmd.Flags().StringSliceVar(
 		&flags.Volumes,
 		"volume", nil,
 		"mount a volume on node containers",
 	)
+	cmd.Flags().DurationVar(
+		&flags.Wait,
+		"wait", time.Duration(0),
+		"wait for Kubernetes nodes to be Ready, reading the node status through the cluster kubeconfig (0 means don't wait)",
+	)
+	cmd.Flags().DurationVar(
+		&flags.PollInterval,
+		"poll-interval", time.Duration(1*time.Second),
+		"interval between readiness probes while waiting for nodes to be Ready",
+	)
+	cmd.Flags().IntVar(
+		&flags.MaxRetries,
+		"max-retries", 0,
+		"maximum number of readiness probes while waiting for nodes to be Ready (0 means retry until the wait timeout)",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Idempotent,
+		"idempotent", false,
+		"leave in place node containers already existing and create only the missing ones",
+	)
+	cmd.Flags().StringVar(
+		&flags.PodSubnet,
+		"pod-subnet", "",
+		"pod subnet to be used by kubeadm, e.g. 10.244.0.0/16 (use comma-separated values for dual-stack)",
+	)
+	cmd.Flags().StringVar(
+		&flags.ServiceSubnet,
+		"service-subnet", "",
+		"service subnet to be used by kubeadm, e.g. 10.96.0.0/12 (use comma-separated values for dual-stack)",
+	)
+	cmd.Flags().StringVar(
+		&flags.IPFamily,
+		"ip-family", "ipv4",
+		"IP family of the cluster, ipv4, ipv6 or dual",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.RegistryMirrors,
+		"registry-mirror", nil,
+		"configure the container runtime of nodes for pulling images from a registry through mirrors, in the registry=mirror-url[,mirror-url] format, e.g. docker.io=http://mirror.local:5000",
+	)
+	cmd.Flags().StringVar(
+		&flags.NodeCPU,
+		"node-cpu", "",
+		"the CPU limit of Kubernetes node containers, in the docker run --cpus format, e.g. 1.5",
+	)
+	cmd.Flags().StringVar(
+		&flags.NodeMemory,
+		"node-memory", "",
+		"the memory limit of Kubernetes node containers, in the docker run --memory format, e.g. 2g; swap is disabled",
+	)
+	cmd.Flags().StringVar(
+		&flags.CgroupDriver,
+		"cgroup-driver", "systemd",
+		"the cgroup driver to be configured for both the kubelet and the container runtime of Kubernetes nodes, systemd or cgroupfs",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.APIServerArgs,
+		"apiserver-extra-args", nil,
+		"a key=value kube-apiserver flag to be set in the ClusterConfiguration used by kubeadm init, e.g. audit-log-maxage=2 (can be repeated)",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.ControllerArgs,
+		"controller-manager-extra-args", nil,
+		"a key=value kube-controller-manager flag to be set in the ClusterConfiguration used by kubeadm init, e.g. node-monitor-grace-period=20s (can be repeated)",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.SchedulerArgs,
+		"scheduler-extra-args", nil,
+		"a key=value kube-scheduler flag to be set in the ClusterConfiguration used by kubeadm init, e.g. v=4 (can be repeated)",
+	)
+	cmd.Flags().StringVar(
+		&flags.PostCreateHook,
+		"post-create-hook", "",
+		"a script to be copied and executed inside each Kubernetes node after creation, with the node name and role as arguments; create fails if the script fails on any node",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.Nodes,
+		"node", nil,
+		"set the image or the version of a specific node, in the name=worker-2,image=kindest/node:v1.17.0 or name=worker-2,version=v1.17.0 format (can be repeated)",
+	)
+	cmd.Flags().StringVar(
+		&flags.Config,
+		"config", "",
+		"a file describing the cluster topology, with per-node role, image, version, cri and kubelet extra args; flags explicitly set override matching config fields",
+	)
+
+	// allows to use e.g. --workers 3 instead of --worker-nodes 3
+	cmd.Flags().SetNormalizeFunc(func(f *flag.FlagSet, name string) flag.NormalizedName {
+		if alias, ok := flagAliases[name]; ok {
+			name = alias
+		}
+		return flag.NormalizedName(name)
+	})
 
 	return cmd
 }
@@ -102,18 +234,63 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 	if flags.ControlPlanes < 0 || flags.Workers < 0 {
 		return errors.Errorf("flags --%s and --%s should not be a negative number", controlPlaneNodesFlagName, workerNodesFlagName)
 	}
+	if flags.ExternalEtcdMembers < 1 {
+		return errors.Errorf("flag --%s should be a positive number", externalEtcdMembersFlagName)
+	}
+
+	// options from flags, keyed by flag name
+	flagOptions := []struct {
+		name   string
+		option manager.CreateOption
+	}{
+		{controlPlaneNodesFlagName, manager.ControlPlanes(flags.ControlPlanes)},
+		{workerNodesFlagName, manager.Workers(flags.Workers)},
+		{"image", manager.Image(flags.ImageName)},
+		{"external-load-balancer", manager.ExternalLoadBalancer(flags.ExternalLoadBalancer)},
+		{"external-etcd", manager.ExternalEtcd(flags.ExternalEtcd)},
+		{externalEtcdMembersFlagName, manager.ExternalEtcdMembers(flags.ExternalEtcdMembers)},
+		{"api-server-port", manager.APIServerPort(flags.APIServerPort)},
+		{"retain", manager.Retain(flags.Retain)},
+		{"volume", manager.Volumes(flags.Volumes)},
+		{"wait", manager.Wait(flags.Wait)},
+		{"poll-interval", manager.PollInterval(flags.PollInterval)},
+		{"max-retries", manager.MaxRetries(flags.MaxRetries)},
+		{"idempotent", manager.Idempotent(flags.Idempotent)},
+		{"pod-subnet", manager.PodSubnet(flags.PodSubnet)},
+		{"service-subnet", manager.ServiceSubnet(flags.ServiceSubnet)},
+		{"ip-family", manager.IPFamily(flags.IPFamily)},
+		{"registry-mirror", manager.RegistryMirrors(flags.RegistryMirrors)},
+		{"node-cpu", manager.NodeCPU(flags.NodeCPU)},
+		{"node-memory", manager.NodeMemory(flags.NodeMemory)},
+		{"cgroup-driver", manager.CgroupDriver(flags.CgroupDriver)},
+		{"apiserver-extra-args", manager.APIServerExtraArgs(flags.APIServerArgs)},
+		{"controller-manager-extra-args", manager.ControllerManagerExtraArgs(flags.ControllerArgs)},
+		{"scheduler-extra-args", manager.SchedulerExtraArgs(flags.SchedulerArgs)},
+		{"post-create-hook", manager.PostCreateHook(flags.PostCreateHook)},
+		{"node", manager.NodeOverrides(flags.Nodes)},
+	}
+
+	// flag values (or defaults) are used first; if a config file is provided, config fields are applied
+	// on top, and then flags explicitly set on the command line override matching config fields
+	options := []manager.CreateOption{}
+	for _, f := range flagOptions {
+		options = append(options, f.option)
+	}
+	if flags.Config != "" {
+		cfg, err := manager.LoadClusterConfig(flags.Config)
+		if err != nil {
+			return err
+		}
+		options = append(options, cfg.Options()...)
+		for _, f := range flagOptions {
+			if cmd.Flags().Changed(f.name) {
+				options = append(options, f.option)
+			}
+		}
+	}
 
 	// get a kinder cluster manager
-	if err = manager.CreateCluster(
-		flags.Name,
-		manager.ControlPlanes(flags.ControlPlanes),
-		manager.Workers(flags.Workers),
-		manager.Image(flags.ImageName),
-		manager.ExternalLoadBalancer(flags.ExternalLoadBalancer),
-		manager.ExternalEtcd(flags.ExternalEtcd),
-		manager.Retain(flags.Retain),
-		manager.Volumes(flags.Volumes),
-	); err != nil {
+	if err = manager.CreateCluster(flags.Name, options...); err != nil {
 		return errors.Wrap(err, "failed to create cluster")
 	}
 
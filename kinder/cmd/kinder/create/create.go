@@ -20,6 +20,7 @@ import (
 	"github.com/spf13/cobra"
 
 	createcluster "k8s.io/kubeadm/kinder/cmd/kinder/create/cluster"
+	createregistry "k8s.io/kubeadm/kinder/cmd/kinder/create/registry"
 )
 
 // NewCommand returns a new cobra.Command for cluster creation
@@ -27,9 +28,10 @@ func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Args:  cobra.NoArgs,
 		Use:   "create",
-		Short: "Creates one of [cluster]",
-		Long:  "Creates one of local Kubernetes cluster (cluster)",
+		Short: "Creates one of [cluster, registry]",
+		Long:  "Creates one of local Kubernetes cluster (cluster) or local registry (registry)",
 	}
 	cmd.AddCommand(createcluster.NewCommand())
+	cmd.AddCommand(createregistry.NewCommand())
 	return cmd
 }
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+type flagpole struct {
+	Name  string
+	Image string
+	Port  int
+}
+
+// NewCommand returns a new cobra.Command for registry creation
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "registry",
+		Short: "Creates a local registry shared by kinder clusters",
+		Long: "Runs a local registry container on the docker network of the nodes, if not already running, and configures\n" +
+			"the container runtime of the cluster nodes for pulling images from it; the registry is reused by all the clusters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&flags.Name,
+		"name", constants.DefaultClusterName,
+		"name of the cluster to be configured for pulling images from the registry",
+	)
+	cmd.Flags().StringVar(
+		&flags.Image,
+		"image", constants.RegistryImage,
+		"the registry image, used only if the registry container does not exists yet",
+	)
+	cmd.Flags().IntVar(
+		&flags.Port,
+		"port", constants.RegistryPort,
+		"the port on the host where the registry is exposed, used only if the registry container does not exists yet (a warning is printed if it does not match the port of an existing registry)",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	options := []manager.RegistryOption{
+		manager.RegistryImage(flags.Image),
+	}
+	// nb. the port is passed only if explicitly set, so create registry can warn when it does not match the port
+	// of an existing registry
+	if cmd.Flags().Changed("port") {
+		options = append(options, manager.RegistryPort(flags.Port))
+	}
+	return manager.CreateRegistry(flags.Name, options...)
+}
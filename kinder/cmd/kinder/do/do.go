@@ -28,6 +28,7 @@ import (
 	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
 	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
 )
 
 type flagpole struct {
@@ -41,7 +42,44 @@ type flagpole struct {
 	DryRun             bool
 	VLevel             int
 	KustomizeDir       string
+	PatchesDir         string
+	DiffConfig         bool
 	Wait               time.Duration
+	RestartStaticPods  bool
+	FeatureGates       string
+	APIServerArgs      []string
+	ControllerArgs     []string
+	SchedulerArgs      []string
+	EtcdArgs           []string
+	EtcdDataDir        string
+	ConfigTemplates    string
+	Downtime           time.Duration
+	Delay              time.Duration
+	Loss               string
+	KubeadmDryRun      bool
+	KubeletExtraArgs   []string
+	Parallel           int
+	CNIProvider        string
+	CNIManifest        string
+	CNIVersion         string
+	SkipSkewCheck      bool
+	SkipCgroupCheck    bool
+	PollInterval       time.Duration
+	MaxRetries         int
+	List               bool
+	Out                string
+	Compare            string
+	TokenTTL           time.Duration
+	DeleteOnly         bool
+	GracePeriod        int
+	SkipPhases         string
+	Expect             []string
+	Keep               bool
+	CleanCNI           bool
+	AuditPolicy        string
+	From               string
+	SwapSize           int
+	SwapOff            bool
 }
 
 // NewCommand returns a new cobra.Command for exec
@@ -50,10 +88,13 @@ func NewCommand() *cobra.Command {
 		Discovery: string(actions.TokenDiscovery),
 	}
 	cmd := &cobra.Command{
-		Args: cobra.ExactArgs(1),
-		Use: "do [flags] ACTION\n\n" +
+		Args: cobra.RangeArgs(1, 2),
+		Use: "do [flags] ACTION [PHASE|NODE]\n\n" +
 			"Args:\n" +
-			fmt.Sprintf("  ACTION is one of %s", actions.KnownActions()),
+			fmt.Sprintf("  ACTION is one of %s\n", actions.KnownActions()) +
+			"  PHASE is the kubeadm init phase to be executed by kubeadm-init-phase, e.g. certs/apiserver\n" +
+			"  NODE is the worker node to be removed by drain-and-delete, the control-plane node for backup-etcd and\n" +
+			"  restore-etcd or the node for set-swap, as an alternative to --only-node",
 		Short: "Executes actions (tasks/sequence of commands) on a cluster",
 		Long: "Action define a set of tasks/sequence of commands to be executed on a cluster. Usage of actions allows \n" +
 			"to automate repetitive operations.",
@@ -104,6 +145,86 @@ func NewCommand() *cobra.Command {
 		"wait", time.Duration(5*time.Minute),
 		"Wait for cluster state to converge after action",
 	)
+	cmd.Flags().DurationVar(
+		&flags.PollInterval,
+		"poll-interval", time.Duration(0),
+		"interval between readiness probes while waiting for cluster state to converge (0 means the default of each action, 1s or 2s for wait-control-plane)",
+	)
+	cmd.Flags().IntVar(
+		&flags.MaxRetries,
+		"max-retries", 0,
+		"maximum number of readiness probes while waiting for cluster state to converge (0 means retry until the wait timeout)",
+	)
+	cmd.Flags().BoolVar(
+		&flags.List,
+		"list", false,
+		"list the phases supported by kubeadm init for kubeadm-init-phase, or the bootstrap tokens for rotate-token, instead of executing the action",
+	)
+	cmd.Flags().StringVar(
+		&flags.Out,
+		"out", "",
+		"the folder where snapshot-manifests copies the static pod manifests, or the file where backup-etcd saves the etcd snapshot",
+	)
+	cmd.Flags().StringVar(
+		&flags.Compare,
+		"compare", "",
+		"the folder with a snapshot of static pod manifests to be compared by snapshot-manifests",
+	)
+	cmd.Flags().DurationVar(
+		&flags.TokenTTL,
+		"token-ttl", 24*time.Hour,
+		"the TTL of the bootstrap token created by rotate-token, e.g. 1s for testing joins with an expired token (0 means never expire)",
+	)
+	cmd.Flags().BoolVar(
+		&flags.DeleteOnly,
+		"delete-only", false,
+		"delete the bootstrap token in rotate-token without creating it again",
+	)
+	cmd.Flags().IntVar(
+		&flags.GracePeriod,
+		"grace-period", -1,
+		"period of time in seconds given to each pod to terminate gracefully when draining a node with drain-and-delete (-1 means the pod default)",
+	)
+	cmd.Flags().StringVar(
+		&flags.SkipPhases,
+		"skip-phases", "",
+		"a comma-separated list of kubeadm phases to be skipped by kubeadm-init and kubeadm-join, e.g. addon/kube-proxy",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.Expect,
+		"expect", nil,
+		"a key=value assertion on the ClusterConfiguration checked by assert-config, where key is a jsonpath or a dotted path, e.g. networking.podSubnet=10.244.0.0/16 (can be repeated)",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Keep,
+		"keep", false,
+		"leave in place the deployment and the service created by smoke-test",
+	)
+	cmd.Flags().BoolVar(
+		&flags.CleanCNI,
+		"clean-cni", false,
+		"remove the CNI configuration left in place by kubeadm-reset, after verifying the reset cleanup",
+	)
+	cmd.Flags().StringVar(
+		&flags.AuditPolicy,
+		"policy", "",
+		"the audit policy file on the host used by enable-audit",
+	)
+	cmd.Flags().StringVar(
+		&flags.From,
+		"from", "",
+		"the etcd snapshot file on the host used by restore-etcd",
+	)
+	cmd.Flags().IntVar(
+		&flags.SwapSize,
+		"swap-size", 0,
+		"the size in MB of the swap file provisioned by set-swap",
+	)
+	cmd.Flags().BoolVar(
+		&flags.SwapOff,
+		"swap-off", false,
+		"disable swap with set-swap, removing the swap file",
+	)
 	cmd.Flags().IntVarP(
 		&flags.VLevel,
 		"kubeadm-verbosity", "v", 0,
@@ -114,6 +235,111 @@ func NewCommand() *cobra.Command {
 		"kustomize-dir", "k", flags.KustomizeDir,
 		"the kustomize folder to be used for init,join and upgrade",
 	)
+	cmd.Flags().StringVar(
+		&flags.PatchesDir,
+		"patches", flags.PatchesDir,
+		"the folder with kubeadm patches to be used for init and join",
+	)
+	cmd.Flags().BoolVar(
+		&flags.DiffConfig,
+		"diff", flags.DiffConfig,
+		"shows differences between the generated kubeadm config and the kubeadm-config ConfigMap",
+	)
+	cmd.Flags().BoolVar(
+		&flags.RestartStaticPods,
+		"restart-static-pods", false,
+		"restart control-plane static pods after kubeadm-certs-renew",
+	)
+	cmd.Flags().StringVar(
+		&flags.FeatureGates,
+		"feature-gates", "",
+		"a set of key=bool pairs describing the kubeadm feature gates to be used for init, e.g. IPv6DualStack=true",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.APIServerArgs,
+		"apiserver-extra-args", nil,
+		"a key=value kube-apiserver flag to be set in the ClusterConfiguration for init, e.g. audit-log-maxage=2 (can be repeated)",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.ControllerArgs,
+		"controller-manager-extra-args", nil,
+		"a key=value kube-controller-manager flag to be set in the ClusterConfiguration for init, e.g. node-monitor-grace-period=20s (can be repeated)",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.SchedulerArgs,
+		"scheduler-extra-args", nil,
+		"a key=value kube-scheduler flag to be set in the ClusterConfiguration for init, e.g. v=4 (can be repeated)",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.EtcdArgs,
+		"etcd-extra-args", nil,
+		"a key=value local etcd flag to be set in the ClusterConfiguration for init, e.g. quota-backend-bytes=16777216 (can be repeated)",
+	)
+	cmd.Flags().StringVar(
+		&flags.EtcdDataDir,
+		"etcd-data-dir", "",
+		"the data dir of the local etcd to be set in the ClusterConfiguration for init, e.g. /var/lib/etcd-test",
+	)
+	cmd.Flags().StringVar(
+		&flags.ConfigTemplates,
+		"config-templates", "",
+		"a directory with kubeadm config templates overriding the embedded ones, one file for each config API version, e.g. v1beta2.yaml",
+	)
+	cmd.Flags().DurationVar(
+		&flags.Downtime,
+		"downtime", time.Duration(30*time.Second),
+		"how long the control-plane node is kept down by simulate-cp-failure",
+	)
+	cmd.Flags().DurationVar(
+		&flags.Delay,
+		"delay", 0,
+		"the latency added by netem to the outgoing traffic of nodes, e.g. 100ms",
+	)
+	cmd.Flags().StringVar(
+		&flags.Loss,
+		"loss", "",
+		"the packet loss percentage added by netem to the outgoing traffic of nodes, e.g. 5%",
+	)
+	cmd.Flags().BoolVar(
+		&flags.KubeadmDryRun,
+		"kubeadm-dry-run", false,
+		"execute kubeadm init with the --dry-run flag and copy the rendered files on the host, without applying changes",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.KubeletExtraArgs,
+		"kubelet-extra-args", nil,
+		"a key=value kubelet flag to be set on nodes for init and join, e.g. eviction-hard=memory.available<5%; use --only-node to target a specific node (can be repeated)",
+	)
+	cmd.Flags().StringVar(
+		&flags.CNIProvider,
+		"provider", string(actions.CalicoCNI),
+		fmt.Sprintf("the CNI plugin to be installed by kubeadm-init and install-cni; use one of %s", actions.KnownCNIProviders()),
+	)
+	cmd.Flags().StringVar(
+		&flags.CNIManifest,
+		"cni-manifest", "",
+		"an URL or a file on the host overriding the default manifest of the CNI plugin",
+	)
+	cmd.Flags().StringVar(
+		&flags.CNIVersion,
+		"cni-version", "",
+		"the version of the CNI plugin manifest to be fetched, e.g. v3.8 for Calico (by default kinder uses a bundled Calico manifest)",
+	)
+	cmd.Flags().BoolVar(
+		&flags.SkipSkewCheck,
+		"skip-skew-check", false,
+		"skip the version skew check executed before kubeadm-join, kubeadm-upgrade and upgrade, e.g. for testing kubeadm skew rejection",
+	)
+	cmd.Flags().BoolVar(
+		&flags.SkipCgroupCheck,
+		"skip-cgroup-driver-check", false,
+		"skip the check executed before kubeadm-init and kubeadm-join ensuring the kubelet and the container runtime use the same cgroup driver, e.g. for testing mismatched cgroup drivers",
+	)
+	cmd.Flags().IntVar(
+		&flags.Parallel,
+		"parallel", 1,
+		"the maximum number of nodes processed in parallel by kubeadm-join (workers), kubeadm-upgrade and upgrade (workers), kubeadm-reset, netem and netem-clear",
+	)
 	return cmd
 }
 
@@ -132,6 +358,58 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) (err error) {
 		return err
 	}
 
+	featureGates, err := actions.ParseFeatureGates(flags.FeatureGates)
+	if err != nil {
+		return err
+	}
+
+	kubeletExtraArgs, err := actions.ParseKubeletExtraArgs(flags.KubeletExtraArgs)
+	if err != nil {
+		return err
+	}
+
+	extraArgs, err := actions.ParseAllControlPlaneExtraArgs(flags.APIServerArgs, flags.ControllerArgs, flags.SchedulerArgs)
+	if err != nil {
+		return err
+	}
+	if extraArgs.Etcd, err = actions.ParseEtcdExtraArgs(flags.EtcdDataDir, flags.EtcdArgs); err != nil {
+		return err
+	}
+	extraArgs.EtcdDataDir = flags.EtcdDataDir
+
+	if flags.ConfigTemplates != "" {
+		if err := kubeadm.ValidateConfigTemplates(flags.ConfigTemplates); err != nil {
+			return err
+		}
+	}
+
+	cniProvider := actions.CNIProvider(strings.ToLower(flags.CNIProvider))
+	if err := actions.ValidateCNIProvider(cniProvider); err != nil {
+		return err
+	}
+
+	if flags.Parallel < 1 {
+		return errors.Errorf("invalid --parallel %d, it must be greater than 0", flags.Parallel)
+	}
+
+	// gets the requested action, and the phase for kubeadm-init-phase or the node for drain-and-delete, backup-etcd,
+	// restore-etcd and set-swap
+	action := args[0]
+	phase := ""
+	if len(args) > 1 {
+		switch action {
+		case "kubeadm-init-phase":
+			phase = args[1]
+		case "drain-and-delete", "backup-etcd", "restore-etcd", "set-swap":
+			if flags.OnlyNode != "" && flags.OnlyNode != args[1] {
+				return errors.Errorf("node %q does not match --only-node %q", args[1], flags.OnlyNode)
+			}
+			flags.OnlyNode = args[1]
+		default:
+			return errors.Errorf("unexpected argument %q, only kubeadm-init-phase accepts a phase and drain-and-delete, backup-etcd, restore-etcd and set-swap a node", args[1])
+		}
+	}
+
 	// get a kinder cluster manager
 	o, err := manager.NewClusterManager(flags.Name)
 	if err != nil {
@@ -151,7 +429,6 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) (err error) {
 	}
 
 	// executed the requested action
-	action := args[0]
 	err = o.DoAction(action,
 		actions.UsePhases(flags.UsePhases),
 		actions.AutomaticCopyCerts(flags.AutomaticCopyCerts),
@@ -161,6 +438,42 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) (err error) {
 		actions.UpgradeVersion(upgradeVersion),
 		actions.VLevel(flags.VLevel),
 		actions.KustomizeDir(flags.KustomizeDir),
+		actions.PatchesDir(flags.PatchesDir),
+		actions.DiffConfig(flags.DiffConfig),
+		actions.RestartStaticPods(flags.RestartStaticPods),
+		actions.FeatureGates(featureGates),
+		actions.ExtraArgs(extraArgs),
+		actions.ConfigTemplates(flags.ConfigTemplates),
+		actions.Downtime(flags.Downtime),
+		actions.Delay(flags.Delay),
+		actions.Loss(flags.Loss),
+		actions.KubeadmDryRun(flags.KubeadmDryRun),
+		actions.KubeletExtraArgs(kubeletExtraArgs),
+		actions.Parallel(flags.Parallel),
+		actions.SkipSkewCheck(flags.SkipSkewCheck),
+		actions.SkipCgroupCheck(flags.SkipCgroupCheck),
+		actions.PollInterval(flags.PollInterval),
+		actions.MaxRetries(flags.MaxRetries),
+		actions.Phase(phase),
+		actions.List(flags.List),
+		actions.Out(flags.Out),
+		actions.Compare(flags.Compare),
+		actions.TokenTTL(flags.TokenTTL),
+		actions.DeleteOnly(flags.DeleteOnly),
+		actions.GracePeriod(flags.GracePeriod),
+		actions.SkipPhases(actions.ParseSkipPhases(flags.SkipPhases)),
+		actions.Expect(flags.Expect),
+		actions.Keep(flags.Keep),
+		actions.CleanCNI(flags.CleanCNI),
+		actions.AuditPolicy(flags.AuditPolicy),
+		actions.From(flags.From),
+		actions.SwapSize(flags.SwapSize),
+		actions.SwapOff(flags.SwapOff),
+		actions.CNI(actions.CNISpec{
+			Provider: cniProvider,
+			Manifest: flags.CNIManifest,
+			Version:  flags.CNIVersion,
+		}),
 	)
 	if err != nil {
 		return errors.Wrapf(err, "failed to exec action %s", action)
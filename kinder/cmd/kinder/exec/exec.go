@@ -17,6 +17,9 @@ limitations under the License.
 package exec
 
 import (
+	"os"
+	osexec "os/exec"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
@@ -25,14 +28,16 @@ import (
 )
 
 type flagpole struct {
-	Name string
+	Name        string
+	Interactive bool
+	TTY         bool
 }
 
 // NewCommand returns a new cobra.Command for exec
 func NewCommand() *cobra.Command {
 	flags := &flagpole{}
 	cmd := &cobra.Command{
-		Args: cobra.MinimumNArgs(2),
+		Args: cobra.MinimumNArgs(1),
 		Use: "exec [flags] NODE_NAME|NODE_SELECTOR -- COMMAND [ARG...]>\n\n" +
 			"Args:\n" +
 			"  NODE_NAME is the container name without the cluster name prefix\n" +
@@ -43,7 +48,8 @@ func NewCommand() *cobra.Command {
 			"    @cpN 	the secondary master nodes \n" +
 			"    @w* 	all the worker nodes\n" +
 			"    @lb 	the external load balancer\n" +
-			"    @etcd 	the external etcd",
+			"    @etcd 	the external etcd members\n" +
+			"  COMMAND defaults to /bin/bash when using both --interactive and --tty",
 		Short: "Executes command on one or more nodes in the local Kubernetes cluster",
 		Long:  "Exec is a \"topology aware\" wrapper on docker exec, allowing to run command on one or more nodes in the local Kubernetes cluster\n",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -55,6 +61,16 @@ func NewCommand() *cobra.Command {
 		"name", constants.DefaultClusterName,
 		"cluster name",
 	)
+	cmd.Flags().BoolVarP(
+		&flags.Interactive,
+		"interactive", "i", false,
+		"keep STDIN open, e.g. for running an interactive shell; requires a single target node",
+	)
+	cmd.Flags().BoolVarP(
+		&flags.TTY,
+		"tty", "t", false,
+		"allocate a TTY, e.g. for running an interactive shell; requires a single target node",
+	)
 	return cmd
 }
 
@@ -65,9 +81,22 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 		return errors.Wrapf(err, "failed to create create a kinder cluster manager for %s", flags.Name)
 	}
 
+	// if no command is provided, defaults to an interactive shell
+	command := args[1:]
+	if len(command) == 0 {
+		if !flags.Interactive || !flags.TTY {
+			return errors.New("a command is required, unless using both --interactive and --tty")
+		}
+		command = []string{"/bin/bash"}
+	}
+
 	// execute the command on selected target nodes
-	err = o.ExecCommand(args[0], args[1:])
+	err = o.ExecCommand(args[0], command, flags.Interactive, flags.TTY)
 	if err != nil {
+		// forwards the exit code of interactive commands
+		if exitErr, ok := errors.Cause(err).(*osexec.ExitError); ok && (flags.Interactive || flags.TTY) {
+			os.Exit(exitErr.ExitCode())
+		}
 		return errors.Wrap(err, "failed to exec command")
 	}
 
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifacts
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+type flagpole struct {
+	Name string
+	Out  string
+}
+
+// NewCommand returns a new cobra.Command for exporting diagnostic artifacts from a cluster
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+
+	cmd := &cobra.Command{
+		Args:  cobra.MaximumNArgs(1),
+		Use:   "artifacts [CLUSTER_NAME] --out DIR",
+		Short: "Collects logs and other diagnostic artifacts from the nodes of a running cluster",
+		Long: "Collects logs and other diagnostic artifacts from the nodes of a running cluster into DIR, one sub folder for each node;\n" +
+			"the cluster name can be passed as an argument or using --name.\n\n" +
+			"Artifacts include the output of the node container, the journal, kubelet and container runtime logs, containers and images in\n" +
+			"the container runtime, container logs, the kubeadm config and the content of the /etc/kubernetes folder",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&flags.Name,
+		"name", constants.DefaultClusterName, "cluster name",
+	)
+	cmd.Flags().StringVar(
+		&flags.Out,
+		"out", "", "folder where artifacts should be stored",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	name := flags.Name
+	if len(args) == 1 {
+		name = args[0]
+	}
+
+	if flags.Out == "" {
+		return errors.New("the --out flag is required")
+	}
+
+	// nb. the cluster status is read without validating it, so it is possible to collect artifacts
+	// also from partially created or broken clusters
+	cluster, err := status.FromDocker(name)
+	if err != nil {
+		return err
+	}
+	if len(cluster.AllNodes()) == 0 {
+		return errors.Errorf("a cluster with the name %q does not exists", name)
+	}
+
+	return actions.CollectArtifacts(cluster, flags.Out)
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package export implements the `export` command
+package export
+
+import (
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/cmd/kinder/export/artifacts"
+	kindlogs "sigs.k8s.io/kind/cmd/kind/export/logs"
+)
+
+// NewCommand returns a new cobra.Command for export
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "export",
+		Short: "Exports one of [logs, artifacts]",
+		Long:  "Exports one of [logs, artifacts]",
+	}
+
+	// add kind top level subcommands re-used without changes
+	cmd.AddCommand(kindlogs.NewCommand())
+
+	// add kinder only commands
+	cmd.AddCommand(artifacts.NewCommand())
+	return cmd
+}
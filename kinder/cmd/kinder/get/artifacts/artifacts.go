@@ -18,6 +18,7 @@ package artifacts
 
 import (
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -38,6 +39,10 @@ type flagpole struct {
 	OnlyKubelet  bool
 	OnlyBinaries bool
 	OnlyImages   bool
+	SkipChecksum bool
+	CacheDir     string
+	CacheTTL     time.Duration
+	NoCache      bool
 }
 
 // NewCommand returns a new cobra.Command for exec
@@ -81,6 +86,23 @@ func NewCommand() *cobra.Command {
 		"Gets only the kube-apiserver, kube-scheduler, kube-controller-manager and kube-proxy image tarballs (instead of all artifacts)",
 	)
 
+	cmd.Flags().BoolVar(&flags.SkipChecksum,
+		"skip-checksum", false,
+		"Skip checksum verification of downloaded artifacts",
+	)
+	cmd.Flags().StringVar(&flags.CacheDir,
+		"cache-dir", extract.DefaultCacheDir(),
+		"Folder where artifacts downloaded from release or ci builds are cached",
+	)
+	cmd.Flags().DurationVar(&flags.CacheTTL,
+		"cache-ttl", extract.DefaultCacheTTL,
+		"Time after which cached artifacts are downloaded again; 0 means cached artifacts never expire",
+	)
+	cmd.Flags().BoolVar(&flags.NoCache,
+		"no-cache", false,
+		"Bypass the cache of downloaded artifacts",
+	)
+
 	return cmd
 }
 
@@ -104,6 +126,10 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 		extract.OnlyKubelet(flags.OnlyKubelet),
 		extract.OnlyKubernetesBinaries(flags.OnlyBinaries),
 		extract.OnlyKubernetesImages(flags.OnlyImages),
+		extract.SkipChecksum(flags.SkipChecksum),
+		extract.WithCacheDir(flags.CacheDir),
+		extract.WithCacheTTL(flags.CacheTTL),
+		extract.NoCache(flags.NoCache),
 	)
 
 	// Extracts the artifacts from the source
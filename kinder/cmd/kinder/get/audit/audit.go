@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/audit"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	ksigsyaml "sigs.k8s.io/yaml"
+)
+
+type flagpole struct {
+	Name   string
+	Output string
+	Out    string
+}
+
+// NewCommand returns a new cobra.Command for getting the kubeadm audit log of a cluster
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.MaximumNArgs(1),
+		Use:   "audit [NAME]",
+		Short: "Prints the kubeadm commands executed on the nodes of a cluster",
+		Long: "Prints the kubeadm commands executed by kinder on the nodes of a cluster, with args, output, exit code and duration;\n" +
+			"commands are recorded only when kinder is executed with the --audit flag",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&flags.Name,
+		"name", constants.DefaultClusterName,
+		"cluster name; it can be passed also as an argument",
+	)
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output", "o", "",
+		"output format, one of json or yaml; if not set, commands and their output are printed as text",
+	)
+	cmd.Flags().StringVar(
+		&flags.Out,
+		"out", "",
+		"export the audit log to a file instead of printing it",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	output := strings.ToLower(flags.Output)
+	if output != "" && output != "json" && output != "yaml" {
+		return errors.Errorf("invalid output format %q. Use one of json, yaml", flags.Output)
+	}
+
+	name := flags.Name
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	entries, err := audit.Read(name)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if flags.Out != "" {
+		f, err := os.Create(flags.Out)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create %s", flags.Out)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := printEntries(w, entries, output); err != nil {
+		return err
+	}
+	if flags.Out != "" {
+		fmt.Printf("Audit log for cluster %q exported to %s (%d kubeadm commands)\n", name, flags.Out, len(entries))
+	}
+	return nil
+}
+
+// printEntries prints the audit log entries in the given format
+func printEntries(w io.Writer, entries []audit.Entry, output string) error {
+	switch output {
+	case "json":
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to encode the audit log")
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case "yaml":
+		b, err := ksigsyaml.Marshal(entries)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode the audit log")
+		}
+		_, err = fmt.Fprint(w, string(b))
+		return err
+	}
+
+	for _, e := range entries {
+		fmt.Fprintf(w, "[%s] %s:$ %s %s\n", e.Time.Format(time.RFC3339), e.Node, e.Command, strings.Join(e.Args, " "))
+		fmt.Fprintf(w, "exit code %d, duration %.3fs\n", e.ExitCode, e.DurationSeconds)
+		if e.Stdout != "" {
+			fmt.Fprintf(w, "--- stdout\n%s\n", strings.TrimRight(e.Stdout, "\n"))
+		}
+		if e.Stderr != "" {
+			fmt.Fprintf(w, "--- stderr\n%s\n", strings.TrimRight(e.Stderr, "\n"))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+type flagpole struct {
+	Name string
+	Node string
+	Out  string
+}
+
+// NewCommand returns a new cobra.Command for getting the kube-apiserver audit log from a control-plane node
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.MaximumNArgs(1),
+		Use:   "audit-log [NAME]",
+		Short: "Prints the kube-apiserver audit log of a control-plane node",
+		Long: "Prints the kube-apiserver audit log written on a control-plane node of a cluster;\n" +
+			"the audit log is written only after enabling audit with kinder do enable-audit --policy <file>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&flags.Name,
+		"name", constants.DefaultClusterName,
+		"cluster name; it can be passed also as an argument",
+	)
+	cmd.Flags().StringVar(
+		&flags.Node,
+		"node", "@cp1",
+		"the control-plane node to fetch the audit log from, e.g. control-plane-2",
+	)
+	cmd.Flags().StringVar(
+		&flags.Out,
+		"out", "",
+		"write the audit log to a file instead of printing it",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	name := flags.Name
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	o, err := manager.NewClusterManager(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create cluster manager for %s", name)
+	}
+
+	nodes, err := o.Cluster.SelectNodes(flags.Node)
+	if err != nil {
+		return err
+	}
+	if len(nodes) != 1 {
+		return errors.Errorf("--node %q should select one control-plane node", flags.Node)
+	}
+
+	lines, err := actions.AuditLog(nodes[0])
+	if err != nil {
+		return err
+	}
+
+	if flags.Out == "" {
+		for _, l := range lines {
+			fmt.Println(l)
+		}
+		return nil
+	}
+
+	if err := ioutil.WriteFile(flags.Out, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", flags.Out)
+	}
+	fmt.Printf("Audit log of node %s exported to %s (%d events)\n", nodes[0].Name(), flags.Out, len(lines))
+	return nil
+}
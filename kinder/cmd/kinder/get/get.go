@@ -20,7 +20,11 @@ import (
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/artifacts"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/audit"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/auditlog"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/clusters"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/images"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/kubeconfig"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/kubeconfigpath"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/nodes"
 )
@@ -30,8 +34,8 @@ func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Args:  cobra.NoArgs,
 		Use:   "get",
-		Short: "Gets one of [clusters, nodes, kubeconfig-path, artifacts]",
-		Long:  "Gets one of [clusters, nodes, kubeconfig-path, artifacts]",
+		Short: "Gets one of [clusters, nodes, kubeconfig, kubeconfig-path, artifacts, images, audit, audit-log]",
+		Long:  "Gets one of [clusters, nodes, kubeconfig, kubeconfig-path, artifacts, images, audit, audit-log]",
 	}
 
 	cmd.AddCommand(clusters.NewCommand())
@@ -42,5 +46,9 @@ func NewCommand() *cobra.Command {
 
 	// add kinder only commands
 	cmd.AddCommand(artifacts.NewCommand())
+	cmd.AddCommand(audit.NewCommand())
+	cmd.AddCommand(auditlog.NewCommand())
+	cmd.AddCommand(images.NewCommand())
+	cmd.AddCommand(kubeconfig.NewCommand())
 	return cmd
 }
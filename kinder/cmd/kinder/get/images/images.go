@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cri"
+)
+
+// NewCommand returns a new cobra.Command for getting the list of images bundled into a node image
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Use:   "images IMAGE",
+		Short: "Lists images bundled into a node image",
+		Long: "Lists images bundled into a node image, including both image tarballs that will be loaded at create time\n" +
+			"and images already available in the container runtime store of the node image",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(cmd, args)
+		},
+	}
+	return cmd
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	runtime, images, err := cri.ImagesInImage(args[0])
+	if err != nil {
+		return err
+	}
+	log.Infof("Detected %s container runtime for image %s", runtime, args[0])
+
+	for _, i := range images {
+		fmt.Println(i)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+type flagpole struct {
+	Name     string
+	Internal bool
+	Output   string
+	Merge    bool
+}
+
+// NewCommand returns a new cobra.Command for getting the kubeconfig of a cluster
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+
+	cmd := &cobra.Command{
+		Args:  cobra.MaximumNArgs(1),
+		Use:   "kubeconfig [CLUSTER_NAME]",
+		Short: "Prints the kubeconfig for the kind cluster by name",
+		Long: "Prints the kubeconfig for the kind cluster by name; the cluster name can be passed as an argument or using --name.\n\n" +
+			"By default the kubeconfig uses the API server port exposed on the host; use --internal for getting a kubeconfig\n" +
+			"with the API server address on the container network, e.g. for clients running in pods or in other containers.\n\n" +
+			"Use --output for writing the kubeconfig to a file, and --merge for merging the kubeconfig into an existing\n" +
+			"kubeconfig file instead (by default the first file in KUBECONFIG or ~/.kube/config)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&flags.Name,
+		"name", constants.DefaultClusterName, "cluster name",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Internal,
+		"internal", false, "use the API server address on the container network instead of the port exposed on the host",
+	)
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output", "o", "", "write the kubeconfig to a file instead of stdout",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Merge,
+		"merge", false, "merge the kubeconfig into an existing kubeconfig file",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	name := flags.Name
+	if len(args) == 1 {
+		name = args[0]
+	}
+
+	o, err := manager.NewClusterManager(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create cluster manager for %s", name)
+	}
+
+	kubeconfig, err := actions.KubeConfig(o.Cluster, flags.Internal)
+	if err != nil {
+		return err
+	}
+
+	if flags.Merge {
+		dest := flags.Output
+		if dest == "" {
+			dest = clientcmd.NewDefaultClientConfigLoadingRules().GetDefaultFilename()
+		}
+		if err := actions.MergeKubeConfig(o.Cluster, kubeconfig, dest); err != nil {
+			return err
+		}
+		fmt.Printf("kubeconfig for cluster %q merged into %s\n", name, dest)
+		return nil
+	}
+
+	if flags.Output == "" {
+		fmt.Print(string(kubeconfig))
+		return nil
+	}
+
+	// 0755 is taken from client-go's config handling logic
+	if err := os.MkdirAll(filepath.Dir(flags.Output), 0755); err != nil {
+		return errors.Wrap(err, "failed to create kubeconfig output directory")
+	}
+	if err := ioutil.WriteFile(flags.Output, kubeconfig, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write kubeconfig file %s", flags.Output)
+	}
+	return nil
+}
@@ -17,16 +17,34 @@ limitations under the License.
 package nodes
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
+	ksigsyaml "sigs.k8s.io/yaml"
 )
 
 type flagpole struct {
-	Name string
+	Name      string
+	ShowRoles bool
+	Output    string
+}
+
+// nodeInfo defines the node information printed when using structured output
+type nodeInfo struct {
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	CRI         string `json:"cri,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Image       string `json:"image"`
+	Status      string `json:"status"`
+	ContainerID string `json:"containerID"`
 }
 
 // NewCommand returns a new cobra.Command for getting the list of nodes in a cluster
@@ -47,17 +65,86 @@ func NewCommand() *cobra.Command {
 		&flags.Name,
 		"name", constants.DefaultClusterName, "cluster name",
 	)
+	cmd.Flags().BoolVar(
+		&flags.ShowRoles,
+		"show-roles", false, "show the role of each node, e.g. control-plane, worker, external-etcd",
+	)
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output", "o", "", "output format, one of json or yaml; if not set, node names are printed",
+	)
 	return cmd
 }
 
 func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	output := strings.ToLower(flags.Output)
+	if output != "" && output != "json" && output != "yaml" {
+		return errors.Errorf("invalid output format %q. Use one of json, yaml", flags.Output)
+	}
+
 	cluster, err := status.FromDocker(flags.Name)
 	if err != nil {
 		return err
 	}
 
+	if output != "" {
+		return printNodes(cluster, output)
+	}
+
 	for _, node := range cluster.AllNodes() {
+		if flags.ShowRoles {
+			fmt.Printf("%s\t%s\n", node.Name(), node.Role())
+			continue
+		}
 		fmt.Println(node.Name())
 	}
 	return nil
 }
+
+// printNodes prints the list of nodes in a cluster in a structured format
+func printNodes(cluster *status.Cluster, output string) error {
+	nodes := []nodeInfo{}
+	for _, node := range cluster.AllNodes() {
+		lines, err := kinddocker.Inspect(node.Name(), "{{.State.Status}} {{.Id}} {{.Config.Image}}")
+		if err != nil {
+			return errors.Wrapf(err, "failed to inspect node %s", node.Name())
+		}
+		fields := strings.Fields(strings.Trim(strings.Join(lines, ""), "'"))
+		if len(fields) != 3 {
+			return errors.Errorf("unexpected docker inspect output for node %s: %s", node.Name(), lines)
+		}
+
+		info := nodeInfo{
+			Name:        node.Name(),
+			Role:        node.Role(),
+			Status:      fields[0],
+			ContainerID: fields[1],
+			Image:       fields[2],
+		}
+
+		// NB. CRI and kubeadm version are not relevant for external etcd and external load balancer,
+		// and can't be read if the node is not running
+		if !node.IsExternalEtcd() && !node.IsExternalLoadBalancer() && info.Status == "running" {
+			if nodeCRI, err := node.CRI(); err == nil {
+				info.CRI = string(nodeCRI)
+			}
+			if v, err := node.KubeadmVersion(); err == nil {
+				info.Version = fmt.Sprintf("v%s", v)
+			}
+		}
+		nodes = append(nodes, info)
+	}
+
+	var b []byte
+	var err error
+	if output == "json" {
+		b, err = json.MarshalIndent(nodes, "", "  ")
+	} else {
+		b, err = ksigsyaml.Marshal(nodes)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the list of nodes")
+	}
+	fmt.Println(strings.TrimSuffix(string(b), "\n"))
+	return nil
+}
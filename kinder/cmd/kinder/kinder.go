@@ -20,27 +20,39 @@ package kinder
 import (
 	"os"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/cmd/kinder/build"
 	"k8s.io/kubeadm/kinder/cmd/kinder/cp"
 	"k8s.io/kubeadm/kinder/cmd/kinder/create"
+	"k8s.io/kubeadm/kinder/cmd/kinder/delete"
 	"k8s.io/kubeadm/kinder/cmd/kinder/do"
 	"k8s.io/kubeadm/kinder/cmd/kinder/exec"
+	"k8s.io/kubeadm/kinder/cmd/kinder/export"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get"
+	"k8s.io/kubeadm/kinder/cmd/kinder/load"
+	"k8s.io/kubeadm/kinder/cmd/kinder/status"
 	"k8s.io/kubeadm/kinder/cmd/kinder/test"
 	"k8s.io/kubeadm/kinder/cmd/kinder/version"
+	"k8s.io/kubeadm/kinder/pkg/audit"
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
 	"k8s.io/kubeadm/kinder/pkg/constants"
-	kinddelete "sigs.k8s.io/kind/cmd/kind/delete"
-	kindexport "sigs.k8s.io/kind/cmd/kind/export"
+	"k8s.io/kubeadm/kinder/pkg/metrics"
 )
 
+// defaultLevel is the log level used when --loglevel and --debug are not set;
+// NB. this is the warning level used by kinder before --debug was added, and it is unchanged
 const defaultLevel = log.WarnLevel
 
 // Flags for the kinder command
 type Flags struct {
-	LogLevel string
+	LogLevel  string
+	LogFormat string
+	Debug     bool
+	Metrics   string
+	Audit     bool
 }
 
 // NewCommand returns a new cobra.Command implementing the root command for kinder
@@ -70,14 +82,36 @@ func NewCommand() *cobra.Command {
 		defaultLevel.String(),
 		"logrus log level [panic, fatal, error, warning, info, debug, trace]",
 	)
-
-	// add kind top level subcommands re-used without changes
-	cmd.AddCommand(kinddelete.NewCommand())
-	cmd.AddCommand(kindexport.NewCommand())
+	cmd.PersistentFlags().StringVar(
+		&flags.LogFormat,
+		"log-format",
+		"text",
+		"logrus log format [text, json]; json logs include fields like the action name and the target node, where relevant",
+	)
+	cmd.PersistentFlags().BoolVar(
+		&flags.Debug,
+		"debug",
+		false,
+		"shortcut for --loglevel=debug; at debug level the commands executed by kinder are logged, including env variables (silent commands are logged at trace level, unless they fail)",
+	)
+	cmd.PersistentFlags().StringVar(
+		&flags.Metrics,
+		"metrics",
+		"",
+		"write operation durations to a file in the Prometheus text exposition format at the end of the run",
+	)
+	cmd.PersistentFlags().BoolVar(
+		&flags.Audit,
+		"audit",
+		false,
+		"record every kubeadm command executed on nodes, with args, output, exit code and duration, in an audit log per cluster (see kinder get audit)",
+	)
 
 	// add kind commands commands customized in kind
 	cmd.AddCommand(build.NewCommand())
 	cmd.AddCommand(create.NewCommand())
+	cmd.AddCommand(delete.NewCommand())
+	cmd.AddCommand(export.NewCommand())
 	cmd.AddCommand(version.NewCommand())
 	cmd.AddCommand(get.NewCommand())
 
@@ -85,6 +119,8 @@ func NewCommand() *cobra.Command {
 	cmd.AddCommand(cp.NewCommand())
 	cmd.AddCommand(do.NewCommand())
 	cmd.AddCommand(exec.NewCommand())
+	cmd.AddCommand(load.NewCommand())
+	cmd.AddCommand(status.NewCommand())
 	cmd.AddCommand(test.NewCommand())
 
 	return cmd
@@ -98,13 +134,34 @@ func runE(flags *Flags, cmd *cobra.Command, args []string) error {
 	} else {
 		level = parsed
 	}
+	// NB. --debug never lowers a more verbose level set with --loglevel, e.g. trace
+	if flags.Debug && level < log.DebugLevel {
+		level = log.DebugLevel
+	}
 	log.SetLevel(level)
+
+	switch flags.LogFormat {
+	case "text":
+		// nb. the text formatter is set by Main
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return errors.Errorf("invalid log format '%s'. Use one of text, json", flags.LogFormat)
+	}
+	metrics.SetOutput(flags.Metrics)
+	audit.Enable(flags.Audit)
 	return nil
 }
 
 // Run runs the `kind` root command
 func Run() error {
-	return NewCommand().Execute()
+	err := NewCommand().Execute()
+
+	// NB. metrics are written also in case of errors, so it is possible to track durations of failed runs
+	if ferr := metrics.Flush(); ferr != nil {
+		log.Error(ferr)
+	}
+	return err
 }
 
 // Main wraps Run and sets the log formatter
@@ -118,6 +175,7 @@ func Main() {
 		TimestampFormat: "15:04:05",
 	})
 	if err := Run(); err != nil {
-		os.Exit(1)
+		// nb. action failures use a specific exit code for each failure reason, e.g. preflight or timeout
+		os.Exit(actions.ExitCode(err))
 	}
 }
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagearchive
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+type flagpole struct {
+	Name string
+}
+
+// NewCommand returns a new cobra.Command for loading an image archive
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args: cobra.RangeArgs(1, 2),
+		Use: "image-archive IMAGE_TARBALL [CLUSTER_NAME]\n\n" +
+			"Args:\n" +
+			"  IMAGE_TARBALL is a tarball created with docker save\n" +
+			"  CLUSTER_NAME is the name of the cluster; it overrides the --name flag",
+		Short: "Loads docker image from archive into nodes",
+		Long:  "Loads a docker image archive into the container runtime of all the Kubernetes nodes in the cluster, skipping nodes already having the images",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+	cmd.Flags().StringVar(
+		&flags.Name, "name",
+		constants.DefaultClusterName,
+		"cluster name",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		flags.Name = args[1]
+	}
+
+	// get a kinder cluster manager
+	o, err := manager.NewClusterManager(flags.Name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create create a kinder cluster manager for %s", flags.Name)
+	}
+
+	// loads the image archive into the cluster nodes
+	if err := o.LoadImageArchive(args[0]); err != nil {
+		return errors.Wrap(err, "failed to load image archive")
+	}
+
+	return nil
+}
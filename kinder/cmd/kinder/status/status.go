@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+type flagpole struct {
+	Name string
+}
+
+// NewCommand returns a new cobra.Command for printing the status of a cluster
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+
+	cmd := &cobra.Command{
+		Args:  cobra.MaximumNArgs(1),
+		Use:   "status [CLUSTER_NAME]",
+		Short: "Prints a quick overview of the status of a cluster",
+		Long: "Prints a quick overview of the status of a cluster; the cluster name can be passed as an argument or using --name.\n\n" +
+			"For each node the overview reports the role, the container runtime, the kubeadm version and the kubeadm phase\n" +
+			"(raw, initialized or joined); additionally, the control-plane endpoint and the API server reachability are reported",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&flags.Name,
+		"name", constants.DefaultClusterName, "cluster name",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	name := flags.Name
+	if len(args) == 1 {
+		name = args[0]
+	}
+
+	// nb. the cluster status is read without validating it, so it is possible to get the status
+	// also of partially created or broken clusters
+	cluster, err := status.FromDocker(name)
+	if err != nil {
+		return err
+	}
+	if len(cluster.AllNodes()) == 0 {
+		return errors.Errorf("a cluster with the name %q does not exists", name)
+	}
+
+	// reads the cluster settings, if possible, for reporting endpoints of the cluster IP family
+	if cluster.BootstrapControlPlane() != nil {
+		if err := cluster.ReadSettings(); err != nil {
+			log.Debugf("Failed to read cluster settings: %v", err)
+		}
+	}
+
+	return actions.ClusterStatus(cluster)
+}
@@ -27,6 +27,8 @@ type flagpole struct {
 	DryRun      bool
 	Verbose     bool
 	ExitOnError bool
+	JUnit       string
+	Vars        map[string]string
 }
 
 // NewCommand returns a new cobra.Command for e2e-kubeadm
@@ -48,7 +50,7 @@ func NewCommand() *cobra.Command {
 	cmd.Flags().BoolVar(
 		&flags.DryRun,
 		"dry-run", false,
-		"only prints workflow commands, without executing them",
+		"validates the workflow and prints the execution plan, without executing it",
 	)
 	cmd.Flags().BoolVar(
 		&flags.Verbose,
@@ -60,6 +62,16 @@ func NewCommand() *cobra.Command {
 		"exit-on-task-error", false,
 		"exit after first task failed",
 	)
+	cmd.Flags().StringVar(
+		&flags.JUnit,
+		"junit", "",
+		"path of the junit report file (default ARTIFACTS/junit_runner.xml)",
+	)
+	cmd.Flags().StringToStringVar(
+		&flags.Vars,
+		"set", nil,
+		"override vars defined in the workflow, e.g. --set kubernetesVersion=v1.17.0",
+	)
 	return cmd
 }
 
@@ -76,6 +88,7 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	w.SetVars(flags.Vars)
 
-	return w.Run(os.Stdout, flags.DryRun, flags.Verbose, flags.ExitOnError, artifacts)
+	return w.Run(os.Stdout, flags.DryRun, flags.Verbose, flags.ExitOnError, artifacts, flags.JUnit)
 }
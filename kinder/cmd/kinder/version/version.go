@@ -17,25 +17,127 @@ limitations under the License.
 package version
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 	kindversion "sigs.k8s.io/kind/cmd/kind/version"
+	ksigsyaml "sigs.k8s.io/yaml"
 )
 
+type flagpole struct {
+	Output string
+}
+
+// versionInfo defines the version information printed when using structured output
+type versionInfo struct {
+	KinderVersion            string `json:"kinderVersion"`
+	KindVersion              string `json:"kindVersion"`
+	DefaultNodeImage         string `json:"defaultNodeImage"`
+	DefaultKubernetesVersion string `json:"defaultKubernetesVersion"`
+	DockerClientVersion      string `json:"dockerClientVersion,omitempty"`
+	ContainerdClientVersion  string `json:"containerdClientVersion,omitempty"`
+}
+
 // NewCommand returns a new cobra.Command for version
 func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+
 	cmd := &cobra.Command{
 		Args:  cobra.NoArgs,
 		Use:   "version",
-		Short: "prints the kind CLI version",
-		Long:  "prints the kind CLI version",
+		Short: "prints the kinder CLI version",
+		Long:  "prints the kinder CLI version, the Kubernetes version of the default node image and the docker/containerd client versions",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Printf("kinder version: %s\nkind   version: %s\n", constants.KinderVersion, kindversion.Version)
-			return nil
+			return runE(flags, cmd, args)
 		},
 	}
+
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output", "o", "", "output format, one of json or yaml; if not set, versions are printed as text",
+	)
 	return cmd
 }
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	output := strings.ToLower(flags.Output)
+	if output != "" && output != "json" && output != "yaml" {
+		return errors.Errorf("invalid output format %q. Use one of json, yaml", flags.Output)
+	}
+
+	info := versionInfo{
+		KinderVersion:            constants.KinderVersion,
+		KindVersion:              kindversion.Version,
+		DefaultNodeImage:         constants.DefaultNodeImage,
+		DefaultKubernetesVersion: imageTag(constants.DefaultNodeImage),
+		DockerClientVersion:      dockerClientVersion(),
+		ContainerdClientVersion:  containerdClientVersion(),
+	}
+
+	if output == "" {
+		fmt.Printf("%-28s%s\n", "kinder version:", info.KinderVersion)
+		fmt.Printf("%-28s%s\n", "kind version:", info.KindVersion)
+		fmt.Printf("%-28s%s\n", "default node image:", info.DefaultNodeImage)
+		fmt.Printf("%-28s%s\n", "default Kubernetes version:", info.DefaultKubernetesVersion)
+		fmt.Printf("%-28s%s\n", "docker client version:", orNotFound(info.DockerClientVersion))
+		fmt.Printf("%-28s%s\n", "containerd client version:", orNotFound(info.ContainerdClientVersion))
+		return nil
+	}
+
+	var b []byte
+	var err error
+	if output == "json" {
+		b, err = json.MarshalIndent(info, "", "  ")
+	} else {
+		b, err = ksigsyaml.Marshal(info)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the version information")
+	}
+	fmt.Println(strings.TrimSuffix(string(b), "\n"))
+	return nil
+}
+
+// imageTag returns the tag of an image, that for node images is the Kubernetes version, e.g. v1.17.0
+func imageTag(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[i+1:]
+	}
+	return "latest"
+}
+
+// dockerClientVersion returns the version of the docker client installed on the host, if any
+func dockerClientVersion() string {
+	// nb. docker version fails if the daemon is not reachable, but the client version is printed anyway,
+	// so the error is ignored
+	stdout, _, _ := exec.NewHostCmd("docker", "version", "--format", "{{.Client.Version}}").SetSilent(true).RunWithOutput()
+	return strings.TrimSpace(stdout)
+}
+
+// containerdClientVersion returns the version of the containerd client installed on the host, if any;
+// the output of ctr --version is e.g. ctr github.com/containerd/containerd v1.3.2
+func containerdClientVersion() string {
+	stdout, _, err := exec.NewHostCmd("ctr", "--version").SetSilent(true).RunWithOutput()
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(stdout)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// orNotFound returns the given version, or a placeholder if the version is not set
+func orNotFound(version string) string {
+	if version == "" {
+		return "not found"
+	}
+	return version
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit implements a recorder of the kubeadm commands executed by kinder on the nodes of a cluster,
+// that are appended as JSON lines to an audit log file per cluster
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
+)
+
+// Entry is the record of a kubeadm command executed on a node
+type Entry struct {
+	Time            time.Time `json:"time"`
+	Node            string    `json:"node"`
+	Command         string    `json:"command"`
+	Args            []string  `json:"args"`
+	Stdout          string    `json:"stdout"`
+	Stderr          string    `json:"stderr"`
+	ExitCode        int       `json:"exitCode"`
+	DurationSeconds float64   `json:"durationSeconds"`
+}
+
+var (
+	mu       sync.Mutex
+	enabled  bool
+	clusters = map[string]string{}
+)
+
+// Enable instructs kinder to record the kubeadm commands executed on nodes
+func Enable(e bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = e
+}
+
+// IsEnabled returns true if kinder is recording the kubeadm commands executed on nodes
+func IsEnabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return enabled
+}
+
+// IsAudited returns true if the command should be recorded, that is if recording is enabled and the command is kubeadm
+func IsAudited(command string) bool {
+	return IsEnabled() && filepath.Base(command) == "kubeadm"
+}
+
+// LogPath returns the path of the audit log file for a cluster, ~/.kinder/audit/<cluster>.jsonl,
+// or an empty string if the home folder cannot be determined
+func LogPath(clusterName string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kinder", "audit", clusterName+".jsonl")
+}
+
+// Record appends an entry to the audit log file of the cluster the node belongs to; errors are logged,
+// because failing to record a command should not fail the command itself
+func Record(e Entry) {
+	if err := record(e); err != nil {
+		log.Warnf("failed to record kubeadm command on node %s in the audit log: %v", e.Node, err)
+	}
+}
+
+func record(e Entry) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	// gets the cluster the node belongs to, caching it for the next commands on the same node
+	clusterName, ok := clusters[e.Node]
+	if !ok {
+		lines, err := kinddocker.Inspect(e.Node, `{{index .Config.Labels "`+constants.ClusterLabelKey+`"}}`)
+		if err != nil || len(lines) != 1 || strings.TrimSpace(lines[0]) == "" {
+			return errors.Errorf("failed to get the cluster for node %s", e.Node)
+		}
+		clusterName = strings.TrimSpace(lines[0])
+		clusters[e.Node] = clusterName
+	}
+
+	path := LogPath(clusterName)
+	if path == "" {
+		return errors.New("failed to get the home folder")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the folder for audit log %s", path)
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the audit entry")
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open audit log %s", path)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return errors.Wrapf(err, "failed to write audit log %s", path)
+	}
+	return nil
+}
+
+// Read returns the entries in the audit log file for a cluster
+func Read(clusterName string) ([]Entry, error) {
+	path := LogPath(clusterName)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Errorf("no audit log found for cluster %q; use the --audit flag for recording kubeadm commands", clusterName)
+		}
+		return nil, errors.Wrapf(err, "failed to open audit log %s", path)
+	}
+	defer f.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	// nb. kubeadm output could be longer than the default max token size
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode audit log %s", path)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read audit log %s", path)
+	}
+	return entries, nil
+}
+
+// Remove deletes the audit log file for a cluster, if any
+func Remove(clusterName string) error {
+	path := LogPath(clusterName)
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove audit log %s", path)
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -28,11 +29,13 @@ import (
 
 	"k8s.io/kubeadm/kinder/pkg/build/bits"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/cri"
 	"k8s.io/kubeadm/kinder/pkg/exec"
 	"k8s.io/kubeadm/kinder/pkg/extract"
 	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
 	kindfs "sigs.k8s.io/kind/pkg/fs"
+	"sigs.k8s.io/kind/pkg/util"
 )
 
 // DefaultBaseImage is the default base image used
@@ -48,10 +51,17 @@ type Context struct {
 	image               string
 	initArtifactsSrc    string
 	imageSrcs           []string
+	extraImages         []string
 	imageNamePrefix     string
 	upgradeArtifactsSrc string
 	kubeadmSrc          string
 	kubeletSrc          string
+	arch                string
+	skipChecksum        bool
+	cacheDir            string
+	cacheTTL            time.Duration
+	noCache             bool
+	containerdVersion   string
 }
 
 // Option is Context configuration option supplied to NewContext
@@ -85,6 +95,13 @@ func WithImageTars(srcs []string) Option {
 	}
 }
 
+// WithExtraImages configures a NewContext to pull and include additional images
+func WithExtraImages(images []string) Option {
+	return func(b *Context) {
+		b.extraImages = append(b.extraImages, images...)
+	}
+}
+
 // WithImageNamePrefix configures a NewContext to add a name prefix to included images tars
 func WithImageNamePrefix(namePrefix string) Option {
 	return func(b *Context) {
@@ -113,11 +130,57 @@ func WithKubelet(src string) Option {
 	}
 }
 
+// WithArch configures a NewContext to use bits for the given arch
+func WithArch(arch string) Option {
+	return func(b *Context) {
+		b.arch = arch
+	}
+}
+
+// WithSkipChecksum configures a NewContext to skip checksum verification of downloaded bits
+func WithSkipChecksum(skipChecksum bool) Option {
+	return func(b *Context) {
+		b.skipChecksum = skipChecksum
+	}
+}
+
+// WithCacheDir configures a NewContext to cache downloaded bits into the given folder
+func WithCacheDir(cacheDir string) Option {
+	return func(b *Context) {
+		b.cacheDir = cacheDir
+	}
+}
+
+// WithCacheTTL configures a NewContext to download again cached bits older than the given TTL
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(b *Context) {
+		b.cacheTTL = ttl
+	}
+}
+
+// WithNoCache configures a NewContext to bypass the cache of downloaded bits
+func WithNoCache(noCache bool) Option {
+	return func(b *Context) {
+		b.noCache = noCache
+	}
+}
+
+// WithContainerdVersion configures a NewContext to replace the containerd binaries in the image
+// with the given containerd release version
+func WithContainerdVersion(version string) Option {
+	return func(b *Context) {
+		b.containerdVersion = version
+	}
+}
+
 // NewContext creates a new Context with default configuration,
 // overridden by the options supplied in the order that they are supplied
 func NewContext(options ...Option) (ctx *Context, err error) {
 	// default options
-	ctx = &Context{}
+	ctx = &Context{
+		arch:     util.GetArch(),
+		cacheTTL: extract.DefaultCacheTTL,
+	}
 
 	// apply user options
 	for _, option := range options {
@@ -133,22 +196,30 @@ func (c *Context) Alter() (err error) {
 	var bitsInstallers []bits.Installer
 
 	if c.initArtifactsSrc != "" {
-		bitsInstallers = append(bitsInstallers, bits.NewInitBits(c.initArtifactsSrc))
+		bitsInstallers = append(bitsInstallers, bits.NewInitBits(c.initArtifactsSrc, c.extractOptions()...))
 	}
 
 	if c.kubeadmSrc != "" {
-		bitsInstallers = append(bitsInstallers, bits.NewBinaryBits(c.kubeadmSrc, "kubeadm"))
+		bitsInstallers = append(bitsInstallers, bits.NewBinaryBits(c.kubeadmSrc, "kubeadm", c.arch, c.extractOptions()...))
 	}
 	if c.kubeletSrc != "" {
-		bitsInstallers = append(bitsInstallers, bits.NewBinaryBits(c.kubeletSrc, "kubelet"))
+		bitsInstallers = append(bitsInstallers, bits.NewBinaryBits(c.kubeletSrc, "kubelet", c.arch, c.extractOptions()...))
 	}
 
 	if len(c.imageSrcs) > 0 {
-		bitsInstallers = append(bitsInstallers, bits.NewImageBits(c.imageSrcs, c.imageNamePrefix))
+		bitsInstallers = append(bitsInstallers, bits.NewImageBits(c.imageSrcs, c.imageNamePrefix, c.extractOptions()...))
+	}
+
+	if len(c.extraImages) > 0 {
+		bitsInstallers = append(bitsInstallers, bits.NewExtraImageBits(c.extraImages))
 	}
 
 	if c.upgradeArtifactsSrc != "" {
-		bitsInstallers = append(bitsInstallers, bits.NewUpgradeBits(c.upgradeArtifactsSrc))
+		bitsInstallers = append(bitsInstallers, bits.NewUpgradeBits(c.upgradeArtifactsSrc, c.extractOptions()...))
+	}
+
+	if c.containerdVersion != "" {
+		bitsInstallers = append(bitsInstallers, bits.NewContainerdBits(c.containerdVersion, c.arch, c.skipChecksum))
 	}
 
 	// create tempdir to alter the image in
@@ -177,6 +248,17 @@ func (c *Context) Alter() (err error) {
 	return c.alterImage(bitsInstallers, bc)
 }
 
+// extractOptions returns the options to be used when retrieving bits
+func (c *Context) extractOptions() []extract.Option {
+	return []extract.Option{
+		extract.WithArch(c.arch),
+		extract.SkipChecksum(c.skipChecksum),
+		extract.WithCacheDir(c.cacheDir),
+		extract.WithCacheTTL(c.cacheTTL),
+		extract.NoCache(c.noCache),
+	}
+}
+
 func (c *Context) prepareBits(bitsInstallers []bits.Installer, bc *bits.BuildContext) error {
 	log.Info("Preparing bits ...")
 
@@ -310,15 +392,26 @@ func (c *Context) alterImage(bitsInstallers []bits.Installer, bc *bits.BuildCont
 		return errors.Wrap(err, "Image alter Failed! Failed to commit image")
 	}
 
+	labels := map[string]string{
+		constants.BaseImageLabelKey: c.baseImage,
+	}
+	if c.containerdVersion != "" {
+		labels[constants.ContainerdVersionLabelKey] = "v" + strings.TrimPrefix(c.containerdVersion, "v")
+	}
+	if err := LabelImage(c.image, labels); err != nil {
+		return errors.Wrap(err, "Image alter Failed!")
+	}
+
 	log.Info("Image alter completed.")
 
 	return nil
 }
 
 func (c *Context) createAlterContainer(bc *bits.BuildContext) (id string, err error) {
-	// attempt to explicitly pull the image if it doesn't exist locally
-	// we don't care if this errors, we'll still try to run which also pulls
-	_, _ = kinddocker.PullIfNotPresent(c.baseImage, 4)
+	// ensure the base image exists locally, pulling it if necessary
+	if err := EnsureImage(c.baseImage); err != nil {
+		return "", err
+	}
 
 	// define docker default args
 	id = "kind-build-" + uuid.New().String()
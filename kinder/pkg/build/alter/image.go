@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
+)
+
+// EnsureImage ensures the image exists locally, pulling it if necessary
+func EnsureImage(image string) error {
+	if _, err := kinddocker.PullIfNotPresent(image, 4); err != nil {
+		return errors.Wrapf(err, "image %s does not exist locally and it can't be pulled", image)
+	}
+	return nil
+}
+
+// LabelImage adds labels to an existing image; this is executed with a docker build
+// having the image itself as a FROM reference, that only adds LABEL instructions
+func LabelImage(image string, labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := []string{"build", "-t", image}
+	for _, k := range keys {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+	args = append(args, "-")
+
+	log.Infof("Adding labels to %s ...", image)
+	cmd := exec.NewHostCmd("docker", args...)
+	cmd.Stdin(strings.NewReader(fmt.Sprintf("FROM %s\n", image)))
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "failed to add labels to image %s", image)
+	}
+	return nil
+}
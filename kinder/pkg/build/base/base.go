@@ -18,14 +18,25 @@ limitations under the License.
 package base
 
 import (
+	"context"
+	"fmt"
 	"go/build"
+	"io/ioutil"
 	"os"
+	osexec "os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/exec"
+	kindconcurrent "sigs.k8s.io/kind/pkg/concurrent"
 	"sigs.k8s.io/kind/pkg/fs"
 	"sigs.k8s.io/kind/pkg/util"
 )
@@ -37,13 +48,63 @@ const DefaultImage = "kindest/base:latest"
 // build configuration
 type BuildContext struct {
 	// option fields
-	sourceDir string
-	image     string
+	sourceDir    string
+	cri          string
+	image        string
+	arch         string
+	goCmd        string
+	builder      string
+	buildArgs    map[string]string
+	keepBuildDir bool
+	labels       map[string]string
+	dryRun       bool
+	exportPath   string
+	buildKit     bool
+	extraFiles   map[string]string
 	// non option fields
-	goCmd string // TODO: should be an option possibly
-	arch  string // TODO: should be an option
+	result BuildResult
 }
 
+// BuildResult holds the reference to the image produced by a build
+type BuildResult struct {
+	// Image is the name:tag of the built image
+	Image string
+	// Digest is the digest of the built image; it is the repo digest if available,
+	// the image ID otherwise
+	Digest string
+}
+
+// knownArchitectures defines the list of architectures supported for building the base image
+var knownArchitectures = []string{"amd64", "arm64", "ppc64le", "s390x"}
+
+// labels automatically added to the base image for provenance tracking
+const (
+	// ArchLabelKey is the label key used for identifying the architecture of the base image
+	ArchLabelKey = "org.kubernetes.kubeadm.kinder.arch"
+
+	// BuiltAtLabelKey is the label key used for identifying the build time of the base image
+	BuiltAtLabelKey = "org.kubernetes.kubeadm.kinder.built-at"
+
+	// VersionLabelKey is the label key used for identifying the kinder version used for building the base image
+	VersionLabelKey = "org.kubernetes.kubeadm.kinder.version"
+)
+
+const (
+	// DockerCRI identifies docker as the container runtime installed in the base image
+	DockerCRI = "docker"
+
+	// CRIOCRI identifies CRI-O as the container runtime installed in the base image
+	CRIOCRI = "crio"
+)
+
+const (
+	// DockerBuilder identifies docker as the tool used for building the base image
+	DockerBuilder = "docker"
+
+	// PodmanBuilder identifies podman as the tool used for building the base image
+	PodmanBuilder = "podman"
+)
+
 // Option is BuildContext configuration option supplied to NewBuildContext
 type Option func(*BuildContext)
 
@@ -54,6 +115,16 @@ func WithSourceDir(sourceDir string) Option {
 	}
 }
 
+// WithCRI configures a NewBuildContext to build a base image with the container runtime `cri`;
+// use one of docker or crio. The container runtime selects the default source dir
+func WithCRI(cri string) Option {
+	return func(b *BuildContext) {
+		if cri != "" {
+			b.cri = cri
+		}
+	}
+}
+
 // WithImage configures a NewBuildContext to tag the built image with `name`
 func WithImage(image string) Option {
 	return func(b *BuildContext) {
@@ -61,13 +132,92 @@ func WithImage(image string) Option {
 	}
 }
 
+// WithArch configures a NewBuildContext to build the entrypoint binary for the architecture `arch`
+func WithArch(arch string) Option {
+	return func(b *BuildContext) {
+		b.arch = arch
+	}
+}
+
+// WithGoCmd configures a NewBuildContext to use the go toolchain at `goCmd` for building the entrypoint binary
+func WithGoCmd(goCmd string) Option {
+	return func(b *BuildContext) {
+		if goCmd != "" {
+			b.goCmd = goCmd
+		}
+	}
+}
+
+// WithBuilder configures a NewBuildContext to use `builder` for building the image; use one of docker or podman
+func WithBuilder(builder string) Option {
+	return func(b *BuildContext) {
+		if builder != "" {
+			b.builder = builder
+		}
+	}
+}
+
+// WithBuildArgs configures a NewBuildContext to pass `buildArgs` as build-time variables to the image build
+func WithBuildArgs(buildArgs map[string]string) Option {
+	return func(b *BuildContext) {
+		b.buildArgs = buildArgs
+	}
+}
+
+// WithKeepBuildDir configures a NewBuildContext to preserve the build dir in case of build failures
+func WithKeepBuildDir(keepBuildDir bool) Option {
+	return func(b *BuildContext) {
+		b.keepBuildDir = keepBuildDir
+	}
+}
+
+// WithLabels configures a NewBuildContext to add `labels` to the built image;
+// labels provided by the user take precedence over labels automatically added by kinder
+func WithLabels(labels map[string]string) Option {
+	return func(b *BuildContext) {
+		b.labels = labels
+	}
+}
+
+// WithDryRun configures a NewBuildContext to print build commands instead of executing them;
+// the build dir is still populated with image sources
+func WithDryRun(dryRun bool) Option {
+	return func(b *BuildContext) {
+		b.dryRun = dryRun
+	}
+}
+
+// WithExportPath configures a NewBuildContext to export the built image to a tarball at `exportPath`
+func WithExportPath(exportPath string) Option {
+	return func(b *BuildContext) {
+		b.exportPath = exportPath
+	}
+}
+
+// WithBuildKit configures a NewBuildContext to use BuildKit with plain progress output when building the image
+func WithBuildKit(buildKit bool) Option {
+	return func(b *BuildContext) {
+		b.buildKit = buildKit
+	}
+}
+
+// WithExtraFiles configures a NewBuildContext to add files to the build context; `extraFiles` maps
+// destination paths, relative to the build context, to source paths on the host
+func WithExtraFiles(extraFiles map[string]string) Option {
+	return func(b *BuildContext) {
+		b.extraFiles = extraFiles
+	}
+}
+
 // NewBuildContext creates a new BuildContext with
 // default configuration
 func NewBuildContext(options ...Option) *BuildContext {
 	ctx := &BuildContext{
-		image: DefaultImage,
-		goCmd: "go",
-		arch:  util.GetArch(),
+		image:   DefaultImage,
+		cri:     DockerCRI,
+		goCmd:   "go",
+		arch:    util.GetArch(),
+		builder: DockerBuilder,
 	}
 	for _, option := range options {
 		option(ctx)
@@ -78,69 +228,438 @@ func NewBuildContext(options ...Option) *BuildContext {
 // Build builds the cluster node image, the sourcedir must be set on
 // the NodeImageBuildContext
 func (c *BuildContext) Build() (err error) {
-	// create tempdir to build in
-	tmpDir, err := fs.TempDir("", "kind-base-image")
+	return c.BuildWithContext(context.Background())
+}
+
+// BuildWithContext builds the cluster node image like Build, but running
+// commands bound to ctx, so the build can be cancelled
+func (c *BuildContext) BuildWithContext(ctx context.Context) (err error) {
+	// validate the build configuration
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	// prepare the build dir
+	buildDir, cleanup, err := c.prepareBuildDir()
 	if err != nil {
 		return err
 	}
-	defer os.RemoveAll(tmpDir)
+	defer func() {
+		cleanup(err)
+	}()
 
-	// populate with image sources
+	// build the entrypoint binary first
+	if err := c.buildEntrypoint(ctx, buildDir, c.arch, filepath.Join(buildDir, "entrypoint", "entrypoint")); err != nil {
+		return err
+	}
+
+	// then the actual docker image
+	if err := c.buildImage(ctx, buildDir, c.image, c.arch); err != nil {
+		return err
+	}
+
+	// then collect the reference to the built image
+	if err := c.inspectImage(ctx, c.image); err != nil {
+		return err
+	}
+
+	// and finally eventually export the image
+	return c.exportImage(ctx, c.image)
+}
+
+// BuildAll builds the cluster node image for each of the given architectures, tagging
+// each image as `image-arch`. Entrypoint binaries are cross-compiled concurrently; a failure
+// for one architecture does not stop the build of the other ones, images successfully built
+// are exported anyway, and errors from all the architectures are returned together.
+func (c *BuildContext) BuildAll(arches []string) (err error) {
+	ctx := context.Background()
+
+	// validate the build configuration
+	for _, arch := range arches {
+		if !isKnownArch(arch) {
+			return errors.Errorf("unsupported architecture %q. Use one of %v", arch, knownArchitectures)
+		}
+	}
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	// prepare the build dir
+	buildDir, cleanup, err := c.prepareBuildDir()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cleanup(err)
+	}()
+
+	// build the entrypoint binaries first, concurrently but bounded by GOMAXPROCS;
+	// the error for each architecture is recorded, so the other architectures can be built anyway
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	entrypointErrs := make([]error, len(arches))
+	fns := []func() error{}
+	for i, arch := range arches {
+		i, arch := i, arch // capture loop variables
+		fns = append(fns, func() error {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			entrypointDir := filepath.Join(buildDir, "entrypoint", arch)
+			if err := os.MkdirAll(entrypointDir, 0755); err != nil {
+				entrypointErrs[i] = errors.Wrapf(err, "failed to create the entrypoint dir for %s", arch)
+				return entrypointErrs[i]
+			}
+			entrypointErrs[i] = c.buildEntrypoint(ctx, buildDir, arch, filepath.Join(entrypointDir, "entrypoint"))
+			return entrypointErrs[i]
+		})
+	}
+	// nb. errors are reported per architecture below
+	_ = kindconcurrent.Coalesce(fns...)
+
+	// then the actual docker images, one for each architecture
+	errs := []error{}
+	images := []string{}
+	for i, arch := range arches {
+		if entrypointErrs[i] != nil {
+			errs = append(errs, errors.Wrapf(entrypointErrs[i], "failed to build the base image for %s", arch))
+			continue
+		}
+		image, err := c.buildArchImage(ctx, buildDir, arch)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to build the base image for %s", arch))
+			continue
+		}
+		images = append(images, image)
+	}
+
+	// and finally eventually export the images successfully built
+	if len(images) > 0 {
+		if err := c.exportImage(ctx, images...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// buildArchImage builds and inspects the base image for one architecture, using the entrypoint
+// binary already built for it, and returns the image name
+func (c *BuildContext) buildArchImage(ctx context.Context, buildDir, arch string) (string, error) {
+	// the Dockerfile expects the entrypoint binary in a well known location
+	if !c.dryRun {
+		if err := fs.CopyFile(
+			filepath.Join(buildDir, "entrypoint", arch, "entrypoint"),
+			filepath.Join(buildDir, "entrypoint", "entrypoint"),
+		); err != nil {
+			return "", errors.Wrapf(err, "failed to prepare the entrypoint binary for %s", arch)
+		}
+	}
+
+	image := fmt.Sprintf("%s-%s", c.image, arch)
+	if err := c.buildImage(ctx, buildDir, image, arch); err != nil {
+		return "", err
+	}
+	if err := c.inspectImage(ctx, image); err != nil {
+		return "", err
+	}
+	return image, nil
+}
+
+// prepareBuildDir creates a tempdir to build in, and populates it with image sources.
+// The returned cleanup func should be invoked with the build error when the build completes.
+func (c *BuildContext) prepareBuildDir() (buildDir string, cleanup func(error), err error) {
 	// if SourceDir is unset then try to autodetect source dir
-	buildDir := tmpDir
 	if c.sourceDir == "" {
 		pkg, err := build.Default.Import("k8s.io/kubeadm/kinder", build.Default.GOPATH, build.FindOnly)
 		if err != nil {
-			return errors.Wrap(err, "failed to locate sources")
+			return "", nil, errors.Wrap(err, "failed to locate sources")
 		}
-		c.sourceDir = filepath.Join(pkg.Dir, "images", "base", "docker")
+		c.sourceDir = filepath.Join(pkg.Dir, "images", "base", c.cri)
+	}
+
+	// validate the source dir before starting the build
+	if err := validateSourceDir(c.sourceDir); err != nil {
+		return "", nil, err
 	}
 
-	err = fs.Copy(c.sourceDir, buildDir)
+	// create tempdir to build in
+	tmpDir, err := fs.TempDir("", "kind-base-image")
 	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func(err error) {
+		// eventually preserve the build dir for debugging purposes
+		if err != nil && c.keepBuildDir {
+			log.Infof("Build failed, preserving the build dir: %s", tmpDir)
+			return
+		}
+		os.RemoveAll(tmpDir)
+	}
+
+	// populate with image sources
+	buildDir = tmpDir
+	if err := fs.Copy(c.sourceDir, buildDir); err != nil {
 		log.Errorf("failed to copy sources to build dir %v", err)
-		return err
+		cleanup(err)
+		return "", nil, err
+	}
+
+	// add extra files, sorted by destination so the copy order is deterministic
+	for _, dest := range sortedKeys(c.extraFiles) {
+		if err := copyExtraFile(c.extraFiles[dest], filepath.Join(buildDir, dest)); err != nil {
+			cleanup(err)
+			return "", nil, err
+		}
 	}
 
 	log.Infof("Building base image in: %s", buildDir)
+	return buildDir, cleanup, nil
+}
 
-	// build the entrypoint binary first
-	if err := c.buildEntrypoint(buildDir); err != nil {
-		return err
+// Result returns the reference to the image produced by the last successful build;
+// the zero value is returned if no build has completed yet
+func (c *BuildContext) Result() BuildResult {
+	return c.result
+}
+
+// validate checks the build configuration before starting the build
+func (c *BuildContext) validate() error {
+	if !isKnownArch(c.arch) {
+		return errors.Errorf("unsupported architecture %q. Use one of %v", c.arch, knownArchitectures)
 	}
+	if _, err := osexec.LookPath(c.goCmd); err != nil {
+		return errors.Wrapf(err, "invalid go command %q", c.goCmd)
+	}
+	switch c.cri {
+	case DockerCRI, CRIOCRI:
+	default:
+		return errors.Errorf("unsupported container runtime %q. Use one of [%s, %s]", c.cri, DockerCRI, CRIOCRI)
+	}
+	switch c.builder {
+	case DockerBuilder, PodmanBuilder:
+	default:
+		return errors.Errorf("unsupported builder %q. Use one of [%s, %s]", c.builder, DockerBuilder, PodmanBuilder)
+	}
+	if _, err := osexec.LookPath(c.builder); err != nil {
+		return errors.Wrapf(err, "%s is required for building the base image, please check it is installed and available in PATH", c.builder)
+	}
+	for k := range c.buildArgs {
+		if k == "" {
+			return errors.New("invalid build arg: the build arg key can't be empty")
+		}
+	}
+	for k := range c.labels {
+		if k == "" {
+			return errors.New("invalid label: the label key can't be empty")
+		}
+	}
+	if c.buildKit && c.builder != DockerBuilder {
+		return errors.Errorf("BuildKit is supported only by the %s builder", DockerBuilder)
+	}
+	for dest, src := range c.extraFiles {
+		if filepath.IsAbs(dest) || strings.HasPrefix(filepath.Clean(dest), "..") {
+			return errors.Errorf("invalid extra file destination %q: it should be a path relative to the build context", dest)
+		}
+		if _, err := os.Stat(src); err != nil {
+			return errors.Wrapf(err, "invalid extra file %q", src)
+		}
+	}
+	if c.exportPath != "" {
+		if err := validateExportDir(filepath.Dir(c.exportPath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// then the actual docker image
-	return c.buildImage(buildDir)
+// copyExtraFile copies an extra file into the build dir, creating intermediate directories if necessary
+func copyExtraFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the directory for extra file %s", dest)
+	}
+	if err := fs.CopyFile(src, dest); err != nil {
+		return errors.Wrapf(err, "failed to copy extra file %s to %s", src, dest)
+	}
+	return nil
+}
+
+// validateSourceDir checks the source dir exists and contains the files required for building the base image
+func validateSourceDir(sourceDir string) error {
+	info, err := os.Stat(sourceDir)
+	if err != nil {
+		return errors.Wrapf(err, "invalid source dir %q", sourceDir)
+	}
+	if !info.IsDir() {
+		return errors.Errorf("invalid source dir %q: it is not a directory", sourceDir)
+	}
+
+	for _, f := range []string{
+		"Dockerfile",
+		filepath.Join("entrypoint", "main.go"),
+	} {
+		if _, err := os.Stat(filepath.Join(sourceDir, f)); err != nil {
+			return errors.Wrapf(err, "invalid source dir %q: missing %s", sourceDir, f)
+		}
+	}
+	return nil
+}
+
+// validateExportDir checks the export dir exists and it is writable
+func validateExportDir(exportDir string) error {
+	f, err := ioutil.TempFile(exportDir, ".kinder-export")
+	if err != nil {
+		return errors.Wrapf(err, "invalid export dir %q: it is not writable", exportDir)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// isKnownArch returns true if arch is one of the known architectures
+func isKnownArch(arch string) bool {
+	for _, a := range knownArchitectures {
+		if arch == a {
+			return true
+		}
+	}
+	return false
 }
 
 // builds the entrypoint binary
-func (c *BuildContext) buildEntrypoint(dir string) error {
+func (c *BuildContext) buildEntrypoint(ctx context.Context, dir, arch, entrypointDest string) error {
 	// NOTE: this binary only uses the go1 stdlib, and is a single file
 	entrypointSrc := filepath.Join(dir, "entrypoint", "main.go")
-	entrypointDest := filepath.Join(dir, "entrypoint", "entrypoint")
 
-	cmd := exec.NewHostCmd(c.goCmd, "build", "-o", entrypointDest, entrypointSrc)
-	cmd.SetEnv(append(os.Environ(), "GOOS=linux", "GOARCH="+c.arch)...)
+	cmd := exec.NewHostCmdContext(ctx, c.goCmd, "build", "-o", entrypointDest, entrypointSrc)
+	cmd.SetEnvOverride(map[string]string{
+		"GOOS":   "linux",
+		"GOARCH": arch,
+	})
+	if c.dryRun {
+		cmd.DryRun()
+	}
 
 	// actually build
-	log.Info("Building entrypoint binary ...")
+	log.Infof("Building entrypoint binary for %s ...", arch)
 	if err := cmd.RunWithEcho(); err != nil {
-		log.Errorf("Entrypoint build Failed! %v", err)
-		return err
+		log.Errorf("Entrypoint build for %s Failed! %v", arch, err)
+		return errors.Wrapf(err, "failed to build the entrypoint binary for %s", arch)
 	}
-	log.Info("Entrypoint build completed.")
+	log.Infof("Entrypoint build for %s completed.", arch)
 	return nil
 }
 
-func (c *BuildContext) buildImage(dir string) error {
+func (c *BuildContext) buildImage(ctx context.Context, dir, image, arch string) error {
 	// build the image, tagged as tagImageAs, using the our tempdir as the context
-	cmd := exec.NewHostCmd("docker", "build", "-t", c.image, dir)
-	log.Info("Starting Docker build ...")
+	var args []string
+	switch c.builder {
+	case PodmanBuilder:
+		// podman requires the docker format for preserving docker specific instructions in the Dockerfile
+		args = []string{"build", "--format", "docker", "--tag", image}
+	default:
+		args = []string{"build", "-t", image}
+		if c.buildKit {
+			args = append(args, "--progress=plain")
+		}
+	}
+
+	// the CRI-O Dockerfile downloads arch specific binaries, so it requires the target arch
+	// (unless explicitly set by the user)
+	if _, ok := c.buildArgs["ARCH"]; c.cri == CRIOCRI && !ok {
+		args = append(args, "--build-arg", fmt.Sprintf("ARCH=%s", arch))
+	}
+
+	// adds build args and labels, sorted by key so the command is deterministic
+	for _, k := range sortedKeys(c.buildArgs) {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", k, c.buildArgs[k]))
+	}
+	labels := c.imageLabels(arch)
+	for _, k := range sortedKeys(labels) {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+
+	args = append(args, dir)
+	cmd := exec.NewHostCmdContext(ctx, c.builder, args...)
+	if c.buildKit {
+		cmd.SetEnvOverride(map[string]string{
+			"DOCKER_BUILDKIT": "1",
+		})
+	}
+	if c.dryRun {
+		cmd.DryRun()
+	}
+	log.Infof("Starting %s build for %s ...", c.builder, image)
 
 	if err := cmd.RunWithEcho(); err != nil {
-		log.Errorf("Docker build Failed! %v", err)
+		log.Errorf("%s build Failed! %v", c.builder, err)
 		return err
 	}
-	log.Info("Docker build completed.")
+	log.Infof("%s build completed.", c.builder)
+	return nil
+}
+
+// inspectImage reads the digest of the built image
+func (c *BuildContext) inspectImage(ctx context.Context, image string) error {
+	if c.dryRun {
+		return nil
+	}
+
+	// locally built images have a repo digest only after being pushed to a registry, so the image ID is used as a fallback
+	lines, err := exec.NewHostCmdContext(ctx, c.builder, "inspect",
+		"--format", "{{if .RepoDigests}}{{index .RepoDigests 0}}{{else}}{{.Id}}{{end}}",
+		image,
+	).RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to inspect image %s", image)
+	}
+	if len(lines) != 1 {
+		return errors.Errorf("failed to inspect image %s: unexpected output %v", image, lines)
+	}
+
+	c.result = BuildResult{
+		Image:  image,
+		Digest: strings.TrimSpace(lines[0]),
+	}
 	return nil
 }
+
+// exportImage saves the built images to a tarball, if an export path is set
+func (c *BuildContext) exportImage(ctx context.Context, images ...string) error {
+	if c.exportPath == "" {
+		return nil
+	}
+
+	cmd := exec.NewHostCmdContext(ctx, c.builder, append([]string{"save", "-o", c.exportPath}, images...)...)
+	if c.dryRun {
+		cmd.DryRun()
+	}
+	log.Infof("Exporting image to %s ...", c.exportPath)
+
+	if err := cmd.RunWithEcho(); err != nil {
+		log.Errorf("Image export Failed! %v", err)
+		return errors.Wrapf(err, "failed to export images %v to %s", images, c.exportPath)
+	}
+	log.Info("Image export completed.")
+	return nil
+}
+
+// imageLabels returns the labels to be added to the base image, merging
+// labels automatically added by kinder with the labels provided by the user
+func (c *BuildContext) imageLabels(arch string) map[string]string {
+	labels := map[string]string{
+		ArchLabelKey:    arch,
+		BuiltAtLabelKey: time.Now().UTC().Format(time.RFC3339),
+		VersionLabelKey: constants.KinderVersion,
+	}
+	for k, v := range c.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// sortedKeys returns the keys of a map, sorted
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
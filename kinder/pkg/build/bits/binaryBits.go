@@ -17,8 +17,10 @@ limitations under the License.
 package bits
 
 import (
+	"debug/elf"
 	"path/filepath"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	"k8s.io/kubeadm/kinder/pkg/extract"
@@ -27,17 +29,22 @@ import (
 // binaryBits defines a bit installer that allows to override the binary files in /usr/bin into the node image
 // using the binary bits existing in the src
 type binaryBits struct {
-	src        string
-	binaryName string
+	src            string
+	binaryName     string
+	arch           string
+	extractOptions []extract.Option
 }
 
 var _ Installer = &binaryBits{}
 
-// NewBinaryBits returns a new binary Installer
-func NewBinaryBits(src, binaryName string) Installer {
+// NewBinaryBits returns a new binary Installer; the binary is expected to be built for the given arch,
+// and extractOptions are used when retrieving the binary from the src
+func NewBinaryBits(src, binaryName, arch string, extractOptions ...extract.Option) Installer {
 	return &binaryBits{
-		src:        src,
-		binaryName: binaryName,
+		src:            src,
+		binaryName:     binaryName,
+		arch:           arch,
+		extractOptions: extractOptions,
 	}
 }
 
@@ -48,12 +55,24 @@ func (b *binaryBits) Prepare(c *BuildContext) (map[string]string, error) {
 	// and save it to the HostBitsPath
 	e := extract.NewExtractor(
 		b.src, c.HostBitsPath(),
-		extract.OnlyKubeadm(b.binaryName == "kubeadm"),
-		extract.OnlyKubelet(b.binaryName == "kubelet"),
+		append(b.extractOptions,
+			extract.OnlyKubeadm(b.binaryName == "kubeadm"),
+			extract.OnlyKubelet(b.binaryName == "kubelet"),
+			extract.WithArch(b.arch),
+		)...,
 	)
 
 	// Extracts the binary bit
-	return e.Extract()
+	paths, err := e.Extract()
+	if err != nil {
+		return nil, err
+	}
+
+	// Validates the binary bit before starting to alter the image
+	if err := ValidateBinary(paths[b.binaryName], b.arch); err != nil {
+		return nil, err
+	}
+	return paths, nil
 }
 
 // Install implements bits.Install
@@ -80,3 +99,30 @@ func (b *binaryBits) Install(c *BuildContext) error {
 
 	return nil
 }
+
+// elfMachines maps the kinder supported architectures to the corresponding ELF machine
+var elfMachines = map[string]elf.Machine{
+	"amd64":   elf.EM_X86_64,
+	"arm64":   elf.EM_AARCH64,
+	"ppc64le": elf.EM_PPC64,
+	"s390x":   elf.EM_S390,
+}
+
+// ValidateBinary checks the file at path is an ELF binary for the given arch
+func ValidateBinary(path, arch string) error {
+	machine, ok := elfMachines[arch]
+	if !ok {
+		return errors.Errorf("unsupported architecture %q", arch)
+	}
+
+	f, err := elf.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "%s is not a valid ELF binary", path)
+	}
+	defer f.Close()
+
+	if f.Machine != machine {
+		return errors.Errorf("%s is a binary for %s, while a binary for %s (%s) is required", path, f.Machine, arch, machine)
+	}
+	return nil
+}
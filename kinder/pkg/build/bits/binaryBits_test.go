@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bits
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestValidateBinary(t *testing.T) {
+	// the test binary itself is a valid ELF binary for the current arch
+	testBinary, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to get the test binary: %v", err)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "kinder-bits")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	notABinary := filepath.Join(tmpDir, "kubeadm")
+	if err := ioutil.WriteFile(notABinary, []byte("#!/bin/sh"), 0755); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	otherArch := "s390x"
+	if runtime.GOARCH == otherArch {
+		otherArch = "amd64"
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		arch          string
+		expectedError bool
+	}{
+		{
+			name: "valid: binary for the current arch",
+			path: testBinary,
+			arch: runtime.GOARCH,
+		},
+		{
+			name:          "invalid: binary for another arch",
+			path:          testBinary,
+			arch:          otherArch,
+			expectedError: true,
+		},
+		{
+			name:          "invalid: not an ELF binary",
+			path:          notABinary,
+			arch:          runtime.GOARCH,
+			expectedError: true,
+		},
+		{
+			name:          "invalid: missing file",
+			path:          filepath.Join(tmpDir, "missing"),
+			arch:          runtime.GOARCH,
+			expectedError: true,
+		},
+		{
+			name:          "invalid: unsupported arch",
+			path:          testBinary,
+			arch:          "mips",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateBinary(test.path, test.arch)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bits
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/extract"
+)
+
+// containerdReleaseURL defines the URL template of the containerd release tarballs; each tarball
+// has a SHA256 checksum published at the same URL with the .sha256sum suffix
+const containerdReleaseURL = "https://github.com/containerd/containerd/releases/download/v%[1]s/containerd-%[1]s-linux-%[2]s.tar.gz"
+
+// containerdBits defines a bit installer that allows to replace the containerd binaries existing in the node image
+// with the binaries of a specific containerd release
+type containerdBits struct {
+	version      string
+	arch         string
+	skipChecksum bool
+}
+
+var _ Installer = &containerdBits{}
+
+// NewContainerdBits returns a new containerdBits installing the given containerd release version, e.g. v1.4.3,
+// for the given arch; the release tarball is verified against the published checksum, unless skipChecksum is set
+func NewContainerdBits(version, arch string, skipChecksum bool) Installer {
+	return &containerdBits{
+		version:      strings.TrimPrefix(version, "v"),
+		arch:         arch,
+		skipChecksum: skipChecksum,
+	}
+}
+
+// Get implements Installer.Get
+func (b *containerdBits) Prepare(c *BuildContext) (map[string]string, error) {
+	// ensure the dest path exists on host/inside the HostBitsPath
+	dst := filepath.Join(c.HostBitsPath(), "containerd")
+	if err := os.Mkdir(dst, 0777); err != nil {
+		return nil, errors.Wrap(err, "failed to make bits dir")
+	}
+
+	// download the release tarball, verify its checksum and extract the binaries;
+	// nb. the tarball is removed once the binaries are extracted
+	url := fmt.Sprintf(containerdReleaseURL, b.version, b.arch)
+	checksumURL := url + ".sha256sum"
+	if b.skipChecksum {
+		checksumURL = ""
+	}
+	tarball := filepath.Join(c.HostBitsPath(), "containerd.tar.gz")
+	defer os.Remove(tarball)
+	log.Infof("Downloading containerd %s from %s", b.version, url)
+	if err := extract.Download(url, checksumURL, tarball); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", tarball)
+	}
+	defer f.Close()
+	paths, err := extractBinaries(f, dst)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to extract %s", url)
+	}
+
+	// Validates the containerd binary before starting to alter the image
+	if _, ok := paths["containerd"]; !ok {
+		return nil, errors.Errorf("%s does not contain the containerd binary", url)
+	}
+	if err := ValidateBinary(paths["containerd"], b.arch); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Install implements bits.Install
+func (b *containerdBits) Install(c *BuildContext) error {
+	// The src path is a subfolder into the alterDir, that is mounted in the
+	// container as /alter
+	src := filepath.Join(c.ContainerBitsPath(), "containerd")
+
+	// replaces each binary in the location where it is installed in the base image,
+	// or in /usr/local/bin for binaries not existing in the base image
+	script := fmt.Sprintf(`set -e
+for f in %[1]s/*; do
+  dest=$(command -v $(basename $f) || echo /usr/local/bin/$(basename $f))
+  cp $f $dest
+  chown root:root $dest
+done`, src)
+	if err := c.RunInContainer("bash", "-c", script); err != nil {
+		log.Errorf("Image alter Failed! %v", err)
+		return err
+	}
+
+	// ensure the binary in the PATH is the requested version
+	lines, err := c.CombinedOutputLinesInContainer("containerd", "--version")
+	if err != nil {
+		return errors.Wrap(err, "failed to get the containerd version")
+	}
+	if len(lines) != 1 || !strings.Contains(lines[0], fmt.Sprintf("v%s ", b.version)) {
+		return errors.Errorf("the containerd binary in the image does not report version v%s: %s", b.version, strings.Join(lines, " "))
+	}
+	log.Infof("Installed %s", lines[0])
+
+	return nil
+}
+
+// extractBinaries extracts the files in the bin folder of a tar.gz stream into dst
+func extractBinaries(r io.Reader, dst string) (map[string]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	paths := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || filepath.Base(filepath.Dir(hdr.Name)) != "bin" {
+			continue
+		}
+
+		name := filepath.Base(hdr.Name)
+		path := filepath.Join(dst, name)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if err := f.Close(); err != nil {
+			return nil, err
+		}
+		paths[name] = path
+	}
+	return paths, nil
+}
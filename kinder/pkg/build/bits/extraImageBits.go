@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bits
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
+)
+
+// extraImageBits defines a bit installer that allows to pull images from a registry and to add them as image tarballs
+// in the /kind/images folder into the node image; those images will be automatically loaded into the container runtime
+type extraImageBits struct {
+	images []string
+}
+
+var _ Installer = &extraImageBits{}
+
+// NewExtraImageBits returns a new extraImageBits
+func NewExtraImageBits(images []string) Installer {
+	// remove duplicated images, preserving order
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, i := range images {
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		unique = append(unique, i)
+	}
+
+	return &extraImageBits{
+		images: unique,
+	}
+}
+
+// Get implements Installer.Get
+func (b *extraImageBits) Prepare(c *BuildContext) (map[string]string, error) {
+	// ensure the dest path exists on host/inside the HostBitsPath
+	dst := filepath.Join(c.HostBitsPath(), "extra-images")
+	if err := os.Mkdir(dst, 0777); err != nil {
+		return nil, errors.Wrap(err, "failed to make bits dir")
+	}
+
+	// for each of the given images
+	allImages := map[string]string{}
+	for _, image := range b.images {
+		// pull the image, if not already present on the host
+		if _, err := kinddocker.PullIfNotPresent(image, 4); err != nil {
+			return nil, errors.Wrapf(err, "failed to pull image %s", image)
+		}
+
+		// save the image into an image tarball
+		name := fmt.Sprintf("%s.tar", imageTarName(image))
+		tar := filepath.Join(dst, name)
+		log.Infof("Saving %s into %s", image, tar)
+		if err := kinddocker.Save(image, tar); err != nil {
+			return nil, errors.Wrapf(err, "failed to save image %s", image)
+		}
+
+		allImages[name] = tar
+	}
+
+	return allImages, nil
+}
+
+// Install implements bits.Install
+func (b *extraImageBits) Install(c *BuildContext) error {
+	// The src path is a subfolder into the alterDir, that is mounted in the
+	// container as /alter
+	src := filepath.Join(c.ContainerBitsPath(), "extra-images")
+
+	// The dest path is /kind/images, a well known folder where kind(er) will
+	// search for pre-loaded images
+	dest := filepath.Join("/kind", "images")
+
+	// create dest folder
+	if err := c.RunInContainer("mkdir", "-p", dest); err != nil {
+		log.Errorf("Image alter failed! %v", err)
+		return err
+	}
+
+	// copy artifacts in
+	if err := c.RunInContainer("rsync", "-r", src+"/", dest); err != nil {
+		log.Errorf("Image alter failed! %v", err)
+		return err
+	}
+
+	// make sure we own the tarballs
+	// TODO: someday we might need a different user ...
+	if err := c.RunInContainer("chown", "-R", "root:root", dest); err != nil {
+		log.Errorf("Image alter failed! %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// imageTarName returns a file name for the tarball of an image, replacing chars not allowed in file names
+func imageTarName(image string) string {
+	return strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image)
+}
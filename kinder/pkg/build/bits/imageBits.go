@@ -29,17 +29,19 @@ import (
 // imageBits defines a bit installer that allows to add new images tarball in the /kind/images folder into the node image;
 // those images will be automatically loaded into docker when the container/the node will start
 type imageBits struct {
-	srcs       []string
-	namePrefix string
+	srcs           []string
+	namePrefix     string
+	extractOptions []extract.Option
 }
 
 var _ Installer = &imageBits{}
 
-// NewImageBits returns a new imageBits
-func NewImageBits(args []string, namePrefix string) Installer {
+// NewImageBits returns a new imageBits; extractOptions are used when retrieving bits from the srcs
+func NewImageBits(args []string, namePrefix string, extractOptions ...extract.Option) Installer {
 	return &imageBits{
-		srcs:       args,
-		namePrefix: namePrefix,
+		srcs:           args,
+		namePrefix:     namePrefix,
+		extractOptions: extractOptions,
 	}
 }
 
@@ -59,8 +61,10 @@ func (b *imageBits) Prepare(c *BuildContext) (map[string]string, error) {
 		// and save it to the dest path (inside HostBitsPath)
 		e := extract.NewExtractor(
 			src, dst,
-			extract.OnlyKubernetesImages(true),
-			extract.WithNamePrefix(b.namePrefix),
+			append(b.extractOptions,
+				extract.OnlyKubernetesImages(true),
+				extract.WithNamePrefix(b.namePrefix),
+			)...,
 		)
 
 		// if the source is a local repository
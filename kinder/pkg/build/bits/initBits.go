@@ -30,15 +30,17 @@ import (
 // initBits defines a bit installer that allows to add Kubernetes binaries & images to the node image;
 // those artifact will be used by the kinder do kubeadm-init script
 type initBits struct {
-	src string
+	src            string
+	extractOptions []extract.Option
 }
 
 var _ Installer = &initBits{}
 
-// NewInitBits returns a new initBits
-func NewInitBits(arg string) Installer {
+// NewInitBits returns a new initBits; extractOptions are used when retrieving bits from the src
+func NewInitBits(arg string, extractOptions ...extract.Option) Installer {
 	return &initBits{
-		src: arg,
+		src:            arg,
+		extractOptions: extractOptions,
 	}
 }
 
@@ -55,6 +57,7 @@ func (b *initBits) Prepare(c *BuildContext) (map[string]string, error) {
 	// and save it to the dst folder
 	e := extract.NewExtractor(
 		b.src, dst,
+		b.extractOptions...,
 	)
 
 	// Extracts the binaries & images
@@ -29,15 +29,17 @@ import (
 // upgradeBits defines a bit installer that allows to add Kubernetes binaries & images to the /kinder/upgrade folder into the node image;
 // those artifact will be used by the kinder do kubeadm-upgrade script
 type upgradeBits struct {
-	src string
+	src            string
+	extractOptions []extract.Option
 }
 
 var _ Installer = &upgradeBits{}
 
-// NewUpgradeBits returns a new upgradeBits
-func NewUpgradeBits(arg string) Installer {
+// NewUpgradeBits returns a new upgradeBits; extractOptions are used when retrieving bits from the src
+func NewUpgradeBits(arg string, extractOptions ...extract.Option) Installer {
 	return &upgradeBits{
-		src: arg,
+		src:            arg,
+		extractOptions: extractOptions,
 	}
 }
 
@@ -54,7 +56,9 @@ func (b *upgradeBits) Prepare(c *BuildContext) (map[string]string, error) {
 	// and save it to the dst folder
 	e := extract.NewExtractor(
 		b.src, dst,
-		extract.WithVersionFolder(true),
+		append(b.extractOptions,
+			extract.WithVersionFolder(true),
+		)...,
 	)
 
 	// Extracts the binary bit
@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/build/alter"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	kindnode "sigs.k8s.io/kind/pkg/build/node"
+)
+
+// Context is used to build the kind(er) node image, and contains
+// build configuration
+type Context struct {
+	image             string
+	baseImage         string
+	mode              string
+	kubeRoot          string
+	os                string
+	kubernetesVersion string
+	containerdVersion string
+}
+
+// Option is Context configuration option supplied to NewContext
+type Option func(*Context)
+
+// WithImage configures a NewContext to tag the built image with `image`
+func WithImage(image string) Option {
+	return func(b *Context) {
+		b.image = image
+	}
+}
+
+// WithBaseImage configures a NewContext to use `image` as the base image;
+// this is the FROM reference of the node image build. If empty, the default base image for the OS is used
+func WithBaseImage(image string) Option {
+	return func(b *Context) {
+		b.baseImage = image
+	}
+}
+
+// WithMode sets the kubernetes build mode for the build context
+func WithMode(mode string) Option {
+	return func(b *Context) {
+		b.mode = mode
+	}
+}
+
+// WithKubeRoot sets the path to the Kubernetes source directory (if empty, the path is autodetected)
+func WithKubeRoot(root string) Option {
+	return func(b *Context) {
+		b.kubeRoot = root
+	}
+}
+
+// WithOS sets the OS of the node image, linux or windows
+func WithOS(os string) Option {
+	return func(b *Context) {
+		b.os = os
+	}
+}
+
+// WithKubernetesVersion sets the Kubernetes release version or release label of the binaries
+// to be added to Windows node images
+func WithKubernetesVersion(version string) Option {
+	return func(b *Context) {
+		b.kubernetesVersion = version
+	}
+}
+
+// WithContainerdVersion sets the containerd release version to be installed in the node image,
+// replacing the containerd binaries bundled in the base image
+func WithContainerdVersion(version string) Option {
+	return func(b *Context) {
+		b.containerdVersion = version
+	}
+}
+
+// NewContext creates a new Context with default configuration,
+// overridden by the options supplied in the order that they are supplied
+func NewContext(options ...Option) (ctx *Context, err error) {
+	// default options
+	ctx = &Context{
+		image: constants.DefaultNodeImage,
+		mode:  kindnode.DefaultMode,
+		os:    LinuxOS,
+	}
+
+	// apply user options
+	for _, option := range options {
+		option(ctx)
+	}
+
+	return ctx, nil
+}
+
+// Build builds the node image starting from the base image, and then records the
+// base image in the node image labels
+func (c *Context) Build() error {
+	switch c.os {
+	case LinuxOS:
+	case WindowsOS:
+		return c.buildWindows()
+	default:
+		return errors.Errorf("unknown OS %q. Use one of [%s, %s]", c.os, LinuxOS, WindowsOS)
+	}
+
+	if c.baseImage == "" {
+		c.baseImage = constants.DefaultBaseImage
+	}
+	if err := alter.EnsureImage(c.baseImage); err != nil {
+		return err
+	}
+
+	// NB. the node image build is delegated to kind
+	ctx, err := kindnode.NewBuildContext(
+		kindnode.WithImage(c.image),
+		kindnode.WithBaseImage(c.baseImage),
+		kindnode.WithMode(c.mode),
+		kindnode.WithKuberoot(c.kubeRoot),
+	)
+	if err != nil {
+		return errors.Wrap(err, "error creating build context")
+	}
+	if err := ctx.Build(); err != nil {
+		return err
+	}
+
+	labels := map[string]string{
+		constants.BaseImageLabelKey: c.baseImage,
+	}
+
+	// eventually replaces containerd in the node image; this alters the image built by kind in place
+	if c.containerdVersion != "" {
+		alterCtx, err := alter.NewContext(
+			alter.WithBaseImage(c.image),
+			alter.WithImage(c.image),
+			alter.WithContainerdVersion(c.containerdVersion),
+		)
+		if err != nil {
+			return errors.Wrap(err, "error creating alter context")
+		}
+		if err := alterCtx.Alter(); err != nil {
+			return errors.Wrap(err, "error installing containerd")
+		}
+		labels[constants.ContainerdVersionLabelKey] = "v" + strings.TrimPrefix(c.containerdVersion, "v")
+	}
+
+	return alter.LabelImage(c.image, labels)
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/build/alter"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/extract"
+	kindfs "sigs.k8s.io/kind/pkg/fs"
+)
+
+const (
+	// LinuxOS identifies node images for Linux nodes
+	LinuxOS = "linux"
+
+	// WindowsOS identifies node images for Windows worker nodes
+	WindowsOS = "windows"
+
+	// DefaultWindowsBaseImage is the default base image used for Windows node images
+	DefaultWindowsBaseImage = "mcr.microsoft.com/windows/servercore:ltsc2019"
+
+	// windowsBinariesURL defines the URL template of the Kubernetes Windows binaries for a release version;
+	// each binary has a SHA256 checksum published at the same URL with the .sha256 suffix
+	windowsBinariesURL = "https://storage.googleapis.com/kubernetes-release/release/%s/bin/windows/amd64/%s"
+
+	// windowsContainerdURL defines the URL of the containerd release for Windows installed in the node image;
+	// the SHA256 checksum is published at the same URL with the .sha256sum suffix
+	windowsContainerdURL = "https://github.com/containerd/containerd/releases/download/v1.4.3/containerd-1.4.3-windows-amd64.tar.gz"
+
+	// windowsCNIPluginsURL defines the URL of the CNI plugins release for Windows installed in the node image;
+	// the SHA256 checksum is published at the same URL with the .sha256 suffix
+	windowsCNIPluginsURL = "https://github.com/containernetworking/plugins/releases/download/v0.8.7/cni-plugins-windows-amd64-v0.8.7.tgz"
+)
+
+// windowsContainerdConfig is the containerd configuration for Windows node images;
+// paths are the same expected by kubelet and by CNI plugins on Windows
+const windowsContainerdConfig = `version = 2
+root = "C:\\ProgramData\\containerd\\root"
+state = "C:\\ProgramData\\containerd\\state"
+
+[grpc]
+  address = "\\\\.\\pipe\\containerd-containerd"
+
+[plugins]
+  [plugins."io.containerd.grpc.v1.cri"]
+    sandbox_image = "mcr.microsoft.com/oss/kubernetes/pause:1.4.1"
+    [plugins."io.containerd.grpc.v1.cri".containerd]
+      snapshotter = "windows"
+      default_runtime_name = "runhcs-wcow-process"
+      [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runhcs-wcow-process]
+        runtime_type = "io.containerd.runhcs.v1"
+    [plugins."io.containerd.grpc.v1.cri".cni]
+      bin_dir = "C:\\opt\\cni\\bin"
+      conf_dir = "C:\\etc\\cni\\net.d"
+`
+
+// windowsKubeletEnv defines the kubelet flags for using containerd, in the same format of kubeadm-flags.env on Linux nodes;
+// NB. the node image does not register a kubelet service, so this file is not read automatically, and it should be used
+// by the scripts starting the kubelet on the Windows host
+const windowsKubeletEnv = `KUBELET_KUBEADM_ARGS=--container-runtime=remote --container-runtime-endpoint=npipe:////./pipe/containerd-containerd --cert-dir=C:\var\lib\kubelet\pki --enable-debugging-handlers --cgroups-per-qos=false --enforce-node-allocatable=""
+`
+
+// windowsDockerfile is the template of the Dockerfile for Windows node images
+const windowsDockerfile = `FROM {{BASE_IMAGE}}
+
+COPY kubelet.exe kubeadm.exe kubectl.exe C:/k/
+COPY containerd.tar.gz cni.tgz C:/
+
+RUN mkdir "C:\Program Files\containerd" && tar.exe -xzf C:\containerd.tar.gz -C "C:\Program Files\containerd" --strip-components=1 && del C:\containerd.tar.gz
+RUN mkdir C:\opt\cni\bin C:\etc\cni\net.d && tar.exe -xzf C:\cni.tgz -C C:\opt\cni\bin && del C:\cni.tgz
+
+COPY containerd.toml "C:/Program Files/containerd/config.toml"
+COPY kubelet.env C:/var/lib/kubelet/kubelet.env
+
+RUN setx /M PATH "C:\k;C:\Program Files\containerd;%PATH%"
+`
+
+// buildWindows builds a node image for Windows worker nodes, with the Windows kubelet/kubeadm/kubectl
+// binaries for the Kubernetes version and containerd as container runtime
+func (c *Context) buildWindows() error {
+	if err := checkWindowsHost(); err != nil {
+		return err
+	}
+
+	version, err := windowsKubernetesVersion(c.kubernetesVersion)
+	if err != nil {
+		return err
+	}
+
+	baseImage := c.baseImage
+	if baseImage == "" {
+		baseImage = DefaultWindowsBaseImage
+	}
+	if err := alter.EnsureImage(baseImage); err != nil {
+		return err
+	}
+
+	// create tempdir to build the image in
+	buildDir, err := kindfs.TempDir("", "kinder-windows-node-image")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(buildDir)
+	log.Infof("Building Windows node image for Kubernetes %s in: %s", version, buildDir)
+
+	// downloads the binaries into the build dir verifying the published checksums, because
+	// docker build does not verify files downloaded with ADD
+	kubeletURL := fmt.Sprintf(windowsBinariesURL, version, "kubelet.exe")
+	kubeadmURL := fmt.Sprintf(windowsBinariesURL, version, "kubeadm.exe")
+	kubectlURL := fmt.Sprintf(windowsBinariesURL, version, "kubectl.exe")
+	downloads := []struct {
+		name, url, checksumURL string
+	}{
+		{name: "kubelet.exe", url: kubeletURL, checksumURL: kubeletURL + ".sha256"},
+		{name: "kubeadm.exe", url: kubeadmURL, checksumURL: kubeadmURL + ".sha256"},
+		{name: "kubectl.exe", url: kubectlURL, checksumURL: kubectlURL + ".sha256"},
+		{name: "containerd.tar.gz", url: windowsContainerdURL, checksumURL: windowsContainerdURL + ".sha256sum"},
+		{name: "cni.tgz", url: windowsCNIPluginsURL, checksumURL: windowsCNIPluginsURL + ".sha256"},
+	}
+	for _, d := range downloads {
+		log.Infof("Downloading %s", d.url)
+		if err := extract.Download(d.url, d.checksumURL, filepath.Join(buildDir, d.name)); err != nil {
+			return err
+		}
+	}
+
+	dockerfile := strings.Replace(windowsDockerfile, "{{BASE_IMAGE}}", baseImage, -1)
+
+	files := map[string]string{
+		"Dockerfile":      dockerfile,
+		"containerd.toml": windowsContainerdConfig,
+		"kubelet.env":     windowsKubeletEnv,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(buildDir, name), []byte(content), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", name)
+		}
+	}
+
+	cmd := exec.NewHostCmd("docker", "build",
+		"-t", c.image,
+		"--label", fmt.Sprintf("%s=%s", constants.BaseImageLabelKey, baseImage),
+		"--label", fmt.Sprintf("%s=%s", constants.NodeOSLabelKey, WindowsOS),
+		buildDir,
+	)
+	if err := cmd.RunWithEcho(); err != nil {
+		return errors.Wrap(err, "failed to build Windows node image")
+	}
+
+	log.Infof("Windows node image %s built", c.image)
+	return nil
+}
+
+// checkWindowsHost ensures the docker daemon is able to build Windows images, that is, it is running
+// Windows containers; Windows images can't be built on Linux hosts
+func checkWindowsHost() error {
+	lines, err := exec.NewHostCmd("docker", "info", "--format", "{{.OSType}}").RunAndCapture()
+	if err != nil {
+		return errors.Wrap(err, "failed to detect the docker daemon OS")
+	}
+	if len(lines) != 1 || strings.TrimSpace(lines[0]) != WindowsOS {
+		return errors.Errorf("building Windows node images requires a docker daemon running Windows containers, got %q", strings.Join(lines, " "))
+	}
+	return nil
+}
+
+// windowsKubernetesVersion returns the Kubernetes release version to be used for Windows node images,
+// resolving release labels if necessary; CI builds and local sources are not supported
+func windowsKubernetesVersion(src string) (string, error) {
+	if src == "" {
+		return "", errors.New("building Windows node images requires a --kubernetes-version")
+	}
+	if extract.GetSourceType(src) != extract.ReleaseLabelOrVersionSource {
+		return "", errors.Errorf("invalid --kubernetes-version %q, Windows node images requires a release version or a release label", src)
+	}
+	if v, err := K8sVersion.ParseSemantic(src); err == nil {
+		return fmt.Sprintf("v%s", v), nil
+	}
+	return extract.ResolveLabel(src)
+}
@@ -21,12 +21,15 @@ package actions
 
 import (
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/metrics"
 )
 
 // action registry defines the list of available actions and the corresponding entry point.
@@ -39,28 +42,115 @@ var actionRegistry = map[string]func(*status.Cluster, *RunOptions) error{
 	"kubeadm-config": func(c *status.Cluster, flags *RunOptions) error {
 		// Nb. this action is invoked automatically at kubeadm init/join time, but it is possible
 		// to invoke it separately as well
-		return KubeadmConfig(c, flags.kubeDNS, flags.automaticCopyCerts, flags.discoveryMode, c.K8sNodes().EligibleForActions()...)
+		nodes := c.K8sNodes().EligibleForActions()
+		if err := KubeadmConfig(c, flags.kubeDNS, flags.automaticCopyCerts, flags.discoveryMode, flags.featureGates, flags.extraArgs, flags.configTemplates, nodes...); err != nil {
+			return err
+		}
+		return PrintKubeadmConfig(c, flags.diffConfig, nodes...)
 	},
 	"kubeadm-init": func(c *status.Cluster, flags *RunOptions) error {
-		return KubeadmInit(c, flags.usePhases, flags.kubeDNS, flags.automaticCopyCerts, flags.kustomizeDir, flags.wait, flags.vLevel)
+		if !flags.skipCgroupCheck {
+			if err := checkCgroupDriver(flags.kubeletExtraArgs, c.BootstrapControlPlane()); err != nil {
+				return err
+			}
+		}
+		return KubeadmInit(c, flags)
+	},
+	"kubeadm-init-phase": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeadmInitPhase(c, flags)
 	},
 	"kubeadm-join": func(c *status.Cluster, flags *RunOptions) error {
-		return KubeadmJoin(c, flags.usePhases, flags.automaticCopyCerts, flags.discoveryMode, flags.kustomizeDir, flags.wait, flags.vLevel)
+		if !flags.skipSkewCheck {
+			if err := checkJoinSkew(c); err != nil {
+				return err
+			}
+		}
+		if !flags.skipCgroupCheck {
+			nodes := append(c.SecondaryControlPlanes().EligibleForActions(), c.Workers().EligibleForActions()...)
+			if err := checkCgroupDriver(flags.kubeletExtraArgs, nodes...); err != nil {
+				return err
+			}
+		}
+		return KubeadmJoin(c, flags)
 	},
 	"kubeadm-upgrade": func(c *status.Cluster, flags *RunOptions) error {
-		return KubeadmUpgrade(c, flags.upgradeVersion, flags.kustomizeDir, flags.wait, flags.vLevel)
+		if !flags.skipSkewCheck {
+			if err := checkUpgradeSkew(c, flags.upgradeVersion); err != nil {
+				return err
+			}
+		}
+		return KubeadmUpgrade(c, flags.upgradeVersion, flags.kustomizeDir, flags.parallel, flags.wait, flags.probe(), flags.vLevel)
+	},
+	"upgrade": func(c *status.Cluster, flags *RunOptions) error {
+		if !flags.skipSkewCheck {
+			if err := checkUpgradeSkew(c, flags.upgradeVersion); err != nil {
+				return err
+			}
+		}
+		return Upgrade(c, flags.upgradeVersion, flags.kustomizeDir, flags.parallel, flags.wait, flags.probe(), flags.vLevel)
 	},
 	"kubeadm-reset": func(c *status.Cluster, flags *RunOptions) error {
-		return KubeadmReset(c, flags.vLevel)
+		return KubeadmReset(c, flags.cleanCNI, flags.parallel, flags.vLevel)
+	},
+	"kubeadm-certs-renew": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeadmCertsRenew(c, flags.restartStaticPods, flags.wait, flags.probe(), flags.vLevel)
+	},
+	"remove-cp": func(c *status.Cluster, flags *RunOptions) error {
+		return RemoveControlPlane(c, flags.vLevel)
+	},
+	"drain-and-delete": func(c *status.Cluster, flags *RunOptions) error {
+		return DrainAndDelete(c, flags.gracePeriod, flags.wait, flags.vLevel)
+	},
+	"install-cni": func(c *status.Cluster, flags *RunOptions) error {
+		return InstallCNI(c, flags.cni, flags.wait, flags.probe())
 	},
 	"copy-certs": func(c *status.Cluster, flags *RunOptions) error {
+		if flags.automaticCopyCerts {
+			return UploadCertificates(c, flags.vLevel)
+		}
 		return CopyCertificates(c)
 	},
 	"cluster-info": func(c *status.Cluster, flags *RunOptions) error {
 		return CluterInfo(c)
 	},
+	"assert-config": func(c *status.Cluster, flags *RunOptions) error {
+		return AssertConfig(c, flags.expect)
+	},
+	"enable-audit": func(c *status.Cluster, flags *RunOptions) error {
+		return EnableAudit(c, flags.auditPolicy, flags.wait, flags.probe())
+	},
+	"backup-etcd": func(c *status.Cluster, flags *RunOptions) error {
+		return BackupEtcd(c, flags.out)
+	},
+	"restore-etcd": func(c *status.Cluster, flags *RunOptions) error {
+		return RestoreEtcd(c, flags.from, flags.wait, flags.probe())
+	},
+	"etcd-health": func(c *status.Cluster, flags *RunOptions) error {
+		return EtcdHealth(c)
+	},
 	"smoke-test": func(c *status.Cluster, flags *RunOptions) error {
-		return SmokeTest(c, flags.wait)
+		return SmokeTest(c, flags.keep, flags.wait, flags.probe())
+	},
+	"netem": func(c *status.Cluster, flags *RunOptions) error {
+		return Netem(c, flags.delay, flags.loss, flags.parallel)
+	},
+	"netem-clear": func(c *status.Cluster, flags *RunOptions) error {
+		return NetemClear(c, flags.parallel)
+	},
+	"set-swap": func(c *status.Cluster, flags *RunOptions) error {
+		return SetSwap(c, flags.swapSize, flags.swapOff)
+	},
+	"simulate-cp-failure": func(c *status.Cluster, flags *RunOptions) error {
+		return SimulateControlPlaneFailure(c, flags.downtime, flags.wait, flags.probe())
+	},
+	"snapshot-manifests": func(c *status.Cluster, flags *RunOptions) error {
+		return SnapshotManifests(c, flags.out, flags.compare)
+	},
+	"rotate-token": func(c *status.Cluster, flags *RunOptions) error {
+		return RotateToken(c, flags.tokenTTL, flags.deleteOnly, flags.list)
+	},
+	"wait-control-plane": func(c *status.Cluster, flags *RunOptions) error {
+		return WaitControlPlane(c, flags.wait, flags.pollInterval, flags.maxRetries)
 	},
 }
 
@@ -135,6 +225,237 @@ func KustomizeDir(kustomizeDir string) Option {
 	}
 }
 
+// DiffConfig option instructs the kubeadm-config action to diff the generated config with the kubeadm-config ConfigMap
+func DiffConfig(diffConfig bool) Option {
+	return func(r *RunOptions) {
+		r.diffConfig = diffConfig
+	}
+}
+
+// PatchesDir option sets the kubeadm patches dir for the kubeadm init and join commands
+func PatchesDir(patchesDir string) Option {
+	return func(r *RunOptions) {
+		r.patchesDir = patchesDir
+	}
+}
+
+// RestartStaticPods option instructs kubeadm-certs-renew to restart control-plane static pods after certificates renewal
+func RestartStaticPods(restartStaticPods bool) Option {
+	return func(r *RunOptions) {
+		r.restartStaticPods = restartStaticPods
+	}
+}
+
+// FeatureGates option instructs kubeadm init to set the given kubeadm feature gates in the ClusterConfiguration
+func FeatureGates(featureGates map[string]bool) Option {
+	return func(r *RunOptions) {
+		r.featureGates = featureGates
+	}
+}
+
+// ExtraArgs option instructs kubeadm init to set the given extra args for the control-plane components in the ClusterConfiguration,
+// on top of the ones set at create time
+func ExtraArgs(extraArgs ControlPlaneExtraArgs) Option {
+	return func(r *RunOptions) {
+		r.extraArgs = extraArgs
+	}
+}
+
+// ConfigTemplates option instructs kinder to read kubeadm config templates from the given directory on the host,
+// e.g. v1beta2.yaml; config API versions without a template in the directory use the embedded templates
+func ConfigTemplates(configTemplates string) Option {
+	return func(r *RunOptions) {
+		r.configTemplates = configTemplates
+	}
+}
+
+// Downtime option sets for how long simulate-cp-failure keeps the control-plane node down
+func Downtime(downtime time.Duration) Option {
+	return func(r *RunOptions) {
+		r.downtime = downtime
+	}
+}
+
+// Delay option sets the latency added by the netem action to the outgoing traffic of nodes
+func Delay(delay time.Duration) Option {
+	return func(r *RunOptions) {
+		r.delay = delay
+	}
+}
+
+// Loss option sets the packet loss percentage added by the netem action to the outgoing traffic of nodes
+func Loss(loss string) Option {
+	return func(r *RunOptions) {
+		r.loss = loss
+	}
+}
+
+// KubeadmDryRun option instructs kubeadm-init to execute kubeadm init with the --dry-run flag
+func KubeadmDryRun(kubeadmDryRun bool) Option {
+	return func(r *RunOptions) {
+		r.kubeadmDryRun = kubeadmDryRun
+	}
+}
+
+// KubeletExtraArgs option sets the kubelet extra args to be used on nodes by kubeadm init and join
+func KubeletExtraArgs(kubeletExtraArgs []string) Option {
+	return func(r *RunOptions) {
+		r.kubeletExtraArgs = kubeletExtraArgs
+	}
+}
+
+// Parallel option sets the maximum number of nodes processed in parallel by actions supporting it
+func Parallel(parallel int) Option {
+	return func(r *RunOptions) {
+		r.parallel = parallel
+	}
+}
+
+// CNI option sets the CNI plugin to be installed by kubeadm init and install-cni
+func CNI(cni CNISpec) Option {
+	return func(r *RunOptions) {
+		r.cni = cni
+	}
+}
+
+// SkipCgroupCheck option instructs kubeadm-init and kubeadm-join actions to skip the check
+// ensuring the kubelet and the container runtime use the same cgroup driver
+func SkipCgroupCheck(skipCgroupCheck bool) Option {
+	return func(r *RunOptions) {
+		r.skipCgroupCheck = skipCgroupCheck
+	}
+}
+
+// SkipSkewCheck option instructs kubeadm-join and upgrade actions to skip the version skew check
+func SkipSkewCheck(skipSkewCheck bool) Option {
+	return func(r *RunOptions) {
+		r.skipSkewCheck = skipSkewCheck
+	}
+}
+
+// PollInterval option sets the interval between readiness probes executed while waiting for cluster state
+// to converge; 0 means the default interval of each action
+func PollInterval(pollInterval time.Duration) Option {
+	return func(r *RunOptions) {
+		r.pollInterval = pollInterval
+	}
+}
+
+// MaxRetries option sets the maximum number of readiness probes executed while waiting for cluster state
+// to converge; 0 means probes are retried until the wait timeout
+func MaxRetries(maxRetries int) Option {
+	return func(r *RunOptions) {
+		r.maxRetries = maxRetries
+	}
+}
+
+// Phase option sets the kubeadm init phase to be executed by kubeadm-init-phase
+func Phase(phase string) Option {
+	return func(r *RunOptions) {
+		r.phase = phase
+	}
+}
+
+// List option instructs kubeadm-init-phase to list the phases supported by kubeadm init, and rotate-token
+// to list the bootstrap tokens, instead of executing the action
+func List(list bool) Option {
+	return func(r *RunOptions) {
+		r.list = list
+	}
+}
+
+// TokenTTL option sets the TTL of the bootstrap token created by rotate-token
+func TokenTTL(tokenTTL time.Duration) Option {
+	return func(r *RunOptions) {
+		r.tokenTTL = tokenTTL
+	}
+}
+
+// DeleteOnly option instructs rotate-token to delete the bootstrap token without creating it again
+func DeleteOnly(deleteOnly bool) Option {
+	return func(r *RunOptions) {
+		r.deleteOnly = deleteOnly
+	}
+}
+
+// Out option sets the folder where snapshot-manifests copies static pod manifests
+func Out(out string) Option {
+	return func(r *RunOptions) {
+		r.out = out
+	}
+}
+
+// Compare option sets the folder with the static pod manifests snapshot to be compared by snapshot-manifests
+func Compare(compare string) Option {
+	return func(r *RunOptions) {
+		r.compare = compare
+	}
+}
+
+// GracePeriod option sets the period of time in seconds given to each pod to terminate gracefully
+// when draining a node with drain-and-delete; -1 means the default value specified in the pod is used
+func GracePeriod(gracePeriod int) Option {
+	return func(r *RunOptions) {
+		r.gracePeriod = gracePeriod
+	}
+}
+
+// SkipPhases option sets the kubeadm phases to be skipped by kubeadm-init and kubeadm-join
+func SkipPhases(skipPhases []string) Option {
+	return func(r *RunOptions) {
+		r.skipPhases = skipPhases
+	}
+}
+
+// Expect option sets the assertions on the ClusterConfiguration checked by assert-config
+func Expect(expect []string) Option {
+	return func(r *RunOptions) {
+		r.expect = expect
+	}
+}
+
+// CleanCNI option instructs kubeadm-reset to remove the CNI configuration, that is not removed by kubeadm reset
+func CleanCNI(cleanCNI bool) Option {
+	return func(r *RunOptions) {
+		r.cleanCNI = cleanCNI
+	}
+}
+
+// Keep option instructs smoke-test to leave in place the test resources
+func Keep(keep bool) Option {
+	return func(r *RunOptions) {
+		r.keep = keep
+	}
+}
+
+// AuditPolicy option sets the audit policy file on the host used by enable-audit
+func AuditPolicy(auditPolicy string) Option {
+	return func(r *RunOptions) {
+		r.auditPolicy = auditPolicy
+	}
+}
+
+// From option sets the etcd snapshot file on the host used by restore-etcd
+func From(from string) Option {
+	return func(r *RunOptions) {
+		r.from = from
+	}
+}
+
+// SwapSize option sets the size in MB of the swap file provisioned by set-swap
+func SwapSize(swapSize int) Option {
+	return func(r *RunOptions) {
+		r.swapSize = swapSize
+	}
+}
+
+// SwapOff option instructs set-swap to disable swap instead of enabling it
+func SwapOff(swapOff bool) Option {
+	return func(r *RunOptions) {
+		r.swapOff = swapOff
+	}
+}
+
 // RunOptions holds options supplied to actions.Run
 type RunOptions struct {
 	kubeDNS            bool
@@ -145,6 +466,46 @@ type RunOptions struct {
 	upgradeVersion     *K8sVersion.Version
 	vLevel             int
 	kustomizeDir       string
+	patchesDir         string
+	diffConfig         bool
+	restartStaticPods  bool
+	featureGates       map[string]bool
+	extraArgs          ControlPlaneExtraArgs
+	configTemplates    string
+	downtime           time.Duration
+	delay              time.Duration
+	loss               string
+	kubeadmDryRun      bool
+	kubeletExtraArgs   []string
+	parallel           int
+	cni                CNISpec
+	skipSkewCheck      bool
+	skipCgroupCheck    bool
+	pollInterval       time.Duration
+	maxRetries         int
+	phase              string
+	list               bool
+	out                string
+	compare            string
+	tokenTTL           time.Duration
+	deleteOnly         bool
+	gracePeriod        int
+	skipPhases         []string
+	expect             []string
+	keep               bool
+	cleanCNI           bool
+	auditPolicy        string
+	from               string
+	swapSize           int
+	swapOff            bool
+}
+
+// probe returns the settings for the readiness probes executed while waiting for cluster state to converge
+func (r *RunOptions) probe() probeConfig {
+	return probeConfig{
+		interval:   r.pollInterval,
+		maxRetries: r.maxRetries,
+	}
 }
 
 // DiscoveryMode defines discovery mode supported by kubeadm join
@@ -192,16 +553,76 @@ func ValidateDiscoveryMode(t DiscoveryMode) error {
 	return nil
 }
 
+// ParseFeatureGates parses a list of feature gates in the key=bool,... format
+func ParseFeatureGates(s string) (map[string]bool, error) {
+	featureGates := map[string]bool{}
+	if strings.TrimSpace(s) == "" {
+		return featureGates, nil
+	}
+
+	for _, gate := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(gate), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("invalid feature gate %q. Use the key=bool format, e.g. IPv6DualStack=true", gate)
+		}
+
+		value, err := strconv.ParseBool(parts[1])
+		if err != nil {
+			return nil, errors.Errorf("invalid value %q for feature gate %s. Use true or false", parts[1], parts[0])
+		}
+		if _, ok := featureGates[parts[0]]; ok {
+			return nil, errors.Errorf("feature gate %s is set more than once", parts[0])
+		}
+		featureGates[parts[0]] = value
+	}
+	return featureGates, nil
+}
+
 // Run executes one action
 func Run(c *status.Cluster, action string, options ...Option) error {
+	return RunAction(c, action, options...).Err
+}
+
+// RunAction executes an action and returns its result; errors caused by commands exiting with a
+// non-zero status are returned as ErrExec, unless the action returned a more specific action error
+func RunAction(c *status.Cluster, action string, options ...Option) *ActionResult {
 	flags := &RunOptions{}
 	for _, o := range options {
 		o(flags)
 	}
 
-	if a, ok := actionRegistry[action]; ok {
-		return a(c, flags)
+	a, ok := actionRegistry[action]
+	if !ok {
+		return &ActionResult{
+			Action: action,
+			Err:    errors.Errorf("%s is not a valid action name. Use one of %s", action, KnownActions()),
+		}
 	}
 
-	return errors.Errorf("%s is not a valid action name. Use one of %s", action, KnownActions())
+	// validate the settings for readiness probes
+	if flags.pollInterval < 0 {
+		return &ActionResult{
+			Action: action,
+			Err:    errors.Errorf("invalid --poll-interval %s, it must be 0 or greater", flags.pollInterval),
+		}
+	}
+	if flags.maxRetries < 0 {
+		return &ActionResult{
+			Action: action,
+			Err:    errors.Errorf("invalid --max-retries %d, it must be 0 or greater", flags.maxRetries),
+		}
+	}
+	start := time.Now()
+	r := &ActionResult{
+		Action: action,
+		Err:    execError(a(c, flags)),
+	}
+	r.Duration = time.Since(start)
+
+	result := "success"
+	if !r.Succeeded() {
+		result = "failure"
+	}
+	metrics.Observe(metrics.ActionSeconds, r.Duration, "action", action, "result", result)
+	return r
 }
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFeatureGates(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedGates map[string]bool
+		expectedError bool
+	}{
+		{
+			name:          "valid: empty string",
+			input:         "",
+			expectedGates: map[string]bool{},
+		},
+		{
+			name:          "valid: blank string",
+			input:         "  ",
+			expectedGates: map[string]bool{},
+		},
+		{
+			name:          "valid: single gate",
+			input:         "IPv6DualStack=true",
+			expectedGates: map[string]bool{"IPv6DualStack": true},
+		},
+		{
+			name:          "valid: multiple gates with spaces",
+			input:         "IPv6DualStack=true, PublicKeysECDSA=false",
+			expectedGates: map[string]bool{"IPv6DualStack": true, "PublicKeysECDSA": false},
+		},
+		{
+			name:          "invalid: missing value",
+			input:         "IPv6DualStack",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: missing key",
+			input:         "=true",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: value is not a bool",
+			input:         "IPv6DualStack=yes please",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: gate set more than once",
+			input:         "IPv6DualStack=true,IPv6DualStack=false",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: trailing comma",
+			input:         "IPv6DualStack=true,",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gates, err := ParseFeatureGates(test.input)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !reflect.DeepEqual(gates, test.expectedGates) {
+				t.Fatalf("expected feature gates: %v, found %v", test.expectedGates, gates)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/client-go/util/jsonpath"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	ksigsyaml "sigs.k8s.io/yaml"
+)
+
+// configAssertion defines an expected value for a field of the ClusterConfiguration
+type configAssertion struct {
+	key      string
+	expected string
+}
+
+// AssertConfig reads the ClusterConfiguration persisted by kubeadm in the kubeadm-config ConfigMap, and checks
+// that the fields selected by the given assertions have the expected values; assertions are in the key=value format,
+// where key is a jsonpath expression or a dotted path, e.g. networking.podSubnet=10.244.0.0/16 or {.apiServer.certSANs[0]}=localhost.
+// All the assertions are checked, and the action fails with a diff of the ones that don't match
+func AssertConfig(c *status.Cluster, expect []string) error {
+	assertions, err := parseConfigAssertions(expect)
+	if err != nil {
+		return err
+	}
+
+	cp1 := c.BootstrapControlPlane()
+	config, err := clusterConfiguration(cp1)
+	if err != nil {
+		return err
+	}
+
+	var diff []string
+	for _, a := range assertions {
+		actual, err := configValue(config, a.key)
+		if err != nil {
+			diff = append(diff, fmt.Sprintf("- %s: %s\n+ %s: <%v>", a.key, a.expected, a.key, err))
+			continue
+		}
+		if actual != a.expected {
+			diff = append(diff, fmt.Sprintf("- %s: %s\n+ %s: %s", a.key, a.expected, a.key, actual))
+			continue
+		}
+		fmt.Printf("%s: %s\n", a.key, actual)
+	}
+
+	if len(diff) > 0 {
+		return errors.Errorf("%d of %d assertions on the ClusterConfiguration failed (- expected, + actual):\n%s", len(diff), len(assertions), strings.Join(diff, "\n"))
+	}
+	fmt.Printf("\nAll the %d assertions on the ClusterConfiguration passed\n", len(assertions))
+	return nil
+}
+
+// parseConfigAssertions parses assertions in the key=value format; the key ends at the first = outside {...}, [...]
+// and (...), so jsonpath filters like {.etcd.local.extraArgs[?(@.name=="x")]} can be used, and values can contain =
+func parseConfigAssertions(expect []string) ([]configAssertion, error) {
+	if len(expect) == 0 {
+		return nil, errors.New("assert-config requires at least one --expect assertion, e.g. --expect networking.podSubnet=10.244.0.0/16")
+	}
+
+	assertions := []configAssertion{}
+	for _, e := range expect {
+		i := assertionSeparator(e)
+		if i < 0 || strings.TrimSpace(e[:i]) == "" {
+			return nil, errors.Errorf("invalid --expect %q. Use the key=value format, e.g. networking.podSubnet=10.244.0.0/16", e)
+		}
+		assertions = append(assertions, configAssertion{key: strings.TrimSpace(e[:i]), expected: e[i+1:]})
+	}
+	return assertions, nil
+}
+
+// assertionSeparator returns the index of the first = outside brackets in an assertion, or -1 if there is none
+func assertionSeparator(e string) int {
+	depth := 0
+	for i, r := range e {
+		switch r {
+		case '{', '[', '(':
+			depth++
+		case '}', ']', ')':
+			if depth > 0 {
+				depth--
+			}
+		case '=':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// clusterConfiguration reads the ClusterConfiguration from the kubeadm-config ConfigMap
+func clusterConfiguration(cp1 *status.Node) (interface{}, error) {
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system",
+		"get", "configmap", "kubeadm-config", "-o=jsonpath={.data.ClusterConfiguration}",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the kubeadm-config ConfigMap")
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("the kubeadm-config ConfigMap does not contain a ClusterConfiguration")
+	}
+
+	content, err := ksigsyaml.YAMLToJSON([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode the ClusterConfiguration")
+	}
+	var config interface{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the ClusterConfiguration")
+	}
+	return config, nil
+}
+
+// configValue returns the value of the field selected by key, that is a jsonpath expression, e.g. {.networking.podSubnet},
+// or a dotted path, e.g. networking.podSubnet
+func configValue(config interface{}, key string) (string, error) {
+	expression := key
+	if !strings.HasPrefix(expression, "{") {
+		expression = "{." + strings.TrimPrefix(expression, ".") + "}"
+	}
+
+	j := jsonpath.New("assert-config")
+	if err := j.Parse(expression); err != nil {
+		return "", errors.Wrapf(err, "invalid key %q", key)
+	}
+	var buf bytes.Buffer
+	if err := j.Execute(&buf, config); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfigAssertions(t *testing.T) {
+	tests := []struct {
+		name               string
+		inputExpect        []string
+		expectedAssertions []configAssertion
+		expectedError      bool
+	}{
+		{
+			name:          "invalid: no assertions",
+			expectedError: true,
+		},
+		{
+			name:        "valid: dotted path",
+			inputExpect: []string{"networking.podSubnet=10.244.0.0/16"},
+			expectedAssertions: []configAssertion{
+				{key: "networking.podSubnet", expected: "10.244.0.0/16"},
+			},
+		},
+		{
+			name:        "valid: jsonpath expression",
+			inputExpect: []string{"{.apiServer.certSANs[0]}=localhost"},
+			expectedAssertions: []configAssertion{
+				{key: "{.apiServer.certSANs[0]}", expected: "localhost"},
+			},
+		},
+		{
+			name:        "valid: jsonpath filter",
+			inputExpect: []string{`{.apiServer.extraVolumes[?(@.name=="audit")].mountPath}=/etc/kubernetes/audit`},
+			expectedAssertions: []configAssertion{
+				{key: `{.apiServer.extraVolumes[?(@.name=="audit")].mountPath}`, expected: "/etc/kubernetes/audit"},
+			},
+		},
+		{
+			name:        "valid: dotted path with filter",
+			inputExpect: []string{`apiServer.extraVolumes[?(@.name=="audit")].readOnly=true`},
+			expectedAssertions: []configAssertion{
+				{key: `apiServer.extraVolumes[?(@.name=="audit")].readOnly`, expected: "true"},
+			},
+		},
+		{
+			name:        "valid: value containing =",
+			inputExpect: []string{"apiServer.extraArgs.feature-gates=Foo=true,Bar=false"},
+			expectedAssertions: []configAssertion{
+				{key: "apiServer.extraArgs.feature-gates", expected: "Foo=true,Bar=false"},
+			},
+		},
+		{
+			name:        "valid: empty value and multiple assertions",
+			inputExpect: []string{" controlPlaneEndpoint =", "clusterName=kind"},
+			expectedAssertions: []configAssertion{
+				{key: "controlPlaneEndpoint", expected: ""},
+				{key: "clusterName", expected: "kind"},
+			},
+		},
+		{
+			name:          "invalid: missing =",
+			inputExpect:   []string{"networking.podSubnet"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: = only inside the jsonpath filter",
+			inputExpect:   []string{`{.apiServer.extraVolumes[?(@.name=="audit")]}`},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: missing key",
+			inputExpect:   []string{"=10.244.0.0/16"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertions, err := parseConfigAssertions(test.inputExpect)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !reflect.DeepEqual(assertions, test.expectedAssertions) {
+				t.Fatalf("expected assertions: %v, found %v", test.expectedAssertions, assertions)
+			}
+		})
+	}
+}
+
+func TestConfigValue(t *testing.T) {
+	config := map[string]interface{}{
+		"networking": map[string]interface{}{"podSubnet": "10.244.0.0/16"},
+		"apiServer": map[string]interface{}{
+			"extraVolumes": []interface{}{
+				map[string]interface{}{"name": "audit", "mountPath": "/etc/kubernetes/audit"},
+				map[string]interface{}{"name": "other", "mountPath": "/other"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		inputKey      string
+		expectedValue string
+		expectedError bool
+	}{
+		{
+			name:          "valid: dotted path",
+			inputKey:      "networking.podSubnet",
+			expectedValue: "10.244.0.0/16",
+		},
+		{
+			name:          "valid: jsonpath expression",
+			inputKey:      "{.networking.podSubnet}",
+			expectedValue: "10.244.0.0/16",
+		},
+		{
+			name:          "valid: jsonpath filter",
+			inputKey:      `{.apiServer.extraVolumes[?(@.name=="audit")].mountPath}`,
+			expectedValue: "/etc/kubernetes/audit",
+		},
+		{
+			name:          "invalid: missing field",
+			inputKey:      "networking.serviceSubnet",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: jsonpath syntax",
+			inputKey:      "{.networking[}",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := configValue(config, test.inputKey)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if value != test.expectedValue {
+				t.Fatalf("expected value: %q, found %q", test.expectedValue, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri"
+)
+
+// checkCgroupDriver ensures the kubelet on the given nodes is going to use the same cgroup driver of the
+// container runtime; the kubelet cgroup driver is the last --cgroup-driver flag in the node specific
+// extra args set at create time followed by the given kubelet extra args, as written by writeKubeletExtraArgs.
+// Nodes where the kubelet cgroup driver is not set, e.g. nodes created by older versions of kinder, are skipped
+func checkCgroupDriver(kubeletExtraArgs []string, nodes ...*status.Node) error {
+	errs := []error{}
+	for _, n := range nodes {
+		settings, err := n.ReadNodeSettings()
+		if err != nil {
+			return err
+		}
+		kubeletDriver := kubeletCgroupDriver(append(append([]string{}, settings.KubeletExtraArgs...), kubeletExtraArgs...))
+		if kubeletDriver == "" {
+			continue
+		}
+
+		nodeCRI, err := n.CRI()
+		if err != nil {
+			return err
+		}
+		actionHelper, err := cri.NewActionHelper(nodeCRI)
+		if err != nil {
+			return err
+		}
+		criDriver, err := actionHelper.GetCgroupDriver(n)
+		if err != nil {
+			return err
+		}
+
+		if kubeletDriver != criDriver {
+			errs = append(errs, errors.Errorf("node %s has the kubelet configured with the %s cgroup driver, but %s uses the %s cgroup driver", n.Name(), kubeletDriver, nodeCRI, criDriver))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return preflightError(errors.Wrap(kerrors.NewAggregate(errs), "cgroup driver check failed (use --skip-cgroup-driver-check for intentionally testing mismatched cgroup drivers)"))
+}
+
+// kubeletCgroupDriver returns the cgroup driver set in a list of --key=value kubelet flags, if any;
+// for kubelet, the last value of a flag wins
+func kubeletCgroupDriver(kubeletExtraArgs []string) status.CgroupDriver {
+	driver := ""
+	for _, arg := range kubeletExtraArgs {
+		if strings.HasPrefix(arg, "--cgroup-driver=") {
+			driver = strings.TrimPrefix(arg, "--cgroup-driver=")
+		}
+	}
+	return status.CgroupDriver(driver)
+}
@@ -67,12 +67,7 @@ func CluterInfo(c *status.Cluster) error {
 		}
 
 		// Get the version of etcdctl from the etcd binary
-		versionArgs := append(etcdArgs, "etcd", "--version")
-		lines, err := cp1.Command("kubectl", versionArgs...).RunAndCapture()
-		if err != nil {
-			return err
-		}
-		etcdctlVersion, err := parseEtcdctlVersion(lines)
+		etcdctlVersion, err := etcdVersion(cp1)
 		if err != nil {
 			return err
 		}
@@ -98,6 +93,19 @@ func CluterInfo(c *status.Cluster) error {
 	return nil
 }
 
+// etcdVersion returns the version of the etcd binary in the etcd static pod of the given control-plane node,
+// that is also the version of etcdctl
+func etcdVersion(n *status.Node) (string, error) {
+	lines, err := n.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "exec", "-n=kube-system", fmt.Sprintf("etcd-%s", n.Name()),
+		"--", "etcd", "--version",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return "", err
+	}
+	return parseEtcdctlVersion(lines)
+}
+
 // parseEtcdctlVersion takes the output lines of 'etcdctl version' and returns the version
 func parseEtcdctlVersion(lines []string) (string, error) {
 	if len(lines) < 1 {
@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// kubeadm phases of a node, as detected by kubeadmPhase
+const (
+	rawPhase         = "raw"
+	initializedPhase = "initialized"
+	joinedPhase      = "joined"
+)
+
+// ClusterStatus prints a quick overview of the cluster: for each node the role, the container runtime,
+// the kubeadm version, the kubeadm phase and the CPU and memory limits of the container, followed by the control-plane endpoint and
+// by the API server reachability from the host.
+// Please note that, differently from other actions, this is designed to work also for partially
+// created or broken clusters, so errors are reported in the overview instead of being returned
+func ClusterStatus(c *status.Cluster) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tROLE\tCRI\tVERSION\tPHASE\tCPU\tMEMORY")
+	for _, n := range c.AllNodes() {
+		cri, version, phase := "-", "-", "-"
+		if !n.IsExternalLoadBalancer() && !n.IsExternalEtcd() {
+			if nodeCRI, err := n.CRI(); err == nil {
+				cri = string(nodeCRI)
+			} else {
+				cri = "unknown"
+			}
+			if v, err := n.KubeadmVersion(); err == nil {
+				version = fmt.Sprintf("v%s", v)
+			} else {
+				version = "unknown"
+			}
+			phase = kubeadmPhase(c, n)
+		}
+		cpu, memory := nodeResources(n)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", n.Name(), n.Role(), cri, version, phase, cpu, memory)
+	}
+	w.Flush()
+	fmt.Println()
+
+	if c.BootstrapControlPlane() == nil {
+		fmt.Println("Control-plane endpoint: none, the cluster does not have control-plane nodes")
+		return nil
+	}
+
+	endpoint, err := controlPlaneEndpoint(c)
+	if err != nil {
+		fmt.Printf("Control-plane endpoint: unknown (%v)\n", err)
+	} else {
+		fmt.Printf("Control-plane endpoint: %s\n", endpoint)
+	}
+
+	hostPort, err := getAPIServerPort(c)
+	if err != nil {
+		fmt.Printf("API server: unknown (%v)\n", err)
+		return nil
+	}
+	url := fmt.Sprintf("https://%s/healthz", net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort)))
+	_, health := apiServerHealth(url)
+	fmt.Printf("API server: %s (%s)\n", url, health)
+	return nil
+}
+
+// kubeadmPhase detects the kubeadm phase of a node by inspecting the files created by kubeadm inside the node:
+// - raw, if kubeadm init/join was not executed yet
+// - initialized, if kubeadm init was executed on the bootstrap control-plane
+// - joined, if kubeadm join was executed on other nodes
+func kubeadmPhase(c *status.Cluster, n *status.Node) string {
+	if err := n.Command("test", "-f", "/etc/kubernetes/kubelet.conf").Silent().Run(); err != nil {
+		return rawPhase
+	}
+	if n.Name() == c.BootstrapControlPlane().Name() {
+		return initializedPhase
+	}
+	return joinedPhase
+}
+
+// nodeResources returns the CPU and memory limits of the node container, formatted for the cluster overview;
+// - is used for no limit
+func nodeResources(n *status.Node) (string, string) {
+	cpus, memory, err := n.Resources()
+	if err != nil {
+		return "unknown", "unknown"
+	}
+	cpu, mem := "-", "-"
+	if cpus > 0 {
+		cpu = strconv.FormatFloat(cpus, 'f', -1, 64)
+	}
+	if memory > 0 {
+		mem = fmt.Sprintf("%dMi", memory/(1024*1024))
+	}
+	return cpu, mem
+}
+
+// controlPlaneEndpoint returns the control-plane endpoint on the container network,
+// that is the external load balancer, if any, or the bootstrap control-plane node
+func controlPlaneEndpoint(c *status.Cluster) (string, error) {
+	if lb := c.ExternalLoadBalancer(); lb != nil {
+		ip, err := nodeIP(c, lb)
+		if err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(ip, fmt.Sprintf("%d", constants.ControlPlanePort)), nil
+	}
+
+	ip, err := nodeIP(c, c.BootstrapControlPlane())
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ip, fmt.Sprintf("%d", constants.APIServerPort)), nil
+}
+
+// nodeIP returns the address of the node for the cluster IP family; in case of dual-stack, the IPv4 address is used
+func nodeIP(c *status.Cluster, n *status.Node) (string, error) {
+	ipv4, ipv6, err := n.IP()
+	if err != nil {
+		return "", err
+	}
+	if c.Settings != nil && c.Settings.IPFamily == status.IPv6Family {
+		return ipv6, nil
+	}
+	return ipv4, nil
+}
+
+// apiServerHealth checks if the API server is reachable at url, and returns if it is healthy
+// together with a message describing its state
+func apiServerHealth(url string) (bool, string) {
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			// nb. the API server is using a certificate signed by the cluster CA
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return false, fmt.Sprintf("not reachable: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Sprintf("reachable, healthz returned %s", resp.Status)
+	}
+	return true, "reachable, healthy"
+}
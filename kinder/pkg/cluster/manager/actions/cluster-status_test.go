@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIServerHealth(t *testing.T) {
+	tests := []struct {
+		name            string
+		inputStatus     int
+		inputClosed     bool
+		expectedHealthy bool
+		expectedMessage string
+	}{
+		{
+			name:            "valid: healthz returns 200",
+			inputStatus:     http.StatusOK,
+			expectedHealthy: true,
+			expectedMessage: "reachable, healthy",
+		},
+		{
+			name:            "invalid: healthz returns 500",
+			inputStatus:     http.StatusInternalServerError,
+			expectedMessage: "reachable, healthz returned 500 Internal Server Error",
+		},
+		{
+			name:            "invalid: healthz returns 403",
+			inputStatus:     http.StatusForbidden,
+			expectedMessage: "reachable, healthz returned 403 Forbidden",
+		},
+		{
+			name:            "invalid: API server not reachable",
+			inputClosed:     true,
+			expectedMessage: "not reachable: ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.inputStatus)
+			}))
+			url := server.URL + "/healthz"
+			if test.inputClosed {
+				server.Close()
+			} else {
+				defer server.Close()
+			}
+
+			healthy, message := apiServerHealth(url)
+			if healthy != test.expectedHealthy {
+				t.Fatalf("expected healthy: %v, found %v, message: %s", test.expectedHealthy, healthy, message)
+			}
+			if !strings.HasPrefix(message, test.expectedMessage) {
+				t.Fatalf("expected message: %q, found %q", test.expectedMessage, message)
+			}
+		})
+	}
+}
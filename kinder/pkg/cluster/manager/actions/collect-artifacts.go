@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// CollectArtifacts collects logs and other diagnostic artifacts from all the nodes in the cluster
+// into dir, using a sub folder for each node.
+// Collection continues in case of errors on a node, and at the end an error summarizing
+// the nodes where collection failed is returned
+func CollectArtifacts(c *status.Cluster, dir string) error {
+	failed := map[string]error{}
+	for _, n := range c.AllNodes() {
+		n.Infof("collecting artifacts")
+
+		if err := collectNodeArtifacts(n, filepath.Join(dir, n.Name())); err != nil {
+			fmt.Printf("failed to collect artifacts from node %s: %v\n", n.Name(), err)
+			failed[n.Name()] = err
+		}
+	}
+
+	if len(failed) > 0 {
+		var nodes []string
+		for n := range failed {
+			nodes = append(nodes, n)
+		}
+		sort.Strings(nodes)
+
+		fmt.Printf("\nArtifacts collected into %s, with errors:\n", dir)
+		for _, n := range nodes {
+			fmt.Printf("- %s: %v\n", n, failed[n])
+		}
+		return errors.Errorf("failed to collect artifacts from %d of %d nodes: %s", len(failed), len(c.AllNodes()), strings.Join(nodes, ", "))
+	}
+
+	fmt.Printf("\nArtifacts collected into %s\n", dir)
+	return nil
+}
+
+// collectNodeArtifacts collects logs and other diagnostic artifacts from a node into dir;
+// collection continues in case of errors, and at the end an error listing the artifacts
+// that failed is returned
+func collectNodeArtifacts(n *status.Node, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s", dir)
+	}
+
+	var failed []string
+	collect := func(file string, lines []string, err error) {
+		// nb. the output is saved also in case of errors, because it can contain useful info
+		if writeErr := ioutil.WriteFile(filepath.Join(dir, file), []byte(strings.Join(lines, "\n")+"\n"), 0644); writeErr != nil && err == nil {
+			err = writeErr
+		}
+		if err != nil {
+			failed = append(failed, file)
+		}
+	}
+
+	// the output of the node container, that is the output of systemd
+	lines, err := exec.NewHostCmd("docker", "logs", n.Name()).RunAndCapture()
+	collect("serial.log", lines, err)
+
+	// external load balancer and external etcd nodes don't run systemd and kubeadm
+	if n.IsExternalLoadBalancer() || n.IsExternalEtcd() {
+		return failedArtifacts(failed)
+	}
+
+	lines, err = n.Command("journalctl", "--no-pager").Silent().RunAndCapture()
+	collect("journal.log", lines, err)
+
+	lines, err = n.Command("journalctl", "--no-pager", "-u", "kubelet.service").Silent().RunAndCapture()
+	collect("kubelet.log", lines, err)
+
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		failed = append(failed, "cri")
+	} else {
+		// nb. the container runtime name matches the name of its systemd service
+		lines, err = n.Command("journalctl", "--no-pager", "-u", fmt.Sprintf("%s.service", nodeCRI)).Silent().RunAndCapture()
+		collect(fmt.Sprintf("%s.log", nodeCRI), lines, err)
+
+		// the state of containers and images in the container runtime
+		cli := "crictl"
+		if nodeCRI == status.DockerRuntime {
+			cli = "docker"
+		}
+		lines, err = n.Command(cli, "ps", "-a").Silent().RunAndCapture()
+		collect("containers.txt", lines, err)
+
+		lines, err = n.Command(cli, "images").Silent().RunAndCapture()
+		collect("images.txt", lines, err)
+	}
+
+	// the logs of all the containers, including the control-plane static pods
+	if err := n.CopyFrom("/var/log/pods", filepath.Join(dir, "pods")); err != nil {
+		failed = append(failed, "pods")
+	}
+
+	lines, err = n.Command("cat", constants.KubeadmConfigPath).Silent().RunAndCapture()
+	collect("kubeadm.conf", lines, err)
+
+	// nb. the kubeadm version is used for interpreting the kubeadm config and logs
+	lines, err = n.Command("kubeadm", "version").Silent().RunAndCapture()
+	collect("kubeadm-version.txt", lines, err)
+
+	lines, err = n.Command("ls", "-laR", "/etc/kubernetes").Silent().RunAndCapture()
+	collect("etc-kubernetes.txt", lines, err)
+
+	return failedArtifacts(failed)
+}
+
+// failedArtifacts returns an error listing the artifacts that failed to be collected, if any
+func failedArtifacts(failed []string) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.Errorf("failed to collect %s", strings.Join(failed, ", "))
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// ControlPlaneExtraArgs defines the extra args to be set in the ClusterConfiguration for the control-plane
+// components, keyed by flag name without the leading --
+type ControlPlaneExtraArgs struct {
+	APIServer         map[string]string
+	ControllerManager map[string]string
+	Scheduler         map[string]string
+	// Etcd and EtcdDataDir are the extra args and the data dir of the local etcd; they are set only
+	// for kubeadm init, and not at create time
+	Etcd        map[string]string
+	EtcdDataDir string
+}
+
+// ParseControlPlaneExtraArgs parses a list of flags for a control-plane component in the key=value format,
+// with or without the leading --, and returns them as a map
+func ParseControlPlaneExtraArgs(component string, args []string) (map[string]string, error) {
+	extraArgs := map[string]string{}
+	for _, arg := range args {
+		parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(arg), "--"), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("invalid %s extra arg %q. Use the key=value format, e.g. v=4", component, arg)
+		}
+		if strings.ContainsAny(arg, " \t\"'\\") {
+			return nil, errors.Errorf("invalid %s extra arg %q. Spaces, quotes and backslashes are not supported", component, arg)
+		}
+		if _, ok := extraArgs[parts[0]]; ok {
+			return nil, errors.Errorf("invalid %s extra args. Arg %q is set more than once", component, parts[0])
+		}
+		extraArgs[parts[0]] = parts[1]
+	}
+	return extraArgs, nil
+}
+
+// ParseAllControlPlaneExtraArgs parses the lists of flags in the key=value format for the kube-apiserver,
+// the kube-controller-manager and the kube-scheduler
+func ParseAllControlPlaneExtraArgs(apiServerArgs, controllerManagerArgs, schedulerArgs []string) (extraArgs ControlPlaneExtraArgs, err error) {
+	if extraArgs.APIServer, err = ParseControlPlaneExtraArgs("kube-apiserver", apiServerArgs); err != nil {
+		return extraArgs, err
+	}
+	if extraArgs.ControllerManager, err = ParseControlPlaneExtraArgs("kube-controller-manager", controllerManagerArgs); err != nil {
+		return extraArgs, err
+	}
+	if extraArgs.Scheduler, err = ParseControlPlaneExtraArgs("kube-scheduler", schedulerArgs); err != nil {
+		return extraArgs, err
+	}
+	return extraArgs, nil
+}
+
+// ParseEtcdExtraArgs parses a list of flags for the local etcd in the key=value format and validates
+// the etcd data dir, if set; the data dir must be an absolute path and it can't be set as an extra arg
+func ParseEtcdExtraArgs(dataDir string, args []string) (map[string]string, error) {
+	if dataDir != "" && !path.IsAbs(dataDir) {
+		return nil, errors.Errorf("invalid etcd data dir %q. It must be an absolute path, e.g. /var/lib/etcd-test", dataDir)
+	}
+
+	extraArgs, err := ParseControlPlaneExtraArgs("etcd", args)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := extraArgs["data-dir"]; ok {
+		return nil, errors.New("invalid etcd extra args. Use the etcd data dir flag instead of data-dir")
+	}
+	if v, ok := extraArgs["quota-backend-bytes"]; ok {
+		if quota, err := strconv.ParseInt(v, 10, 64); err != nil || quota <= 0 {
+			return nil, errors.Errorf("invalid etcd extra arg quota-backend-bytes=%s. It must be a positive number of bytes, e.g. 2147483648", v)
+		}
+	}
+	return extraArgs, nil
+}
+
+// controlPlaneExtraArgs returns the extra args set at create time, if any, with the given extra args on top
+func controlPlaneExtraArgs(c *status.Cluster, extraArgs ControlPlaneExtraArgs) ControlPlaneExtraArgs {
+	merge := func(created, requested map[string]string) map[string]string {
+		merged := map[string]string{}
+		for k, v := range created {
+			merged[k] = v
+		}
+		for k, v := range requested {
+			merged[k] = v
+		}
+		return merged
+	}
+
+	return ControlPlaneExtraArgs{
+		APIServer:         merge(c.Settings.APIServerExtraArgs, extraArgs.APIServer),
+		ControllerManager: merge(c.Settings.ControllerManagerExtraArgs, extraArgs.ControllerManager),
+		Scheduler:         merge(c.Settings.SchedulerExtraArgs, extraArgs.Scheduler),
+		Etcd:              extraArgs.Etcd,
+		EtcdDataDir:       extraArgs.EtcdDataDir,
+	}
+}
+
+// isEmpty returns true if no extra args are set for any control-plane component
+func (e ControlPlaneExtraArgs) isEmpty() bool {
+	return len(e.APIServer) == 0 && len(e.ControllerManager) == 0 && len(e.Scheduler) == 0
+}
+
+// hasLocalEtcd returns true if the extra args or the data dir are set for the local etcd
+func (e ControlPlaneExtraArgs) hasLocalEtcd() bool {
+	return len(e.Etcd) > 0 || e.EtcdDataDir != ""
+}
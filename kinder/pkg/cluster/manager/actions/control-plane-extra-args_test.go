@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseControlPlaneExtraArgs(t *testing.T) {
+	tests := []struct {
+		name              string
+		inputArgs         []string
+		expectedExtraArgs map[string]string
+		expectedError     bool
+	}{
+		{
+			name:              "valid: no args",
+			expectedExtraArgs: map[string]string{},
+		},
+		{
+			name:      "valid: args with and without the leading --",
+			inputArgs: []string{"v=4", "--audit-log-maxage=2"},
+			expectedExtraArgs: map[string]string{
+				"v":                "4",
+				"audit-log-maxage": "2",
+			},
+		},
+		{
+			name:      "valid: value containing = and ,",
+			inputArgs: []string{"feature-gates=Foo=true,Bar=false"},
+			expectedExtraArgs: map[string]string{
+				"feature-gates": "Foo=true,Bar=false",
+			},
+		},
+		{
+			name:      "valid: empty value",
+			inputArgs: []string{"--profiling="},
+			expectedExtraArgs: map[string]string{
+				"profiling": "",
+			},
+		},
+		{
+			name:          "invalid: missing value",
+			inputArgs:     []string{"--v"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: missing key",
+			inputArgs:     []string{"=4"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: value with spaces",
+			inputArgs:     []string{"audit-policy-file=/etc/foo bar"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: value with backslashes",
+			inputArgs:     []string{`audit-policy-file=C:\foo`},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: arg set more than once",
+			inputArgs:     []string{"v=4", "--v=2"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			extraArgs, err := ParseControlPlaneExtraArgs("kube-apiserver", test.inputArgs)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !reflect.DeepEqual(extraArgs, test.expectedExtraArgs) {
+				t.Fatalf("expected extra args: %v, found %v", test.expectedExtraArgs, extraArgs)
+			}
+		})
+	}
+}
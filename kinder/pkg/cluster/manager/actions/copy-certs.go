@@ -17,6 +17,8 @@ limitations under the License.
 package actions
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -24,6 +26,7 @@ import (
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
 )
 
 // CopyCertificates actions automate the manual copy of
@@ -79,3 +82,92 @@ func copyCertificatesToNode(c *status.Cluster, n *status.Node) error {
 
 	return nil
 }
+
+// UploadCertificates action uploads the certificates to be shared across control-plane nodes
+// from the bootstrap control-plane into the kubeadm-certs secret, using a new certificate key that
+// is stored in the cluster settings and automatically used by kubeadm join with automatic copy certs
+func UploadCertificates(c *status.Cluster, vLevel int) error {
+	key, err := newCertificateKey()
+	if err != nil {
+		return err
+	}
+
+	if err := uploadCertificates(c, key, vLevel); err != nil {
+		return err
+	}
+
+	// stores the certificate key in the cluster, so it can be used by subsequent kubeadm join
+	c.Settings.CertificateKey = key
+	if err := c.WriteSettings(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nCertificate key: %s\n", key)
+	return nil
+}
+
+// ensureUploadedCertificates checks the certificates uploaded to the kubeadm-certs secret still exist, and
+// if not, uploads them again using the given certificate key. This is necessary because
+// kubeadm deletes uploaded certificates after two hours
+func ensureUploadedCertificates(c *status.Cluster, key string, vLevel int) error {
+	cp1 := c.BootstrapControlPlane()
+	if err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+		"get", "secret", "kubeadm-certs", "-n=kube-system",
+	).Silent().Run(); err == nil {
+		return nil
+	}
+
+	cp1.Infof("Certificates uploaded to the kubeadm-certs secret are expired or missing, uploading them again")
+	return uploadCertificates(c, key, vLevel)
+}
+
+// uploadCertificates executes kubeadm init phase upload-certs on the bootstrap control-plane using the given certificate key
+func uploadCertificates(c *status.Cluster, key string, vLevel int) error {
+	cp1 := c.BootstrapControlPlane()
+
+	// automatic copy certs is supported starting from v1.14
+	if cp1.MustKubeadmVersion().LessThan(constants.V1_14) {
+		return errors.New("--automatic-copy-certs can't be used with kubeadm older than v1.14")
+	}
+
+	cp1.Infof("Uploading cluster certificates")
+
+	uploadCertsArgs := []string{
+		"init", "phase", "upload-certs",
+		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+		fmt.Sprintf("--certificate-key=%s", key),
+		fmt.Sprintf("--v=%d", vLevel),
+	}
+	if cp1.MustKubeadmVersion().AtLeast(constants.V1_15) {
+		uploadCertsArgs = append(uploadCertsArgs, "--upload-certs")
+	} else {
+		// if before v1.15, upload-certs flag requires --experimental prefix
+		uploadCertsArgs = append(uploadCertsArgs, "--experimental-upload-certs")
+	}
+
+	if err := cp1.Command(
+		"kubeadm", uploadCertsArgs...,
+	).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to upload certificates from %s", cp1.Name())
+	}
+	return nil
+}
+
+// certificateKey returns the certificate key to be used for automatic copy certs, that is the key
+// stored by the copy-certs action, if any, or the well known key used by kinder at init time
+func certificateKey(c *status.Cluster) string {
+	if c.Settings != nil && c.Settings.CertificateKey != "" {
+		return c.Settings.CertificateKey
+	}
+	return constants.CertificateKey
+}
+
+// newCertificateKey returns a new random certificate key, with the same format used by kubeadm (32 bytes, hex encoded)
+func newCertificateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate the certificate key")
+	}
+	return hex.EncodeToString(b), nil
+}
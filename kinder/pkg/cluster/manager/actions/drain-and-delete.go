@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// DrainAndDelete removes a worker node from the cluster, executing in order all the steps of the
+// worker decommission: the node is drained, the Node object is deleted, kubeadm reset is executed on
+// the node and finally the node container is removed; if the drain fails, pods that failed to evict
+// are reported and the node is left in place.
+// The worker node to remove should be selected with the --only-node flag.
+func DrainAndDelete(c *status.Cluster, gracePeriod int, timeout time.Duration, vLevel int) error {
+	targets := c.Workers().EligibleForActions()
+	if len(targets) != 1 {
+		return errors.New("please select the worker node to remove with the --only-node flag")
+	}
+	n := targets[0]
+	cp1 := c.BootstrapControlPlane()
+
+	// drains the node; kubectl drain cordons the node before evicting pods
+	cp1.Infof("draining Node %s (grace period %d, timeout %s)", n.Name(), gracePeriod, timeout)
+	if err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "drain", n.Name(),
+		"--ignore-daemonsets", "--delete-local-data", "--force",
+		fmt.Sprintf("--grace-period=%d", gracePeriod), fmt.Sprintf("--timeout=%s", timeout),
+	).RunWithEcho(); err != nil {
+		pods, podsErr := podsNotEvicted(cp1, n)
+		if podsErr != nil {
+			return errors.Wrapf(err, "failed to drain Node %s", n.Name())
+		}
+		return errors.Wrapf(err, "failed to drain Node %s; pods that failed to evict:\n%s", n.Name(), strings.Join(pods, "\n"))
+	}
+
+	// deletes the Node object
+	cp1.Infof("deleting Node %s", n.Name())
+	if err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "delete", "node", n.Name(),
+	).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to delete Node %s", n.Name())
+	}
+
+	// executes kubeadm reset on the node
+	criSocket, err := nodeCRISocket(n)
+	if err != nil {
+		return err
+	}
+	if err := n.Command(
+		"kubeadm", "reset", "--force", fmt.Sprintf("--cri-socket=%s", criSocket), fmt.Sprintf("--v=%d", vLevel),
+	).RunWithEcho(); err != nil {
+		return err
+	}
+
+	// removes the node container
+	n.Infof("removing node container")
+	if err := exec.NewHostCmd("docker", "rm", "-f", "-v", n.Name()).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to remove node %s", n.Name())
+	}
+
+	fmt.Printf("\nWorker node %s removed\n", n.Name())
+	return nil
+}
+
+// podsNotEvicted returns the pods still scheduled on a node after drain, ignoring pods managed
+// by DaemonSets, that are not evicted by kubectl drain
+func podsNotEvicted(cp1, n *status.Node) ([]string, error) {
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "get", "pods", "--all-namespaces",
+		fmt.Sprintf("--field-selector=spec.nodeName=%s", n.Name()),
+		"-o", `jsonpath={range .items[*]}{.metadata.namespace}/{.metadata.name} {.metadata.ownerReferences[0].kind}{"\n"}{end}`,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list pods on Node %s", n.Name())
+	}
+
+	var pods []string
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) == 0 || (len(fields) > 1 && fields[1] == "DaemonSet") {
+			continue
+		}
+		pods = append(pods, fields[0])
+	}
+	return pods, nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	ksigsyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	// auditPolicyDir is the folder where the audit policy is written on control-plane nodes
+	auditPolicyDir = "/etc/kubernetes/audit"
+
+	// auditLogDir is the folder where the API server writes the audit log on control-plane nodes
+	auditLogDir = "/var/log/kubernetes/audit"
+)
+
+var (
+	auditPolicyPath = path.Join(auditPolicyDir, "policy.yaml")
+	auditLogPath    = path.Join(auditLogDir, "audit.log")
+)
+
+// auditPolicy describes the subset of the audit Policy API that is validated by kinder
+type auditPolicy struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Rules      []struct {
+		Level string `json:"level"`
+	} `json:"rules"`
+}
+
+// EnableAudit action enables the log backend of the kube-apiserver audit on control-plane nodes,
+// writing the given audit policy on the nodes and injecting the audit flags and the required volumes
+// into the kube-apiserver static pod manifest; the kubelet then restarts the API server, and the action
+// waits for the audit log to be written. Use kinder get audit-log for fetching the audit log from a node.
+func EnableAudit(c *status.Cluster, policy string, wait time.Duration, probe probeConfig) error {
+	if policy == "" {
+		return errors.New("enable-audit requires an audit policy file set with the --policy flag")
+	}
+	content, err := ioutil.ReadFile(policy)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read audit policy %s", policy)
+	}
+	if err := validateAuditPolicy(content); err != nil {
+		return errors.Wrapf(err, "invalid audit policy %s", policy)
+	}
+
+	for _, n := range c.ControlPlanes().EligibleForActions() {
+		n.Infof("enabling kube-apiserver audit with policy %s", policy)
+
+		manifestPath := path.Join(manifestsDir, "kube-apiserver.yaml")
+		lines, err := n.Command("cat", manifestPath).Silent().RunAndCapture()
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s on node %s; enable-audit should be executed after kubeadm init/join", manifestPath, n.Name())
+		}
+		manifest, err := auditManifest([]byte(strings.Join(lines, "\n")))
+		if err != nil {
+			return errors.Wrapf(err, "failed to update %s on node %s", manifestPath, n.Name())
+		}
+
+		// writes the policy first, so it is available when the kubelet restarts the API server
+		if err := n.Command("mkdir", "-p", auditPolicyDir, auditLogDir).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to create audit folders on node %s", n.Name())
+		}
+		if err := n.WriteFile(auditPolicyPath, content); err != nil {
+			return errors.Wrapf(err, "failed to write the audit policy on node %s", n.Name())
+		}
+		if err := n.WriteFile(manifestPath, manifest); err != nil {
+			return errors.Wrapf(err, "failed to write %s on node %s", manifestPath, n.Name())
+		}
+
+		n.Infof("waiting for kube-apiserver to restart with audit enabled (timeout %s)", wait)
+		if pass := waitFor(c, n, wait, probe,
+			auditLogExists,
+			staticPodIsReady("kube-apiserver"),
+		); !pass {
+			return timeoutError("kube-apiserver on node %s did not restart with audit enabled", n.Name())
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Audit enabled; the audit log is written to %s on control-plane nodes, see kinder get audit-log\n", auditLogPath)
+	return nil
+}
+
+// validateAuditPolicy checks an audit policy can be parsed as an audit.k8s.io Policy with valid rules
+func validateAuditPolicy(content []byte) error {
+	p := &auditPolicy{}
+	if err := ksigsyaml.Unmarshal(content, p); err != nil {
+		return errors.Wrap(err, "failed to decode the audit policy")
+	}
+
+	switch p.APIVersion {
+	case "audit.k8s.io/v1", "audit.k8s.io/v1beta1", "audit.k8s.io/v1alpha1":
+	default:
+		return errors.Errorf("unknown apiVersion %q, use audit.k8s.io/v1", p.APIVersion)
+	}
+	if p.Kind != "Policy" {
+		return errors.Errorf("unknown kind %q, use Policy", p.Kind)
+	}
+	if len(p.Rules) == 0 {
+		return errors.New("the audit policy should have at least one rule")
+	}
+	for i, r := range p.Rules {
+		switch r.Level {
+		case "None", "Metadata", "Request", "RequestResponse":
+		default:
+			return errors.Errorf("invalid level %q for rule %d, use one of None, Metadata, Request or RequestResponse", r.Level, i+1)
+		}
+	}
+	return nil
+}
+
+// auditManifest returns the kube-apiserver static pod manifest with the audit flags and the volumes for
+// the audit policy and the audit log; existing audit settings, if any, are replaced
+func auditManifest(manifest []byte) ([]byte, error) {
+	pod := &corev1.Pod{}
+	if err := ksigsyaml.Unmarshal(manifest, pod); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the kube-apiserver manifest")
+	}
+	if len(pod.Spec.Containers) != 1 {
+		return nil, errors.Errorf("the kube-apiserver manifest should have one container, got %d", len(pod.Spec.Containers))
+	}
+	container := &pod.Spec.Containers[0]
+
+	flags := map[string]string{
+		"--audit-policy-file": auditPolicyPath,
+		"--audit-log-path":    auditLogPath,
+	}
+	var command []string
+	for _, arg := range container.Command {
+		if _, ok := flags[strings.SplitN(arg, "=", 2)[0]]; !ok {
+			command = append(command, arg)
+		}
+	}
+	for _, flag := range []string{"--audit-policy-file", "--audit-log-path"} {
+		command = append(command, fmt.Sprintf("%s=%s", flag, flags[flag]))
+	}
+	container.Command = command
+
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	for _, v := range []struct {
+		name     string
+		dir      string
+		readOnly bool
+	}{
+		{"audit-policy", auditPolicyDir, true},
+		{"audit-log", auditLogDir, false},
+	} {
+		var volumes []corev1.Volume
+		for _, x := range pod.Spec.Volumes {
+			if x.Name != v.name {
+				volumes = append(volumes, x)
+			}
+		}
+		pod.Spec.Volumes = append(volumes, corev1.Volume{
+			Name: v.name,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: v.dir, Type: &hostPathType},
+			},
+		})
+
+		var mounts []corev1.VolumeMount
+		for _, x := range container.VolumeMounts {
+			if x.Name != v.name {
+				mounts = append(mounts, x)
+			}
+		}
+		container.VolumeMounts = append(mounts, corev1.VolumeMount{Name: v.name, MountPath: v.dir, ReadOnly: v.readOnly})
+	}
+
+	b, err := ksigsyaml.Marshal(pod)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode the kube-apiserver manifest")
+	}
+	return b, nil
+}
+
+// auditLogExists implement a function that test when the audit log exists on a node
+func auditLogExists(c *status.Cluster, n *status.Node) bool {
+	if err := n.Command("test", "-f", auditLogPath).Silent().Run(); err != nil {
+		return false
+	}
+	fmt.Printf("Audit log %s exists on node %s\n", auditLogPath, n.Name())
+	return true
+}
+
+// AuditLog returns the kube-apiserver audit log written on a control-plane node by enable-audit
+func AuditLog(n *status.Node) ([]string, error) {
+	if !n.IsControlPlane() {
+		return nil, errors.Errorf("node %s is not a control-plane node", n.Name())
+	}
+	lines, err := n.Command("cat", auditLogPath).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s on node %s; audit should be enabled with kinder do enable-audit", auditLogPath, n.Name())
+	}
+	return lines, nil
+}
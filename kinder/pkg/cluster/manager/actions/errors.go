@@ -0,0 +1,187 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	osexec "os/exec"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Action errors are classified by Reason using errors.Cause, so they are detected also when wrapped with
+// errors.Wrap; nb. action errors do not implement Cause, so errors.Cause stops at them
+
+// ErrPreflight is returned by actions when a check executed before changing the cluster fails,
+// e.g. the cgroup driver check executed before kubeadm init/join
+type ErrPreflight struct {
+	Err error
+}
+
+// ErrExec is returned by actions when a command executed on a node or on the host fails
+type ErrExec struct {
+	Err error
+}
+
+// ErrTimeout is returned by actions when the cluster does not reach the target state in the expected time
+type ErrTimeout struct {
+	Err error
+}
+
+// ErrVersionSkew is returned by actions when the version skew check fails
+type ErrVersionSkew struct {
+	Err error
+}
+
+// Error implements the error interface
+func (e *ErrPreflight) Error() string {
+	return e.Err.Error()
+}
+
+// Error implements the error interface
+func (e *ErrExec) Error() string {
+	return e.Err.Error()
+}
+
+// Error implements the error interface
+func (e *ErrTimeout) Error() string {
+	return e.Err.Error()
+}
+
+// Error implements the error interface
+func (e *ErrVersionSkew) Error() string {
+	return e.Err.Error()
+}
+
+// FailureReason defines the category of an action failure
+type FailureReason string
+
+const (
+	// PreflightFailure is the reason of ErrPreflight
+	PreflightFailure = FailureReason("preflight")
+
+	// ExecFailure is the reason of ErrExec
+	ExecFailure = FailureReason("exec")
+
+	// TimeoutFailure is the reason of ErrTimeout
+	TimeoutFailure = FailureReason("timeout")
+
+	// VersionSkewFailure is the reason of ErrVersionSkew
+	VersionSkewFailure = FailureReason("version-skew")
+
+	// UnknownFailure is the reason of errors that are not action errors
+	UnknownFailure = FailureReason("unknown")
+)
+
+// exitCodes defines the exit code of kinder for each failure reason, so callers running kinder as a
+// subprocess, e.g. the workflow runner, can detect the failure reason; 1 is used for unknown failures
+var exitCodes = map[FailureReason]int{
+	PreflightFailure:   3,
+	ExecFailure:        4,
+	TimeoutFailure:     5,
+	VersionSkewFailure: 6,
+}
+
+// Reason returns the failure reason of an error returned by an action, also if wrapped with errors.Wrap;
+// an empty reason is returned for nil errors
+func Reason(err error) FailureReason {
+	switch errors.Cause(err).(type) {
+	case nil:
+		return ""
+	case *ErrPreflight:
+		return PreflightFailure
+	case *ErrExec:
+		return ExecFailure
+	case *ErrTimeout:
+		return TimeoutFailure
+	case *ErrVersionSkew:
+		return VersionSkewFailure
+	}
+	return UnknownFailure
+}
+
+// ExitCode returns the kinder exit code for an error returned by an action
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if code, ok := exitCodes[Reason(err)]; ok {
+		return code
+	}
+	return 1
+}
+
+// ErrorFromExitCode returns the action error for a kinder exit code, wrapping the given error,
+// e.g. the error returned by exec.Cmd.Wait; the error is returned as is for unknown exit codes
+func ErrorFromExitCode(code int, err error) error {
+	switch code {
+	case exitCodes[PreflightFailure]:
+		return &ErrPreflight{Err: err}
+	case exitCodes[ExecFailure]:
+		return &ErrExec{Err: err}
+	case exitCodes[TimeoutFailure]:
+		return &ErrTimeout{Err: err}
+	case exitCodes[VersionSkewFailure]:
+		return &ErrVersionSkew{Err: err}
+	}
+	return err
+}
+
+// ActionResult describes the outcome of an action executed by RunAction
+type ActionResult struct {
+	// Action is the name of the action
+	Action string
+	// Duration is the time spent executing the action
+	Duration time.Duration
+	// Err is the error returned by the action, if any
+	Err error
+}
+
+// Succeeded returns true if the action completed without errors
+func (r *ActionResult) Succeeded() bool {
+	return r.Err == nil
+}
+
+// Reason returns the failure reason of the action, or an empty reason if the action succeeded
+func (r *ActionResult) Reason() FailureReason {
+	return Reason(r.Err)
+}
+
+// execError classifies errors returned by actions that are not action errors yet, wrapping errors caused
+// by commands exiting with a non-zero status into ErrExec
+func execError(err error) error {
+	if Reason(err) != UnknownFailure {
+		return err
+	}
+	if _, ok := errors.Cause(err).(*osexec.ExitError); ok {
+		return &ErrExec{Err: err}
+	}
+	return err
+}
+
+// preflightError wraps an error returned by a check executed before an action into ErrPreflight
+func preflightError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ErrPreflight{Err: err}
+}
+
+// timeoutError returns an ErrTimeout with a message in the "timeout: ..." format used by kinder
+func timeoutError(format string, args ...interface{}) error {
+	return &ErrTimeout{Err: errors.Errorf("timeout: "+format, args...)}
+}
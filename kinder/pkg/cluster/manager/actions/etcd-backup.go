@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	ksigsyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	// etcdSnapshotFile is the name of the etcd snapshot file on nodes; the snapshot is written in the etcd data dir,
+	// because this is the only folder on the node that is writable from the etcd static pod
+	etcdSnapshotFile = "kinder-snapshot.db"
+
+	// etcdRestoreDir is the name of the folder in the etcd data dir where the snapshot is restored
+	etcdRestoreDir = "kinder-restore"
+
+	// manifestsRestoreDir is the folder where static pod manifests are moved while restoring etcd
+	manifestsRestoreDir = "/etc/kubernetes/manifests-kinder-restore"
+)
+
+// BackupEtcd action saves a snapshot of the stacked etcd running on a control-plane node using
+// etcdctl snapshot save with the kubeadm managed certificates, and then copies the snapshot to the out file on the host.
+// The control-plane node should be selected with the --only-node flag, unless the cluster has only one control-plane node.
+func BackupEtcd(c *status.Cluster, out string) error {
+	if out == "" {
+		return errors.New("backup-etcd requires the file where the snapshot is saved, set with the --out flag")
+	}
+	n, err := etcdActionNode(c, "backup-etcd")
+	if err != nil {
+		return err
+	}
+
+	flags, err := etcdManifestFlags(n)
+	if err != nil {
+		return err
+	}
+	snapshot := path.Join(flags["data-dir"], etcdSnapshotFile)
+
+	etcdArgs, err := etcdctlArgs(n)
+	if err != nil {
+		return err
+	}
+
+	n.Infof("saving etcd snapshot")
+	if err := n.Command("kubectl", append(etcdArgs, "snapshot", "save", snapshot)...).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to save etcd snapshot on node %s", n.Name())
+	}
+	defer n.Command("rm", "-f", snapshot).Silent().Run()
+
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s", filepath.Dir(out))
+	}
+	if err := n.CopyFrom(snapshot, out); err != nil {
+		return errors.Wrapf(err, "failed to copy etcd snapshot from node %s", n.Name())
+	}
+
+	fmt.Printf("\netcd snapshot of node %s saved to %s\n", n.Name(), out)
+	return nil
+}
+
+// RestoreEtcd action restores the stacked etcd running on a control-plane node from a snapshot on the host, as
+// described in the kubeadm etcd restore runbook: the snapshot is restored with etcdctl snapshot restore in a new
+// data dir, static pods are stopped, the etcd data dir is replaced with the restored one, and then static pods
+// are started again; the action finally waits for the control-plane and checks etcd health.
+// The previous etcd data is kept in the member.pre-restore folder of the etcd data dir.
+// Please note that this action supports only clusters with one control-plane node, because restoring a
+// multi-member etcd cluster requires all the members to be restored from the same snapshot.
+func RestoreEtcd(c *status.Cluster, from string, wait time.Duration, probe probeConfig) error {
+	if from == "" {
+		return errors.New("restore-etcd requires the snapshot file on the host, set with the --from flag")
+	}
+	if _, err := os.Stat(from); err != nil {
+		return errors.Wrapf(err, "invalid --from")
+	}
+	if len(c.ControlPlanes()) != 1 {
+		return errors.New("restore-etcd supports only clusters with one control-plane node")
+	}
+	n, err := etcdActionNode(c, "restore-etcd")
+	if err != nil {
+		return err
+	}
+
+	flags, err := etcdManifestFlags(n)
+	if err != nil {
+		return err
+	}
+	dataDir := flags["data-dir"]
+	snapshot := path.Join(dataDir, etcdSnapshotFile)
+	restoreDir := path.Join(dataDir, etcdRestoreDir)
+
+	etcdArgs, err := etcdctlArgs(n)
+	if err != nil {
+		return err
+	}
+
+	// restores the snapshot in a new data dir, using the running etcd static pod for executing etcdctl
+	// nb. the etcd binaries are available only in the etcd image, so this is done before stopping static pods
+	if err := n.CopyTo(from, snapshot); err != nil {
+		return errors.Wrapf(err, "failed to copy etcd snapshot to node %s", n.Name())
+	}
+	defer n.Command("rm", "-rf", snapshot, restoreDir).Silent().Run()
+
+	n.Infof("restoring etcd snapshot %s", from)
+	if err := n.Command("rm", "-rf", restoreDir).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to cleanup %s on node %s", restoreDir, n.Name())
+	}
+	if err := n.Command("kubectl", append(etcdArgs,
+		"snapshot", "restore", snapshot,
+		fmt.Sprintf("--data-dir=%s", restoreDir),
+		fmt.Sprintf("--name=%s", flags["name"]),
+		fmt.Sprintf("--initial-cluster=%s", flags["initial-cluster"]),
+		fmt.Sprintf("--initial-advertise-peer-urls=%s", flags["initial-advertise-peer-urls"]),
+	)...).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to restore etcd snapshot on node %s", n.Name())
+	}
+
+	// stops static pods, moving static pod manifests out of the folder watched by the kubelet
+	n.Infof("stopping static pods (timeout %s)", wait)
+	if err := n.Command("mv", manifestsDir, manifestsRestoreDir).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to move static pod manifests on node %s", n.Name())
+	}
+	if pass := waitFor(c, n, wait, probe, etcdIsStopped); !pass {
+		return timeoutError("etcd on node %s did not stop; static pod manifests are in %s", n.Name(), manifestsRestoreDir)
+	}
+	fmt.Println()
+
+	// replaces the etcd data with the restored one
+	n.Infof("replacing etcd data in %s", dataDir)
+	if err := n.Command("sh", "-c", fmt.Sprintf(
+		"rm -rf %[1]s/member.pre-restore && mv %[1]s/member %[1]s/member.pre-restore && mv %[2]s/member %[1]s/member",
+		dataDir, restoreDir,
+	)).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to replace etcd data on node %s; static pod manifests are in %s", n.Name(), manifestsRestoreDir)
+	}
+
+	// starts static pods again
+	n.Infof("starting static pods")
+	if err := n.Command("mv", manifestsRestoreDir, manifestsDir).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to move static pod manifests on node %s", n.Name())
+	}
+	if err := waitNewControlPlaneNodeReady(c, n, wait, probe); err != nil {
+		return err
+	}
+
+	// verifies cluster health after restore
+	if err := checkEtcdHealth(c, 1); err != nil {
+		return err
+	}
+	fmt.Printf("\netcd on node %s restored from %s\n", n.Name(), from)
+	return nil
+}
+
+// etcdActionNode returns the control-plane node targeted by backup-etcd and restore-etcd
+func etcdActionNode(c *status.Cluster, action string) (*status.Node, error) {
+	if c.ExternalEtcd() != nil {
+		return nil, errors.Errorf("%s supports only stacked etcd, while the cluster is using external etcd", action)
+	}
+	targets := c.ControlPlanes().EligibleForActions()
+	if len(targets) != 1 {
+		return nil, errors.Errorf("please select the control-plane node for %s with the --only-node flag", action)
+	}
+	return targets[0], nil
+}
+
+// etcdManifestFlags returns the flags of the etcd static pod on a control-plane node, keyed by flag name
+// without the leading --
+func etcdManifestFlags(n *status.Node) (map[string]string, error) {
+	manifestPath := path.Join(manifestsDir, "etcd.yaml")
+	lines, err := n.Command("cat", manifestPath).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s on node %s", manifestPath, n.Name())
+	}
+
+	pod := &corev1.Pod{}
+	if err := ksigsyaml.Unmarshal([]byte(strings.Join(lines, "\n")), pod); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s on node %s", manifestPath, n.Name())
+	}
+	if len(pod.Spec.Containers) != 1 {
+		return nil, errors.Errorf("%s on node %s should have one container, got %d", manifestPath, n.Name(), len(pod.Spec.Containers))
+	}
+
+	flags := map[string]string{}
+	for _, arg := range pod.Spec.Containers[0].Command {
+		parts := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)
+		if len(parts) == 2 {
+			flags[parts[0]] = parts[1]
+		}
+	}
+	for _, f := range []string{"data-dir", "name", "initial-cluster", "initial-advertise-peer-urls"} {
+		if flags[f] == "" {
+			return nil, errors.Errorf("%s on node %s does not set the --%s flag", manifestPath, n.Name(), f)
+		}
+	}
+	return flags, nil
+}
+
+// etcdIsStopped implement a function that test when etcd is not listening on the client port of a node
+func etcdIsStopped(c *status.Cluster, n *status.Node) bool {
+	if err := n.Command("bash", "-c", "echo > /dev/tcp/127.0.0.1/2379").Silent().Run(); err == nil {
+		return false
+	}
+	fmt.Printf("etcd on node %s is stopped\n", n.Name())
+	return true
+}
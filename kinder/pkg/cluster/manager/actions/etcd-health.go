@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	versionutils "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// etcdMemberList is the subset of the etcdctl member list -w json output used by kinder
+type etcdMemberList struct {
+	Members []struct {
+		ID         uint64   `json:"ID"`
+		Name       string   `json:"name"`
+		ClientURLs []string `json:"clientURLs"`
+	} `json:"members"`
+}
+
+// etcdEndpointHealth is the health of an endpoint, as reported by etcdctl endpoint health
+type etcdEndpointHealth struct {
+	Endpoint string
+	Health   bool
+	Took     string
+	Error    string
+}
+
+// EtcdHealth checks the stacked etcd cluster using etcdctl member list and endpoint health, printing
+// a table with members and their health; the action fails if any member is unhealthy or if the number
+// of members does not match the number of control-plane nodes
+func EtcdHealth(c *status.Cluster) error {
+	return checkEtcdHealth(c, len(c.ControlPlanes()))
+}
+
+// checkEtcdHealth implements EtcdHealth, checking that the etcd cluster has the expected number of members
+func checkEtcdHealth(c *status.Cluster, expectedMembers int) error {
+	if c.ExternalEtcd() != nil {
+		return errors.New("etcd-health supports only stacked etcd, while the cluster is using external etcd")
+	}
+
+	// commands are executed on the bootstrap control-plane
+	cp1 := c.BootstrapControlPlane()
+
+	etcdArgs, err := etcdctlArgs(cp1)
+	if err != nil {
+		return err
+	}
+
+	var members etcdMemberList
+	if err := etcdctlJSON(cp1, append(etcdArgs, "member", "list", "-w=json"), "{", &members); err != nil {
+		return errors.Wrap(err, "failed to list etcd members")
+	}
+
+	// NB. the endpoints are taken from the member list instead of using --cluster, and the text output is parsed
+	// instead of using -w=json, so the health check works also with etcdctl older than v3.4.0
+	endpoints := []string{}
+	for _, m := range members.Members {
+		endpoints = append(endpoints, m.ClientURLs...)
+	}
+	lines, _ := cp1.Command("kubectl", append(etcdArgs, fmt.Sprintf("--endpoints=%s", strings.Join(endpoints, ",")), "endpoint", "health")...).RunAndCapture()
+	healthByEndpoint := parseEndpointHealth(lines)
+
+	unhealthy := []string{}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tID\tCLIENT URL\tHEALTH\tTOOK")
+	for _, m := range members.Members {
+		clientURL, healthy, took := "-", "unknown", "-"
+		if len(m.ClientURLs) > 0 {
+			clientURL = m.ClientURLs[0]
+		}
+		if h, ok := healthByEndpoint[clientURL]; ok {
+			took = h.Took
+			healthy = "healthy"
+			if !h.Health {
+				healthy = fmt.Sprintf("unhealthy: %s", h.Error)
+			}
+		}
+		if healthy != "healthy" {
+			unhealthy = append(unhealthy, m.Name)
+		}
+		fmt.Fprintf(w, "%s\t%x\t%s\t%s\t%s\n", m.Name, m.ID, clientURL, healthy, took)
+	}
+	w.Flush()
+	fmt.Println()
+
+	if len(unhealthy) > 0 {
+		return errors.Errorf("etcd members %s are not healthy", strings.Join(unhealthy, ", "))
+	}
+	if len(members.Members) != expectedMembers {
+		return errors.Errorf("etcd has %d members, but the cluster has %d control-plane nodes", len(members.Members), expectedMembers)
+	}
+
+	fmt.Printf("etcd is healthy, with %d members\n", len(members.Members))
+	return nil
+}
+
+// parseEndpointHealth parses the text output of etcdctl endpoint health, that is a line for each endpoint in the form
+// <endpoint> is healthy: successfully committed proposal: took = <duration>, or <endpoint> is unhealthy: <error>;
+// nb. etcdctl exits with an error when printing the health of unhealthy endpoints, so errors are not checked
+func parseEndpointHealth(lines []string) map[string]etcdEndpointHealth {
+	health := map[string]etcdEndpointHealth{}
+	for _, line := range lines {
+		var h etcdEndpointHealth
+		switch {
+		case strings.Contains(line, " is healthy: "):
+			parts := strings.SplitN(line, " is healthy: ", 2)
+			h = etcdEndpointHealth{Endpoint: parts[0], Health: true, Took: "-"}
+			if i := strings.Index(parts[1], "took = "); i >= 0 {
+				h.Took = strings.TrimPrefix(parts[1][i:], "took = ")
+			}
+		case strings.Contains(line, " is unhealthy: "):
+			parts := strings.SplitN(line, " is unhealthy: ", 2)
+			h = etcdEndpointHealth{Endpoint: parts[0], Error: parts[1], Took: "-"}
+		default:
+			continue
+		}
+		health[h.Endpoint] = h
+	}
+	return health
+}
+
+// etcdctlArgs returns the kubectl args for running etcdctl with the v3 API in the etcd static pod of the given
+// control-plane node, using the kubeadm managed certificates; args for the etcdctl command can be appended
+func etcdctlArgs(n *status.Node) ([]string, error) {
+	etcdArgs := []string{
+		"--kubeconfig=/etc/kubernetes/admin.conf", "exec", "-n=kube-system", fmt.Sprintf("etcd-%s", n.Name()),
+		"--",
+	}
+
+	// Get the version of etcdctl from the etcd binary
+	etcdctlVersion, err := etcdVersion(n)
+	if err != nil {
+		return nil, err
+	}
+	version, err := versionutils.ParseGeneric(etcdctlVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse etcd version")
+	}
+
+	// NB. before v3.4.0 etcdctl defaults to the v2 API, and the ETCDCTL_API env variable can't be set via kubectl exec,
+	// so etcdctl is executed via sh, passing the etcdctl args as positional parameters; newer etcd images don't have sh
+	if version.LessThan(versionutils.MustParseGeneric("v3.4.0")) {
+		etcdArgs = append(etcdArgs, "sh", "-c", `ETCDCTL_API=3 exec etcdctl "$@"`, "etcdctl")
+	} else {
+		etcdArgs = append(etcdArgs, "etcdctl")
+	}
+
+	// NB. with the v3 API, etcdctl uses the new certificate flags for all the etcd versions
+	etcdArgs = append(etcdArgs, "--endpoints=https://127.0.0.1:2379")
+	etcdArgs = append(etcdArgs, etcdCertArgsNew...)
+	return etcdArgs, nil
+}
+
+// etcdctlJSON runs etcdctl via kubectl on the node and decodes the JSON output, that is the first output line
+// starting with the given prefix; nb. errors are checked only after parsing because
+// etcdctl exits with an error also when printing the health of unhealthy endpoints
+func etcdctlJSON(n *status.Node, args []string, prefix string, v interface{}) error {
+	lines, runErr := n.Command("kubectl", args...).RunAndCapture()
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			return json.Unmarshal([]byte(line), v)
+		}
+	}
+	if runErr != nil {
+		return errors.Wrapf(runErr, "etcdctl output:\n%s", strings.Join(lines, "\n"))
+	}
+	return errors.Errorf("unexpected etcdctl output:\n%s", strings.Join(lines, "\n"))
+}
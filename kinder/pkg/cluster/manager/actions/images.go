@@ -38,7 +38,7 @@ func checkImagesForVersion(n *status.Node, version string) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to read expected images for version %s from %s", version, n.Name())
 	}
-	log.Debugf("List of images kubeadm is going to use %s\n", expected)
+	log.WithField("node", n.Name()).Debugf("List of images kubeadm is going to use %s\n", expected)
 
 	// gets the list of images already pre-loaded in the node
 	nodeCRI, err := n.CRI()
@@ -55,7 +55,7 @@ func checkImagesForVersion(n *status.Node, version string) error {
 	if err != nil {
 		return err
 	}
-	log.Debugf("List of images already pre-loaded in the node %s\n", current)
+	log.WithField("node", n.Name()).Debugf("List of images already pre-loaded in the node %s\n", current)
 
 	// Compare expected and current image and report result
 	var currentMap = map[string]string{}
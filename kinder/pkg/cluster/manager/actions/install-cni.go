@@ -0,0 +1,205 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/data"
+)
+
+// CNIProvider defines the CNI plugins supported by kinder
+type CNIProvider string
+
+const (
+	// CalicoCNI installs Calico; this is the default CNI plugin
+	CalicoCNI = CNIProvider("calico")
+
+	// KindnetCNI installs kindnet
+	KindnetCNI = CNIProvider("kindnet")
+
+	// CiliumCNI installs Cilium
+	CiliumCNI = CNIProvider("cilium")
+
+	// NoCNI skips the CNI plugin installation
+	NoCNI = CNIProvider("none")
+)
+
+// cniManifestURLs defines the URL templates of the manifest for each CNI plugin, and the default version;
+// default versions are pinned, so the manifest applied by kinder does not change over time
+var cniManifestURLs = map[CNIProvider]struct {
+	url            string
+	defaultVersion string
+}{
+	CalicoCNI:  {url: "https://docs.projectcalico.org/%s/manifests/calico.yaml"},
+	KindnetCNI: {url: "https://raw.githubusercontent.com/aojea/kindnet/%s/install-kindnet.yaml", defaultVersion: "v1.0.0"},
+	CiliumCNI:  {url: "https://raw.githubusercontent.com/cilium/cilium/%s/install/kubernetes/quick-install.yaml", defaultVersion: "v1.6"},
+}
+
+// KnownCNIProviders returns the list of known CNIProvider
+func KnownCNIProviders() []string {
+	return []string{
+		string(CalicoCNI),
+		string(KindnetCNI),
+		string(CiliumCNI),
+		string(NoCNI),
+	}
+}
+
+// ValidateCNIProvider validates a CNIProvider
+func ValidateCNIProvider(p CNIProvider) error {
+	switch p {
+	case CalicoCNI:
+	case KindnetCNI:
+	case CiliumCNI:
+	case NoCNI:
+	default:
+		return errors.Errorf("unknown CNI provider %q. Use one of %s", p, KnownCNIProviders())
+	}
+	return nil
+}
+
+// CNISpec defines the CNI plugin to be installed in the cluster
+type CNISpec struct {
+	// Provider of the CNI plugin; if empty, Calico is used
+	Provider CNIProvider
+	// Manifest is an URL or a file on the host overriding the default manifest of the provider
+	Manifest string
+	// Version of the provider manifest to be fetched, overriding the default one
+	Version string
+}
+
+// InstallCNI action installs the CNI plugin in the cluster and waits for nodes to become Ready.
+// Please note that this action is automatically executed by kubeadm init, but it is possible
+// to invoke it separately as well, e.g. after kubeadm init with --provider=none
+func InstallCNI(c *status.Cluster, cni CNISpec, wait time.Duration, probe probeConfig) error {
+	if cni.Provider == NoCNI {
+		return errors.New("install-cni requires a CNI provider other than none")
+	}
+
+	if err := installCNI(c, cni); err != nil {
+		return err
+	}
+
+	// waits for nodes already part of the cluster to become Ready
+	for _, n := range c.K8sNodes() {
+		if kubeadmPhase(c, n) == rawPhase {
+			continue
+		}
+		waitReady := waitNewWorkerNodeReady
+		if n.IsControlPlane() {
+			waitReady = waitNewControlPlaneNodeReady
+		}
+		if err := waitReady(c, n, wait, probe); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installCNI applies the manifest of the CNI plugin using the bootstrap control-plane node
+func installCNI(c *status.Cluster, cni CNISpec) error {
+	cp1 := c.BootstrapControlPlane()
+
+	provider := cni.Provider
+	if provider == "" {
+		provider = CalicoCNI
+	}
+	if err := ValidateCNIProvider(provider); err != nil {
+		return err
+	}
+	if provider == NoCNI {
+		cp1.Infof("skipping CNI plugin installation")
+		return nil
+	}
+
+	if provider == CalicoCNI {
+		// Calico requires net.ipv4.conf.all.rp_filter to be set to 0 or 1.
+		// If you require loose RPF and you are not concerned about spoofing, this check can be disabled by setting the IgnoreLooseRPF configuration parameter to 'true'.
+		for _, cp := range c.K8sNodes() {
+			if err := cp.Command(
+				"sysctl", "-w", "net.ipv4.conf.all.rp_filter=1",
+			).Silent().Run(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// gets the manifest to be applied; the bundled Calico manifest is used if not requested otherwise
+	manifest := cni.Manifest
+	if manifest == "" && (provider != CalicoCNI || cni.Version != "") {
+		version := cni.Version
+		if version == "" {
+			version = cniManifestURLs[provider].defaultVersion
+		}
+		manifest = fmt.Sprintf(cniManifestURLs[provider].url, version)
+	}
+
+	switch {
+	case manifest == "":
+		cp1.Infof("applying Calico version 3.8.2")
+		cmd := cp1.Command("kubectl", "apply", "--kubeconfig=/etc/kubernetes/admin.conf", "-f", "-")
+		cmd.Stdin(strings.NewReader(data.CalicoCNI3_8_2))
+		if err := cmd.RunWithEcho(); err != nil {
+			return err
+		}
+	case strings.HasPrefix(manifest, "http://") || strings.HasPrefix(manifest, "https://"):
+		cp1.Infof("applying %s from %s", provider, manifest)
+		if err := cp1.Command(
+			"kubectl", "apply", "--kubeconfig=/etc/kubernetes/admin.conf", "-f", manifest,
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to apply %s", manifest)
+		}
+	default:
+		content, err := ioutil.ReadFile(manifest)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read CNI manifest %s", manifest)
+		}
+		cp1.Infof("applying %s from %s", provider, manifest)
+		cmd := cp1.Command("kubectl", "apply", "--kubeconfig=/etc/kubernetes/admin.conf", "-f", "-")
+		cmd.Stdin(strings.NewReader(string(content)))
+		if err := cmd.RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to apply %s", manifest)
+		}
+	}
+
+	if provider == CalicoCNI {
+		// Fix calico as per https://alexbrand.dev/post/creating-a-kind-cluster-with-calico-networking/
+		if err := cp1.Command(
+			"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system", "set", "env", "daemonset/calico-node", "FELIX_IGNORELOOSERPF=true",
+		).RunWithEcho(); err != nil {
+			return err
+		}
+	}
+
+	if provider == KindnetCNI {
+		// kindnet manifests hardcode the kind pod subnet, so it is replaced with the pod subnet of the cluster
+		if err := cp1.Command(
+			"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system", "set", "env", "daemonset/kindnet", fmt.Sprintf("POD_SUBNET=%s", clusterPodSubnet(c)),
+		).RunWithEcho(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
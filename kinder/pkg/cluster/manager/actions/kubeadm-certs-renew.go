@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/cri"
+)
+
+// KubeadmCertsRenew executes the kubeadm certs renew workflow on control-plane nodes, printing
+// certificates expiration before and after renewal; if requested, control-plane static pods
+// are restarted in order to make them use the renewed certificates
+func KubeadmCertsRenew(c *status.Cluster, restartStaticPods bool, wait time.Duration, probe probeConfig, vLevel int) error {
+	for _, cp := range c.ControlPlanes().EligibleForActions() {
+		certsArgs := kubeadmCertsArgs(cp)
+
+		cp.Infof("certificates expiration before renewal")
+		if err := kubeadmCertsCheckExpiration(cp, certsArgs, vLevel); err != nil {
+			return err
+		}
+
+		cp.Infof("renewing certificates")
+		if err := cp.Command(
+			"kubeadm", append(certsArgs, "renew", "all", fmt.Sprintf("--v=%d", vLevel))...,
+		).RunWithEcho(); err != nil {
+			return err
+		}
+
+		if restartStaticPods {
+			cp.Infof("restarting control-plane static pods")
+			if err := restartControlPlaneStaticPods(c, cp); err != nil {
+				return err
+			}
+
+			if err := waitNewControlPlaneNodeReady(c, cp, wait, probe); err != nil {
+				return err
+			}
+		}
+
+		cp.Infof("certificates expiration after renewal")
+		if err := kubeadmCertsCheckExpiration(cp, certsArgs, vLevel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kubeadmCertsArgs returns the args for invoking the kubeadm certs command,
+// that graduated from the alpha command in v1.20
+func kubeadmCertsArgs(n *status.Node) []string {
+	if n.MustKubeadmVersion().LessThan(constants.V1_20) {
+		return []string{"alpha", "certs"}
+	}
+	return []string{"certs"}
+}
+
+// kubeadmCertsCheckExpiration prints certificates expiration, if supported by kubeadm
+func kubeadmCertsCheckExpiration(n *status.Node, certsArgs []string, vLevel int) error {
+	// NB. kubeadm certs check-expiration was introduced in v1.15
+	if n.MustKubeadmVersion().LessThan(constants.V1_15) {
+		fmt.Println("kubeadm certs check-expiration is not supported before v1.15, skipping")
+		return nil
+	}
+
+	return n.Command(
+		"kubeadm", append(certsArgs, "check-expiration", fmt.Sprintf("--v=%d", vLevel))...,
+	).RunWithEcho()
+}
+
+// restartControlPlaneStaticPods restarts the control-plane static pods, thus forcing the
+// control-plane components to read certificates again
+func restartControlPlaneStaticPods(c *status.Cluster, n *status.Node) error {
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		return err
+	}
+
+	actionHelper, err := cri.NewActionHelper(nodeCRI)
+	if err != nil {
+		return err
+	}
+
+	pods := []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler"}
+	// if the cluster is using local etcd, also the etcd static pod should be restarted
+	if c.ExternalEtcd() == nil {
+		pods = append(pods, "etcd")
+	}
+
+	if err := actionHelper.RestartStaticPods(n, pods...); err != nil {
+		return errors.Wrapf(err, "failed to restart static pods on node %s", n.Name())
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
@@ -36,28 +37,33 @@ type kubeadmConfigOptions struct {
 	kubeDNS            bool
 	automaticCopyCerts bool
 	discoveryMode      DiscoveryMode
+	featureGates       map[string]bool
+	extraArgs          ControlPlaneExtraArgs
+	templatesDir       string
 }
 
 // KubeadmInitConfig action writes the InitConfiguration into /kind/kubeadm.conf file on all the K8s nodes in the cluster.
 // Please note that this action is automatically executed at create time, but it is possible
 // to invoke it separately as well.
-func KubeadmInitConfig(c *status.Cluster, kubeDNS bool, automaticCopyCerts bool, nodes ...*status.Node) error {
+func KubeadmInitConfig(c *status.Cluster, kubeDNS bool, automaticCopyCerts bool, featureGates map[string]bool, extraArgs ControlPlaneExtraArgs, templatesDir string, nodes ...*status.Node) error {
 	// defaults everything not relevant for the Init Config
-	return KubeadmConfig(c, kubeDNS, automaticCopyCerts, TokenDiscovery, nodes...)
+	return KubeadmConfig(c, kubeDNS, automaticCopyCerts, TokenDiscovery, featureGates, extraArgs, templatesDir, nodes...)
 }
 
 // KubeadmJoinConfig action writes the JoinConfiguration into /kind/kubeadm.conf file on all the K8s nodes in the cluster.
 // Please note that this action is automatically executed at create time, but it is possible
 // to invoke it separately as well.
-func KubeadmJoinConfig(c *status.Cluster, automaticCopyCerts bool, discoveryMode DiscoveryMode, nodes ...*status.Node) error {
+func KubeadmJoinConfig(c *status.Cluster, automaticCopyCerts bool, discoveryMode DiscoveryMode, templatesDir string, nodes ...*status.Node) error {
 	// defaults everything not relevant for the join Config
-	return KubeadmConfig(c, false, automaticCopyCerts, discoveryMode, nodes...)
+	// NB. feature gates and control-plane extra args are not relevant for the join Config, because kubeadm join reads
+	// the ClusterConfiguration from the cluster
+	return KubeadmConfig(c, false, automaticCopyCerts, discoveryMode, nil, ControlPlaneExtraArgs{}, templatesDir, nodes...)
 }
 
 // KubeadmConfig action writes the /kind/kubeadm.conf file on all the K8s nodes in the cluster.
 // Please note that this action is automatically executed at create time, but it is possible
 // to invoke it separately as well.
-func KubeadmConfig(c *status.Cluster, kubeDNS bool, automaticCopyCerts bool, discoveryMode DiscoveryMode, nodes ...*status.Node) error {
+func KubeadmConfig(c *status.Cluster, kubeDNS bool, automaticCopyCerts bool, discoveryMode DiscoveryMode, featureGates map[string]bool, extraArgs ControlPlaneExtraArgs, templatesDir string, nodes ...*status.Node) error {
 	cp1 := c.BootstrapControlPlane()
 
 	// get installed kubernetes version from the node image
@@ -93,17 +99,44 @@ func KubeadmConfig(c *status.Cluster, kubeDNS bool, automaticCopyCerts bool, dis
 		APIBindPort:          constants.APIServerPort,
 		APIServerAddress:     controlPlaneIP,
 		Token:                constants.Token,
-		PodSubnet:            "192.168.0.0/16", // default for calico
-		ServiceSubnet:        "",               // let kubeadm apply default
+		PodSubnet:            clusterPodSubnet(c),
+		ServiceSubnet:        "", // let kubeadm apply default
 		ControlPlane:         true,
 		IPv6:                 c.Settings.IPFamily == status.IPv6Family,
 	}
 
+	// use subnets for the cluster IP family
+	switch c.Settings.IPFamily {
+	case status.IPv6Family:
+		configData.ServiceSubnet = "fd00:10:96::/112"
+	case status.DualStackFamily:
+		configData.ServiceSubnet = "10.96.0.0/12,fd00:10:96::/112"
+
+		// dual-stack requires the IPv6DualStack feature gate before v1.21, when it is enabled by default
+		if v, err := K8sVersion.ParseSemantic(kubeVersion); err == nil && v.LessThan(constants.V1_21) {
+			if _, ok := featureGates["IPv6DualStack"]; !ok {
+				gates := map[string]bool{"IPv6DualStack": true}
+				for k, v := range featureGates {
+					gates[k] = v
+				}
+				featureGates = gates
+			}
+		}
+	}
+
+	// use the service subnet set at create time, if any
+	if c.Settings.ServiceSubnet != "" {
+		configData.ServiceSubnet = c.Settings.ServiceSubnet
+	}
+
 	// create configOptions with all the kinder flags that impact on the kubeadm config generation
 	configOptions := kubeadmConfigOptions{
 		kubeDNS:            kubeDNS,
 		automaticCopyCerts: automaticCopyCerts,
 		discoveryMode:      discoveryMode,
+		featureGates:       featureGates,
+		extraArgs:          controlPlaneExtraArgs(c, extraArgs),
+		templatesDir:       templatesDir,
 	}
 
 	// writs the kubeadm config file on all the K8s nodes.
@@ -116,6 +149,73 @@ func KubeadmConfig(c *status.Cluster, kubeDNS bool, automaticCopyCerts bool, dis
 	return nil
 }
 
+// PrintKubeadmConfig prints the /kind/kubeadm.conf file on the given nodes; if requested,
+// it prints also the unified diff between the ClusterConfiguration kinder feeds to kubeadm
+// and the ClusterConfiguration persisted by kubeadm in the kubeadm-config ConfigMap.
+func PrintKubeadmConfig(c *status.Cluster, diff bool, nodes ...*status.Node) error {
+	for _, n := range nodes {
+		if err := n.Command(
+			"cat", constants.KubeadmConfigPath,
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to read %s from node %s", constants.KubeadmConfigPath, n.Name())
+		}
+	}
+
+	if !diff {
+		return nil
+	}
+
+	return diffKubeadmConfig(c.BootstrapControlPlane())
+}
+
+// diffKubeadmConfig prints the differences between the ClusterConfiguration in the /kind/kubeadm.conf file
+// and the ClusterConfiguration persisted in the kubeadm-config ConfigMap. Please note that the persisted
+// ClusterConfiguration includes also values defaulted by kubeadm.
+func diffKubeadmConfig(cp1 *status.Node) error {
+	cp1.Infof("Comparing %s with the kubeadm-config ConfigMap", constants.KubeadmConfigPath)
+
+	lines, err := cp1.Command(
+		"cat", constants.KubeadmConfigPath,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s from node %s", constants.KubeadmConfigPath, cp1.Name())
+	}
+	intended := selectYamlFramentByKind(strings.Join(lines, "\n"), "ClusterConfiguration")
+
+	lines, err = cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system", "get", "configmap", "kubeadm-config",
+		"-o=jsonpath={.data.ClusterConfiguration}",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrap(err, "failed to read the kubeadm-config ConfigMap. Please ensure that kubeadm-init is already completed")
+	}
+	persisted := strings.Join(lines, "\n")
+
+	if strings.TrimSpace(intended) == strings.TrimSpace(persisted) {
+		fmt.Println("No differences found")
+		return nil
+	}
+
+	// writes both configs on the node and uses diff for generating an unified diff
+	intendedPath := "/kinder/kubeadm-config-intended.yaml"
+	persistedPath := "/kinder/kubeadm-config-persisted.yaml"
+	if err := cp1.WriteFile(intendedPath, []byte(intended+"\n")); err != nil {
+		return err
+	}
+	if err := cp1.WriteFile(persistedPath, []byte(persisted+"\n")); err != nil {
+		return err
+	}
+
+	// nb. diff exits with 1 when files differ, so only exit codes greater than 1 are considered errors
+	if err := cp1.Command(
+		"sh", "-c", fmt.Sprintf("diff -u --label kubeadm-config --label %s %s %s; test $? -le 1", constants.KubeadmConfigPath, persistedPath, intendedPath),
+	).RunWithEcho(); err != nil {
+		return errors.Wrap(err, "failed to compare kubeadm config")
+	}
+
+	return nil
+}
+
 // getControlPlaneAddress return the join address that is the control plane endpoint in case the cluster has
 // an external load balancer in front of the control-plane nodes, otherwise the address of the
 // bootstrap control plane node.
@@ -167,7 +267,7 @@ func writeKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigDa
 		return errors.Wrap(err, "failed to generate kubeadm config content")
 	}
 
-	log.Debugf("generated config:\n%s", kubeadmConfig)
+	log.WithField("node", n.Name()).Debugf("generated config:\n%s", kubeadmConfig)
 
 	// copy the config to the node
 	if err := n.WriteFile(constants.KubeadmConfigPath, []byte(kubeadmConfig)); err != nil {
@@ -185,7 +285,7 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 	}
 
 	// generate the "raw config", using the kubeadm config template provided by kind
-	rawconfig, err := kubeadm.Config(kubeadmVersion, data)
+	rawconfig, err := kubeadm.Config(kubeadmVersion, data, options.templatesDir)
 	if err != nil {
 		return "", err
 	}
@@ -194,8 +294,7 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 	var patches = []string{}
 	var jsonPatches = []kindkustomize.PatchJSON6902{}
 
-	// add patches for instructing kubeadm to use the CRI runtime engine  installed on a node
-	// NB. this is a no-op in case of containerd, because it is already the default in the raw config
+	// add patches for instructing kubeadm to use the CRI socket of the runtime engine installed on a node
 	// TODO: currently we are always specifying the CRI kubeadm should use; it will be nice in the future to
 	// have the possibility to test the kubeadm CRI autodetection
 	nodeCRI, err := n.CRI()
@@ -220,7 +319,7 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 	// NB. this is a no-op in case of kubeadm config API older than v1beta2, because
 	// this feature was not supported before (the --certificate-key flag should be used instead)
 	if options.automaticCopyCerts && n.IsControlPlane() {
-		automaticCopyCertsPatches, err := kubeadm.GetAutomaticCopyCertsPatches(kubeadmVersion)
+		automaticCopyCertsPatches, err := kubeadm.GetAutomaticCopyCertsPatches(kubeadmVersion, certificateKey(c))
 		if err != nil {
 			return "", err
 		}
@@ -237,6 +336,36 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 		patches = append(patches, kubeDNSPatch)
 	}
 
+	// if requested, add patches for setting feature gates; this applies only to the bootstrap control-plane,
+	// because the ClusterConfiguration is used only at kubeadm init time
+	if len(options.featureGates) > 0 && n == c.BootstrapControlPlane() {
+		featureGatesPatch, err := kubeadm.GetFeatureGatesPatch(kubeadmVersion, options.featureGates)
+		if err != nil {
+			return "", err
+		}
+		patches = append(patches, featureGatesPatch)
+	}
+
+	// if requested, add patches for setting control-plane components extra args; this applies only to the
+	// bootstrap control-plane, because the ClusterConfiguration is used only at kubeadm init time
+	if !options.extraArgs.isEmpty() && n == c.BootstrapControlPlane() {
+		extraArgsPatch, err := kubeadm.GetControlPlaneExtraArgsPatch(kubeadmVersion, options.extraArgs.APIServer, options.extraArgs.ControllerManager, options.extraArgs.Scheduler)
+		if err != nil {
+			return "", err
+		}
+		patches = append(patches, extraArgsPatch)
+	}
+
+	// if requested, add patches for setting the local etcd data dir and extra args; as for other
+	// control-plane components, this applies only to the bootstrap control-plane
+	if options.extraArgs.hasLocalEtcd() && n == c.BootstrapControlPlane() {
+		localEtcdPatch, err := kubeadm.GetLocalEtcdPatch(kubeadmVersion, options.extraArgs.EtcdDataDir, options.extraArgs.Etcd)
+		if err != nil {
+			return "", err
+		}
+		patches = append(patches, localEtcdPatch)
+	}
+
 	// if requested to use file discovery and not the first control-plane, add patches for using file discovery
 	if options.discoveryMode != TokenDiscovery && !(n == c.BootstrapControlPlane()) {
 		// remove token from config
@@ -270,20 +399,24 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 		}
 	}
 
-	// if the cluster is using an external etcd node, add patches for configuring access
+	// if the cluster is using external etcd nodes, add patches for configuring access
 	// to external etcd cluster
 	if c.ExternalEtcd() != nil {
-		externalEtcdIP, externalEtcdIPV6, err := c.ExternalEtcd().IP()
-		if err != nil {
-			return "", errors.Wrapf(err, "failed to get IP for node: %s", c.ExternalEtcd().Name())
-		}
+		var externalEtcdIPs []string
+		for _, n := range c.ExternalEtcdMembers() {
+			externalEtcdIP, externalEtcdIPV6, err := n.IP()
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to get IP for node: %s", n.Name())
+			}
 
-		// configure the right protocol addresses
-		if c.Settings.IPFamily == status.IPv6Family {
-			externalEtcdIP = externalEtcdIPV6
+			// configure the right protocol addresses
+			if c.Settings.IPFamily == status.IPv6Family {
+				externalEtcdIP = externalEtcdIPV6
+			}
+			externalEtcdIPs = append(externalEtcdIPs, externalEtcdIP)
 		}
 
-		externalEtcdPatch, err := kubeadm.GetExternalEtcdPatch(kubeadmVersion, externalEtcdIP)
+		externalEtcdPatch, err := kubeadm.GetExternalEtcdPatch(kubeadmVersion, externalEtcdIPs)
 		if err != nil {
 			return "", err
 		}
@@ -443,3 +576,18 @@ func selectYamlFramentByKind(rawconfig string, kind ...string) string {
 
 	return strings.Join(config, yamlSeparator)
 }
+
+// clusterPodSubnet returns the pod subnet of the cluster, that is the subnet set at create time, if any,
+// or the default subnet for the cluster IP family
+func clusterPodSubnet(c *status.Cluster) string {
+	if c.Settings.PodSubnet != "" {
+		return c.Settings.PodSubnet
+	}
+	switch c.Settings.IPFamily {
+	case status.IPv6Family:
+		return "fd00:10:244::/56"
+	case status.DualStackFamily:
+		return "192.168.0.0/16,fd00:10:244::/56"
+	}
+	return "192.168.0.0/16" // default for calico
+}
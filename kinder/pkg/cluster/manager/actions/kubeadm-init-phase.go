@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// KubeadmInitPhase executes a single kubeadm init phase, e.g. certs or certs/apiserver, on the bootstrap control-plane
+// node or on the control-plane node selected with --only-node, using the kubeadm config of the cluster.
+// If the list option is set, the phases supported by kubeadm are printed instead.
+func KubeadmInitPhase(c *status.Cluster, flags *RunOptions) error {
+	controlPlanes := c.ControlPlanes().EligibleForActions()
+	if len(controlPlanes) == 0 {
+		return errors.New("kubeadm-init-phase requires a control-plane node")
+	}
+	cp := controlPlanes[0]
+
+	if flags.list {
+		phases, err := kubeadmPhases(cp, "init")
+		if err != nil {
+			return err
+		}
+		fmt.Println(strings.Join(phases, "\n"))
+		return nil
+	}
+
+	if flags.phase == "" {
+		return errors.New("kubeadm-init-phase requires a phase, e.g. kinder do kubeadm-init-phase certs/apiserver; use --list for getting the list of phases")
+	}
+
+	// phases can be given using the kubeadm phase tree notation, e.g. certs/apiserver, or using spaces, e.g. "certs apiserver"
+	parts := strings.FieldsFunc(flags.phase, func(r rune) bool { return r == '/' || r == ' ' })
+	if len(parts) == 0 {
+		return errors.Errorf("invalid phase %q", flags.phase)
+	}
+
+	// fail fast if required to use kustomize and kubeadm less than v1.16
+	if flags.kustomizeDir != "" && cp.MustKubeadmVersion().LessThan(constants.V1_16) {
+		return errors.New("--kustomize-dir can't be used with kubeadm older than v1.16")
+	}
+
+	// if kustomize copy patches to the node
+	if flags.kustomizeDir != "" {
+		if err := copyPatchesToNode(cp, flags.kustomizeDir); err != nil {
+			return err
+		}
+	}
+
+	// if kubeadm patches copy patches to the node
+	if flags.patchesDir != "" {
+		if err := copyKubeadmPatchesToNode(cp, flags.patchesDir); err != nil {
+			return err
+		}
+	}
+
+	// prepares the kubeadm config on this node
+	if err := KubeadmInitConfig(c, flags.kubeDNS, flags.automaticCopyCerts, flags.featureGates, flags.extraArgs, flags.configTemplates, cp); err != nil {
+		return err
+	}
+
+	args := append([]string{"init", "phase"}, parts...)
+	args = append(args, fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", flags.vLevel))
+	switch parts[0] {
+	case "preflight":
+		args = append(args, constants.KubeadmIgnorePreflightErrorsFlag)
+	case "control-plane", "etcd":
+		if flags.kustomizeDir != "" {
+			args = append(args, "-k", constants.KustomizeDir)
+		}
+		args = append(args, kubeadmPatchesArgs(cp, flags.patchesDir)...)
+	}
+
+	return cp.Command("kubeadm", args...).RunWithEcho()
+}
+
+// kubeadmPhases returns the phase tree of a kubeadm command, e.g. init or join, as printed by kubeadm <command> --help
+func kubeadmPhases(n *status.Node, command string) ([]string, error) {
+	lines, err := n.Command("kubeadm", command, "--help").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get kubeadm %s phases", command)
+	}
+
+	var phases []string
+	inPhases := false
+	for _, l := range lines {
+		switch {
+		case strings.Contains(l, "executes the following phases"):
+			inPhases = true
+		case inPhases && strings.TrimSpace(l) == "```":
+			// nb. the phase tree is wrapped in a code block
+		case inPhases && strings.TrimSpace(l) == "":
+			if len(phases) > 0 {
+				return phases, nil
+			}
+		case inPhases:
+			phases = append(phases, l)
+		}
+	}
+	if len(phases) == 0 {
+		return nil, errors.Errorf("failed to find the phases in the kubeadm %s --help output", command)
+	}
+	return phases, nil
+}
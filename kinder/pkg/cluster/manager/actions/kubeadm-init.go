@@ -17,42 +17,67 @@ limitations under the License.
 package actions
 
 import (
-	"bytes"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
-	"k8s.io/kubeadm/kinder/pkg/data"
+	"k8s.io/kubeadm/kinder/pkg/metrics"
 )
 
 // KubeadmInit executes the kubeadm init workflow including also post init task
-// like installing the CNI network plugin
-func KubeadmInit(c *status.Cluster, usePhases, kubeDNS, automaticCopyCerts bool, kustomizeDir string, wait time.Duration, vLevel int) (err error) {
+// like installing the CNI network plugin, using the given run options
+func KubeadmInit(c *status.Cluster, flags *RunOptions) (err error) {
 	cp1 := c.BootstrapControlPlane()
 
+	// fail fast if required to use kubeadm dry-run with phases, because phases are executed one by one
+	// and later phases depend on the changes applied by the previous ones
+	if flags.kubeadmDryRun && flags.usePhases {
+		return errors.New("--kubeadm-dry-run can't be used with --use-phases")
+	}
+
+	// fail fast if required to skip phases with phases, because kinder executes a fixed list of phases
+	if len(flags.skipPhases) > 0 && flags.usePhases {
+		return errors.New("--skip-phases can't be used with --use-phases")
+	}
+
+	// fail fast if required to configure the local etcd in a cluster with external etcd
+	if flags.extraArgs.hasLocalEtcd() && c.ExternalEtcd() != nil {
+		return errors.New("--etcd-data-dir and --etcd-extra-args can't be used with external etcd")
+	}
+
 	// fail fast if required to use automatic copy certs and kubeadm less than v1.14
-	if automaticCopyCerts && cp1.MustKubeadmVersion().LessThan(constants.V1_14) {
+	if flags.automaticCopyCerts && cp1.MustKubeadmVersion().LessThan(constants.V1_14) {
 		return errors.New("--automatic-copy-certs can't be used with kubeadm older than v1.14")
 	}
 
 	// fail fast if required to use kustomize and kubeadm less than v1.16
-	if kustomizeDir != "" && cp1.MustKubeadmVersion().LessThan(constants.V1_16) {
+	if flags.kustomizeDir != "" && cp1.MustKubeadmVersion().LessThan(constants.V1_16) {
 		return errors.New("--kustomize-dir can't be used with kubeadm older than v1.16")
 	}
 
+	// fail fast if required to skip phases not recognized by kubeadm
+	if err := validateSkipPhases(cp1, "init", flags.skipPhases); err != nil {
+		return err
+	}
+
 	// if kustomize copy patches to the node
-	if kustomizeDir != "" {
-		if err := copyPatchesToNode(cp1, kustomizeDir); err != nil {
+	if flags.kustomizeDir != "" {
+		if err := copyPatchesToNode(cp1, flags.kustomizeDir); err != nil {
+			return err
+		}
+	}
+
+	// if kubeadm patches copy patches to the node
+	if flags.patchesDir != "" {
+		if err := copyKubeadmPatchesToNode(cp1, flags.patchesDir); err != nil {
 			return err
 		}
 	}
@@ -67,35 +92,50 @@ func KubeadmInit(c *status.Cluster, usePhases, kubeDNS, automaticCopyCerts bool,
 		return err
 	}
 
+	// if kubelet extra args, write the kubelet drop-in on the node
+	// NB. this is skipped in case of kubeadm dry-run because it changes the node
+	if !flags.kubeadmDryRun {
+		if err := writeKubeletExtraArgs(cp1, flags.kubeletExtraArgs); err != nil {
+			return err
+		}
+	}
+
 	// prepares the kubeadm config on this node
-	if err := KubeadmInitConfig(c, kubeDNS, automaticCopyCerts, cp1); err != nil {
+	if err := KubeadmInitConfig(c, flags.kubeDNS, flags.automaticCopyCerts, flags.featureGates, flags.extraArgs, flags.configTemplates, cp1); err != nil {
 		return err
 	}
 
+	// if requested, execs kubeadm init in dry-run mode only, without changing the node and the loadbalancer
+	if flags.kubeadmDryRun {
+		return kubeadmInitDryRun(cp1, flags.skipPhases, flags.kustomizeDir, flags.patchesDir, flags.vLevel)
+	}
+
 	// prepares the loadbalancer config
 	if err := LoadBalancer(c, cp1); err != nil {
 		return err
 	}
 
 	// execs the kubeadm init workflow
-	if usePhases {
-		err = kubeadmInitWithPhases(cp1, automaticCopyCerts, kustomizeDir, vLevel)
+	start := time.Now()
+	if flags.usePhases {
+		err = kubeadmInitWithPhases(cp1, flags.automaticCopyCerts, flags.kustomizeDir, flags.patchesDir, flags.vLevel)
 	} else {
-		err = kubeadmInit(cp1, automaticCopyCerts, kustomizeDir, vLevel)
+		err = kubeadmInit(cp1, flags.automaticCopyCerts, flags.skipPhases, flags.kustomizeDir, flags.patchesDir, flags.vLevel)
 	}
 	if err != nil {
 		return err
 	}
 
 	// completes post init task by installing the CNI network plugin
-	if err := postInit(c, wait); err != nil {
+	if err := postInit(c, flags.cni, flags.wait, flags.probe()); err != nil {
 		return err
 	}
+	metrics.Since(metrics.InitSeconds, start, "node", cp1.Name())
 
 	return nil
 }
 
-func kubeadmInit(cp1 *status.Node, automaticCopyCerts bool, kustomizeDir string, vLevel int) error {
+func kubeadmInit(cp1 *status.Node, automaticCopyCerts bool, skipPhases []string, kustomizeDir, patchesDir string, vLevel int) error {
 	initArgs := []string{
 		"init",
 		constants.KubeadmIgnorePreflightErrorsFlag,
@@ -119,6 +159,8 @@ func kubeadmInit(cp1 *status.Node, automaticCopyCerts bool, kustomizeDir string,
 	if kustomizeDir != "" {
 		initArgs = append(initArgs, "-k", constants.KustomizeDir)
 	}
+	initArgs = append(initArgs, kubeadmPatchesArgs(cp1, patchesDir)...)
+	initArgs = append(initArgs, skipPhasesArgs(skipPhases)...)
 
 	if err := cp1.Command(
 		"kubeadm", initArgs...,
@@ -129,7 +171,53 @@ func kubeadmInit(cp1 *status.Node, automaticCopyCerts bool, kustomizeDir string,
 	return nil
 }
 
-func kubeadmInitWithPhases(cp1 *status.Node, automaticCopyCerts bool, kustomizeDir string, vLevel int) error {
+// kubeadmInitDryRun executes kubeadm init with the --dry-run flag and then copies the files rendered
+// by kubeadm (certificates, kubeconfig files and manifests) from the node to a temporary folder on the host
+func kubeadmInitDryRun(cp1 *status.Node, skipPhases []string, kustomizeDir, patchesDir string, vLevel int) error {
+	initArgs := []string{
+		"init",
+		"--dry-run",
+		constants.KubeadmIgnorePreflightErrorsFlag,
+		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+		fmt.Sprintf("--v=%d", vLevel),
+	}
+	if kustomizeDir != "" {
+		initArgs = append(initArgs, "-k", constants.KustomizeDir)
+	}
+	initArgs = append(initArgs, kubeadmPatchesArgs(cp1, patchesDir)...)
+	initArgs = append(initArgs, skipPhasesArgs(skipPhases)...)
+
+	if err := cp1.Command(
+		"kubeadm", initArgs...,
+	).RunWithEcho(); err != nil {
+		return err
+	}
+
+	// gets the folder where kubeadm rendered files, that is the most recent dry-run folder
+	// e.g. /etc/kubernetes/tmp/kubeadm-init-dryrun123456789
+	lines, err := cp1.Command(
+		"/bin/bash", "-c", //use shell to get * resolved into the container
+		"ls -td /etc/kubernetes/tmp/kubeadm-init-dryrun* | head -1",
+	).Silent().RunAndCapture()
+	if err != nil || len(lines) != 1 {
+		return errors.Errorf("failed to find the kubeadm init dry-run folder on node %s", cp1.Name())
+	}
+	dryRunDir := lines[0]
+
+	dest, err := ioutil.TempDir("", "kinder-kubeadm-init-dryrun")
+	if err != nil {
+		return errors.Wrap(err, "failed to create a temporary folder for the kubeadm init dry-run output")
+	}
+	if err := cp1.CopyFrom(dryRunDir+"/.", dest); err != nil {
+		return errors.Wrapf(err, "failed to copy %s from node %s", dryRunDir, cp1.Name())
+	}
+
+	fmt.Printf("\nkubeadm init was executed in dry-run mode: nothing was applied to node %s.\n", cp1.Name())
+	fmt.Printf("Files rendered by kubeadm have been copied to %s\n", dest)
+	return nil
+}
+
+func kubeadmInitWithPhases(cp1 *status.Node, automaticCopyCerts bool, kustomizeDir, patchesDir string, vLevel int) error {
 	if err := cp1.Command(
 		"kubeadm", "init", "phase", "preflight", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
 		constants.KubeadmIgnorePreflightErrorsFlag,
@@ -161,6 +249,7 @@ func kubeadmInitWithPhases(cp1 *status.Node, automaticCopyCerts bool, kustomizeD
 	if kustomizeDir != "" {
 		controlplaneArgs = append(controlplaneArgs, "-k", constants.KustomizeDir)
 	}
+	controlplaneArgs = append(controlplaneArgs, kubeadmPatchesArgs(cp1, patchesDir)...)
 	if err := cp1.Command(
 		"kubeadm", controlplaneArgs...,
 	).RunWithEcho(); err != nil {
@@ -173,6 +262,7 @@ func kubeadmInitWithPhases(cp1 *status.Node, automaticCopyCerts bool, kustomizeD
 	if kustomizeDir != "" {
 		etcdArgs = append(etcdArgs, "-k", constants.KustomizeDir)
 	}
+	etcdArgs = append(etcdArgs, kubeadmPatchesArgs(cp1, patchesDir)...)
 	if err := cp1.Command(
 		"kubeadm", etcdArgs...,
 	).RunWithEcho(); err != nil {
@@ -241,35 +331,15 @@ func kubeadmInitWithPhases(cp1 *status.Node, automaticCopyCerts bool, kustomizeD
 	return nil
 }
 
-func postInit(c *status.Cluster, wait time.Duration) error {
+func postInit(c *status.Cluster, cni CNISpec, wait time.Duration, probe probeConfig) error {
 	cp1 := c.BootstrapControlPlane()
 
 	if err := copyKubeConfigToHost(c); err != nil {
 		return err
 	}
 
-	// Calico requires net.ipv4.conf.all.rp_filter to be set to 0 or 1.
-	// If you require loose RPF and you are not concerned about spoofing, this check can be disabled by setting the IgnoreLooseRPF configuration parameter to 'true'.
-	for _, cp := range c.K8sNodes() {
-		if err := cp.Command(
-			"sysctl", "-w", "net.ipv4.conf.all.rp_filter=1",
-		).Silent().Run(); err != nil {
-			return err
-		}
-	}
-
-	// Apply a CNI plugin using a hardcoded manifest
-	cmd := cp1.Command("kubectl", "apply", "--kubeconfig=/etc/kubernetes/admin.conf", "-f", "-")
-	cp1.Infof("applying Calico version 3.8.2")
-	cmd.Stdin(strings.NewReader(data.CalicoCNI3_8_2))
-	if err := cmd.RunWithEcho(); err != nil {
-		return err
-	}
-
-	// Fix calico as per https://alexbrand.dev/post/creating-a-kind-cluster-with-calico-networking/
-	if err := cp1.Command(
-		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system", "set", "env", "daemonset/calico-node", "FELIX_IGNORELOOSERPF=true",
-	).RunWithEcho(); err != nil {
+	// Apply the CNI plugin
+	if err := installCNI(c, cni); err != nil {
 		return err
 	}
 
@@ -286,8 +356,11 @@ func postInit(c *status.Cluster, wait time.Duration) error {
 	//	return errors.Wrap(err, "failed to add default storage class")
 	//}
 
-	if err := waitNewControlPlaneNodeReady(c, cp1, wait); err != nil {
-		return err
+	// nb. without a CNI plugin the node does not become Ready
+	if cni.Provider != NoCNI {
+		if err := waitNewControlPlaneNodeReady(c, cp1, wait, probe); err != nil {
+			return err
+		}
 	}
 
 	fmt.Printf(
@@ -331,33 +404,15 @@ func getAPIServerPort(c *status.Cluster) (int32, error) {
 	return c.BootstrapControlPlane().Ports(constants.APIServerPort)
 }
 
-// matches kubeconfig server entry like:
-//    server: https://172.17.0.2:6443
-// which we rewrite to:
-//    server: https://$ADDRESS:$PORT
-var serverAddressRE = regexp.MustCompile(`^(\s+server:) https://.*:\d+$`)
-
 // writeKubeConfig writes a fixed KUBECONFIG to dest
 // this should only be called on a control plane node
 // While copying to the host machine the control plane address
 // is replaced with local host and the control plane port with
 // a randomly generated port reserved during node creation.
 func writeKubeConfig(c *status.Cluster, hostPort int32) error {
-	lines, err := c.BootstrapControlPlane().Command("cat", "/etc/kubernetes/admin.conf").Silent().RunAndCapture()
+	kubeconfig, err := kubeConfigWithServer(c, net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort)))
 	if err != nil {
-		return errors.Wrap(err, "failed to get kubeconfig from node")
-	}
-
-	// fix the config file, swapping out the server for the forwarded localhost:port
-	var buff bytes.Buffer
-	for _, line := range lines {
-		match := serverAddressRE.FindStringSubmatch(line)
-		if len(match) > 1 {
-			addr := net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort))
-			line = fmt.Sprintf("%s https://%s", match[1], addr)
-		}
-		buff.WriteString(line)
-		buff.WriteString("\n")
+		return err
 	}
 
 	// create the directory to contain the KUBECONFIG file.
@@ -368,7 +423,7 @@ func writeKubeConfig(c *status.Cluster, hostPort int32) error {
 		return errors.Wrap(err, "failed to create kubeconfig output directory")
 	}
 
-	return ioutil.WriteFile(dest, buff.Bytes(), 0600)
+	return ioutil.WriteFile(dest, kubeconfig, 0600)
 }
 
 func copyPatchesToNode(n *status.Node, dir string) error {
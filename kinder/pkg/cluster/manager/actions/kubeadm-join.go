@@ -21,47 +21,88 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/metrics"
 )
 
 // KubeadmJoin executes the kubeadm join workflow both for control-plane nodes and
-// worker nodes
-func KubeadmJoin(c *status.Cluster, usePhases, automaticCopyCerts bool, discoveryMode DiscoveryMode, kustomizeDir string, wait time.Duration, vLevel int) (err error) {
-	if err := joinControlPlanes(c, usePhases, automaticCopyCerts, discoveryMode, kustomizeDir, wait, vLevel); err != nil {
+// worker nodes, using the given run options
+func KubeadmJoin(c *status.Cluster, flags *RunOptions) (err error) {
+	// kubeadm join reads the ClusterConfiguration, including feature gates, from the cluster, so
+	// the gates set at kubeadm init time are used
+	if len(flags.featureGates) > 0 {
+		log.Warn("--feature-gates is ignored by kubeadm join; joining nodes use the feature gates set by kubeadm-init")
+	}
+
+	// fail fast if required to skip phases with phases, because kinder executes a fixed list of phases
+	if len(flags.skipPhases) > 0 && flags.usePhases {
+		return errors.New("--skip-phases can't be used with --use-phases")
+	}
+
+	// fail fast if required to use kubeadm patches with phases on worker nodes, because
+	// the phases executed when joining a worker node do not apply patches
+	if flags.patchesDir != "" && flags.usePhases && len(c.Workers().EligibleForActions()) > 0 {
+		return errors.New("--patches can't be used with --use-phases when joining worker nodes")
+	}
+
+	// fail fast if required to skip phases not recognized by kubeadm, before joining any node
+	for _, n := range append(c.SecondaryControlPlanes().EligibleForActions(), c.Workers().EligibleForActions()...) {
+		if err := validateSkipPhases(n, "join", flags.skipPhases); err != nil {
+			return err
+		}
+	}
+
+	if err := joinControlPlanes(c, flags); err != nil {
 		return err
 	}
 
-	if err := joinWorkers(c, usePhases, automaticCopyCerts, discoveryMode, wait, vLevel); err != nil {
+	if err := joinWorkers(c, flags); err != nil {
 		return err
 	}
 	return nil
 }
 
-func joinControlPlanes(c *status.Cluster, usePhases, automaticCopyCerts bool, discoveryMode DiscoveryMode, kustomizeDir string, wait time.Duration, vLevel int) (err error) {
+func joinControlPlanes(c *status.Cluster, flags *RunOptions) (err error) {
 	cpX := []*status.Node{c.BootstrapControlPlane()}
 
+	// if automatic copy certs, ensure certificates uploaded to the cluster are not expired
+	key := certificateKey(c)
+	if flags.automaticCopyCerts && len(c.SecondaryControlPlanes().EligibleForActions()) > 0 {
+		if err := ensureUploadedCertificates(c, key, flags.vLevel); err != nil {
+			return err
+		}
+	}
+
 	for _, cp2 := range c.SecondaryControlPlanes().EligibleForActions() {
 		// automatic copy certs is supported starting from v1.14
-		if automaticCopyCerts && !cp2.MustKubeadmVersion().AtLeast(constants.V1_14) {
+		if flags.automaticCopyCerts && !cp2.MustKubeadmVersion().AtLeast(constants.V1_14) {
 			return errors.New("--automatic-copy-certs can't be used with kubeadm older than v1.14")
 		}
 
 		// fail fast if required to use kustomize and kubeadm less than v1.16
-		if kustomizeDir != "" && cp2.MustKubeadmVersion().LessThan(constants.V1_16) {
+		if flags.kustomizeDir != "" && cp2.MustKubeadmVersion().LessThan(constants.V1_16) {
 			return errors.New("--kustomize-dir can't be used with kubeadm older than v1.16")
 		}
 
 		// if kustomize copy patches to the node
-		if kustomizeDir != "" {
-			if err := copyPatchesToNode(cp2, kustomizeDir); err != nil {
+		if flags.kustomizeDir != "" {
+			if err := copyPatchesToNode(cp2, flags.kustomizeDir); err != nil {
+				return err
+			}
+		}
+
+		// if kubeadm patches copy patches to the node
+		if flags.patchesDir != "" {
+			if err := copyKubeadmPatchesToNode(cp2, flags.patchesDir); err != nil {
 				return err
 			}
 		}
 
 		// if not automatic copy certs, simulate manual copy
-		if !automaticCopyCerts {
+		if !flags.automaticCopyCerts {
 			if err := copyCertificatesToNode(c, cp2); err != nil {
 				return err
 			}
@@ -77,20 +118,26 @@ func joinControlPlanes(c *status.Cluster, usePhases, automaticCopyCerts bool, di
 			return err
 		}
 
+		// if kubelet extra args, write the kubelet drop-in on the node
+		if err := writeKubeletExtraArgs(cp2, flags.kubeletExtraArgs); err != nil {
+			return err
+		}
+
 		// prepares the kubeadm config on this node
 		// NB. kubeDNS flag is set to false because it is not relevant for joinConfiguration
-		if err := KubeadmJoinConfig(c, automaticCopyCerts, discoveryMode, cp2); err != nil {
+		if err := KubeadmJoinConfig(c, flags.automaticCopyCerts, flags.discoveryMode, flags.configTemplates, cp2); err != nil {
 			return err
 		}
 
 		// executes the kubeadm join control-plane workflow
-		if usePhases {
-			err = kubeadmJoinControlPlaneWithPhases(cp2, automaticCopyCerts, kustomizeDir, vLevel)
+		start := time.Now()
+		if flags.usePhases {
+			err = kubeadmJoinControlPlaneWithPhases(cp2, flags.automaticCopyCerts, key, flags.kustomizeDir, flags.patchesDir, flags.vLevel)
 		} else {
-			err = kubeadmJoinControlPlane(cp2, automaticCopyCerts, kustomizeDir, vLevel)
+			err = kubeadmJoinControlPlane(cp2, flags.automaticCopyCerts, key, flags.skipPhases, flags.kustomizeDir, flags.patchesDir, flags.vLevel)
 		}
 		if err != nil {
-			return err
+			return tokenJoinError(c, err)
 		}
 
 		// updates the loadbalancer config with the new cp node
@@ -99,14 +146,15 @@ func joinControlPlanes(c *status.Cluster, usePhases, automaticCopyCerts bool, di
 			return err
 		}
 
-		if err := waitNewControlPlaneNodeReady(c, cp2, wait); err != nil {
+		if err := waitNewControlPlaneNodeReady(c, cp2, flags.wait, flags.probe()); err != nil {
 			return err
 		}
+		metrics.Since(metrics.JoinSeconds, start, "node", cp2.Name())
 	}
 	return nil
 }
 
-func kubeadmJoinControlPlane(cp *status.Node, automaticCopyCerts bool, kustomizeDir string, vLevel int) (err error) {
+func kubeadmJoinControlPlane(cp *status.Node, automaticCopyCerts bool, certificateKey string, skipPhases []string, kustomizeDir, patchesDir string, vLevel int) (err error) {
 	joinArgs := []string{
 		"join",
 		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
@@ -117,13 +165,15 @@ func kubeadmJoinControlPlane(cp *status.Node, automaticCopyCerts bool, kustomize
 		// if before v1.15, add certificate key flag (for >= 15, certificate key is passed via the config file)
 		if cp.MustKubeadmVersion().LessThan(constants.V1_15) {
 			joinArgs = append(joinArgs,
-				fmt.Sprintf("--certificate-key=%s", constants.CertificateKey),
+				fmt.Sprintf("--certificate-key=%s", certificateKey),
 			)
 		}
 	}
 	if kustomizeDir != "" {
 		joinArgs = append(joinArgs, "-k", constants.KustomizeDir)
 	}
+	joinArgs = append(joinArgs, kubeadmPatchesArgs(cp, patchesDir)...)
+	joinArgs = append(joinArgs, skipPhasesArgs(skipPhases)...)
 
 	if err := cp.Command(
 		"kubeadm", joinArgs...,
@@ -134,7 +184,7 @@ func kubeadmJoinControlPlane(cp *status.Node, automaticCopyCerts bool, kustomize
 	return nil
 }
 
-func kubeadmJoinControlPlaneWithPhases(cp *status.Node, automaticCopyCerts bool, kustomizeDir string, vLevel int) (err error) {
+func kubeadmJoinControlPlaneWithPhases(cp *status.Node, automaticCopyCerts bool, certificateKey, kustomizeDir, patchesDir string, vLevel int) (err error) {
 	// kubeadm join phase preflight
 	preflightArgs := []string{
 		"join", "phase", "preflight",
@@ -146,7 +196,7 @@ func kubeadmJoinControlPlaneWithPhases(cp *status.Node, automaticCopyCerts bool,
 		// if before v1.15, add certificate key flag (for >= 15, certificate key is passed via the config file)
 		if cp.MustKubeadmVersion().LessThan(constants.V1_15) {
 			preflightArgs = append(preflightArgs,
-				fmt.Sprintf("--certificate-key=%s", constants.CertificateKey),
+				fmt.Sprintf("--certificate-key=%s", certificateKey),
 			)
 		}
 	}
@@ -167,13 +217,14 @@ func kubeadmJoinControlPlaneWithPhases(cp *status.Node, automaticCopyCerts bool,
 		// if before v1.15, add certificate key flag (for >= 15, certificate key is passed via the config file)
 		if cp.MustKubeadmVersion().LessThan(constants.V1_15) {
 			prepareArgs = append(prepareArgs,
-				fmt.Sprintf("--certificate-key=%s", constants.CertificateKey),
+				fmt.Sprintf("--certificate-key=%s", certificateKey),
 			)
 		}
 	}
 	if kustomizeDir != "" {
 		prepareArgs = append(prepareArgs, "-k", constants.KustomizeDir)
 	}
+	prepareArgs = append(prepareArgs, kubeadmPatchesArgs(cp, patchesDir)...)
 
 	if err := cp.Command(
 		"kubeadm", prepareArgs...,
@@ -199,11 +250,12 @@ func kubeadmJoinControlPlaneWithPhases(cp *status.Node, automaticCopyCerts bool,
 	if kustomizeDir != "" {
 		controlPlaneArgs = append(controlPlaneArgs, "-k", constants.KustomizeDir)
 	}
+	controlPlaneArgs = append(controlPlaneArgs, kubeadmPatchesArgs(cp, patchesDir)...)
 	if automaticCopyCerts {
 		// if before v1.15, add certificate key flag (for >= 15, certificate key is passed via the config file)
 		if cp.MustKubeadmVersion().LessThan(constants.V1_15) {
 			controlPlaneArgs = append(controlPlaneArgs,
-				fmt.Sprintf("--certificate-key=%s", constants.CertificateKey),
+				fmt.Sprintf("--certificate-key=%s", certificateKey),
 			)
 		}
 	}
@@ -217,12 +269,20 @@ func kubeadmJoinControlPlaneWithPhases(cp *status.Node, automaticCopyCerts bool,
 	return nil
 }
 
-func joinWorkers(c *status.Cluster, usePhases, automaticCopyCerts bool, discoveryMode DiscoveryMode, wait time.Duration, vLevel int) (err error) {
-	for _, w := range c.Workers().EligibleForActions() {
-		if usePhases && !w.MustKubeadmVersion().AtLeast(constants.V1_14) {
+func joinWorkers(c *status.Cluster, flags *RunOptions) error {
+	// NB. worker nodes do not depend on each other, so they can be joined in parallel
+	return forEachNode(c.Workers().EligibleForActions(), flags.parallel, func(w *status.Node) error {
+		if flags.usePhases && !w.MustKubeadmVersion().AtLeast(constants.V1_14) {
 			return errors.New("--automatic-copy-certs can't be used with kubeadm older than v1.14")
 		}
 
+		// if kubeadm patches copy patches to the node
+		if flags.patchesDir != "" {
+			if err := copyKubeadmPatchesToNode(w, flags.patchesDir); err != nil {
+				return err
+			}
+		}
+
 		// checks pre-loaded images available on the node (this will report missing images, if any)
 		kubeVersion, err := w.KubeVersion()
 		if err != nil {
@@ -233,34 +293,47 @@ func joinWorkers(c *status.Cluster, usePhases, automaticCopyCerts bool, discover
 			return err
 		}
 
+		// if kubelet extra args, write the kubelet drop-in on the node
+		if err := writeKubeletExtraArgs(w, flags.kubeletExtraArgs); err != nil {
+			return err
+		}
+
 		// prepares the kubeadm config on this node
-		if err := KubeadmJoinConfig(c, false, discoveryMode, w); err != nil {
+		if err := KubeadmJoinConfig(c, false, flags.discoveryMode, flags.configTemplates, w); err != nil {
 			return err
 		}
 
 		// executes the kubeadm join workflow
-		if usePhases {
-			err = kubeadmJoinWorkerWithPhases(w, vLevel)
+		start := time.Now()
+		if flags.usePhases {
+			err = kubeadmJoinWorkerWithPhases(w, flags.vLevel)
 		} else {
-			err = kubeadmJoinWorker(w, vLevel)
+			err = kubeadmJoinWorker(w, flags.skipPhases, flags.patchesDir, flags.vLevel)
 		}
 		if err != nil {
-			return err
+			return tokenJoinError(c, err)
 		}
 
-		if err := waitNewWorkerNodeReady(c, w, wait); err != nil {
+		if err := waitNewWorkerNodeReady(c, w, flags.wait, flags.probe()); err != nil {
 			return err
 		}
-	}
-	return nil
+		metrics.Since(metrics.JoinSeconds, start, "node", w.Name())
+		return nil
+	})
 }
 
-func kubeadmJoinWorker(w *status.Node, vLevel int) (err error) {
-	if err := w.Command(
-		"kubeadm", "join",
+func kubeadmJoinWorker(w *status.Node, skipPhases []string, patchesDir string, vLevel int) (err error) {
+	joinArgs := []string{
+		"join",
 		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
 		fmt.Sprintf("--v=%d", vLevel),
 		constants.KubeadmIgnorePreflightErrorsFlag,
+	}
+	joinArgs = append(joinArgs, kubeadmPatchesArgs(w, patchesDir)...)
+	joinArgs = append(joinArgs, skipPhasesArgs(skipPhases)...)
+
+	if err := w.Command(
+		"kubeadm", joinArgs...,
 	).RunWithEcho(); err != nil {
 		return err
 	}
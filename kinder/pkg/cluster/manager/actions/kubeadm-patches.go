@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// kubeadmPatchRegexp matches the file names accepted by kubeadm for patches, that are
+// target[suffix][+patchtype].extension, e.g. kube-apiserver0+merge.yaml
+var kubeadmPatchRegexp = regexp.MustCompile(`^(kube-apiserver|kube-controller-manager|kube-scheduler|etcd|kubeletconfiguration)[^+.]*(\+(strategic|merge|json))?\.(json|yaml)$`)
+
+// kubeadmPatchesArgs returns the args for passing the kubeadm patches folder to kubeadm;
+// the flag was introduced in v1.19 as --experimental-patches and graduated to --patches in v1.22
+func kubeadmPatchesArgs(n *status.Node, patchesDir string) []string {
+	if patchesDir == "" {
+		return nil
+	}
+	if n.MustKubeadmVersion().LessThan(constants.V1_22) {
+		return []string{"--experimental-patches", constants.PatchesDir}
+	}
+	return []string{"--patches", constants.PatchesDir}
+}
+
+// validatePatchesDir checks all the files in the kubeadm patches folder have a name
+// recognized by kubeadm, because otherwise kubeadm silently ignores them
+func validatePatchesDir(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read patches folder %s", dir)
+	}
+
+	var patches, unknown []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if !kubeadmPatchRegexp.MatchString(file.Name()) {
+			unknown = append(unknown, file.Name())
+			continue
+		}
+		patches = append(patches, file.Name())
+	}
+
+	if len(unknown) > 0 {
+		return nil, errors.Errorf("patches folder %s contains files not recognized by kubeadm: %s. Patch files should be named target[suffix][+patchtype].extension, where target is one of kube-apiserver, kube-controller-manager, kube-scheduler, etcd, kubeletconfiguration, patchtype is one of strategic, merge, json and extension is one of json, yaml", dir, strings.Join(unknown, ", "))
+	}
+	if len(patches) == 0 {
+		return nil, errors.Errorf("patches folder %s does not contain any patch file", dir)
+	}
+	return patches, nil
+}
+
+// copyKubeadmPatchesToNode validates and copies the kubeadm patches to the node
+func copyKubeadmPatchesToNode(n *status.Node, dir string) error {
+	if n.MustKubeadmVersion().LessThan(constants.V1_19) {
+		return errors.Errorf("kubeadm patches are not supported by kubeadm %s, requires v1.19 or greater", n.MustKubeadmVersion())
+	}
+
+	patches, err := validatePatchesDir(dir)
+	if err != nil {
+		return err
+	}
+
+	n.Infof("Importing kubeadm patches from %s", dir)
+
+	// creates the folder for kubeadm patches
+	if err := n.Command("mkdir", "-p", constants.PatchesDir).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s folder", constants.PatchesDir)
+	}
+
+	// copies kubeadm patches
+	for _, patch := range patches {
+		match := kubeadmPatchRegexp.FindStringSubmatch(patch)
+		patchType := match[3]
+		if patchType == "" {
+			patchType = "strategic"
+		}
+		n.Infof("applying patch %s to %s (%s)", patch, match[1], patchType)
+
+		hostPath := filepath.Join(dir, patch)
+		nodePath := filepath.Join(constants.PatchesDir, patch)
+		if err := n.CopyTo(hostPath, nodePath); err != nil {
+			return errors.Wrapf(err, "failed to copy from host path %q to node path %q for node %q",
+				hostPath, nodePath, n.Name())
+		}
+	}
+
+	return nil
+}
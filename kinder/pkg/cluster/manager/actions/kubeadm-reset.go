@@ -18,19 +18,173 @@ package actions
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/cri"
 )
 
-// KubeadmReset executes the kubeadm reset workflow
-func KubeadmReset(c *status.Cluster, vLevel int) error {
+// KubeadmReset executes the kubeadm reset workflow, and then verifies that nodes were actually cleaned up;
+// if cleanCNI is set, the CNI configuration left in place by kubeadm reset is removed after the verification
+func KubeadmReset(c *status.Cluster, cleanCNI bool, parallel int, vLevel int) error {
 	//TODO: implements kubeadm reset with phases
-	for _, n := range c.K8sNodes().EligibleForActions() {
+	var mu sync.Mutex
+	reset := map[string]bool{}
+	resetErr := forEachNode(c.K8sNodes().EligibleForActions(), parallel, func(n *status.Node) error {
+		criSocket, err := nodeCRISocket(n)
+		if err != nil {
+			return err
+		}
+
 		if err := n.Command(
-			"kubeadm", "reset", "--force", fmt.Sprintf("--v=%d", vLevel),
+			"kubeadm", "reset", "--force", fmt.Sprintf("--cri-socket=%s", criSocket), fmt.Sprintf("--v=%d", vLevel),
 		).RunWithEcho(); err != nil {
 			return err
 		}
+		mu.Lock()
+		reset[n.Name()] = true
+		mu.Unlock()
+
+		if err := verifyResetCleanup(c, n); err != nil {
+			return err
+		}
+
+		if cleanCNI {
+			n.Infof("removing CNI configuration from %s", cniConfigDir)
+			if err := n.Command("rm", "-rf", cniConfigDir).Silent().Run(); err != nil {
+				return errors.Wrapf(err, "failed to remove CNI configuration from node %s", n.Name())
+			}
+		}
+		return nil
+	})
+	// NB. in case of parallel execution, the loadbalancer config is updated also if some nodes failed,
+	// so it does not point to control-plane nodes that were reset
+	if resetErr != nil && parallel <= 1 {
+		return resetErr
+	}
+
+	// updates the loadbalancer config removing the control-plane nodes that were reset
+	var cpX status.NodeList
+	for _, cp := range c.ControlPlanes() {
+		if !reset[cp.Name()] {
+			cpX = append(cpX, cp)
+		}
+	}
+	if len(cpX) < len(c.ControlPlanes()) {
+		if err := LoadBalancer(c, cpX...); err != nil {
+			return err
+		}
+	}
+
+	return resetErr
+}
+
+// cniConfigDir is the folder where CNI plugins write their configuration on nodes
+const cniConfigDir = "/etc/cni/net.d"
+
+// verifyResetCleanup checks that kubeadm reset actually cleaned up the node, so it is possible
+// to run kubeadm init/join on the node again
+func verifyResetCleanup(c *status.Cluster, n *status.Node) error {
+	n.Infof("verifying reset cleanup")
+
+	dirs := []string{"/etc/kubernetes/manifests", "/etc/kubernetes/pki"}
+	if n.IsControlPlane() && c.ExternalEtcd() == nil {
+		dirs = append(dirs, "/var/lib/etcd")
+	}
+
+	var residue []string
+	for _, d := range dirs {
+		lines, err := dirEntries(n, d)
+		if err != nil {
+			return err
+		}
+		residue = append(residue, lines...)
+	}
+
+	// NB. since v1.15 kubeadm reset does not clean the CNI configuration, and it instructs the user to do so;
+	// in this case the CNI configuration left in place is reported as a warning only
+	cniResidue, err := dirEntries(n, cniConfigDir)
+	if err != nil {
+		return err
+	}
+	if len(cniResidue) > 0 {
+		if n.MustKubeadmVersion().LessThan(constants.V1_15) {
+			residue = append(residue, cniResidue...)
+		} else {
+			log.Warnf("kubeadm reset does not clean the CNI configuration on node %s (use --clean-cni for removing it):\n%s", n.Name(), strings.Join(cniResidue, "\n"))
+		}
 	}
+
+	containers, err := runningContainersAfterReset(n)
+	if err != nil {
+		return err
+	}
+	for _, container := range containers {
+		residue = append(residue, fmt.Sprintf("container %s", container))
+	}
+
+	if len(residue) > 0 {
+		return errors.Errorf("kubeadm reset did not clean up node %s; residue:\n%s", n.Name(), strings.Join(residue, "\n"))
+	}
+
+	fmt.Println("node cleaned up")
 	return nil
 }
+
+// dirEntries returns the entries in a folder on the node, if any
+func dirEntries(n *status.Node, dir string) ([]string, error) {
+	lines, err := n.Command(
+		"bash", "-c", fmt.Sprintf("find %s -mindepth 1 -maxdepth 1 2>/dev/null || true", dir),
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s on node %s", dir, n.Name())
+	}
+	return lines, nil
+}
+
+// runningContainersAfterReset returns the Kubernetes containers still running on the node;
+// because containers could take some time to stop, this is retried for a while
+func runningContainersAfterReset(n *status.Node) ([]string, error) {
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		return nil, err
+	}
+
+	actionHelper, err := cri.NewActionHelper(nodeCRI)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []string
+	for i := 0; i < 10; i++ {
+		containers, err = actionHelper.GetRunningContainers(n)
+		if err != nil || len(containers) == 0 {
+			return containers, err
+		}
+		time.Sleep(3 * time.Second)
+	}
+	return containers, nil
+}
+
+// nodeCRISocket returns the CRI socket for the container runtime installed on a node
+// NB. the CRI socket is passed explicitly to kubeadm, instead of relying on kubeadm CRI autodetection,
+// because nodes with docker have also the containerd socket
+func nodeCRISocket(n *status.Node) (string, error) {
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		return "", err
+	}
+
+	criConfigHelper, err := cri.NewConfigHelper(nodeCRI)
+	if err != nil {
+		return "", err
+	}
+
+	return criConfigHelper.GetCRISocket()
+}
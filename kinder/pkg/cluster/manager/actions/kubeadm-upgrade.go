@@ -35,80 +35,105 @@ import (
 //
 // The implementation assumes that the kubeadm/kubelet/kubectl binaries and all the necessary images
 // for the new kubernetes version are available in the /kinder/upgrade/{version} folder.
-func KubeadmUpgrade(c *status.Cluster, upgradeVersion *K8sVersion.Version, kustomizeDir string, wait time.Duration, vLevel int) (err error) {
+func KubeadmUpgrade(c *status.Cluster, upgradeVersion *K8sVersion.Version, kustomizeDir string, parallel int, wait time.Duration, probe probeConfig, vLevel int) (err error) {
 	if upgradeVersion == nil {
 		return errors.New("kubeadm-upgrade actions requires the --upgrade-version parameter to be set")
 	}
 
-	preloadUpgradeImages(c, upgradeVersion)
+	preloadUpgradeImages(c, upgradeVersion, parallel)
 
+	// control-plane nodes are upgraded one by one, because kubeadm upgrade apply should be executed on the
+	// bootstrap control-plane first and control-plane nodes should not be upgraded at the same time;
+	// instead, worker nodes do not depend on each other, so they can be upgraded in parallel
+	var controlPlanes, workers status.NodeList
 	for _, n := range c.K8sNodes().EligibleForActions() {
-
-		// fail fast if required to use kustomize and kubeadm less than v1.16
-		if kustomizeDir != "" && n.MustKubeadmVersion().LessThan(constants.V1_16) {
-			return errors.New("--kustomize-dir can't be used with kubeadm older than v1.16")
-		}
-
-		// if kustomize copy patches to the node
-		if kustomizeDir != "" {
-			if err := copyPatchesToNode(n, kustomizeDir); err != nil {
-				return err
-			}
+		if n.IsControlPlane() {
+			controlPlanes = append(controlPlanes, n)
+		} else {
+			workers = append(workers, n)
 		}
+	}
 
-		if err := upgradeKubeadmBinary(n, upgradeVersion); err != nil {
+	for _, n := range controlPlanes {
+		if err := kubeadmUpgradeOnNode(c, n, upgradeVersion, kustomizeDir, wait, probe, vLevel); err != nil {
 			return err
 		}
+	}
 
-		if n.Name() == c.BootstrapControlPlane().Name() {
-			err = kubeadmUpgradeApply(c, n, upgradeVersion, kustomizeDir, wait, vLevel)
-		} else {
-			err = kubeadmUpgradeNode(c, n, upgradeVersion, kustomizeDir, wait, vLevel)
-		}
-		if err != nil {
-			return err
-		}
+	return forEachNode(workers, parallel, func(n *status.Node) error {
+		return kubeadmUpgradeOnNode(c, n, upgradeVersion, kustomizeDir, wait, probe, vLevel)
+	})
+}
 
-		if err := upgradeKubeletKubectl(c, n, upgradeVersion, wait); err != nil {
+// kubeadmUpgradeOnNode executes the kubeadm upgrade workflow on a node, including also deployment of new
+// kubeadm/kubelet/kubectl binaries
+func kubeadmUpgradeOnNode(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, kustomizeDir string, wait time.Duration, probe probeConfig, vLevel int) (err error) {
+	// fail fast if required to use kustomize and kubeadm less than v1.16
+	if kustomizeDir != "" && n.MustKubeadmVersion().LessThan(constants.V1_16) {
+		return errors.New("--kustomize-dir can't be used with kubeadm older than v1.16")
+	}
+
+	// if kustomize copy patches to the node
+	if kustomizeDir != "" {
+		if err := copyPatchesToNode(n, kustomizeDir); err != nil {
 			return err
 		}
 	}
 
+	if err := upgradeKubeadmBinary(n, upgradeVersion); err != nil {
+		return err
+	}
+
+	if n.Name() == c.BootstrapControlPlane().Name() {
+		err = kubeadmUpgradeApply(c, n, upgradeVersion, kustomizeDir, wait, probe, vLevel)
+	} else {
+		err = kubeadmUpgradeNode(c, n, upgradeVersion, kustomizeDir, wait, probe, vLevel)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := upgradeKubeletKubectl(c, n, upgradeVersion, wait, probe); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func preloadUpgradeImages(c *status.Cluster, upgradeVersion *K8sVersion.Version) {
+func preloadUpgradeImages(c *status.Cluster, upgradeVersion *K8sVersion.Version, parallel int) {
 	srcFolder := filepath.Join("/kinder", "upgrade", fmt.Sprintf("v%s", upgradeVersion))
 
 	// load images cached on the node into CRI engine
 	// this should be executed on all nodes before running kubeadm upgrade apply in order to
 	// get everything in place when kubeadm creates pre-pull daemonsets (if not, this might be blocking in case of
 	// images not available on public registry, like e.g. pre-release images)
-	for _, n := range c.K8sNodes() {
+	// NB. errors are printed but ignored, so the upgrade can continue
+	_ = forEachNode(c.K8sNodes(), parallel, func(n *status.Node) error {
 		n.Infof("pre-loading images required for the upgrade")
 		nodeCRI, err := n.CRI()
 		if err != nil {
 			fmt.Printf("error detecting CRI: %v", err)
-			continue
+			return nil
 		}
 
 		actionHelper, err := cri.NewActionHelper(nodeCRI)
 		if err != nil {
 			fmt.Printf("error creating the action helper: %v", err)
-			continue
+			return nil
 		}
 
 		if err := actionHelper.PreLoadUpgradeImages(n, srcFolder); err != nil {
 			fmt.Printf("error PreLoadUpgradeImages: %v", err)
-			continue
+			return nil
 		}
 
 		// checks pre-loaded images available on the node (this will report missing images, if any)
 		if err := checkImagesForVersion(n, upgradeVersion.String()); err != nil {
 			fmt.Printf("error ReportImages: %v", err)
-			continue
+			return nil
 		}
-	}
+		return nil
+	})
 }
 
 func upgradeKubeadmBinary(n *status.Node, upgradeVersion *K8sVersion.Version) error {
@@ -126,7 +151,7 @@ func upgradeKubeadmBinary(n *status.Node, upgradeVersion *K8sVersion.Version) er
 	return nil
 }
 
-func kubeadmUpgradeApply(c *status.Cluster, cp1 *status.Node, upgradeVersion *K8sVersion.Version, kustomizeDir string, wait time.Duration, vLevel int) error {
+func kubeadmUpgradeApply(c *status.Cluster, cp1 *status.Node, upgradeVersion *K8sVersion.Version, kustomizeDir string, wait time.Duration, probe probeConfig, vLevel int) error {
 	applyArgs := []string{
 		"upgrade", "apply", "-f", fmt.Sprintf("v%s", upgradeVersion), fmt.Sprintf("--v=%d", vLevel),
 	}
@@ -139,18 +164,18 @@ func kubeadmUpgradeApply(c *status.Cluster, cp1 *status.Node, upgradeVersion *K8
 		return err
 	}
 
-	if err := waitControlPlaneUpgraded(c, cp1, upgradeVersion, wait); err != nil {
+	if err := waitControlPlaneUpgraded(c, cp1, upgradeVersion, wait, probe); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func kubeadmUpgradeNode(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, kustomizeDir string, wait time.Duration, vLevel int) error {
+func kubeadmUpgradeNode(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, kustomizeDir string, wait time.Duration, probe probeConfig, vLevel int) error {
 	// waitKubeletHasRBAC waits for the kubelet to have access to the expected config map
 	// please note that this is a temporary workaround for a problem we are observing on upgrades while
 	// executing node upgrades immediately after control-plane upgrade.
-	if err := waitKubeletHasRBAC(c, n, upgradeVersion, wait); err != nil {
+	if err := waitKubeletHasRBAC(c, n, upgradeVersion, wait, probe); err != nil {
 		return err
 	}
 
@@ -189,7 +214,7 @@ func kubeadmUpgradeNode(c *status.Cluster, n *status.Node, upgradeVersion *K8sVe
 	}
 
 	if n.IsControlPlane() {
-		if err := waitControlPlaneUpgraded(c, n, upgradeVersion, wait); err != nil {
+		if err := waitControlPlaneUpgraded(c, n, upgradeVersion, wait, probe); err != nil {
 			return err
 		}
 	}
@@ -197,7 +222,7 @@ func kubeadmUpgradeNode(c *status.Cluster, n *status.Node, upgradeVersion *K8sVe
 	return nil
 }
 
-func upgradeKubeletKubectl(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration) error {
+func upgradeKubeletKubectl(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration, probe probeConfig) error {
 	n.Infof("upgrade kubelet and kubectl binaries")
 
 	srcFolder := filepath.Join("/kinder", "upgrade", fmt.Sprintf("v%s", upgradeVersion))
@@ -235,7 +260,7 @@ func upgradeKubeletKubectl(c *status.Cluster, n *status.Node, upgradeVersion *K8
 		return err
 	}
 
-	if err := waitKubeletUpgraded(c, n, upgradeVersion, wait); err != nil {
+	if err := waitKubeletUpgraded(c, n, upgradeVersion, wait, probe); err != nil {
 		return err
 	}
 
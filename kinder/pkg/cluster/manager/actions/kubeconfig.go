@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// matches kubeconfig server entry like:
+//
+//	server: https://172.17.0.2:6443
+//
+// which we rewrite to:
+//
+//	server: https://$ADDRESS:$PORT
+var serverAddressRE = regexp.MustCompile(`^(\s+server:) https://.*:\d+$`)
+
+// KubeConfig returns the admin kubeconfig for the cluster.
+// If internal is set, the kubeconfig uses the API server address on the container network, as
+// generated by kubeadm, and it can be used e.g. by clients running in pods or in other containers;
+// otherwise the API server address is replaced with localhost and the port exposed on the host
+func KubeConfig(c *status.Cluster, internal bool) ([]byte, error) {
+	if c.BootstrapControlPlane() == nil {
+		return nil, errors.New("the cluster does not have a bootstrap control-plane node")
+	}
+
+	if internal {
+		return kubeConfigWithServer(c, "")
+	}
+
+	hostPort, err := getAPIServerPort(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the API server port on the host")
+	}
+	return kubeConfigWithServer(c, net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort)))
+}
+
+// kubeConfigWithServer returns the admin kubeconfig for the cluster, with the server
+// entry rewritten to the given address (if any)
+func kubeConfigWithServer(c *status.Cluster, addr string) ([]byte, error) {
+	lines, err := c.BootstrapControlPlane().Command("cat", "/etc/kubernetes/admin.conf").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kubeconfig from node")
+	}
+
+	var buff bytes.Buffer
+	for _, line := range lines {
+		// fix the config file, swapping out the server for the given address
+		match := serverAddressRE.FindStringSubmatch(line)
+		if addr != "" && len(match) > 1 {
+			line = fmt.Sprintf("%s https://%s", match[1], addr)
+		}
+		buff.WriteString(line)
+		buff.WriteString("\n")
+	}
+	return buff.Bytes(), nil
+}
+
+// MergeKubeConfig merges the kubeconfig into the kubeconfig file at dest, creating the file if it does not exist.
+// In order to avoid clashes with other clusters, the cluster, user and context entries in the kubeconfig
+// are renamed after the kinder cluster name; the merged context is set as current context
+func MergeKubeConfig(c *status.Cluster, kubeconfig []byte, dest string) error {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse kubeconfig")
+	}
+
+	currentContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return errors.Errorf("invalid kubeconfig, context %q does not exist", config.CurrentContext)
+	}
+	cluster, ok := config.Clusters[currentContext.Cluster]
+	if !ok {
+		return errors.Errorf("invalid kubeconfig, cluster %q does not exist", currentContext.Cluster)
+	}
+	authInfo, ok := config.AuthInfos[currentContext.AuthInfo]
+	if !ok {
+		return errors.Errorf("invalid kubeconfig, user %q does not exist", currentContext.AuthInfo)
+	}
+
+	existing := clientcmdapi.NewConfig()
+	if _, err := os.Stat(dest); err == nil {
+		existing, err = clientcmd.LoadFromFile(dest)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read kubeconfig file %s", dest)
+		}
+	}
+
+	name := fmt.Sprintf("kinder-%s", c.Name())
+	existing.Clusters[name] = cluster
+	existing.AuthInfos[name] = authInfo
+	context := clientcmdapi.NewContext()
+	context.Cluster = name
+	context.AuthInfo = name
+	existing.Contexts[name] = context
+	existing.CurrentContext = name
+
+	// create the directory to contain the KUBECONFIG file.
+	// 0755 is taken from client-go's config handling logic: https://github.com/kubernetes/client-go/blob/5d107d4ebc00ee0ea606ad7e39fd6ce4b0d9bf9e/tools/clientcmd/loader.go#L412
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return errors.Wrap(err, "failed to create kubeconfig output directory")
+	}
+
+	if err := clientcmd.WriteToFile(*existing, dest); err != nil {
+		return errors.Wrapf(err, "failed to write kubeconfig file %s", dest)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// kubeletExtraArgsDropIn is the systemd drop-in file used by kinder for setting kubelet extra args
+const kubeletExtraArgsDropIn = "/etc/systemd/system/kubelet.service.d/20-kinder-extra-args.conf"
+
+// kubeletExtraArgsDropInTemplate overrides the kubelet ExecStart defined in the kubeadm drop-in
+// (see pkg/build/bits/initBits.go) adding the kinder extra args; a dedicated variable is used because
+// KUBELET_EXTRA_ARGS is already set in /etc/default/kubelet, that takes precedence over Environment settings
+const kubeletExtraArgsDropInTemplate = `[Service]
+Environment="KINDER_KUBELET_EXTRA_ARGS=%s"
+ExecStart=
+ExecStart=/usr/bin/kubelet $KUBELET_KUBECONFIG_ARGS $KUBELET_CONFIG_ARGS $KUBELET_KUBEADM_ARGS $KUBELET_EXTRA_ARGS $KINDER_KUBELET_EXTRA_ARGS
+`
+
+// ParseKubeletExtraArgs parses a list of kubelet flags in the key=value format, with or without
+// the leading --, and returns them as a list of --key=value flags
+func ParseKubeletExtraArgs(args []string) ([]string, error) {
+	flags := []string{}
+	for _, arg := range args {
+		parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(arg), "--"), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("invalid kubelet extra arg %q. Use the key=value format, e.g. eviction-hard=memory.available<5%%", arg)
+		}
+		if strings.ContainsAny(arg, " \t\"'\\") {
+			return nil, errors.Errorf("invalid kubelet extra arg %q. Spaces, quotes and backslashes are not supported", arg)
+		}
+		flags = append(flags, fmt.Sprintf("--%s=%s", parts[0], parts[1]))
+	}
+	return flags, nil
+}
+
+// writeKubeletExtraArgs writes the kubelet systemd drop-in with the given extra args on the node, prepended by the
+// node specific extra args set at create time, if any (for kubelet, the last value of a flag wins);
+// this should be called before kubeadm init/join, that are responsible for (re)starting the kubelet
+func writeKubeletExtraArgs(n *status.Node, kubeletExtraArgs []string) error {
+	settings, err := n.ReadNodeSettings()
+	if err != nil {
+		return err
+	}
+	kubeletExtraArgs = append(append([]string{}, settings.KubeletExtraArgs...), kubeletExtraArgs...)
+
+	if len(kubeletExtraArgs) == 0 {
+		return nil
+	}
+
+	n.Infof("Setting kubelet extra args %s", strings.Join(kubeletExtraArgs, " "))
+
+	// nb. % is used for systemd specifiers, so it should be escaped
+	args := strings.Replace(strings.Join(kubeletExtraArgs, " "), "%", "%%", -1)
+	dropIn := fmt.Sprintf(kubeletExtraArgsDropInTemplate, args)
+	if err := n.WriteFile(kubeletExtraArgsDropIn, []byte(dropIn)); err != nil {
+		return errors.Wrapf(err, "failed to write %s on node %s", kubeletExtraArgsDropIn, n.Name())
+	}
+
+	if err := n.Command("systemctl", "daemon-reload").Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to reload systemd on node %s", n.Name())
+	}
+	return nil
+}
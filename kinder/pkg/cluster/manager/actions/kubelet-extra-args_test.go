@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKubeletExtraArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputArgs     []string
+		expectedFlags []string
+		expectedError bool
+	}{
+		{
+			name:          "valid: no args",
+			expectedFlags: []string{},
+		},
+		{
+			name:          "valid: args with and without the leading --",
+			inputArgs:     []string{"v=2", "--max-pods=50"},
+			expectedFlags: []string{"--v=2", "--max-pods=50"},
+		},
+		{
+			name:          "valid: value containing =",
+			inputArgs:     []string{"node-labels=foo=bar"},
+			expectedFlags: []string{"--node-labels=foo=bar"},
+		},
+		{
+			name:          "valid: empty value",
+			inputArgs:     []string{"--node-labels="},
+			expectedFlags: []string{"--node-labels="},
+		},
+		{
+			name:          "valid: value with %",
+			inputArgs:     []string{"eviction-hard=memory.available<5%"},
+			expectedFlags: []string{"--eviction-hard=memory.available<5%"},
+		},
+		{
+			name:          "invalid: missing value",
+			inputArgs:     []string{"--v"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: missing key",
+			inputArgs:     []string{"--=2"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: value with spaces",
+			inputArgs:     []string{"node-labels=foo bar"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: value with quotes",
+			inputArgs:     []string{`node-labels="foo"`},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags, err := ParseKubeletExtraArgs(test.inputArgs)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !reflect.DeepEqual(flags, test.expectedFlags) {
+				t.Fatalf("expected flags: %v, found %v", test.expectedFlags, flags)
+			}
+		})
+	}
+}
@@ -70,7 +70,7 @@ func LoadBalancer(c *status.Cluster, nodes ...*status.Node) error {
 	}
 
 	// create loadbalancer config on the node
-	log.Debugf("Writing loadbalancer config on %s...", lb.Name())
+	log.WithField("node", lb.Name()).Debugf("Writing loadbalancer config on %s...", lb.Name())
 
 	if err := lb.WriteFile(constants.LoadBalancerConfigPath, []byte(loadbalancerConfig)); err != nil {
 		return errors.Wrap(err, "failed to copy loadbalancer config to node")
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// netemDevice is the network interface of the node containers where netem rules are applied
+const netemDevice = "eth0"
+
+// Netem applies tc netem rules adding latency and/or packet loss to the outgoing traffic of
+// the selected nodes; existing netem rules on the nodes are replaced.
+func Netem(c *status.Cluster, delay time.Duration, loss string, parallel int) error {
+	if delay < 0 || delay%time.Millisecond != 0 {
+		return errors.Errorf("invalid --delay %s, it must be a positive duration in milliseconds, e.g. 100ms", delay)
+	}
+	if delay == 0 && loss == "" {
+		return errors.New("netem requires at least one of --delay or --loss to be set")
+	}
+
+	args := []string{"qdisc", "replace", "dev", netemDevice, "root", "netem"}
+	if delay > 0 {
+		args = append(args, "delay", fmt.Sprintf("%dms", int64(delay/time.Millisecond)))
+	}
+	if loss != "" {
+		percent, err := parseLossPercent(loss)
+		if err != nil {
+			return err
+		}
+		args = append(args, "loss", fmt.Sprintf("%s%%", percent))
+	}
+
+	return forEachNode(c.K8sNodes().EligibleForActions(), parallel, func(n *status.Node) error {
+		if err := checkTC(n); err != nil {
+			return err
+		}
+
+		n.Infof("applying netem rules")
+		if err := n.Command("tc", args...).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to apply netem rules on node %s", n.Name())
+		}
+		return nil
+	})
+}
+
+// NetemClear removes the tc netem rules applied by the netem action from the selected nodes
+func NetemClear(c *status.Cluster, parallel int) error {
+	return forEachNode(c.K8sNodes().EligibleForActions(), parallel, func(n *status.Node) error {
+		if err := checkTC(n); err != nil {
+			return err
+		}
+
+		lines, err := n.Command("tc", "qdisc", "show", "dev", netemDevice).Silent().RunAndCapture()
+		if err != nil {
+			return errors.Wrapf(err, "failed to read qdisc rules on node %s", n.Name())
+		}
+		if !strings.Contains(strings.Join(lines, "\n"), "netem") {
+			n.Infof("no netem rules to remove")
+			return nil
+		}
+
+		n.Infof("removing netem rules")
+		if err := n.Command(
+			"tc", "qdisc", "del", "dev", netemDevice, "root",
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to remove netem rules on node %s", n.Name())
+		}
+		return nil
+	})
+}
+
+// parseLossPercent validates a packet loss percentage in the 5% or 5 format
+func parseLossPercent(loss string) (string, error) {
+	percent := strings.TrimSuffix(strings.TrimSpace(loss), "%")
+	value, err := strconv.ParseFloat(percent, 64)
+	// nb. the range check is written for rejecting also NaN
+	if err != nil || !(value >= 0 && value <= 100) {
+		return "", errors.Errorf("invalid --loss %q, it must be a percentage between 0%% and 100%%, e.g. 5%%", loss)
+	}
+	// nb. the value is formatted again, because tc does not support e.g. the exponent format accepted by ParseFloat
+	return strconv.FormatFloat(value, 'f', -1, 64), nil
+}
+
+// checkTC ensures the tc tooling required for netem is available in the node image
+func checkTC(n *status.Node) error {
+	if err := n.Command("which", "tc").Silent().Run(); err != nil {
+		return errors.Errorf("tc is not available on node %s; please use a node image including the iproute2 package", n.Name())
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestParseLossPercent(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expectedPercent string
+		expectedError   bool
+	}{
+		{
+			name:            "valid: percentage with %",
+			input:           "5%",
+			expectedPercent: "5",
+		},
+		{
+			name:            "valid: percentage without %",
+			input:           "5",
+			expectedPercent: "5",
+		},
+		{
+			name:            "valid: decimal percentage with spaces",
+			input:           " 0.5% ",
+			expectedPercent: "0.5",
+		},
+		{
+			name:            "valid: 0%",
+			input:           "0%",
+			expectedPercent: "0",
+		},
+		{
+			name:            "valid: 100%",
+			input:           "100%",
+			expectedPercent: "100",
+		},
+		{
+			name:            "valid: exponent format",
+			input:           "1e1",
+			expectedPercent: "10",
+		},
+		{
+			name:          "invalid: empty",
+			input:         "",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: not a number",
+			input:         "five%",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: negative",
+			input:         "-1%",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: more than 100%",
+			input:         "100.1%",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: NaN",
+			input:         "NaN",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: double %",
+			input:         "5%%",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			percent, err := parseLossPercent(test.input)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if percent != test.expectedPercent {
+				t.Fatalf("expected percent: %q, found %q", test.expectedPercent, percent)
+			}
+		})
+	}
+}
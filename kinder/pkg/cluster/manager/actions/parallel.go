@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// forEachNode executes fn on the given nodes using a pool of up to parallel workers.
+// If parallel is 1 (or less), nodes are processed one by one in order, and the first error stops the execution,
+// otherwise errors are aggregated so a failure on a node does not stop the operation on the other nodes.
+func forEachNode(nodes status.NodeList, parallel int, fn func(*status.Node) error) error {
+	if parallel <= 1 {
+		for _, n := range nodes {
+			if err := fn(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	work := make(chan *status.Node)
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < parallel && i < len(nodes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range work {
+				if err := fn(n); err != nil {
+					mu.Lock()
+					errs = append(errs, errors.Wrapf(err, "node %s", n.Name()))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, n := range nodes {
+		work <- n
+	}
+	close(work)
+	wg.Wait()
+
+	return kerrors.NewAggregate(errs)
+}
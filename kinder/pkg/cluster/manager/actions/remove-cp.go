@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// RemoveControlPlane removes a secondary control-plane node from the cluster, executing in order
+// all the steps required for a graceful HA scale-down: the etcd member is removed, kubeadm reset is executed
+// on the node, the Node object is deleted, the node container is removed and finally the load balancer
+// config is updated; after removal, the action verifies that etcd is still healthy.
+// The control-plane node to remove should be selected with the --only-node flag.
+func RemoveControlPlane(c *status.Cluster, vLevel int) error {
+	targets := c.ControlPlanes().EligibleForActions()
+	if len(targets) != 1 {
+		return errors.New("please select the control-plane node to remove with the --only-node flag")
+	}
+	n := targets[0]
+
+	// NB. the bootstrap control-plane is used by kinder for running kubectl commands, so it can't be removed
+	cp1 := c.BootstrapControlPlane()
+	if n.Name() == cp1.Name() {
+		return errors.Errorf("the bootstrap control-plane node %s can't be removed", n.Name())
+	}
+
+	// removes the etcd member, if the cluster is using stacked etcd
+	if c.ExternalEtcd() == nil {
+		if err := removeEtcdMember(cp1, n); err != nil {
+			return err
+		}
+	}
+
+	// executes kubeadm reset on the node
+	criSocket, err := nodeCRISocket(n)
+	if err != nil {
+		return err
+	}
+	if err := n.Command(
+		"kubeadm", "reset", "--force", fmt.Sprintf("--cri-socket=%s", criSocket), fmt.Sprintf("--v=%d", vLevel),
+	).RunWithEcho(); err != nil {
+		return err
+	}
+
+	// deletes the Node object
+	cp1.Infof("deleting Node %s", n.Name())
+	if err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "delete", "node", n.Name(),
+	).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to delete Node %s", n.Name())
+	}
+
+	// removes the node container
+	n.Infof("removing node container")
+	if err := exec.NewHostCmd("docker", "rm", "-f", "-v", n.Name()).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to remove node %s", n.Name())
+	}
+
+	// updates the loadbalancer config removing the control-plane node
+	var cpX status.NodeList
+	for _, cp := range c.ControlPlanes() {
+		if cp.Name() != n.Name() {
+			cpX = append(cpX, cp)
+		}
+	}
+	if err := LoadBalancer(c, cpX...); err != nil {
+		return err
+	}
+
+	// verifies etcd remains healthy after removal
+	if c.ExternalEtcd() == nil {
+		if err := checkEtcdHealth(c, len(cpX)); err != nil {
+			return errors.Wrapf(err, "etcd is not healthy after removing node %s", n.Name())
+		}
+	}
+
+	fmt.Printf("\nControl-plane node %s removed\n", n.Name())
+	return nil
+}
+
+// removeEtcdMember removes the etcd member hosted on the given node from the stacked etcd cluster
+func removeEtcdMember(cp1, n *status.Node) error {
+	etcdArgs, err := etcdctlArgs(cp1)
+	if err != nil {
+		return err
+	}
+
+	var members etcdMemberList
+	if err := etcdctlJSON(cp1, append(etcdArgs, "member", "list", "-w=json"), "{", &members); err != nil {
+		return errors.Wrap(err, "failed to list etcd members")
+	}
+
+	for _, m := range members.Members {
+		if m.Name != n.Name() {
+			continue
+		}
+
+		cp1.Infof("removing etcd member %s", n.Name())
+		if err := cp1.Command(
+			"kubectl", append(etcdArgs, "member", "remove", fmt.Sprintf("%x", m.ID))...,
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to remove etcd member %s", n.Name())
+		}
+		return nil
+	}
+
+	return errors.Errorf("etcd member for node %s not found", n.Name())
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// RotateToken action deletes the bootstrap token used by kinder for joining nodes and, unless deleteOnly is set,
+// creates it again with the given TTL; e.g. a short TTL allows to test kubeadm join with an expired token.
+// Please note that the token value does not change, because it is part of the kubeadm config generated by kinder.
+func RotateToken(c *status.Cluster, ttl time.Duration, deleteOnly, list bool) error {
+	cp1 := c.BootstrapControlPlane()
+
+	if list {
+		return cp1.Command(
+			"kubeadm", "token", "list", "--kubeconfig=/etc/kubernetes/admin.conf",
+		).RunWithEcho()
+	}
+
+	if ttl < 0 {
+		return errors.Errorf("invalid --token-ttl %s, it must be a positive duration or 0 for a token that never expires", ttl)
+	}
+
+	exists, _, err := tokenStatus(cp1)
+	if err != nil {
+		return err
+	}
+	if exists {
+		cp1.Infof("deleting bootstrap token %s", tokenID())
+		if err := cp1.Command(
+			"kubeadm", "token", "delete", tokenID(), "--kubeconfig=/etc/kubernetes/admin.conf",
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to delete the bootstrap token %s", tokenID())
+		}
+	} else {
+		cp1.Infof("bootstrap token %s does not exist, skipping delete", tokenID())
+	}
+
+	if deleteOnly {
+		return nil
+	}
+
+	cp1.Infof("creating bootstrap token %s with TTL %s", tokenID(), ttl)
+	if err := cp1.Command(
+		"kubeadm", "token", "create", constants.Token,
+		fmt.Sprintf("--ttl=%s", ttl),
+		"--kubeconfig=/etc/kubernetes/admin.conf",
+	).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to create the bootstrap token %s", tokenID())
+	}
+	return nil
+}
+
+// tokenID returns the public part of the bootstrap token used by kinder, that is how the token is identified
+// by kubeadm token delete and in kubeadm logs
+func tokenID() string {
+	return strings.SplitN(constants.Token, ".", 2)[0]
+}
+
+// tokenStatus returns if the bootstrap token used by kinder exists in the cluster, and if it is expired;
+// nb. expired tokens are reported with an <invalid> TTL until they are removed by the token cleaner
+func tokenStatus(cp1 *status.Node) (exists, expired bool, err error) {
+	lines, err := cp1.Command(
+		"kubeadm", "token", "list", "--kubeconfig=/etc/kubernetes/admin.conf",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return false, false, errors.Wrap(err, "failed to list bootstrap tokens")
+	}
+
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], tokenID()+".") {
+			continue
+		}
+		return true, fields[1] == "<invalid>", nil
+	}
+	return false, false, nil
+}
+
+// tokenJoinError returns the error of a failed kubeadm join, adding a clear message in case the
+// failure is caused by the bootstrap token being expired or deleted, e.g. after rotate-token
+func tokenJoinError(c *status.Cluster, err error) error {
+	exists, expired, tokenErr := tokenStatus(c.BootstrapControlPlane())
+	switch {
+	case tokenErr != nil:
+		return err
+	case !exists:
+		return errors.Wrapf(err, "kubeadm join failed; the bootstrap token %s does not exist, it is expired and removed or it was deleted by rotate-token", tokenID())
+	case expired:
+		return errors.Wrapf(err, "kubeadm join failed; the bootstrap token %s is expired", tokenID())
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// swapFile is the swap file created by set-swap on nodes
+// nb. the file is created in /var, that is a volume on the host, because swap files are not supported on overlay filesystems
+const swapFile = "/var/kinder-swapfile"
+
+// SetSwap action provisions a swap file of the given size in MB inside Kubernetes nodes and enables it,
+// or, if off is set, disables and removes the swap file; the resulting memory and swap state is reported
+// with free -m. This allows to test kubeadm-init and kubeadm-join on swap-enabled nodes
+// (please note that kinder already sets the kubelet --fail-swap-on=false flag and ignores the Swap preflight check).
+// Use the --only-node flag for targeting a specific node.
+// Please note that swap is a resource of the host kernel: swapon adds the swap file to the swap of the host, that is
+// visible from the host and from all the node containers, and free -m reports the host-wide memory and swap state.
+// Node containers with a memory limit can't use swap, because the memory+swap limit is set equal to the memory limit.
+func SetSwap(c *status.Cluster, size int, off bool) error {
+	if !off && size <= 0 {
+		return errors.New("set-swap requires a swap size in MB greater than 0, set with the --swap-size flag, or the --swap-off flag")
+	}
+
+	nodes := c.K8sNodes().EligibleForActions()
+	if !off {
+		log.Warnf("swap is a resource of the host kernel: the swap enabled by set-swap is added to the swap of the host and it is visible from all the nodes")
+		for _, n := range nodes {
+			_, memory, err := n.Resources()
+			if err != nil {
+				return err
+			}
+			if memory > 0 {
+				log.Warnf("node %s has a memory limit, and the memory+swap limit of node containers is set equal to the memory limit, so swap can't be used by the node", n.Name())
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		if off {
+			n.Infof("disabling swap")
+			if err := n.Command("sh", "-c", fmt.Sprintf(
+				"if [ -f %[1]s ]; then swapoff %[1]s 2>/dev/null || true; rm -f %[1]s; fi", swapFile,
+			)).RunWithEcho(); err != nil {
+				return errors.Wrapf(err, "failed to disable swap on node %s", n.Name())
+			}
+		} else {
+			n.Infof("enabling %dMB of swap", size)
+			if err := n.Command("sh", "-c", fmt.Sprintf(
+				"(swapoff %[1]s 2>/dev/null || true) && dd if=/dev/zero of=%[1]s bs=1M count=%[2]d status=none && chmod 600 %[1]s && mkswap %[1]s && swapon %[1]s",
+				swapFile, size,
+			)).RunWithEcho(); err != nil {
+				return errors.Wrapf(err, "failed to enable swap on node %s", n.Name())
+			}
+		}
+
+		// nb. free -m reports the memory and swap state of the host
+		if err := n.Command("free", "-m").RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to get the memory state of node %s", n.Name())
+		}
+	}
+	return nil
+}
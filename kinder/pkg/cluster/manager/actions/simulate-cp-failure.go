@@ -0,0 +1,164 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// apiServerCheckInterval defines how often the API server is checked during a simulated failure
+const apiServerCheckInterval = 2 * time.Second
+
+// SimulateControlPlaneFailure simulates the failure of a control-plane node by stopping the node container,
+// waiting for the given downtime and then starting the node container again; the API server
+// is checked through the control-plane endpoint during the whole sequence, and an error is returned if
+// it was not available while the node was down.
+// The control-plane node to stop should be selected with the --only-node flag.
+func SimulateControlPlaneFailure(c *status.Cluster, downtime, wait time.Duration, probe probeConfig) error {
+	// with stacked etcd, stopping a control-plane node stops also an etcd member, and etcd keeps
+	// the quorum only with at least three members
+	minControlPlanes := 3
+	if c.ExternalEtcd() != nil {
+		minControlPlanes = 2
+	}
+	if len(c.ControlPlanes()) < minControlPlanes {
+		return errors.Errorf("simulate-cp-failure requires a cluster with at least %d control-plane nodes, otherwise the cluster can't stay available", minControlPlanes)
+	}
+
+	targets := c.ControlPlanes().EligibleForActions()
+	if len(targets) != 1 {
+		return errors.New("please select the control-plane node to stop with the --only-node flag")
+	}
+	n := targets[0]
+
+	hostPort, err := getAPIServerPort(c)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the API server port")
+	}
+	url := fmt.Sprintf("https://%s/healthz", net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort)))
+
+	m := newAPIServerMonitor(url)
+	go m.run()
+
+	n.Infof("stopping node container for %s", downtime)
+	if err := exec.NewHostCmd("docker", "stop", n.Name()).RunWithEcho(); err != nil {
+		m.stop()
+		return errors.Wrapf(err, "failed to stop node %s", n.Name())
+	}
+	m.setNodeDown(true)
+
+	time.Sleep(downtime)
+
+	n.Infof("starting node container")
+	if err := exec.NewHostCmd("docker", "start", n.Name()).RunWithEcho(); err != nil {
+		m.stop()
+		return errors.Wrapf(err, "failed to start node %s", n.Name())
+	}
+	m.setNodeDown(false)
+
+	err = waitNewControlPlaneNodeReady(c, n, wait, probe)
+	m.stop()
+
+	fmt.Printf("API server %s was available in %d/%d checks while %s was down, and in %d/%d checks overall\n",
+		url, m.downChecks-m.downFailures, m.downChecks, n.Name(), m.checks-m.failures, m.checks)
+
+	if err != nil {
+		return errors.Wrapf(err, "node %s did not recover after restart", n.Name())
+	}
+	if m.downFailures > 0 {
+		return errors.Errorf("the API server was not available while node %s was down", n.Name())
+	}
+	return nil
+}
+
+// apiServerMonitor periodically checks the API server healthz endpoint, printing availability changes
+// and keeping track of failed checks, both overall and while the target node is down
+type apiServerMonitor struct {
+	url  string
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu                       sync.Mutex
+	nodeDown                 bool
+	checks, failures         int
+	downChecks, downFailures int
+}
+
+func newAPIServerMonitor(url string) *apiServerMonitor {
+	m := &apiServerMonitor{
+		url:  url,
+		done: make(chan struct{}),
+	}
+	m.wg.Add(1)
+	return m
+}
+
+// run checks the API server until the monitor is stopped
+func (m *apiServerMonitor) run() {
+	defer m.wg.Done()
+
+	last := ""
+	for {
+		healthy, health := apiServerHealth(m.url)
+
+		m.mu.Lock()
+		m.checks++
+		if m.nodeDown {
+			m.downChecks++
+		}
+		if !healthy {
+			m.failures++
+			if m.nodeDown {
+				m.downFailures++
+			}
+		}
+		m.mu.Unlock()
+
+		// only changes in the API server availability are printed
+		if health != last {
+			fmt.Printf("[%s] API server %s\n", time.Now().Format("15:04:05"), health)
+			last = health
+		}
+
+		select {
+		case <-m.done:
+			return
+		case <-time.After(apiServerCheckInterval):
+		}
+	}
+}
+
+// setNodeDown records if the target node is currently down
+func (m *apiServerMonitor) setNodeDown(down bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodeDown = down
+}
+
+// stop stops the monitor and waits for the last check to complete
+func (m *apiServerMonitor) stop() {
+	close(m.done)
+	m.wg.Wait()
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// ParseSkipPhases parses a comma-separated list of kubeadm phases to be skipped, e.g. addon/kube-proxy,mark-control-plane
+func ParseSkipPhases(skipPhases string) []string {
+	var phases []string
+	for _, p := range strings.Split(skipPhases, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			phases = append(phases, p)
+		}
+	}
+	return phases
+}
+
+// validateSkipPhases checks that the phases to be skipped are recognized by the kubeadm command, e.g. init or join,
+// for the version installed on the node
+func validateSkipPhases(n *status.Node, command string, skipPhases []string) error {
+	if len(skipPhases) == 0 {
+		return nil
+	}
+
+	// nb. kubeadm join phases are available starting from v1.14
+	if command == "join" && n.MustKubeadmVersion().LessThan(constants.V1_14) {
+		return errors.New("--skip-phases can't be used for kubeadm join with kubeadm older than v1.14")
+	}
+
+	tree, err := kubeadmPhases(n, command)
+	if err != nil {
+		return err
+	}
+	known := kubeadmPhaseNames(tree)
+
+	var unknown []string
+	for _, p := range skipPhases {
+		if !known[p] {
+			unknown = append(unknown, p)
+		}
+	}
+	if len(unknown) > 0 {
+		return errors.Errorf("unknown kubeadm %s phases %s for node %s (see kubeadm %s --help for the list of phases)", command, strings.Join(unknown, ", "), n.Name(), command)
+	}
+	return nil
+}
+
+// kubeadmPhaseNames returns the names of the phases in a kubeadm phase tree, using the notation accepted
+// by --skip-phases, e.g. certs and certs/apiserver
+func kubeadmPhaseNames(tree []string) map[string]bool {
+	names := map[string]bool{}
+	parent := ""
+	for _, l := range tree {
+		fields := strings.Fields(l)
+		if len(fields) == 0 {
+			continue
+		}
+		name := fields[0]
+		if strings.HasPrefix(name, "/") {
+			names[parent+name] = true
+			continue
+		}
+		parent = name
+		names[name] = true
+	}
+	return names
+}
+
+// skipPhasesArgs returns the flag for skipping the given kubeadm phases, if any
+func skipPhasesArgs(skipPhases []string) []string {
+	if len(skipPhases) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("--skip-phases=%s", strings.Join(skipPhases, ","))}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKubeadmPhaseNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputTree     []string
+		expectedNames map[string]bool
+	}{
+		{
+			name:          "valid: empty tree",
+			expectedNames: map[string]bool{},
+		},
+		{
+			name: "valid: phases without sub phases",
+			inputTree: []string{
+				"preflight                    Run pre-flight checks",
+				"kubelet-start                Write kubelet settings and (re)start the kubelet",
+			},
+			expectedNames: map[string]bool{
+				"preflight":     true,
+				"kubelet-start": true,
+			},
+		},
+		{
+			name: "valid: phases with sub phases",
+			inputTree: []string{
+				"preflight                    Run pre-flight checks",
+				"certs                        Certificate generation",
+				"  /ca                          Generate the self-signed Kubernetes CA",
+				"  /apiserver                   Generate the certificate for serving the Kubernetes API",
+				"upload-certs                 Upload certificates to kubeadm-certs",
+				"addon                        Install required addons for passing conformance tests",
+				"  /coredns                     Install the CoreDNS addon to a Kubernetes cluster",
+				"  /kube-proxy                  Install the kube-proxy addon to a Kubernetes cluster",
+			},
+			expectedNames: map[string]bool{
+				"preflight":        true,
+				"certs":            true,
+				"certs/ca":         true,
+				"certs/apiserver":  true,
+				"upload-certs":     true,
+				"addon":            true,
+				"addon/coredns":    true,
+				"addon/kube-proxy": true,
+			},
+		},
+		{
+			name: "valid: blank lines are ignored",
+			inputTree: []string{
+				"certs                        Certificate generation",
+				"",
+				"  /ca                          Generate the self-signed Kubernetes CA",
+			},
+			expectedNames: map[string]bool{
+				"certs":    true,
+				"certs/ca": true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := kubeadmPhaseNames(test.inputTree)
+			if !reflect.DeepEqual(names, test.expectedNames) {
+				t.Fatalf("expected phases: %v, found %v", test.expectedNames, names)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package actions
 
 import (
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -27,14 +28,37 @@ import (
 )
 
 // SmokeTest actions execute a set of simple test checking proper functioning of
-// deployments, services/type node port, kubectl logs & exec & DNS resolution
-func SmokeTest(c *status.Cluster, wait time.Duration) error {
+// deployments, pod readiness, services/type ClusterIP and NodePort, kubectl logs & exec & DNS resolution;
+// in case of failures, diagnostics about the test pods are printed. Test resources are deleted at the end
+// of the test, unless keep is set
+func SmokeTest(c *status.Cluster, keep bool, wait time.Duration, probe probeConfig) error {
 	// test are executed on the bootstrap control-plane
 	cp1 := c.BootstrapControlPlane()
 
 	// cleanups garbage from previous test
 	cleanupSmokeTest(cp1)
 
+	err := smokeTest(c, cp1, wait, probe)
+	if err != nil {
+		printSmokeTestDiagnostics(cp1)
+	}
+
+	// cleanups, if not requested otherwise, and print final message
+	if !keep {
+		cleanupSmokeTest(cp1)
+	} else {
+		fmt.Println("\nKeeping deployments/nginx and service/nginx (--keep)")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nSmoke test passed!\n")
+
+	return nil
+}
+
+// smokeTest implements the SmokeTest steps
+func smokeTest(c *status.Cluster, cp1 *status.Node, wait time.Duration, probe probeConfig) error {
 	// Test deployments
 	cp1.Infof("test deployments")
 
@@ -46,10 +70,23 @@ func SmokeTest(c *status.Cluster, wait time.Duration) error {
 		return err
 	}
 
-	if err := waitForPodsRunning(c, cp1, wait, "nginx", 1); err != nil {
+	if err := waitForPodsRunning(c, cp1, wait, probe, "nginx", 1); err != nil {
 		return err
 	}
 
+	// Test pod readiness
+	cp1.Infof("test pod readiness")
+
+	if wait > 0 {
+		if err := cp1.Command(
+			"kubectl",
+			"--kubeconfig=/etc/kubernetes/admin.conf",
+			"wait", "--for=condition=Ready", "pods", "-l", "run=nginx", fmt.Sprintf("--timeout=%s", wait),
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "nginx pod did not become Ready")
+		}
+	}
+
 	// Test service type NodePort
 	cp1.Infof("service type NodePort")
 
@@ -71,6 +108,18 @@ func SmokeTest(c *status.Cluster, wait time.Duration) error {
 		return err
 	}
 
+	// Test service ClusterIP
+	cp1.Infof("test service ClusterIP")
+
+	clusterIP, err := getClusterIP(cp1, "nginx")
+	if err != nil {
+		return err
+	}
+
+	if err := checkClusterIP(c, clusterIP); err != nil {
+		return err
+	}
+
 	podName, err := getPodName(cp1, "nginx")
 	if err != nil {
 		return err
@@ -87,32 +136,36 @@ func SmokeTest(c *status.Cluster, wait time.Duration) error {
 	}
 	fmt.Printf("%d logs lines returned\n", len(lines))
 
-	// Test kubectl exec
-	cp1.Infof("test kubectl exec")
+	// Test kubectl exec and DNS resolution
+	cp1.Infof("test kubectl exec and DNS resolution")
 
-	lines, err = cp1.Command(
-		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "exec", podName, "--", "nslookup", "kubernetes",
-	).RunAndCapture()
-	if err != nil {
-		return errors.Wrapf(err, "failed to run kubectl exec")
+	if err := checkDNS(cp1, podName, "kubernetes", "kubernetes.default.svc.cluster.local"); err != nil {
+		return err
 	}
-	fmt.Printf("%d output lines returned\n", len(lines))
-
-	// Test DNS resolution
-	cp1.Infof("test DNS resolution")
-
-	if len(lines) < 3 || !strings.Contains(lines[3], "kubernetes.default.svc.cluster.local") {
-		return errors.Wrapf(err, "dns resolution error")
+	if err := checkDNS(cp1, podName, "nginx", "nginx.default.svc.cluster.local"); err != nil {
+		return err
 	}
-	fmt.Printf("kubernetes service answers to %s\n", lines[3])
-
-	// cleanups and print final message
-	cleanupSmokeTest(cp1)
-	fmt.Printf("\nSmoke test passed!\n")
 
 	return nil
 }
 
+// printSmokeTestDiagnostics prints the status of the test pods and the recent events, for helping
+// to investigate smoke test failures
+func printSmokeTestDiagnostics(cp1 *status.Node) {
+	cp1.Infof("smoke test failed, printing diagnostics")
+
+	for _, args := range [][]string{
+		{"get", "pods", "-l", "run=nginx", "-o", "wide"},
+		{"describe", "pods", "-l", "run=nginx"},
+		{"get", "service", "nginx", "-o", "wide"},
+		{"get", "events", "--sort-by=.lastTimestamp"},
+	} {
+		cp1.Command(
+			"kubectl", append([]string{"--kubeconfig=/etc/kubernetes/admin.conf"}, args...)...,
+		).RunWithEcho()
+	}
+}
+
 func cleanupSmokeTest(cp1 *status.Node) {
 	cp1.Command(
 		"kubectl",
@@ -174,6 +227,60 @@ func checkNodePort(c *status.Cluster, port string) error {
 	return nil
 }
 
+func getClusterIP(n *status.Node, svc string) (string, error) {
+	lines, err := n.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "get", "svc", svc, "--output=jsonpath='{.spec.clusterIP}'",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get cluster IP")
+	}
+	if len(lines) != 1 {
+		return "", errors.New("failed to parse cluster IP")
+	}
+
+	return strings.Trim(lines[0], "'"), nil
+}
+
+func checkClusterIP(c *status.Cluster, ip string) error {
+	for _, n := range c.K8sNodes() {
+		fmt.Printf("checking cluster IP %s on node %s...", ip, n.Name())
+
+		lines, err := n.Command(
+			"curl", "-Is", fmt.Sprintf("http://%s", net.JoinHostPort(ip, "80")),
+		).Silent().RunAndCapture()
+		if err != nil {
+			return errors.Wrapf(err, "error checking cluster IP")
+		}
+
+		if len(lines) > 0 && strings.Trim(lines[0], "\n\r") == "HTTP/1.1 200 OK" {
+			fmt.Printf("pass!\n")
+			continue
+		}
+
+		return errors.Errorf("cluster IP %s doesn't works on node %s", ip, n.Name())
+	}
+
+	return nil
+}
+
+// checkDNS resolves a name from inside a pod, checking the answer contains the expected fully qualified name
+func checkDNS(cp1 *status.Node, podName, name, fqdn string) error {
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "exec", podName, "--", "nslookup", name,
+	).RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to run kubectl exec")
+	}
+
+	for _, l := range lines {
+		if strings.Contains(l, fqdn) {
+			fmt.Printf("%s answers to %s\n", name, strings.TrimSpace(l))
+			return nil
+		}
+	}
+	return errors.Errorf("dns resolution error: %s not found in the nslookup output for %s:\n%s", fqdn, name, strings.Join(lines, "\n"))
+}
+
 func getPodName(n *status.Node, label string) (string, error) {
 	lines, err := n.Command(
 		"kubectl",
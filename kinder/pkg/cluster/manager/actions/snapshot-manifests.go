@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// manifestsDir is the folder where kubeadm writes static pod manifests on nodes
+const manifestsDir = "/etc/kubernetes/manifests"
+
+// SnapshotManifests copies the static pod manifests from control-plane nodes into the out dir, using a
+// sub folder for each node, or, if compare is set, diffs the manifests on nodes against a snapshot previously
+// captured into the compare dir; differences are printed as unified diffs and make the action fail.
+// Sub folders are named after the node name without the cluster name prefix, e.g. control-plane, so snapshots
+// taken on different clusters can be compared.
+func SnapshotManifests(c *status.Cluster, out, compare string) error {
+	if (out == "") == (compare == "") {
+		return errors.New("snapshot-manifests requires exactly one of --out or --compare")
+	}
+
+	nodes := c.ControlPlanes().EligibleForActions()
+	if compare == "" {
+		for _, n := range nodes {
+			dir := filepath.Join(out, snapshotNodeDir(c, n))
+			if err := copyManifests(n, dir); err != nil {
+				return err
+			}
+			n.Infof("static pod manifests copied into %s", dir)
+		}
+		return nil
+	}
+
+	// copies the current manifests in a temporary folder, and then diffs them against the snapshot
+	tmpDir, err := ioutil.TempDir("", "kinder-manifests")
+	if err != nil {
+		return errors.Wrap(err, "failed to create a temporary folder")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var changed []string
+	for _, n := range nodes {
+		snapshotDir := filepath.Join(compare, snapshotNodeDir(c, n))
+		if _, err := os.Stat(snapshotDir); err != nil {
+			return errors.Wrapf(err, "failed to read the snapshot for node %s", n.Name())
+		}
+
+		currentDir := filepath.Join(tmpDir, snapshotNodeDir(c, n))
+		if err := copyManifests(n, currentDir); err != nil {
+			return err
+		}
+
+		n.Infof("comparing static pod manifests with %s", snapshotDir)
+		if err := exec.NewHostCmd("diff", "-ruN", snapshotDir, currentDir).RunWithEcho(); err != nil {
+			changed = append(changed, n.Name())
+		}
+	}
+
+	if len(changed) > 0 {
+		return errors.Errorf("static pod manifests differ from the snapshot in %s on nodes: %s", compare, strings.Join(changed, ", "))
+	}
+	fmt.Printf("Static pod manifests match the snapshot in %s\n", compare)
+	return nil
+}
+
+// snapshotNodeDir returns the name of the snapshot sub folder for a node
+func snapshotNodeDir(c *status.Cluster, n *status.Node) string {
+	return strings.TrimPrefix(n.Name(), c.Name()+"-")
+}
+
+// copyManifests copies the static pod manifests from a node into dir
+func copyManifests(n *status.Node, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s", dir)
+	}
+	if err := n.CopyFrom(manifestsDir+"/.", dir); err != nil {
+		return errors.Wrapf(err, "failed to copy static pod manifests from node %s", n.Name())
+	}
+	return nil
+}
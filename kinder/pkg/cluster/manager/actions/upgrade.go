@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// Upgrade drives the full upgrade sequence described in the kubeadm upgrade runbook:
+// the bootstrap control-plane is upgraded with kubeadm upgrade apply, then remaining control-plane
+// nodes and finally workers are upgraded with kubeadm upgrade node; on each node kubeadm, kubelet
+// and kubectl binaries are swapped with the ones in the upgrade folder, and the action waits for the node
+// to reach the target version before proceeding with the next one.
+//
+// Before starting, the action checks that upgrade binaries for the target version exist on all the nodes,
+// and that the sequence is respected when the action is executed on a subset of nodes only.
+func Upgrade(c *status.Cluster, upgradeVersion *K8sVersion.Version, kustomizeDir string, parallel int, wait time.Duration, probe probeConfig, vLevel int) error {
+	if upgradeVersion == nil {
+		return errors.New("upgrade actions requires the --upgrade-version parameter to be set")
+	}
+
+	if err := checkUpgradeSequence(c, upgradeVersion); err != nil {
+		return preflightError(err)
+	}
+
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		if err := checkUpgradeBinaries(n, upgradeVersion); err != nil {
+			return preflightError(err)
+		}
+	}
+
+	if err := KubeadmUpgrade(c, upgradeVersion, kustomizeDir, parallel, wait, probe, vLevel); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nNodes upgraded to v%s\n", upgradeVersion)
+	return nil
+}
+
+// checkUpgradeSequence ensures the bootstrap control-plane is upgraded before any other node and that
+// the target version is not older than the version currently installed
+func checkUpgradeSequence(c *status.Cluster, upgradeVersion *K8sVersion.Version) error {
+	cp1 := c.BootstrapControlPlane()
+	if cp1 == nil {
+		return errors.New("the cluster does not have a bootstrap control-plane node to upgrade")
+	}
+
+	currentVersion, err := cp1.KubeadmVersion()
+	if err != nil {
+		return err
+	}
+
+	if upgradeVersion.LessThan(currentVersion) {
+		return errors.Errorf("the upgrade version v%s is older than the version installed on node %s (v%s)", upgradeVersion, cp1.Name(), currentVersion)
+	}
+
+	// if the bootstrap control-plane is not part of this upgrade, it should be already upgraded,
+	// because kubeadm upgrade node should be executed only after kubeadm upgrade apply
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		if n.Name() == cp1.Name() {
+			return nil
+		}
+	}
+	if !currentVersion.AtLeast(upgradeVersion) {
+		return errors.Errorf("the bootstrap control-plane node %s should be upgraded to v%s before other nodes", cp1.Name(), upgradeVersion)
+	}
+	return nil
+}
+
+// checkUpgradeBinaries ensures that kubeadm, kubelet and kubectl binaries for the target version
+// are available in the upgrade folder on the node
+func checkUpgradeBinaries(n *status.Node, upgradeVersion *K8sVersion.Version) error {
+	srcFolder := filepath.Join("/kinder", "upgrade", fmt.Sprintf("v%s", upgradeVersion))
+	for _, binary := range []string{"kubeadm", "kubelet", "kubectl"} {
+		if err := n.Command(
+			"test", "-f", filepath.Join(srcFolder, binary),
+		).Silent().Run(); err != nil {
+			return errors.Errorf("%s binary for v%s is missing in %s on node %s; please create the node image with --with-upgrade-artifacts", binary, upgradeVersion, srcFolder, n.Name())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// checkJoinSkew ensures the nodes joining the cluster respect the version skew policy, that is:
+//   - kubeadm is the same version of the control-plane or one minor version newer
+//   - kubelet is not newer than the control-plane, and at most one minor version older on control-plane nodes
+//     or two minor versions older on worker nodes
+func checkJoinSkew(c *status.Cluster) error {
+	cp1 := c.BootstrapControlPlane()
+	controlPlaneVersion, err := cp1.KubeadmVersion()
+	if err != nil {
+		return err
+	}
+
+	nodes := append(c.SecondaryControlPlanes().EligibleForActions(), c.Workers().EligibleForActions()...)
+
+	errs := []error{}
+	for _, n := range nodes {
+		kubeadmVersion, err := n.KubeadmVersion()
+		if err != nil {
+			return err
+		}
+		if skew := minorSkew(kubeadmVersion, controlPlaneVersion); skew < 0 || skew > 1 {
+			errs = append(errs, errors.Errorf("node %s has kubeadm v%s, but kubeadm join requires the control-plane version v%s or one minor version newer", n.Name(), kubeadmVersion, controlPlaneVersion))
+		}
+
+		kubeletVersion, err := kubeletVersion(n)
+		if err != nil {
+			return err
+		}
+		maxOlder := 2
+		if n.IsControlPlane() {
+			maxOlder = 1
+		}
+		if skew := minorSkew(kubeletVersion, controlPlaneVersion); skew > 0 || skew < -maxOlder {
+			errs = append(errs, errors.Errorf("node %s has kubelet v%s, but kubelet should not be newer than the control-plane version v%s or more than %d minor versions older", n.Name(), kubeletVersion, controlPlaneVersion, maxOlder))
+		}
+	}
+
+	return skewError(errs)
+}
+
+// checkUpgradeSkew ensures the upgrade respects the version skew policy, that is:
+// - the upgrade version is at most one minor version newer than the control-plane, because kubeadm can't skip minor versions
+// - kubelet on nodes not part of this upgrade is at most two minor versions older than the upgrade version
+func checkUpgradeSkew(c *status.Cluster, upgradeVersion *K8sVersion.Version) error {
+	if upgradeVersion == nil {
+		// nb. the upgrade actions fail later with a proper error message
+		return nil
+	}
+
+	cp1 := c.BootstrapControlPlane()
+	controlPlaneVersion, err := cp1.KubeadmVersion()
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	if skew := minorSkew(upgradeVersion, controlPlaneVersion); skew > 1 {
+		errs = append(errs, errors.Errorf("the upgrade version v%s is more than one minor version newer than the control-plane version v%s; kubeadm does not support skipping minor versions", upgradeVersion, controlPlaneVersion))
+	}
+
+	upgrading := map[string]bool{}
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		upgrading[n.Name()] = true
+	}
+	for _, n := range c.K8sNodes() {
+		if upgrading[n.Name()] {
+			continue
+		}
+		kubeletVersion, err := kubeletVersion(n)
+		if err != nil {
+			return err
+		}
+		if skew := minorSkew(kubeletVersion, upgradeVersion); skew < -2 {
+			errs = append(errs, errors.Errorf("node %s has kubelet v%s, that is more than two minor versions older than the upgrade version v%s", n.Name(), kubeletVersion, upgradeVersion))
+		}
+	}
+
+	return skewError(errs)
+}
+
+// kubeletVersion returns the kubelet version installed on the node
+func kubeletVersion(n *status.Node) (*K8sVersion.Version, error) {
+	lines, err := n.Command("kubelet", "--version").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get kubelet version on node %s", n.Name())
+	}
+	if len(lines) != 1 {
+		return nil, errors.Errorf("kubelet version should only be one line, got %d lines", len(lines))
+	}
+
+	// nb. the output of kubelet --version is e.g. Kubernetes v1.17.0
+	v := strings.TrimSpace(strings.TrimPrefix(lines[0], "Kubernetes"))
+	version, err := K8sVersion.ParseSemantic(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%q is not a valid kubelet version", v)
+	}
+	return version, nil
+}
+
+// minorSkew returns the difference in minor versions between a and b, e.g. 1 if a is v1.17 and b is v1.16;
+// versions with different major are considered as far apart
+func minorSkew(a, b *K8sVersion.Version) int {
+	if a.Major() != b.Major() {
+		if a.Major() > b.Major() {
+			return 100
+		}
+		return -100
+	}
+	return int(a.Minor()) - int(b.Minor())
+}
+
+// skewError aggregates version skew errors, adding a hint about how to skip the check
+func skewError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ErrVersionSkew{Err: errors.Wrap(kerrors.NewAggregate(errs), "version skew check failed (use --skip-skew-check for intentionally testing version skew)")}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestMinorSkew(t *testing.T) {
+	tests := []struct {
+		name         string
+		inputA       string
+		inputB       string
+		expectedSkew int
+	}{
+		{
+			name:         "same version",
+			inputA:       "v1.17.0",
+			inputB:       "v1.17.0",
+			expectedSkew: 0,
+		},
+		{
+			name:         "same minor with different patch",
+			inputA:       "v1.17.3",
+			inputB:       "v1.17.0",
+			expectedSkew: 0,
+		},
+		{
+			name:         "a is newer",
+			inputA:       "v1.17.0",
+			inputB:       "v1.15.2",
+			expectedSkew: 2,
+		},
+		{
+			name:         "a is older",
+			inputA:       "v1.14.0",
+			inputB:       "v1.17.0-alpha.1",
+			expectedSkew: -3,
+		},
+		{
+			name:         "a has a newer major",
+			inputA:       "v2.0.0",
+			inputB:       "v1.17.0",
+			expectedSkew: 100,
+		},
+		{
+			name:         "a has an older major",
+			inputA:       "v1.17.0",
+			inputB:       "v2.0.0",
+			expectedSkew: -100,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := K8sVersion.MustParseSemantic(test.inputA)
+			b := K8sVersion.MustParseSemantic(test.inputB)
+			if skew := minorSkew(a, b); skew != test.expectedSkew {
+				t.Fatalf("expected skew: %d, found %d", test.expectedSkew, skew)
+			}
+		})
+	}
+}
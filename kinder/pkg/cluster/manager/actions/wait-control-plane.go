@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// defaultControlPlanePollInterval is the interval between readiness probes executed by wait-control-plane
+const defaultControlPlanePollInterval = 2 * time.Second
+
+// controlPlaneCheck defines a named readiness probe executed by wait-control-plane on a control-plane node
+type controlPlaneCheck struct {
+	name string
+	node *status.Node
+	fn   func() bool
+}
+
+// WaitControlPlane action waits for control-plane nodes to become healthy, polling the API server /healthz
+// and /readyz endpoints and checking that control-plane static pods, including etcd when stacked, are Ready.
+// On timeout or after maxRetries probes, if greater than 0, the action fails reporting the checks still failing.
+func WaitControlPlane(c *status.Cluster, wait, pollInterval time.Duration, maxRetries int) error {
+	// if timeout is 0 (e.g. with --dry-run), exit fast
+	if wait == time.Duration(0) {
+		fmt.Println("Timeout set 0, skipping wait")
+		return nil
+	}
+	if pollInterval == 0 {
+		pollInterval = defaultControlPlanePollInterval
+	}
+
+	var checks []controlPlaneCheck
+	for _, n := range c.ControlPlanes().EligibleForActions() {
+		checks = append(checks, controlPlaneChecks(c, n)...)
+	}
+
+	fmt.Printf("Waiting for control-plane to become healthy (timeout %s, poll interval %s)\n", wait, pollInterval)
+	deadline := time.Now().Add(wait)
+	for retry := 1; ; retry++ {
+		var failing []controlPlaneCheck
+		for _, ch := range checks {
+			if !ch.fn() {
+				failing = append(failing, ch)
+			}
+		}
+		checks = failing
+
+		if len(checks) == 0 {
+			fmt.Println("Control-plane is healthy")
+			return nil
+		}
+
+		if time.Now().After(deadline) || (maxRetries > 0 && retry >= maxRetries) {
+			names := []string{}
+			for _, ch := range checks {
+				names = append(names, fmt.Sprintf("%s on node %s", ch.name, ch.node.Name()))
+			}
+			return timeoutError("control-plane did not become healthy, failing checks: %s", strings.Join(names, ", "))
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// controlPlaneChecks returns the readiness probes for a control-plane node
+func controlPlaneChecks(c *status.Cluster, n *status.Node) []controlPlaneCheck {
+	endpoints := []string{"healthz"}
+	// nb. /readyz is supported in kube-apiserver v1.16 or greater
+	if v, err := n.KubeadmVersion(); err == nil && !v.LessThan(constants.V1_16) {
+		endpoints = append(endpoints, "readyz")
+	}
+
+	pods := []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler"}
+	if c.ExternalEtcd() == nil {
+		pods = append(pods, "etcd")
+	}
+
+	var checks []controlPlaneCheck
+	for _, e := range endpoints {
+		e := e
+		checks = append(checks, controlPlaneCheck{
+			name: fmt.Sprintf("API server /%s", e),
+			node: n,
+			fn:   func() bool { return apiServerEndpointIsOk(n, e) },
+		})
+	}
+	for _, p := range pods {
+		isReady := staticPodIsReady(p)
+		checks = append(checks, controlPlaneCheck{
+			name: fmt.Sprintf("static Pod %s", p),
+			node: n,
+			fn:   func() bool { return isReady(c, n) },
+		})
+	}
+	return checks
+}
+
+// apiServerEndpointIsOk implement a function that test when an API server health endpoint on the node returns 200
+func apiServerEndpointIsOk(n *status.Node, endpoint string) bool {
+	lines, err := n.Command(
+		"curl", "-k", "-s", "-o", "/dev/null", "-w", "%{http_code}",
+		fmt.Sprintf("https://localhost:%d/%s", constants.APIServerPort, endpoint),
+	).Silent().RunAndCapture()
+	if err != nil || len(lines) == 0 || strings.TrimSpace(lines[0]) != "200" {
+		return false
+	}
+	fmt.Printf("API server /%s on node %s is ok\n", endpoint, n.Name())
+	return true
+}
@@ -23,74 +23,72 @@ import (
 	"strings"
 	"time"
 
-	"github.com/pkg/errors"
-
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 )
 
 // waitNewControlPlaneNodeReady waits for a new control plane node reaching the target state after init/join
-func waitNewControlPlaneNodeReady(c *status.Cluster, n *status.Node, wait time.Duration) error {
+func waitNewControlPlaneNodeReady(c *status.Cluster, n *status.Node, wait time.Duration, probe probeConfig) error {
 	n.Infof("waiting for Node and control-plane Pods to become Ready (timeout %s)", wait)
-	if pass := waitFor(c, n, wait,
+	if pass := waitFor(c, n, wait, probe,
 		nodeIsReady,
 		staticPodIsReady("kube-apiserver"),
 		staticPodIsReady("kube-controller-manager"),
 		staticPodIsReady("kube-scheduler"),
 	); !pass {
-		return errors.New("timeout: Node and control-plane did not reach target state")
+		return timeoutError("Node and control-plane did not reach target state")
 	}
 	fmt.Println()
 	return nil
 }
 
-func waitForPodsRunning(c *status.Cluster, n *status.Node, wait time.Duration, label string, replicas int) error {
-	if pass := waitFor(c, n, wait,
+func waitForPodsRunning(c *status.Cluster, n *status.Node, wait time.Duration, probe probeConfig, label string, replicas int) error {
+	if pass := waitFor(c, n, wait, probe,
 		podsAreRunning(n, label, replicas),
 	); !pass {
-		return errors.New("timeout: Node and control-plane did not reach target state")
+		return timeoutError("Node and control-plane did not reach target state")
 	}
 	fmt.Println()
 	return nil
 }
 
 // waitNewWorkerNodeReady waits for a new control plane node reaching the target state after join
-func waitNewWorkerNodeReady(c *status.Cluster, n *status.Node, wait time.Duration) error {
+func waitNewWorkerNodeReady(c *status.Cluster, n *status.Node, wait time.Duration, probe probeConfig) error {
 	n.Infof("waiting for Node to become Ready (timeout %s)", wait)
-	if pass := waitFor(c, n, wait,
+	if pass := waitFor(c, n, wait, probe,
 		nodeIsReady,
 	); !pass {
-		return errors.New("timeout: Node did not reach target state")
+		return timeoutError("Node did not reach target state")
 	}
 	fmt.Println()
 	return nil
 }
 
 // waitControlPlaneUpgraded waits for a control plane node reaching the target state after upgrade
-func waitControlPlaneUpgraded(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration) error {
+func waitControlPlaneUpgraded(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration, probe probeConfig) error {
 	version := kubernetesVersionToImageTag(upgradeVersion.String())
 
 	n.Infof("waiting for control-plane Pods to restart with the new version (timeout %s)", wait)
-	if pass := waitFor(c, n, wait,
+	if pass := waitFor(c, n, wait, probe,
 		staticPodHasVersion("kube-apiserver", version),
 		staticPodHasVersion("kube-controller-manager", version),
 		staticPodHasVersion("kube-scheduler", version),
 	); !pass {
-		return errors.New("timeout: control-plane did not reach target state")
+		return timeoutError("control-plane did not reach target state")
 	}
 	fmt.Println()
 	return nil
 }
 
 // waitKubeletUpgraded waits for a node reaching the target state after upgrade
-func waitKubeletUpgraded(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration) error {
+func waitKubeletUpgraded(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration, probe probeConfig) error {
 	version := upgradeVersion.String()
 
 	n.Infof("waiting for node to restart with the new version (timeout %s)", wait)
-	if pass := waitFor(c, n, wait,
+	if pass := waitFor(c, n, wait, probe,
 		nodeHasKubernetesVersion(version),
 	); !pass {
-		return errors.New("timeout: node did not reach target state")
+		return timeoutError("node did not reach target state")
 	}
 	fmt.Println()
 	return nil
@@ -99,34 +97,51 @@ func waitKubeletUpgraded(c *status.Cluster, n *status.Node, upgradeVersion *K8sV
 // waitKubeletHasRBAC waits for the kubelet to have access to the expected config map
 // please note that this is a temporary workaround for a problem we are observing on upgrades while
 // executing node upgrades immediately after control-plane upgrade.
-func waitKubeletHasRBAC(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration) error {
+func waitKubeletHasRBAC(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration, probe probeConfig) error {
 	n.Infof("waiting for kubelet RBAC validation - workaround (timeout %s)", wait)
-	if pass := waitFor(c, n, wait,
+	if pass := waitFor(c, n, wait, probe,
 		kubeletHasRBAC(upgradeVersion.Major(), upgradeVersion.Minor()),
 	); !pass {
-		return errors.New("timeout: Node did not reach target state")
+		return timeoutError("Node did not reach target state")
 	}
 	fmt.Println()
 	return nil
 }
 
+// defaultProbeInterval is the interval between readiness probes executed while waiting for conditions
+const defaultProbeInterval = 1 * time.Second
+
+// probeConfig tunes the readiness probes executed by waitFor
+type probeConfig struct {
+	// interval between probes; 0 means defaultProbeInterval
+	interval time.Duration
+	// maxRetries is the maximum number of probes before failing; 0 means probes are retried until the timeout
+	maxRetries int
+}
+
 // try defines a function that test a condition to be waited for
 type try func(*status.Cluster, *status.Node) bool
 
 // waitFor implements the waiter core logic that is responsible for testing all the given contitions
-// until are satisfied or a timeout are reached
-func waitFor(c *status.Cluster, n *status.Node, timeout time.Duration, conditions ...try) bool {
+// until are satisfied or a timeout are reached; if probe.maxRetries is greater than 0, waitFor fails
+// also when a condition is not satisfied after probe.maxRetries probes
+func waitFor(c *status.Cluster, n *status.Node, timeout time.Duration, probe probeConfig, conditions ...try) bool {
 	// if timeout is 0 or no conditions are defined, exit fast
 	if timeout == time.Duration(0) {
 		fmt.Println("Timeout set 0, skipping wait")
 		return true
 	}
 
+	interval := probe.interval
+	if interval == 0 {
+		interval = defaultProbeInterval
+	}
+
 	// sets the timeout timer
 	timer := time.NewTimer(timeout)
 
 	// runs all the conditions in parallel
-	pass := make(chan bool)
+	pass := make(chan bool, len(conditions))
 	for _, wc := range conditions {
 		// clone the condition func to make the closure point to right value
 		// even after the for loop moves to the next condition
@@ -137,13 +152,17 @@ func waitFor(c *status.Cluster, n *status.Node, timeout time.Duration, condition
 			// creates an arbitrary skew before starting a wait loop
 			time.Sleep(time.Duration(rand.Intn(500)) * time.Millisecond)
 
-			for {
+			for retry := 1; ; retry++ {
 				if x(c, n) {
 					pass <- true
-					break
+					return
+				}
+				if probe.maxRetries > 0 && retry >= probe.maxRetries {
+					pass <- false
+					return
 				}
 				// add a little delay + jitter before retry
-				time.Sleep(1*time.Second + time.Duration(rand.Intn(500))*time.Millisecond)
+				time.Sleep(interval + time.Duration(rand.Intn(500))*time.Millisecond)
 			}
 		}()
 	}
@@ -152,7 +171,11 @@ func waitFor(c *status.Cluster, n *status.Node, timeout time.Duration, condition
 	passed := 0
 	for {
 		select {
-		case <-pass:
+		case ok := <-pass:
+			if !ok {
+				fmt.Printf("Conditions not satisfied after %d retries\n", probe.maxRetries)
+				return false
+			}
 			passed++
 			if passed == len(conditions) {
 				return true
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+func TestWaitForProbeConfig(t *testing.T) {
+	tests := []struct {
+		name             string
+		probe            probeConfig
+		passAfter        int
+		expectedPass     bool
+		expectedAttempts int
+	}{
+		{
+			name:             "condition satisfied before max retries",
+			probe:            probeConfig{interval: time.Millisecond, maxRetries: 3},
+			passAfter:        2,
+			expectedPass:     true,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "condition not satisfied after max retries",
+			probe:            probeConfig{interval: time.Millisecond, maxRetries: 2},
+			passAfter:        3,
+			expectedPass:     false,
+			expectedAttempts: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			condition := func(*status.Cluster, *status.Node) bool {
+				attempts++
+				return attempts >= test.passAfter
+			}
+
+			pass := waitFor(nil, nil, time.Minute, test.probe, condition)
+			if pass != test.expectedPass || attempts != test.expectedAttempts {
+				t.Fatalf("expected pass %v after %d attempts, found pass %v after %d attempts",
+					test.expectedPass, test.expectedAttempts, pass, attempts)
+			}
+		})
+	}
+}
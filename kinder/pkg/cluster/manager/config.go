@@ -0,0 +1,286 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	ksigsyaml "sigs.k8s.io/yaml"
+)
+
+// ClusterConfig describes the topology of a kinder cluster; it is read from the file passed to
+// kinder create cluster --config, and each field matches the corresponding create cluster flag
+type ClusterConfig struct {
+	// Image is the node image used for nodes not defining a specific image or version
+	Image                string       `json:"image,omitempty"`
+	ExternalEtcd         bool         `json:"externalEtcd,omitempty"`
+	ExternalEtcdMembers  int          `json:"externalEtcdMembers,omitempty"`
+	ExternalLoadBalancer bool         `json:"externalLoadBalancer,omitempty"`
+	APIServerPort        int32        `json:"apiServerPort,omitempty"`
+	Volumes              []string     `json:"volumes,omitempty"`
+	PodSubnet            string       `json:"podSubnet,omitempty"`
+	ServiceSubnet        string       `json:"serviceSubnet,omitempty"`
+	IPFamily             string       `json:"ipFamily,omitempty"`
+	RegistryMirrors      []string     `json:"registryMirrors,omitempty"`
+	NodeCPU              string       `json:"nodeCPU,omitempty"`
+	NodeMemory           string       `json:"nodeMemory,omitempty"`
+	CgroupDriver         string       `json:"cgroupDriver,omitempty"`
+	Nodes                []NodeConfig `json:"nodes,omitempty"`
+	// APIServerExtraArgs, ControllerManagerExtraArgs and SchedulerExtraArgs are control-plane components
+	// flags in the key=value format to be set in the ClusterConfiguration used by kubeadm init
+	APIServerExtraArgs         []string `json:"apiServerExtraArgs,omitempty"`
+	ControllerManagerExtraArgs []string `json:"controllerManagerExtraArgs,omitempty"`
+	SchedulerExtraArgs         []string `json:"schedulerExtraArgs,omitempty"`
+	// PostCreateHook is a script on the host to be executed inside each Kubernetes node after creation
+	PostCreateHook string `json:"postCreateHook,omitempty"`
+}
+
+// NodeConfig describes a control-plane or worker node in the ClusterConfig
+type NodeConfig struct {
+	// Role of the node, control-plane or worker
+	Role string `json:"role"`
+	// Image is the node image to be used for this node
+	Image string `json:"image,omitempty"`
+	// Version selects the node image with the given Kubernetes version, e.g. v1.17.0;
+	// the image repository is the same of the cluster image; this is ignored if the image is set
+	Version string `json:"version,omitempty"`
+	// CRI is the container runtime expected in the node image; if set, create fails if the image
+	// contains a different container runtime
+	CRI string `json:"cri,omitempty"`
+	// KubeletExtraArgs are kubelet flags in the key=value format to be set on the node by kubeadm init/join
+	KubeletExtraArgs []string `json:"kubeletExtraArgs,omitempty"`
+	// Volumes are additional volumes to be mounted on this node container
+	Volumes []string `json:"volumes,omitempty"`
+	// CPU and Memory are the resource limits of this node container, overriding nodeCPU and nodeMemory
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// LoadClusterConfig reads and validates a ClusterConfig file
+func LoadClusterConfig(path string) (*ClusterConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read config file %s", path)
+	}
+
+	cfg := &ClusterConfig{}
+	if err := ksigsyaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode config file %s", path)
+	}
+
+	if _, err := actions.ParseAllControlPlaneExtraArgs(cfg.APIServerExtraArgs, cfg.ControllerManagerExtraArgs, cfg.SchedulerExtraArgs); err != nil {
+		return nil, errors.Wrapf(err, "invalid control-plane extra args in config file %s", path)
+	}
+
+	for i := range cfg.Nodes {
+		n := &cfg.Nodes[i]
+		switch n.Role {
+		case constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue:
+		default:
+			return nil, errors.Errorf("invalid role %q for node %d in config file %s. Use one of %s or %s", n.Role, i+1, path, constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue)
+		}
+		if n.Version != "" {
+			if _, err := K8sVersion.ParseSemantic(n.Version); err != nil {
+				return nil, errors.Wrapf(err, "invalid version %q for node %d in config file %s", n.Version, i+1, path)
+			}
+		}
+		switch status.ContainerRuntime(n.CRI) {
+		case "", status.ContainerdRuntime, status.CRIORuntime, status.DockerRuntime:
+		default:
+			return nil, errors.Errorf("invalid cri %q for node %d in config file %s. Use one of %s, %s or %s", n.CRI, i+1, path, status.ContainerdRuntime, status.CRIORuntime, status.DockerRuntime)
+		}
+		args, err := actions.ParseKubeletExtraArgs(n.KubeletExtraArgs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid kubeletExtraArgs for node %d in config file %s", i+1, path)
+		}
+		n.KubeletExtraArgs = args
+	}
+
+	return cfg, nil
+}
+
+// Options returns the CreateOptions corresponding to the fields set in the ClusterConfig
+func (cfg *ClusterConfig) Options() []CreateOption {
+	var options []CreateOption
+	if cfg.Image != "" {
+		options = append(options, Image(cfg.Image))
+	}
+	if cfg.ExternalEtcd {
+		options = append(options, ExternalEtcd(cfg.ExternalEtcd))
+	}
+	if cfg.ExternalEtcdMembers > 0 {
+		options = append(options, ExternalEtcdMembers(cfg.ExternalEtcdMembers))
+	}
+	if cfg.ExternalLoadBalancer {
+		options = append(options, ExternalLoadBalancer(cfg.ExternalLoadBalancer))
+	}
+	if cfg.APIServerPort != 0 {
+		options = append(options, APIServerPort(cfg.APIServerPort))
+	}
+	if len(cfg.Volumes) > 0 {
+		options = append(options, Volumes(cfg.Volumes))
+	}
+	if cfg.PodSubnet != "" {
+		options = append(options, PodSubnet(cfg.PodSubnet))
+	}
+	if cfg.ServiceSubnet != "" {
+		options = append(options, ServiceSubnet(cfg.ServiceSubnet))
+	}
+	if cfg.IPFamily != "" {
+		options = append(options, IPFamily(cfg.IPFamily))
+	}
+	if len(cfg.RegistryMirrors) > 0 {
+		options = append(options, RegistryMirrors(cfg.RegistryMirrors))
+	}
+	if cfg.NodeCPU != "" {
+		options = append(options, NodeCPU(cfg.NodeCPU))
+	}
+	if cfg.NodeMemory != "" {
+		options = append(options, NodeMemory(cfg.NodeMemory))
+	}
+	if cfg.CgroupDriver != "" {
+		options = append(options, CgroupDriver(cfg.CgroupDriver))
+	}
+	if len(cfg.APIServerExtraArgs) > 0 {
+		options = append(options, APIServerExtraArgs(cfg.APIServerExtraArgs))
+	}
+	if len(cfg.ControllerManagerExtraArgs) > 0 {
+		options = append(options, ControllerManagerExtraArgs(cfg.ControllerManagerExtraArgs))
+	}
+	if len(cfg.SchedulerExtraArgs) > 0 {
+		options = append(options, SchedulerExtraArgs(cfg.SchedulerExtraArgs))
+	}
+	if cfg.PostCreateHook != "" {
+		options = append(options, PostCreateHook(cfg.PostCreateHook))
+	}
+	if len(cfg.Nodes) > 0 {
+		controlPlanes, workers := 0, 0
+		for _, n := range cfg.Nodes {
+			if n.Role == constants.ControlPlaneNodeRoleValue {
+				controlPlanes++
+			} else {
+				workers++
+			}
+		}
+		options = append(options, ControlPlanes(controlPlanes), Workers(workers), Nodes(cfg.Nodes))
+	}
+	return options
+}
+
+// nodeImage returns the image for a node, that is the image set in the node config, or the image with the
+// version set in the node config, or the image for the cluster
+func nodeImage(image string, n NodeConfig) string {
+	if n.Image != "" {
+		return n.Image
+	}
+	if n.Version == "" {
+		return image
+	}
+
+	// replaces the tag of the cluster image, if any, with the requested version
+	repository := image
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repository = image[:i]
+	}
+	return repository + ":" + n.Version
+}
+
+// applyNodeOverrides parses node overrides in the name=worker-2,image=kindest/node:v1.17.0 or
+// name=worker-2,version=v1.17.0 format, and applies them to the config of the corresponding nodes; node names
+// can be set with or without the cluster name prefix and the dash before the index, e.g. kind-worker-2 or worker2
+func applyNodeOverrides(clusterName string, flags *CreateOptions) error {
+	nodeName := regexp.MustCompile(fmt.Sprintf(`^(?:%s-)?(%s|%s)-?(\d+)$`, regexp.QuoteMeta(clusterName), constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue))
+
+	overridden := map[string]bool{}
+	for _, o := range flags.nodeOverrides {
+		fields := map[string]string{}
+		for _, kv := range strings.Split(o, ",") {
+			parts := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+			if len(parts) != 2 || parts[1] == "" {
+				return errors.Errorf("invalid --node %q. Use the name=worker-2,image=kindest/node:v1.17.0 or name=worker-2,version=v1.17.0 format", o)
+			}
+			switch parts[0] {
+			case "name", "image", "version":
+			default:
+				return errors.Errorf("invalid --node %q. Unknown field %q, use one of name, image or version", o, parts[0])
+			}
+			fields[parts[0]] = parts[1]
+		}
+		if fields["name"] == "" || (fields["image"] == "" && fields["version"] == "") {
+			return errors.Errorf("invalid --node %q. The name and one of image or version are required", o)
+		}
+		if fields["version"] != "" {
+			if _, err := K8sVersion.ParseSemantic(fields["version"]); err != nil {
+				return errors.Wrapf(err, "invalid --node %q. Invalid version %q", o, fields["version"])
+			}
+		}
+
+		m := nodeName.FindStringSubmatch(fields["name"])
+		if m == nil {
+			return errors.Errorf("invalid --node %q. Invalid node name %q, use e.g. control-plane-1 or worker-2", o, fields["name"])
+		}
+		role := m[1]
+		index, _ := strconv.Atoi(m[2])
+		count := flags.workers
+		if role == constants.ControlPlaneNodeRoleValue {
+			count = flags.controlPlanes
+		}
+		if index < 1 || index > count {
+			return errors.Errorf("invalid --node %q. The cluster has %d %s nodes", o, count, role)
+		}
+		key := fmt.Sprintf("%s-%d", role, index)
+		if overridden[key] {
+			return errors.Errorf("invalid --node %q. Node %s is set more than once", o, key)
+		}
+		overridden[key] = true
+
+		// gets the config for the n-th node with the given role, adding node configs if required
+		var cfg *NodeConfig
+		n := 0
+		for i := range flags.nodes {
+			if flags.nodes[i].Role == role {
+				n++
+				if n == index {
+					cfg = &flags.nodes[i]
+					break
+				}
+			}
+		}
+		for cfg == nil {
+			flags.nodes = append(flags.nodes, NodeConfig{Role: role})
+			n++
+			if n == index {
+				cfg = &flags.nodes[len(flags.nodes)-1]
+			}
+		}
+
+		// nb. the image takes precedence on the version, so an override with only the version clears the image
+		cfg.Image = fields["image"]
+		cfg.Version = fields["version"]
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyNodeOverrides(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputNodes    []NodeConfig
+		inputOverride []string
+		expectedNodes []NodeConfig
+		expectedError bool
+	}{
+		{
+			name:          "valid: no overrides",
+			inputNodes:    []NodeConfig{{Role: "worker", Image: "foo"}},
+			expectedNodes: []NodeConfig{{Role: "worker", Image: "foo"}},
+		},
+		{
+			name:          "valid: image override adds the node config",
+			inputOverride: []string{"name=worker-2,image=kindest/node:v1.17.0"},
+			expectedNodes: []NodeConfig{
+				{Role: "worker"},
+				{Role: "worker", Image: "kindest/node:v1.17.0"},
+			},
+		},
+		{
+			name:          "valid: version override with the cluster name prefix and without dash",
+			inputOverride: []string{"name=kind-control-plane1,version=v1.17.0"},
+			expectedNodes: []NodeConfig{
+				{Role: "control-plane", Version: "v1.17.0"},
+			},
+		},
+		{
+			name: "valid: override of an existing node config clears the image",
+			inputNodes: []NodeConfig{
+				{Role: "control-plane"},
+				{Role: "worker", Image: "foo", CPU: "1"},
+			},
+			inputOverride: []string{"name=worker-1, version=v1.17.0"},
+			expectedNodes: []NodeConfig{
+				{Role: "control-plane"},
+				{Role: "worker", Version: "v1.17.0", CPU: "1"},
+			},
+		},
+		{
+			name:          "valid: multiple overrides",
+			inputOverride: []string{"name=worker-1,image=foo", "name=control-plane-1,image=bar"},
+			expectedNodes: []NodeConfig{
+				{Role: "worker", Image: "foo"},
+				{Role: "control-plane", Image: "bar"},
+			},
+		},
+		{
+			name:          "invalid: missing name",
+			inputOverride: []string{"image=foo"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: missing image and version",
+			inputOverride: []string{"name=worker-1"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: unknown field",
+			inputOverride: []string{"name=worker-1,cri=containerd"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: empty value",
+			inputOverride: []string{"name=worker-1,image="},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: version is not semver",
+			inputOverride: []string{"name=worker-1,version=latest"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: unknown role",
+			inputOverride: []string{"name=etcd-1,image=foo"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: other cluster name prefix",
+			inputOverride: []string{"name=other-worker-1,image=foo"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: index out of range",
+			inputOverride: []string{"name=worker-3,image=foo"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: index 0",
+			inputOverride: []string{"name=worker-0,image=foo"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid: node overridden more than once",
+			inputOverride: []string{"name=worker-1,image=foo", "name=kind-worker1,image=bar"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &CreateOptions{
+				controlPlanes: 1,
+				workers:       2,
+				nodes:         test.inputNodes,
+				nodeOverrides: test.inputOverride,
+			}
+			err := applyNodeOverrides("kind", flags)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !reflect.DeepEqual(flags.nodes, test.expectedNodes) {
+				t.Fatalf("expected nodes: %+v, found %+v", test.expectedNodes, flags.nodes)
+			}
+		})
+	}
+}
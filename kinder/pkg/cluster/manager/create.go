@@ -18,14 +18,28 @@ package manager
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/cri"
+	"k8s.io/kubeadm/kinder/pkg/cri/util"
 	kindconcurrent "sigs.k8s.io/kind/pkg/concurrent"
 	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
 	kindexec "sigs.k8s.io/kind/pkg/exec"
@@ -38,8 +52,27 @@ type CreateOptions struct {
 	image                string
 	externalLoadBalancer bool
 	externalEtcd         bool
+	externalEtcdMembers  int
 	retain               bool
 	volumes              []string
+	wait                 time.Duration
+	pollInterval         time.Duration
+	maxRetries           int
+	idempotent           bool
+	podSubnet            string
+	serviceSubnet        string
+	ipFamily             status.ClusterIPFamily
+	nodes                []NodeConfig
+	registryMirrors      []string
+	apiServerArgs        []string
+	controllerArgs       []string
+	schedulerArgs        []string
+	postCreateHook       string
+	nodeOverrides        []string
+	apiServerPort        int32
+	nodeCPU              string
+	nodeMemory           string
+	cgroupDriver         status.CgroupDriver
 }
 
 // CreateOption is a configuration option supplied to Create
@@ -73,6 +106,14 @@ func ExternalEtcd(externalEtcd bool) CreateOption {
 	}
 }
 
+// ExternalEtcdMembers sets the number of members of the external etcd cluster.
+// NB. setting a number of members greater than one implies that an external etcd is added to the cluster
+func ExternalEtcdMembers(members int) CreateOption {
+	return func(c *CreateOptions) {
+		c.externalEtcdMembers = members
+	}
+}
+
 // ExternalLoadBalancer instruct create to add an external loadbalancer to the cluster.
 // NB. this happens automatically when there are more than two control plane instances, but with this flag
 // it is possible to override the default behaviour
@@ -96,6 +137,145 @@ func Volumes(volumes []string) CreateOption {
 	}
 }
 
+// Wait option instructs create cluster to wait for Kubernetes nodes to be Ready, reading the node status
+// through the cluster kubeconfig; 0 means don't wait
+func Wait(wait time.Duration) CreateOption {
+	return func(c *CreateOptions) {
+		c.wait = wait
+	}
+}
+
+// PollInterval option sets the interval between readiness probes executed while waiting for nodes to be Ready
+func PollInterval(pollInterval time.Duration) CreateOption {
+	return func(c *CreateOptions) {
+		c.pollInterval = pollInterval
+	}
+}
+
+// MaxRetries option sets the maximum number of readiness probes executed while waiting for nodes to be Ready;
+// 0 means probes are retried until the wait timeout
+func MaxRetries(maxRetries int) CreateOption {
+	return func(c *CreateOptions) {
+		c.maxRetries = maxRetries
+	}
+}
+
+// Idempotent option instructs create cluster to leave in place node containers already existing,
+// and to create only the missing ones
+func Idempotent(idempotent bool) CreateOption {
+	return func(c *CreateOptions) {
+		c.idempotent = idempotent
+	}
+}
+
+// PodSubnet option sets the pod subnet to be used by kubeadm; use comma-separated values for dual-stack
+func PodSubnet(podSubnet string) CreateOption {
+	return func(c *CreateOptions) {
+		c.podSubnet = podSubnet
+	}
+}
+
+// ServiceSubnet option sets the service subnet to be used by kubeadm; use comma-separated values for dual-stack
+func ServiceSubnet(serviceSubnet string) CreateOption {
+	return func(c *CreateOptions) {
+		c.serviceSubnet = serviceSubnet
+	}
+}
+
+// IPFamily option sets the IP family of the cluster, ipv4, ipv6 or dual
+func IPFamily(ipFamily string) CreateOption {
+	return func(c *CreateOptions) {
+		c.ipFamily = status.ClusterIPFamily(ipFamily)
+	}
+}
+
+// Nodes option sets the config of control-plane and worker nodes, e.g. for using a different image on each node;
+// the n-th control-plane (worker) node created uses the config of the n-th control-plane (worker) node in the list, if any
+func Nodes(nodes []NodeConfig) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodes = nodes
+	}
+}
+
+// RegistryMirrors sets the registry mirrors to be configured in the container runtime of nodes,
+// in the registry=mirror-url[,mirror-url] format, e.g. docker.io=http://mirror.local:5000
+func RegistryMirrors(registryMirrors []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.registryMirrors = registryMirrors
+	}
+}
+
+// APIServerExtraArgs sets the kube-apiserver extra args in the key=value format, e.g. audit-log-maxage=2,
+// that will be set in the ClusterConfiguration used by kubeadm init
+func APIServerExtraArgs(apiServerArgs []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.apiServerArgs = apiServerArgs
+	}
+}
+
+// ControllerManagerExtraArgs sets the kube-controller-manager extra args in the key=value format,
+// that will be set in the ClusterConfiguration used by kubeadm init
+func ControllerManagerExtraArgs(controllerManagerArgs []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.controllerArgs = controllerManagerArgs
+	}
+}
+
+// SchedulerExtraArgs sets the kube-scheduler extra args in the key=value format,
+// that will be set in the ClusterConfiguration used by kubeadm init
+func SchedulerExtraArgs(schedulerArgs []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.schedulerArgs = schedulerArgs
+	}
+}
+
+// PostCreateHook sets a script on the host to be copied and executed inside each Kubernetes node created,
+// with the node name and role as arguments
+func PostCreateHook(postCreateHook string) CreateOption {
+	return func(c *CreateOptions) {
+		c.postCreateHook = postCreateHook
+	}
+}
+
+// NodeOverrides sets the image or the version of specific nodes, in the name=worker-2,image=kindest/node:v1.17.0
+// or name=worker-2,version=v1.17.0 format, e.g. for creating clusters with nodes at different versions;
+// overrides take precedence over the node config set with Nodes
+func NodeOverrides(nodeOverrides []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodeOverrides = nodeOverrides
+	}
+}
+
+// APIServerPort sets the port on the host where the API server is exposed, that is the port of the external
+// load balancer, if any, or the port of the bootstrap control-plane node; if not set, a free port is used
+func APIServerPort(apiServerPort int32) CreateOption {
+	return func(c *CreateOptions) {
+		c.apiServerPort = apiServerPort
+	}
+}
+
+// NodeCPU sets the CPU limit of Kubernetes node containers, in the docker run --cpus format, e.g. 1.5
+func NodeCPU(nodeCPU string) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodeCPU = nodeCPU
+	}
+}
+
+// NodeMemory sets the memory limit of Kubernetes node containers, in the docker run --memory format, e.g. 2g
+func NodeMemory(nodeMemory string) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodeMemory = nodeMemory
+	}
+}
+
+// CgroupDriver sets the cgroup driver to be configured for both the kubelet and the container runtime
+// of Kubernetes nodes, systemd or cgroupfs
+func CgroupDriver(cgroupDriver string) CreateOption {
+	return func(c *CreateOptions) {
+		c.cgroupDriver = status.CgroupDriver(cgroupDriver)
+	}
+}
+
 // CreateCluster creates a new kinder cluster
 func CreateCluster(clusterName string, options ...CreateOption) error {
 	flags := &CreateOptions{}
@@ -103,20 +283,122 @@ func CreateCluster(clusterName string, options ...CreateOption) error {
 		o(flags)
 	}
 
+	// validate the API server port
+	if flags.apiServerPort < 0 || flags.apiServerPort > 65535 {
+		return errors.Errorf("invalid --api-server-port %d, it must be between 1 and 65535", flags.apiServerPort)
+	}
+
+	// validate the resource limits of node containers, both for all the nodes and for specific nodes
+	if err := validateNodeResources("--node-cpu", flags.nodeCPU, "--node-memory", flags.nodeMemory); err != nil {
+		return err
+	}
+	for i, n := range flags.nodes {
+		if err := validateNodeResources(fmt.Sprintf("cpu for node %d", i+1), n.CPU, fmt.Sprintf("memory for node %d", i+1), n.Memory); err != nil {
+			return err
+		}
+	}
+
+	// more than one external etcd member implies an external etcd
+	if flags.externalEtcdMembers > 1 {
+		flags.externalEtcd = true
+	}
+
+	// validate the IP family, and checks the docker network supports IPv6 if required
+	switch flags.ipFamily {
+	case "":
+		flags.ipFamily = status.IPv4Family
+	case status.IPv4Family:
+	case status.IPv6Family, status.DualStackFamily:
+		enabled, err := util.IPv6Enabled()
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return errors.Errorf("--ip-family=%s requires IPv6 to be enabled on the docker bridge network; please set ipv6 and fixed-cidr-v6 in the docker daemon configuration", flags.ipFamily)
+		}
+	default:
+		return errors.Errorf("invalid --ip-family %q, supported values are %s, %s or %s", flags.ipFamily, status.IPv4Family, status.IPv6Family, status.DualStackFamily)
+	}
+
+	// validate the cgroup driver
+	switch flags.cgroupDriver {
+	case "":
+		flags.cgroupDriver = status.SystemdCgroupDriver
+	case status.SystemdCgroupDriver, status.CgroupfsCgroupDriver:
+	default:
+		return errors.Errorf("invalid --cgroup-driver %q, supported values are %s or %s", flags.cgroupDriver, status.SystemdCgroupDriver, status.CgroupfsCgroupDriver)
+	}
+
+	// validate subnets before creating any node
+	if err := validateSubnets("pod-subnet", flags.podSubnet); err != nil {
+		return err
+	}
+	if err := validateSubnets("service-subnet", flags.serviceSubnet); err != nil {
+		return err
+	}
+
+	// validate registry mirrors before creating any node
+	mirrors, err := parseRegistryMirrors(flags.registryMirrors)
+	if err != nil {
+		return err
+	}
+
+	// validate control-plane extra args before creating any node
+	extraArgs, err := actions.ParseAllControlPlaneExtraArgs(flags.apiServerArgs, flags.controllerArgs, flags.schedulerArgs)
+	if err != nil {
+		return err
+	}
+
+	// validate node overrides before creating any node, and apply them to the node config
+	if err := applyNodeOverrides(clusterName, flags); err != nil {
+		return err
+	}
+
+	// validate the post create hook before creating any node
+	if flags.postCreateHook != "" {
+		info, err := os.Stat(flags.postCreateHook)
+		if err != nil {
+			return errors.Wrapf(err, "invalid --post-create-hook")
+		}
+		if info.IsDir() {
+			return errors.Errorf("invalid --post-create-hook %s, it must be a file", flags.postCreateHook)
+		}
+	}
+
 	// Check if the cluster name already exists
 	known, err := status.IsKnown(clusterName)
 	if err != nil {
 		return err
 	}
+	if known && !flags.idempotent {
+		return errors.Errorf("a cluster with the name %q already exists; use --idempotent to create only the missing nodes", clusterName)
+	}
+
+	// if idempotent, gets the list of node containers already existing, that should be left in place
+	existing := map[string]bool{}
 	if known {
-		return errors.Errorf("a cluster with the name %q already exists", clusterName)
+		c, err := status.FromDocker(clusterName)
+		if err != nil {
+			return err
+		}
+		for _, n := range c.AllNodes() {
+			existing[n.Name()] = true
+		}
 	}
 
-	fmt.Printf("Creating cluster %q ...\n", clusterName)
+	// checks the API server port is available before creating any node
+	// nb. the check is skipped if the node exposing the API server already exists
+	if flags.apiServerPort != 0 {
+		for _, n := range nodesToCreate(clusterName, flags) {
+			if n.APIServerPort != 0 && !existing[n.Name] {
+				if err := util.CheckHostPort(n.APIServerPort); err != nil {
+					return errors.Wrapf(err, "invalid --api-server-port")
+				}
+			}
+		}
+	}
 
-	// attempt to explicitly pull the required node image if it doesn't exist locally
-	// we don't care if this errors, we'll still try to run which also pulls
-	ensureNodeImage(flags.image)
+	fmt.Printf("Creating cluster %q ...\n", clusterName)
 
 	handleErr := func(err error) error {
 		// In case of errors nodes are deleted (except if retain is explicitly set)
@@ -125,6 +407,10 @@ func CreateCluster(clusterName string, options ...CreateOption) error {
 				log.Error(err)
 			} else {
 				for _, n := range c.AllNodes() {
+					// nb. node containers existing before create are not deleted
+					if existing[n.Name()] {
+						continue
+					}
 					if err := kindexec.Command(
 						"docker",
 						"rm",
@@ -145,10 +431,26 @@ func CreateCluster(clusterName string, options ...CreateOption) error {
 	if err := createNodes(
 		clusterName,
 		flags,
+		existing,
+		mirrors,
+		extraArgs,
 	); err != nil {
 		return handleErr(err)
 	}
 
+	// run the post create hook on the nodes, if requested
+	if flags.postCreateHook != "" {
+		if err := runPostCreateHook(clusterName, flags.postCreateHook, existing); err != nil {
+			return handleErr(err)
+		}
+	}
+
+	// wait for Kubernetes nodes to be Ready, if requested
+	if err := waitNodesReady(clusterName, flags.wait, flags.pollInterval, flags.maxRetries); err != nil {
+		log.Error(err)
+		return err
+	}
+
 	fmt.Println()
 	fmt.Printf("Nodes creation complete. You can now continue creating a Kubernetes cluster using\n")
 	fmt.Printf("kinder do, the kinder swiss knife 🚀!\n")
@@ -156,26 +458,78 @@ func CreateCluster(clusterName string, options ...CreateOption) error {
 	return nil
 }
 
-func createNodes(clusterName string, flags *CreateOptions) error {
+func createNodes(clusterName string, flags *CreateOptions, existing map[string]bool, mirrors map[string][]string, extraArgs actions.ControlPlaneExtraArgs) error {
 	// compute the desired nodes, and inform the user that we are setting them up
 	desiredNodes := nodesToCreate(clusterName, flags)
-	numberOfNodes := len(desiredNodes)
+
+	// compute the external etcd members, if any
+	var etcdNames []string
 	if flags.externalEtcd {
-		numberOfNodes++
+		if flags.externalEtcdMembers <= 1 {
+			etcdNames = []string{fmt.Sprintf("%s-etcd", clusterName)}
+		} else {
+			for n := 0; n < flags.externalEtcdMembers; n++ {
+				etcdNames = append(etcdNames, fmt.Sprintf("%s-etcd-%d", clusterName, n+1))
+			}
+		}
+	}
+
+	// if there are existing node containers, check them against the desired nodes and
+	// skip nodes already created
+	if len(existing) > 0 {
+		var err error
+		desiredNodes, etcdNames, err = reconcileNodes(existing, desiredNodes, etcdNames)
+		if err != nil {
+			return err
+		}
 	}
-	fmt.Printf("Preparing nodes %s\n", strings.Repeat("📦", numberOfNodes))
 
+	// attempt to explicitly pull the required node images if they don't exist locally, and
 	// detect CRI runtime installed into images before actually creating nodes
-	runtime, err := status.InspectCRIinImage(flags.image)
-	if err != nil {
-		log.Errorf("Error detecting CRI for images %s! %v", flags.image, err)
-		return err
+	createHelpers := map[string]*cri.CreateHelper{}
+	for _, desiredNode := range desiredNodes {
+		if desiredNode.Image == "" || createHelpers[desiredNode.Image] != nil {
+			continue
+		}
+
+		// we don't care if this errors, we'll still try to run which also pulls
+		ensureNodeImage(desiredNode.Image)
+
+		runtime, err := status.InspectCRIinImage(desiredNode.Image)
+		if err != nil {
+			log.Errorf("Error detecting CRI for images %s! %v", desiredNode.Image, err)
+			return err
+		}
+		log.Infof("Detected %s container runtime for image %s", runtime, desiredNode.Image)
+
+		createHelper, err := cri.NewCreateHelper(runtime)
+		if err != nil {
+			log.Errorf("Error creating NewCreateHelper for CRI %s! %v", desiredNode.Image, err)
+			return err
+		}
+		createHelpers[desiredNode.Image] = createHelper
 	}
-	log.Infof("Detected %s container runtime for image %s", runtime, flags.image)
 
-	createHelper, err := cri.NewCreateHelper(runtime)
+	// checks the container runtime requested for nodes matches the one installed into images
+	for _, desiredNode := range desiredNodes {
+		if desiredNode.CRI == "" {
+			continue
+		}
+		runtime, err := status.InspectCRIinImage(desiredNode.Image)
+		if err != nil {
+			return err
+		}
+		if runtime != desiredNode.CRI {
+			return errors.Errorf("node %s requires %s container runtime, but image %s contains %s", desiredNode.Name, desiredNode.CRI, desiredNode.Image, runtime)
+		}
+	}
+
+	numberOfNodes := len(desiredNodes) + len(etcdNames)
+	fmt.Printf("Preparing nodes %s\n", strings.Repeat("📦", numberOfNodes))
+
+	// nb. CRI specific methods are not used for creating the external load balancer and the external etcd
+	defaultHelper, err := cri.NewCreateHelper(status.ContainerdRuntime)
 	if err != nil {
-		log.Errorf("Error creating NewCreateHelper for CRI %s! %v", flags.image, err)
 		return err
 	}
 
@@ -186,9 +540,9 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 		fns = append(fns, func() error {
 			switch desiredNode.Role {
 			case constants.ExternalLoadBalancerNodeRoleValue:
-				return createHelper.CreateExternalLoadBalancer(clusterName, desiredNode.Name)
+				return defaultHelper.CreateExternalLoadBalancer(clusterName, desiredNode.Name, desiredNode.APIServerPort)
 			case constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue:
-				return createHelper.CreateNode(clusterName, desiredNode.Name, flags.image, desiredNode.Role, flags.volumes)
+				return createHelpers[desiredNode.Image].CreateNode(clusterName, desiredNode.Name, desiredNode.Image, desiredNode.Role, desiredNode.Volumes, flags.ipFamily != status.IPv4Family, desiredNode.APIServerPort, desiredNode.Resources)
 			default:
 				return nil
 			}
@@ -201,7 +555,7 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 	}
 
 	// add an external etcd if explicitly requested
-	if flags.externalEtcd {
+	if len(etcdNames) > 0 {
 		log.Info("Getting required etcd image...")
 		c, err := status.FromDocker(clusterName)
 		if err != nil {
@@ -217,9 +571,16 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 		// we don't care if this errors, we'll still try to run which also pulls
 		_, _ = kinddocker.PullIfNotPresent(etcdImage, 4)
 
-		log.Info("Creating external etcd...")
-		if err := createHelper.CreateExternalEtcd(clusterName, fmt.Sprintf("%s-etcd", clusterName), etcdImage); err != nil {
-			return err
+		if flags.externalEtcdMembers <= 1 {
+			log.Info("Creating external etcd...")
+			if err := defaultHelper.CreateExternalEtcd(clusterName, etcdNames[0], etcdImage); err != nil {
+				return err
+			}
+		} else {
+			log.Infof("Creating external etcd cluster with %d members...", flags.externalEtcdMembers)
+			if err := defaultHelper.CreateExternalEtcdCluster(clusterName, etcdNames, etcdImage); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -231,58 +592,320 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 
 	// writes to the nodes the cluster settings that will be re-used by kinder during the cluster lifecycle.
 	c.Settings = &status.ClusterSettings{
-		IPFamily: status.IPv4Family, // support for ipv6 is still WIP
+		IPFamily:                   flags.ipFamily,
+		PodSubnet:                  flags.podSubnet,
+		ServiceSubnet:              flags.serviceSubnet,
+		APIServerExtraArgs:         extraArgs.APIServer,
+		ControllerManagerExtraArgs: extraArgs.ControllerManager,
+		SchedulerExtraArgs:         extraArgs.Scheduler,
+		CgroupDriver:               flags.cgroupDriver,
 	}
 	if err := c.WriteSettings(); err != nil {
 		return err
 	}
 
 	// writes to the nodes the node settings
+	// nb. node containers existing before create keep their settings
+	specs := map[string]nodeSpec{}
+	for _, desiredNode := range desiredNodes {
+		specs[desiredNode.Name] = desiredNode
+	}
 	for _, n := range c.K8sNodes() {
-		if err := n.WriteNodeSettings(&status.NodeSettings{}); err != nil {
+		if existing[n.Name()] {
+			continue
+		}
+		kubeVersion, err := n.KubeVersion()
+		if err != nil {
+			return err
+		}
+		// nb. the kubelet cgroup driver is set before node specific extra args, so it can be overridden on single nodes
+		kubeletExtraArgs := append([]string{fmt.Sprintf("--cgroup-driver=%s", flags.cgroupDriver)}, specs[n.Name()].KubeletExtraArgs...)
+		if err := n.WriteNodeSettings(&status.NodeSettings{
+			KubeletExtraArgs:  kubeletExtraArgs,
+			Image:             specs[n.Name()].Image,
+			KubernetesVersion: kubeVersion,
+		}); err != nil {
 			return err
 		}
 	}
 
+	// loads the images bundled in the node image into the container runtime of each node, concurrently
+	// NB. this is executed at create time, because only the selected container runtime can make
+	// images visible to the kubelet
+	log.Info("Loading images into nodes...")
+	fns = []func() error{}
+	for _, n := range c.K8sNodes() {
+		// nb. images are already loaded into node containers existing before create
+		if existing[n.Name()] {
+			continue
+		}
+		runtime, err := n.CRI()
+		if err != nil {
+			return err
+		}
+		actionHelper, err := cri.NewActionHelper(runtime)
+		if err != nil {
+			return err
+		}
+		n := n // capture loop variable
+		fns = append(fns, func() error {
+			// configures the cgroup driver and registry mirrors first, so the container runtime is restarted before loading images
+			if err := actionHelper.ConfigureCgroupDriver(n, flags.cgroupDriver); err != nil {
+				return err
+			}
+			if len(mirrors) > 0 {
+				if err := actionHelper.ConfigureRegistryMirrors(n, mirrors); err != nil {
+					return err
+				}
+			}
+			return actionHelper.LoadImages(n, "/kind/images")
+		})
+	}
+	if err := kindconcurrent.UntilError(fns); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// runPostCreateHook copies the hook script into the Kubernetes nodes created and executes it,
+// passing the node name and role as arguments; node containers existing before create are skipped
+func runPostCreateHook(clusterName, hook string, existing map[string]bool) error {
+	c, err := status.FromDocker(clusterName)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range c.K8sNodes() {
+		if existing[n.Name()] {
+			continue
+		}
+
+		n.Infof("Running post create hook %s", hook)
+		if err := n.Command("mkdir", "-p", filepath.Dir(constants.PostCreateHookPath)).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to create the post create hook folder on node %s", n.Name())
+		}
+		if err := n.CopyTo(hook, constants.PostCreateHookPath); err != nil {
+			return errors.Wrapf(err, "failed to copy the post create hook to node %s", n.Name())
+		}
+		if err := n.Command("chmod", "+x", constants.PostCreateHookPath).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to make the post create hook executable on node %s", n.Name())
+		}
+		if err := n.Command(constants.PostCreateHookPath, n.Name(), n.Role()).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "post create hook failed on node %s", n.Name())
+		}
+	}
+	return nil
+}
+
+// reconcileNodes checks existing node containers against the desired nodes, warning about containers that exist
+// but don't match the requested spec, and returns the desired nodes and the external etcd members still to be created
+func reconcileNodes(existing map[string]bool, desiredNodes []nodeSpec, etcdNames []string) ([]nodeSpec, []string, error) {
+	desired := map[string]bool{}
+
+	var missingNodes []nodeSpec
+	for _, n := range desiredNodes {
+		desired[n.Name] = true
+		if !existing[n.Name] {
+			missingNodes = append(missingNodes, n)
+			continue
+		}
+
+		// nb. the image is not set for the external load balancer, that uses a different image
+		fmt.Printf("Node %s already exists, skipping\n", n.Name)
+		warnIfNotMatching(n.Name, n.Role, n.Image)
+	}
+
+	// external etcd members are created all together, because each member should know all the others
+	var missingEtcd []string
+	for _, name := range etcdNames {
+		desired[name] = true
+		if !existing[name] {
+			missingEtcd = append(missingEtcd, name)
+			continue
+		}
+		fmt.Printf("Node %s already exists, skipping\n", name)
+		warnIfNotMatching(name, constants.ExternalEtcdNodeRoleValue, "")
+	}
+	if len(missingEtcd) > 0 && len(missingEtcd) < len(etcdNames) {
+		return nil, nil, errors.Errorf("the external etcd cluster is partially created, external etcd members %s are missing; please delete the cluster and create it again", strings.Join(missingEtcd, ", "))
+	}
+
+	var extra []string
+	for name := range existing {
+		if !desired[name] {
+			extra = append(extra, name)
+		}
+	}
+	if len(extra) > 0 {
+		sort.Strings(extra)
+		log.Warnf("Node containers %s exist but they are not part of the requested cluster; they are left in place", strings.Join(extra, ", "))
+	}
+
+	return missingNodes, missingEtcd, nil
+}
+
+// warnIfNotMatching logs a warning if the existing node container does not match the
+// requested role and image (if not empty), or if it is not running
+func warnIfNotMatching(name, role, image string) {
+	lines, err := kinddocker.Inspect(name, fmt.Sprintf("{{index .Config.Labels %q}} {{.Config.Image}} {{.State.Running}}", constants.NodeRoleKey))
+	if err != nil || len(lines) != 1 {
+		log.Warnf("Failed to inspect node container %s: %v", name, err)
+		return
+	}
+
+	fields := strings.Fields(strings.Trim(lines[0], "'"))
+	if len(fields) != 3 {
+		log.Warnf("Unexpected docker inspect output for node container %s: %s", name, lines[0])
+		return
+	}
+	if fields[0] != role {
+		log.Warnf("Node container %s exists with role %s, but role %s is requested", name, fields[0], role)
+	}
+	if image != "" && fields[1] != image {
+		log.Warnf("Node container %s exists with image %s, but image %s is requested", name, fields[1], image)
+	}
+	if fields[2] != "true" {
+		log.Warnf("Node container %s exists but it is not running", name)
+	}
+}
+
+// parseRegistryMirrors parses registry mirrors in the registry=mirror-url[,mirror-url] format into a map
+// of mirror URLs by registry; the same registry can be listed many times, and mirrors are appended
+func parseRegistryMirrors(registryMirrors []string) (map[string][]string, error) {
+	mirrors := map[string][]string{}
+	for _, rm := range registryMirrors {
+		parts := strings.SplitN(rm, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid --registry-mirror %q, use the registry=mirror-url format, e.g. docker.io=http://mirror.local:5000", rm)
+		}
+		for _, m := range strings.Split(parts[1], ",") {
+			u, err := url.Parse(m)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, errors.Errorf("invalid --registry-mirror %q, mirror %q must be an http or https URL", rm, m)
+			}
+			mirrors[parts[0]] = append(mirrors[parts[0]], m)
+		}
+	}
+	return mirrors, nil
+}
+
+// nodeMemoryFormat matches the docker run --memory format, that is a number with an optional b, k, m or g unit, e.g. 2g
+var nodeMemoryFormat = regexp.MustCompile(`^([0-9]+)[bkmgBKMG]?$`)
+
+// validateNodeResources checks that the CPU and memory limits of node containers, if set, are positive values
+// in the docker run --cpus and --memory format; field names are used in error messages
+func validateNodeResources(cpuField, cpu, memoryField, memory string) error {
+	if cpu != "" {
+		if v, err := strconv.ParseFloat(cpu, 64); err != nil || v <= 0 {
+			return errors.Errorf("invalid %s %q, it must be a positive number of CPUs, e.g. 1.5", cpuField, cpu)
+		}
+	}
+	if memory != "" {
+		m := nodeMemoryFormat.FindStringSubmatch(memory)
+		if m == nil || strings.Trim(m[1], "0") == "" {
+			return errors.Errorf("invalid %s %q, it must be a positive amount of memory with an optional b, k, m or g unit, e.g. 2g", memoryField, memory)
+		}
+	}
+	return nil
+}
+
+// validateSubnets checks that subnets is empty, a CIDR or a comma-separated pair of IPv4 and IPv6 CIDRs (dual-stack)
+func validateSubnets(flagName, subnets string) error {
+	if subnets == "" {
+		return nil
+	}
+
+	cidrs := strings.Split(subnets, ",")
+	if len(cidrs) > 2 {
+		return errors.Errorf("invalid --%s %q, at most two comma-separated CIDRs are supported (dual-stack)", flagName, subnets)
+	}
+
+	ipv6 := 0
+	for _, cidr := range cidrs {
+		ip, _, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return errors.Errorf("invalid --%s %q, %q is not a valid CIDR", flagName, subnets, cidr)
+		}
+		if ip.To4() == nil {
+			ipv6++
+		}
+	}
+	if len(cidrs) == 2 && ipv6 != 1 {
+		return errors.Errorf("invalid --%s %q, dual-stack requires one IPv4 and one IPv6 CIDR", flagName, subnets)
+	}
 	return nil
 }
 
 // nodeSpec describes a node to create purely from the container aspect
 // this does not include eg starting kubernetes (see actions for that)
 type nodeSpec struct {
-	Name string
-	Role string
+	Name             string
+	Role             string
+	Image            string
+	CRI              status.ContainerRuntime
+	Volumes          []string
+	KubeletExtraArgs []string
+	// APIServerPort is the port on the host where the API server is exposed by this node, if 0, a free port is used
+	APIServerPort int32
+	// Resources are the CPU and memory limits of the node container
+	Resources util.NodeResources
 }
 
 // nodesToCreate return the list of nodes to create for the cluster
 func nodesToCreate(clusterName string, flags *CreateOptions) []nodeSpec {
 	var desiredNodes []nodeSpec
 
-	// prepare nodes explicitly
-	for n := 0; n < flags.controlPlanes; n++ {
-		role := constants.ControlPlaneNodeRoleValue
-		desiredNode := nodeSpec{
-			Name: fmt.Sprintf("%s-%s-%d", clusterName, role, n+1),
-			Role: role,
+	// prepare nodes explicitly, using the node config with the same role and index, if any
+	for _, x := range []struct {
+		role  string
+		count int
+	}{
+		{constants.ControlPlaneNodeRoleValue, flags.controlPlanes},
+		{constants.WorkerNodeRoleValue, flags.workers},
+	} {
+		var configs []NodeConfig
+		for _, cfg := range flags.nodes {
+			if cfg.Role == x.role {
+				configs = append(configs, cfg)
+			}
 		}
-		desiredNodes = append(desiredNodes, desiredNode)
-	}
-	for n := 0; n < flags.workers; n++ {
-		role := constants.WorkerNodeRoleValue
-		desiredNode := nodeSpec{
-			Name: fmt.Sprintf("%s-%s-%d", clusterName, role, n+1),
-			Role: role,
+
+		for n := 0; n < x.count; n++ {
+			cfg := NodeConfig{}
+			if n < len(configs) {
+				cfg = configs[n]
+			}
+			desiredNode := nodeSpec{
+				Name:             fmt.Sprintf("%s-%s-%d", clusterName, x.role, n+1),
+				Role:             x.role,
+				Image:            nodeImage(flags.image, cfg),
+				CRI:              status.ContainerRuntime(cfg.CRI),
+				Volumes:          append(append([]string{}, flags.volumes...), cfg.Volumes...),
+				KubeletExtraArgs: cfg.KubeletExtraArgs,
+				Resources:        util.NodeResources{CPU: flags.nodeCPU, Memory: flags.nodeMemory},
+			}
+			if cfg.CPU != "" {
+				desiredNode.Resources.CPU = cfg.CPU
+			}
+			if cfg.Memory != "" {
+				desiredNode.Resources.Memory = cfg.Memory
+			}
+			desiredNodes = append(desiredNodes, desiredNode)
 		}
-		desiredNodes = append(desiredNodes, desiredNode)
 	}
 
 	// add an external load balancer if explicitly requested or if there are multiple control planes
+	// nb. the API server port is assigned to the external load balancer, if any, or to the first control-plane node
 	if flags.externalLoadBalancer || flags.controlPlanes > 1 {
 		role := constants.ExternalLoadBalancerNodeRoleValue
 		desiredNodes = append(desiredNodes, nodeSpec{
-			Name: fmt.Sprintf("%s-lb", clusterName),
-			Role: role,
+			Name:          fmt.Sprintf("%s-lb", clusterName),
+			Role:          role,
+			APIServerPort: flags.apiServerPort,
 		})
+	} else if flags.controlPlanes > 0 {
+		desiredNodes[0].APIServerPort = flags.apiServerPort
 	}
 
 	return desiredNodes
@@ -296,3 +919,76 @@ func ensureNodeImage(image string) {
 	// we don't care if this errors, we'll still try to run which also pulls
 	_, _ = kinddocker.PullIfNotPresent(image, 4)
 }
+
+// waitNodesReady waits for all the Kubernetes nodes in the cluster to be Ready, polling the node status
+// through the cluster kubeconfig every pollInterval; in case of timeout or after maxRetries probes, if greater than 0,
+// the error lists the nodes that are not Ready.
+// Please note that nodes become Ready only after Kubernetes is initialized on them, e.g. by the post create hook
+// or when re-running create on an existing cluster with the idempotent option
+func waitNodesReady(clusterName string, wait, pollInterval time.Duration, maxRetries int) error {
+	if wait == 0 {
+		return nil
+	}
+	if pollInterval <= 0 {
+		return errors.Errorf("invalid poll interval %s, it must be greater than 0", pollInterval)
+	}
+	if maxRetries < 0 {
+		return errors.Errorf("invalid max retries %d, it must be 0 or greater", maxRetries)
+	}
+
+	c, err := status.FromDocker(clusterName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read cluster %s status", clusterName)
+	}
+
+	fmt.Printf("Waiting for nodes to be Ready (timeout %s, poll interval %s)...\n", wait, pollInterval)
+	start := time.Now()
+	for retry := 1; ; retry++ {
+		notReady, err := nodesNotReady(c)
+		if err == nil && len(notReady) == 0 {
+			return nil
+		}
+		if time.Since(start) > wait || (maxRetries > 0 && retry >= maxRetries) {
+			if err != nil {
+				return errors.Wrapf(err, "timeout: nodes %s are not Ready after %s", strings.Join(notReady, ", "), time.Since(start).Round(time.Second))
+			}
+			return errors.Errorf("timeout: nodes %s are not Ready after %s", strings.Join(notReady, ", "), time.Since(start).Round(time.Second))
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// nodesNotReady returns the sorted names of the Kubernetes nodes in the cluster that are not Ready,
+// reading the node status through the cluster kubeconfig; in case of error, all the nodes are returned
+func nodesNotReady(c *status.Cluster) ([]string, error) {
+	notReady := map[string]bool{}
+	for _, n := range c.K8sNodes() {
+		notReady[n.Name()] = true
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", c.KubeConfigPath())
+	if err != nil {
+		return sortedKeys(notReady), errors.Wrapf(err, "failed to load kubeconfig %s", c.KubeConfigPath())
+	}
+	config.Timeout = 5 * time.Second
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return sortedKeys(notReady), errors.Wrap(err, "failed to create the Kubernetes client")
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return sortedKeys(notReady), errors.Wrap(err, "failed to list nodes")
+	}
+
+	for _, node := range nodes.Items {
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == corev1.NodeReady && cond.Status == corev1.ConditionTrue {
+				delete(notReady, node.Name)
+			}
+		}
+	}
+
+	return sortedKeys(notReady), nil
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/kubeadm/kinder/pkg/audit"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// DeleteOptions holds all the options used at delete time
+type DeleteOptions struct {
+	force bool
+}
+
+// DeleteOption is a configuration option supplied to Delete
+type DeleteOption func(*DeleteOptions)
+
+// Force option instructs delete to remove also partially initialized resources, that is node containers
+// without the cluster label but named after the cluster, and to not fail if the cluster is unknown
+func Force(force bool) DeleteOption {
+	return func(c *DeleteOptions) {
+		c.force = force
+	}
+}
+
+// DeleteCluster deletes all the containers of a kinder cluster, including orphaned containers
+// left in place by an interrupted create; containers are deleted in parallel, and the
+// operation continues in case of errors, that are aggregated at the end
+func DeleteCluster(clusterName string, options ...DeleteOption) error {
+	flags := &DeleteOptions{}
+	for _, o := range options {
+		o(flags)
+	}
+
+	// gets the expected nodes, that are containers with the cluster label and a valid node role;
+	// nb. errors are ignored, because the orphan detection pass below finds also containers not
+	// recognized as nodes, e.g. containers created by an interrupted create
+	expected := map[string]bool{}
+	if c, err := status.FromDocker(clusterName); err == nil {
+		for _, n := range c.AllNodes() {
+			expected[n.Name()] = true
+		}
+	} else {
+		log.Debugf("failed to read the nodes of cluster %s: %v", clusterName, err)
+	}
+
+	// orphan detection pass
+	containers, err := listClusterContainers(clusterName, flags.force)
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		if flags.force {
+			fmt.Printf("No resources found for cluster %q\n", clusterName)
+			removeKubeConfig(clusterName)
+			removeAuditLog(clusterName)
+			return nil
+		}
+		return errors.Errorf("unknown cluster %q", clusterName)
+	}
+
+	fmt.Printf("Deleting cluster %q ...\n", clusterName)
+	for _, name := range containers {
+		if !expected[name] {
+			fmt.Printf("Found orphaned container %s\n", name)
+		}
+	}
+
+	errs := deleteInParallel(containers, func(name string) error {
+		return exec.NewHostCmd(
+			"docker", "rm",
+			"-f", // force the container to be deleted now
+			"-v", // delete volumes
+			name,
+		).Run()
+	})
+
+	removeKubeConfig(clusterName)
+	removeAuditLog(clusterName)
+
+	if len(errs) > 0 {
+		return errors.Wrapf(kerrors.NewAggregate(errs), "failed to delete cluster %s", clusterName)
+	}
+	return nil
+}
+
+// listClusterContainers returns the containers with the cluster label, in any state; if force is set, also containers
+// without the cluster label but named after the cluster nodes are returned
+func listClusterContainers(clusterName string, force bool) ([]string, error) {
+	lines, err := exec.NewHostCmd("docker",
+		"ps",
+		"-a",         // show stopped nodes
+		"--no-trunc", // don't truncate
+		// filter for containers with the cluster label
+		"--filter", fmt.Sprintf("label=%s=%s", constants.ClusterLabelKey, clusterName),
+		"--format", `{{.Names}}`,
+	).SetSilent(true).RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list containers for cluster %s", clusterName)
+	}
+
+	names := map[string]bool{}
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			names[l] = true
+		}
+	}
+
+	if force {
+		// matches the names assigned to nodes at create time, e.g. kind-control-plane-1, kind-worker-1, kind-etcd, kind-lb
+		nodeName := regexp.MustCompile(fmt.Sprintf(`^%s-(control-plane-\d+|worker-\d+|etcd(-\d+)?|lb)$`, regexp.QuoteMeta(clusterName)))
+
+		lines, err := exec.NewHostCmd("docker",
+			"ps",
+			"-a", // show stopped nodes
+			"--format", `{{.Names}}`,
+		).SetSilent(true).RunAndCapture()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list containers for cluster %s", clusterName)
+		}
+		for _, l := range lines {
+			if l = strings.TrimSpace(l); nodeName.MatchString(l) {
+				names[l] = true
+			}
+		}
+	}
+
+	return sortedKeys(names), nil
+}
+
+// deleteInParallel executes fn on the given resources in parallel, printing the result for each resource,
+// and returns the errors, if any
+func deleteInParallel(names []string, fn func(string) error) []error {
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := fn(name); err != nil {
+				mu.Lock()
+				errs = append(errs, errors.Wrapf(err, "failed to delete %s", name))
+				mu.Unlock()
+				return
+			}
+			fmt.Printf("Deleted %s\n", name)
+		}(name)
+	}
+	wg.Wait()
+	return errs
+}
+
+// removeKubeConfig removes the kubeconfig file written on the host for the cluster, if any
+func removeKubeConfig(clusterName string) {
+	path := status.KubeConfigPath(clusterName)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Warningf("Tried to remove %s but received error: %s", path, err)
+	}
+
+	// check if $KUBECONFIG is set and let the user know to unset if so
+	if strings.Contains(os.Getenv("KUBECONFIG"), path) {
+		fmt.Printf("$KUBECONFIG is still set to use %s even though that file has been deleted, remember to unset it\n", path)
+	}
+}
+
+// removeAuditLog removes the audit log of the kubeadm commands executed on the cluster nodes, if any
+func removeAuditLog(clusterName string) {
+	if err := audit.Remove(clusterName); err != nil {
+		log.Warning(err)
+	}
+}
+
+// sortedKeys returns the keys of a set sorted, so the output is stable
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
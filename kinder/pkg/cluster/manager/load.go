@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/kubeadm/kinder/pkg/cri"
+)
+
+// imageArchiveNodePath is the path where the image archive is copied on nodes before loading it
+const imageArchiveNodePath = "/kind/image-archive.tar"
+
+// LoadImageArchive loads an image tarball created with docker save into the container runtime of
+// all the Kubernetes nodes in the cluster; nodes already having all the images in the tarball with the same
+// image ID are skipped, without copying the tarball, while images rebuilt with the same tag are loaded again.
+// The result for each node is printed, and the action fails if loading fails on any node
+func (c *ClusterManager) LoadImageArchive(tarball string) error {
+	images, err := cri.ImagesInArchive(tarball)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, n := range c.K8sNodes() {
+		result, err := func() (string, error) {
+			runtime, err := n.CRI()
+			if err != nil {
+				return "", err
+			}
+			actionHelper, err := cri.NewActionHelper(runtime)
+			if err != nil {
+				return "", err
+			}
+
+			if actionHelper.HasImageIDs(n, images) {
+				return "images already present, skipped", nil
+			}
+
+			if err := n.CopyTo(tarball, imageArchiveNodePath); err != nil {
+				return "", errors.Wrapf(err, "failed to copy %s to node %s", tarball, n.Name())
+			}
+			defer n.Command("rm", "-f", imageArchiveNodePath).Silent().Run()
+
+			if err := actionHelper.LoadImage(n, imageArchiveNodePath); err != nil {
+				return "", errors.Wrapf(err, "failed to load %s into %s on %s", tarball, runtime, n.Name())
+			}
+			return fmt.Sprintf("loaded into %s", runtime), nil
+		}()
+		if err != nil {
+			fmt.Printf("%s: failed, %v\n", n.Name(), err)
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Printf("%s: %s\n", n.Name(), result)
+	}
+
+	if len(images) > 0 {
+		var tags []string
+		for t := range images {
+			tags = append(tags, t)
+		}
+		sort.Strings(tags)
+		fmt.Printf("\nImages: %s\n", strings.Join(tags, ", "))
+	}
+
+	if len(errs) > 0 {
+		return errors.Wrapf(kerrors.NewAggregate(errs), "failed to load %s", tarball)
+	}
+	return nil
+}
@@ -18,6 +18,9 @@ package manager
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -85,17 +88,37 @@ func (c *ClusterManager) OnlyNode(node string) {
 // Actions are repetitive, high level workflows composed
 // by one or more lower level commands
 func (c *ClusterManager) DoAction(action string, options ...actions.Option) error {
-	log.Infof("Running action %s...", action)
+	log.WithField("action", action).Infof("Running action %s...", action)
 	return actions.Run(c.Cluster, action, options...)
 }
 
 // ExecCommand is a topology aware wrapper of docker exec
-func (c *ClusterManager) ExecCommand(nodeSelector string, args []string) error {
+func (c *ClusterManager) ExecCommand(nodeSelector string, args []string, interactive, tty bool) error {
 	nodes, err := c.SelectNodes(nodeSelector)
 	if err != nil {
 		return err
 	}
 
+	// if interactive, attach the command to the current process stdin/stdout/stderr, eventually with a TTY;
+	// NB. docker exec takes care of forwarding window resizes to the TTY
+	if interactive || tty {
+		if len(nodes) != 1 {
+			return errors.Errorf("interactive commands can be executed only on a single node, %d nodes selected", len(nodes))
+		}
+
+		cmdArgs := []string{"exec"}
+		if interactive {
+			cmdArgs = append(cmdArgs, "--interactive")
+		}
+		if tty {
+			cmdArgs = append(cmdArgs, "--tty")
+		}
+		cmdArgs = append(cmdArgs, nodes[0].Name())
+		cmdArgs = append(cmdArgs, args...)
+
+		return exec.NewHostCmd("docker", cmdArgs...).RunInteractive()
+	}
+
 	log.Infof("%d nodes selected as target for the command", len(nodes))
 	for _, node := range nodes {
 		fmt.Printf("🚀 Executing command on node %s 🚀\n", node.Name())
@@ -113,8 +136,9 @@ func (c *ClusterManager) ExecCommand(nodeSelector string, args []string) error {
 	return nil
 }
 
-// CopyFile is a topology aware wrapper of docker cp
-func (c *ClusterManager) CopyFile(source, target string) error {
+// CopyFile is a topology aware wrapper of docker cp; folders are copied recursively, and
+// if archive is set, also uid/gid of copied files are preserved
+func (c *ClusterManager) CopyFile(source, target string, archive bool) error {
 	sourceNodes, sourcePath, err := c.ResolveNodesPath(source)
 	if err != nil {
 		return err
@@ -144,24 +168,46 @@ func (c *ClusterManager) CopyFile(source, target string) error {
 		return errors.Errorf("no target node matches given criteria")
 	}
 
+	// if copying between nodes, copy from the source node to a temporary folder on the host first,
+	// and then use the temporary folder as a source for copying to target nodes
 	if sourceNodes != nil && targetNodes != nil {
-		// create tmp folder
-		// cp locally
-		return errors.Errorf("copy between nodes not implemented yet!")
+		tmpDir, err := ioutil.TempDir("", "kinder-cp")
+		if err != nil {
+			return errors.Wrap(err, "failed to create a temporary folder")
+		}
+		defer os.RemoveAll(tmpDir)
+
+		fmt.Printf("Copying from %s ...\n", sourceNodes[0].Name())
+		tmpPath := filepath.Join(tmpDir, filepath.Base(sourcePath))
+		if err := dockerCopy(sourceNodes[0].Name()+":"+sourcePath, tmpPath, archive); err != nil {
+			return err
+		}
+		sourcePath = tmpPath
 	}
 
 	if targetNodes == nil {
 		fmt.Printf("Copying from %s ...\n", sourceNodes[0].Name())
-		if err := sourceNodes[0].CopyFrom(sourcePath, targetPath); err != nil {
+		if err := dockerCopy(sourceNodes[0].Name()+":"+sourcePath, targetPath, archive); err != nil {
 			return err
 		}
 	}
 
 	for _, n := range targetNodes {
 		fmt.Printf("Copying to %s ...\n", n.Name())
-		if err := n.CopyTo(sourcePath, targetPath); err != nil {
+		if err := dockerCopy(sourcePath, n.Name()+":"+targetPath, archive); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// dockerCopy copies files/folders between a node and the local filesystem using docker cp
+func dockerCopy(source, target string, archive bool) error {
+	args := []string{"cp"}
+	if archive {
+		args = append(args, "--archive")
+	}
+	args = append(args, source, target)
+
+	return exec.NewHostCmd("docker", args...).RunWithEcho()
+}
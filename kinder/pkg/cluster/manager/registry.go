@@ -0,0 +1,232 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/cri"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
+)
+
+// RegistryOptions holds all the options used at create registry time
+type RegistryOptions struct {
+	image string
+	port  int
+}
+
+// RegistryOption is a configuration option supplied to CreateRegistry
+type RegistryOption func(*RegistryOptions)
+
+// RegistryImage sets the image for the registry container
+func RegistryImage(image string) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.image = image
+	}
+}
+
+// RegistryPort sets the port on the host where the registry is exposed; if not set, constants.RegistryPort is used
+func RegistryPort(port int) RegistryOption {
+	return func(c *RegistryOptions) {
+		c.port = port
+	}
+}
+
+// CreateRegistry runs the registry container shared by kinder clusters, if not already running, and configures
+// the container runtime of the Kubernetes nodes of the given cluster for pulling images from it.
+// Like in the kind local registry pattern, nodes are configured for pulling localhost:<port> images through a
+// mirror pointing to the registry container, so the same image references work on the host and on nodes;
+// the registry container runs on the same docker network of the nodes, and its IP is mapped to the registry
+// container name in the /etc/hosts file of nodes, so running create registry again refreshes the IP if it changes
+func CreateRegistry(clusterName string, options ...RegistryOption) error {
+	flags := &RegistryOptions{
+		image: constants.RegistryImage,
+	}
+	for _, o := range options {
+		o(flags)
+	}
+	port := flags.port
+	if port == 0 {
+		port = constants.RegistryPort
+	}
+	if port < 1 || port > 65535 {
+		return errors.Errorf("invalid --port %d, it must be between 1 and 65535", port)
+	}
+
+	known, err := status.IsKnown(clusterName)
+	if err != nil {
+		return err
+	}
+	if !known {
+		return errors.Errorf("a cluster with the name %q does not exists", clusterName)
+	}
+	c, err := status.FromDocker(clusterName)
+	if err != nil {
+		return err
+	}
+
+	// runs the registry container, or reuses the existing one, e.g. created for another cluster
+	exists, running, err := registryState()
+	if err != nil {
+		return err
+	}
+	switch {
+	case !exists:
+		fmt.Printf("Creating registry %s ...\n", constants.RegistryContainerName)
+		if err := exec.NewHostCmd("docker", "run",
+			"--detach",
+			"--restart=always",
+			"--name", constants.RegistryContainerName,
+			"--label", fmt.Sprintf("%s=true", constants.RegistryLabelKey),
+			"--publish", fmt.Sprintf("127.0.0.1:%d:%d", port, constants.RegistryPort),
+			flags.image,
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to create registry %s", constants.RegistryContainerName)
+		}
+	case running:
+		fmt.Printf("Using existing registry %s\n", constants.RegistryContainerName)
+	default:
+		fmt.Printf("Starting existing registry %s ...\n", constants.RegistryContainerName)
+		if err := exec.NewHostCmd("docker", "start", constants.RegistryContainerName).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to start registry %s", constants.RegistryContainerName)
+		}
+	}
+
+	ip, hostPort, err := registryAddresses()
+	if err != nil {
+		return err
+	}
+	if exists && flags.port != 0 && flags.port != hostPort {
+		log.Warnf("registry %s already exists and it is exposed on port %d of the host, --port %d is ignored", constants.RegistryContainerName, hostPort, flags.port)
+	}
+
+	// images are referenced as localhost:<port>/image both on the host and on nodes, and nodes pull them
+	// from the registry container reached by name
+	registry := net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort))
+	endpoint := net.JoinHostPort(constants.RegistryContainerName, fmt.Sprintf("%d", constants.RegistryPort))
+
+	var dockerNodes []string
+	for _, n := range c.K8sNodes() {
+		nodeCRI, err := n.CRI()
+		if err != nil {
+			return err
+		}
+		actionHelper, err := cri.NewActionHelper(nodeCRI)
+		if err != nil {
+			return err
+		}
+
+		// nb. /etc/hosts is bind mounted by docker, so it is rewritten in place instead of using sed -i
+		if err := n.Command("sh", "-c", fmt.Sprintf(
+			"grep -v ' %[1]s$' /etc/hosts > /tmp/hosts.kinder; echo '%[2]s %[1]s' >> /tmp/hosts.kinder && cat /tmp/hosts.kinder > /etc/hosts && rm -f /tmp/hosts.kinder",
+			constants.RegistryContainerName, ip,
+		)).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to add %s to /etc/hosts on node %s", constants.RegistryContainerName, n.Name())
+		}
+
+		n.Infof("configuring the container runtime for pulling images of %s from %s", registry, endpoint)
+		if err := actionHelper.ConfigureInsecureRegistry(n, registry, endpoint); err != nil {
+			return err
+		}
+		if nodeCRI == status.DockerRuntime {
+			dockerNodes = append(dockerNodes, n.Name())
+		}
+	}
+
+	fmt.Printf("\nRegistry %s is ready for cluster %q\n", constants.RegistryContainerName, clusterName)
+	fmt.Printf("Push images from the host to %s and use the same references on nodes, e.g. %s/my-image:latest\n", registry, registry)
+	if len(dockerNodes) > 0 {
+		// nb. docker supports mirrors only for Docker Hub, so nodes using docker pull images from the registry container
+		fmt.Printf("Nodes %s use docker, that does not support registry mirrors; use %s/my-image:latest on these nodes\n", strings.Join(dockerNodes, ", "), endpoint)
+	}
+	return nil
+}
+
+// DeleteRegistry removes the registry container shared by kinder clusters
+func DeleteRegistry() error {
+	exists, _, err := registryState()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Errorf("registry %s does not exists", constants.RegistryContainerName)
+	}
+
+	fmt.Printf("Deleting registry %s ...\n", constants.RegistryContainerName)
+	if err := exec.NewHostCmd(
+		"docker", "rm",
+		"-f", // force the container to be deleted now
+		"-v", // delete volumes
+		constants.RegistryContainerName,
+	).Run(); err != nil {
+		return errors.Wrapf(err, "failed to delete registry %s", constants.RegistryContainerName)
+	}
+	return nil
+}
+
+// registryState returns if the registry container exists, and if it is running
+func registryState() (bool, bool, error) {
+	lines, err := exec.NewHostCmd("docker",
+		"ps",
+		"-a", // show stopped containers
+		"--filter", fmt.Sprintf("label=%s", constants.RegistryLabelKey),
+		"--filter", fmt.Sprintf("name=^%s$", constants.RegistryContainerName),
+		"--format", `{{.Names}}`,
+	).SetSilent(true).RunAndCapture()
+	if err != nil {
+		return false, false, errors.Wrapf(err, "failed to list containers for registry %s", constants.RegistryContainerName)
+	}
+	if len(lines) == 0 {
+		return false, false, nil
+	}
+
+	lines, err = kinddocker.Inspect(constants.RegistryContainerName, "{{.State.Running}}")
+	if err != nil {
+		return true, false, errors.Wrapf(err, "failed to get the state of registry %s", constants.RegistryContainerName)
+	}
+	return true, len(lines) == 1 && lines[0] == "true", nil
+}
+
+// registryAddresses returns the IP of the registry container on the docker network of the nodes,
+// and the port where the registry is exposed on the host
+func registryAddresses() (string, int, error) {
+	lines, err := kinddocker.Inspect(constants.RegistryContainerName, fmt.Sprintf(
+		`{{.NetworkSettings.IPAddress}} {{(index (index .NetworkSettings.Ports "%d/tcp") 0).HostPort}}`, constants.RegistryPort,
+	))
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to get the address of registry %s", constants.RegistryContainerName)
+	}
+	if len(lines) != 1 || len(strings.Fields(lines[0])) != 2 {
+		return "", 0, errors.Errorf("invalid address for registry %s: %q", constants.RegistryContainerName, lines)
+	}
+
+	fields := strings.Fields(lines[0])
+	hostPort, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "invalid port for registry %s: %q", constants.RegistryContainerName, fields[1])
+	}
+	return fields[0], hostPort, nil
+}
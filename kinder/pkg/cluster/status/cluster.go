@@ -38,7 +38,7 @@ type Cluster struct {
 	k8sNodes             NodeList
 	controlPlanes        NodeList
 	workers              NodeList
-	externalEtcd         *Node
+	externalEtcdMembers  NodeList
 	externalLoadBalancer *Node
 }
 
@@ -52,6 +52,20 @@ type ClusterSettings struct {
 	// kind configuration settings that are used to configure the cluster when
 	// generating the kubeadm config file.
 	IPFamily ClusterIPFamily `json:"ipFamily,omitempty"`
+	// CertificateKey is the key used for encrypting the certificates uploaded by the copy-certs action,
+	// that is used by kubeadm join when automatic copy certs is enabled
+	CertificateKey string `json:"certificateKey,omitempty"`
+	// PodSubnet and ServiceSubnet are the subnets set at create time, that are used
+	// in the kubeadm config networking; in case of dual-stack, they hold comma-separated values
+	PodSubnet     string `json:"podSubnet,omitempty"`
+	ServiceSubnet string `json:"serviceSubnet,omitempty"`
+	// APIServerExtraArgs, ControllerManagerExtraArgs and SchedulerExtraArgs are the control-plane components
+	// extra args set at create time, that are used in the kubeadm config ClusterConfiguration
+	APIServerExtraArgs         map[string]string `json:"apiServerExtraArgs,omitempty"`
+	ControllerManagerExtraArgs map[string]string `json:"controllerManagerExtraArgs,omitempty"`
+	SchedulerExtraArgs         map[string]string `json:"schedulerExtraArgs,omitempty"`
+	// CgroupDriver is the cgroup driver configured at create time for both the kubelet and the container runtime
+	CgroupDriver CgroupDriver `json:"cgroupDriver,omitempty"`
 }
 
 // ClusterIPFamily defines cluster network IP family
@@ -62,6 +76,18 @@ const (
 	IPv4Family ClusterIPFamily = "ipv4"
 	// IPv6Family sets ClusterIPFamily to ipv6
 	IPv6Family ClusterIPFamily = "ipv6"
+	// DualStackFamily sets ClusterIPFamily to dual-stack, with IPv4 as a primary family
+	DualStackFamily ClusterIPFamily = "dual"
+)
+
+// CgroupDriver defines the cgroup driver used by the kubelet and the container runtime
+type CgroupDriver string
+
+const (
+	// SystemdCgroupDriver sets CgroupDriver to systemd; this is the default cgroup driver
+	SystemdCgroupDriver CgroupDriver = "systemd"
+	// CgroupfsCgroupDriver sets CgroupDriver to cgroupfs
+	CgroupfsCgroupDriver CgroupDriver = "cgroupfs"
 )
 
 // ListClusters is part of the providers.Provider interface
@@ -229,10 +255,7 @@ func (c *Cluster) add(node *Node) error {
 	}
 
 	if node.IsExternalEtcd() {
-		if c.externalEtcd != nil {
-			return errors.Errorf("unable to add the node to the cluster. A cluster can not have more than one node with role %q", constants.ExternalEtcdNodeRoleValue)
-		}
-		c.externalEtcd = node
+		c.externalEtcdMembers = append(c.externalEtcdMembers, node)
 	}
 
 	if node.IsExternalLoadBalancer() {
@@ -283,9 +306,17 @@ func (c *Cluster) Workers() NodeList {
 	return c.workers
 }
 
-// ExternalEtcd returns the node with external-etcd role, if defined
+// ExternalEtcd returns the first node with external-etcd role, if defined
 func (c *Cluster) ExternalEtcd() *Node {
-	return c.externalEtcd
+	if len(c.externalEtcdMembers) == 0 {
+		return nil
+	}
+	return c.externalEtcdMembers[0]
+}
+
+// ExternalEtcdMembers returns all the nodes with external-etcd role, if any
+func (c *Cluster) ExternalEtcdMembers() NodeList {
+	return c.externalEtcdMembers
 }
 
 // ExternalLoadBalancer returns the node with external-load-balancer role, if defined
@@ -334,7 +365,7 @@ func (c *Cluster) SelectNodes(nodeSelector string) (nodes NodeList, err error) {
 		case "@lb":
 			return toNodeList(c.ExternalLoadBalancer()), nil
 		case "@etcd":
-			return toNodeList(c.ExternalEtcd()), nil
+			return c.ExternalEtcdMembers(), nil
 		default:
 			return nil, errors.Errorf("Invalid node selector %q. Use one of [@all, @cp*, @cp1, @cpn, @w*, @lb, @etcd]", nodeSelector)
 		}
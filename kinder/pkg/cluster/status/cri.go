@@ -35,6 +35,8 @@ const (
 	DockerRuntime ContainerRuntime = "docker"
 	// ContainerdRuntime refers to the containerd container runtime
 	ContainerdRuntime ContainerRuntime = "containerd"
+	// CRIORuntime refers to the CRI-O container runtime
+	CRIORuntime ContainerRuntime = "crio"
 )
 
 // InspectCRIinImage inspect an image and detects the installed container runtime
@@ -79,5 +81,15 @@ func InspectCRIinContainer(id string) (ContainerRuntime, error) {
 		return DockerRuntime, nil
 	}
 
+	lines, err = exec.NewNodeCmd(id, "/bin/sh", "-c", `which crio || true`).Silent().RunAndCapture()
+
+	if err != nil {
+		return ContainerRuntime(""), errors.Wrap(err, "error detecting CRI")
+	}
+
+	if len(lines) > 0 {
+		return CRIORuntime, nil
+	}
+
 	return ContainerdRuntime, nil
 }
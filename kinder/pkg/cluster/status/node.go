@@ -60,7 +60,12 @@ type Node struct {
 // and actions for setting up a working cluster can happen at different time
 // (while in kind everything happen within an atomic operation).
 type NodeSettings struct {
-	// NB Currently there are no persistent node settings used by kind, but we are preserving this feature for future changes
+	// KubeletExtraArgs are node specific kubelet flags set at create time, that are used by kubeadm init/join
+	KubeletExtraArgs []string `json:"kubeletExtraArgs,omitempty"`
+	// Image and KubernetesVersion are the node image used for creating the node and the Kubernetes version it contains,
+	// that can be different on each node, e.g. in clusters for testing version skew
+	Image             string `json:"image,omitempty"`
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
 }
 
 // NewNode returns a new kinder.Node wrapper
@@ -360,6 +365,28 @@ func (n *Node) Ports(containerPort int32) (hostPort int32, err error) {
 	return hostPort, nil
 }
 
+// Resources returns the CPU and memory limits of the node container, as a number of CPUs
+// and a number of bytes; 0 means no limit
+func (n *Node) Resources() (cpus float64, memory int64, err error) {
+	lines, err := kinddocker.Inspect(n.name, "{{.HostConfig.NanoCpus}} {{.HostConfig.Memory}}")
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to get container details")
+	}
+	if len(lines) != 1 || len(strings.Fields(lines[0])) != 2 {
+		return 0, 0, errors.Errorf("container resources should have 2 values, got %q", lines)
+	}
+	fields := strings.Fields(lines[0])
+	nanoCPUs, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to parse container CPU limit")
+	}
+	memory, err = strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to parse container memory limit")
+	}
+	return float64(nanoCPUs) / 1e9, memory, nil
+}
+
 // IP returns the IP address of the node
 func (n *Node) IP() (ipv4 string, ipv6 string, err error) {
 	// use the cached version first
@@ -73,6 +73,15 @@ const (
 	// TODO: send a PR to define this value in a kind constant (currently it is not)
 	KubeadmConfigPath = "/kind/kubeadm.conf"
 
+	// DockerCRISocket defines the CRI socket kubeadm should use on nodes with docker as container runtime
+	DockerCRISocket = "/var/run/dockershim.sock"
+
+	// ContainerdCRISocket defines the CRI socket kubeadm should use on nodes with containerd as container runtime
+	ContainerdCRISocket = "/run/containerd/containerd.sock"
+
+	// CRIOCRISocket defines the CRI socket kubeadm should use on nodes with CRI-O as container runtime
+	CRIOCRISocket = "/var/run/crio/crio.sock"
+
 	// KubeadmIgnorePreflightErrorsFlag holds the default list of preflight errors to skip
 	// on "kubeadm init" and "kubeadm join"
 	KubeadmIgnorePreflightErrorsFlag = "--ignore-preflight-errors=Swap,SystemVerification,FileContent--proc-sys-net-bridge-bridge-nf-call-iptables"
@@ -105,6 +114,36 @@ const (
 
 	// KustomizeDir defines the path to patches stored on node
 	KustomizeDir = "/kinder/kustomize"
+
+	// PatchesDir defines the path to kubeadm patches stored on node
+	PatchesDir = "/kinder/patches"
+
+	// PostCreateHookPath defines the path where the post create hook script is copied on nodes
+	PostCreateHookPath = "/kinder/post-create-hook"
+
+	// BaseImageLabelKey is applied to node images built by kinder for tracking the base image used for the build
+	BaseImageLabelKey = "io.x-k8s.kinder.base-image"
+
+	// ContainerdVersionLabelKey is applied to node images built by kinder with a pinned containerd version
+	ContainerdVersionLabelKey = "io.x-k8s.kinder.containerd-version"
+
+	// NodeOSLabelKey is applied to node images built by kinder for non Linux nodes for tracking the node OS
+	NodeOSLabelKey = "io.x-k8s.kinder.os"
+)
+
+// constants used by the kinder registry, that is a registry container shared by kinder clusters
+const (
+	// RegistryContainerName defines the name of the registry container
+	RegistryContainerName = "kinder-registry"
+
+	// RegistryLabelKey is applied to the registry container for identification
+	RegistryLabelKey = "io.x-k8s.kinder.registry"
+
+	// RegistryImage defines the default registry image:tag
+	RegistryImage = "registry:2"
+
+	// RegistryPort defines the port where the registry is listening inside the registry container
+	RegistryPort = 5000
 )
 
 // kubernetes releases, used for branching code according to K8s release or kubeadm release version
@@ -123,10 +162,23 @@ var (
 
 	// V1.17 minor version
 	V1_17 = K8sVersion.MustParseSemantic("v1.17.0-0")
+
+	// V1.19 minor version
+	V1_19 = K8sVersion.MustParseSemantic("v1.19.0-0")
+
+	// V1.20 minor version
+	V1_20 = K8sVersion.MustParseSemantic("v1.20.0-0")
+
+	// V1.21 minor version
+	V1_21 = K8sVersion.MustParseSemantic("v1.21.0-0")
+
+	// V1.22 minor version
+	V1_22 = K8sVersion.MustParseSemantic("v1.22.0-0")
 )
 
-// other constants
-const (
-	// KinderVersion is the kinder CLI version
+// other variables
+var (
+	// KinderVersion is the kinder CLI version; it can be overridden at build time, e.g.
+	// -ldflags "-X k8s.io/kubeadm/kinder/pkg/constants.KinderVersion=0.1.0-alpha.3+abcdef"
 	KinderVersion = "0.1.0-alpha.3"
 )
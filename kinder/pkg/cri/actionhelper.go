@@ -17,11 +17,19 @@ limitations under the License.
 package cri
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/cri/containerd"
+	"k8s.io/kubeadm/kinder/pkg/cri/crio"
 	"k8s.io/kubeadm/kinder/pkg/cri/docker"
+	kindconcurrent "sigs.k8s.io/kind/pkg/concurrent"
 )
 
 // ActionHelper helper provides CRI specific methods used by kind(er) actions
@@ -41,19 +49,240 @@ func (h *ActionHelper) PreLoadUpgradeImages(n *status.Node, srcFolder string) er
 	switch h.cri {
 	case status.ContainerdRuntime:
 		return containerd.PreLoadUpgradeImages(n, srcFolder)
+	case status.CRIORuntime:
+		return crio.PreLoadUpgradeImages(n, srcFolder)
 	case status.DockerRuntime:
 		return docker.PreLoadUpgradeImages(n, srcFolder)
 	}
 	return errors.Errorf("unknown cri: %s", h.cri)
 }
 
+// LoadImage loads an image tarball into the selected container runtime that exists inside a kind(er) node
+func (h *ActionHelper) LoadImage(n *status.Node, tarball string) error {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.LoadImage(n, tarball)
+	case status.CRIORuntime:
+		return crio.LoadImage(n, tarball)
+	case status.DockerRuntime:
+		return docker.LoadImage(n, tarball)
+	}
+	return errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// LoadImages loads all the image tarballs in srcFolder into the selected container runtime
+// that exists inside a kind(er) node; tarballs with images already available in the container runtime are skipped,
+// so it is safe to call this method many times, while the missing ones are loaded concurrently
+func (h *ActionHelper) LoadImages(n *status.Node, srcFolder string) error {
+	// waits for the container runtime to be ready, and gets the images already available
+	var current []string
+	var err error
+	for until := time.Now().Add(30 * time.Second); ; time.Sleep(time.Second) {
+		if current, err = h.GetImages(n); err == nil || time.Now().After(until) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	tarballs, err := imageTarballs(n, srcFolder)
+	if err != nil {
+		return err
+	}
+
+	fns := []func() error{}
+	for _, tarball := range tarballs {
+		tags := imageTagsInTarball(n, tarball)
+		if len(tags) > 0 && hasImages(current, tags) {
+			log.Debugf("Skipping %s on %s, images %s already present", tarball, n.Name(), strings.Join(tags, ", "))
+			continue
+		}
+
+		tarball := tarball // capture loop variable
+		fns = append(fns, func() error {
+			log.Debugf("Loading %s on %s", tarball, n.Name())
+			if err := h.LoadImage(n, tarball); err != nil {
+				return errors.Wrapf(err, "failed to load %s into %s on %s", tarball, h.cri, n.Name())
+			}
+			return nil
+		})
+	}
+	return kindconcurrent.UntilError(fns)
+}
+
+// GetImageID returns the ID of an image available in the selected container runtime that exists inside a kind(er) node
+func (h *ActionHelper) GetImageID(n *status.Node, image string) (string, error) {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.GetImageID(n, image)
+	case status.CRIORuntime:
+		return crio.GetImageID(n, image)
+	case status.DockerRuntime:
+		return docker.GetImageID(n, image)
+	}
+	return "", errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// HasImageIDs returns true if all the images, a map of image tag to image ID, are available in the selected
+// container runtime that exists inside a kind(er) node with exactly the same image ID; in case of errors
+// false is returned, so the images will be loaded anyway
+func (h *ActionHelper) HasImageIDs(n *status.Node, images map[string]string) bool {
+	if len(images) == 0 {
+		return false
+	}
+	for tag, id := range images {
+		current, err := h.GetImageID(n, tag)
+		if err != nil || current != id {
+			return false
+		}
+	}
+	return true
+}
+
+// imageTarballs returns the image tarballs in srcFolder
+func imageTarballs(n *status.Node, srcFolder string) ([]string, error) {
+	// NB. node images built without kinder could not have the srcFolder
+	tarballs, err := n.Command(
+		"sh", "-c", fmt.Sprintf("test ! -d %[1]s || find %[1]s -name '*.tar'", srcFolder),
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list image tarballs in %s on %s", srcFolder, n.Name())
+	}
+	return tarballs, nil
+}
+
+// imageTagsInTarball returns the image tags stored in the manifest of an image tarball;
+// in case of errors an empty list is returned, so the tarball will be loaded anyway
+func imageTagsInTarball(n *status.Node, tarball string) []string {
+	lines, err := n.Command(
+		"tar", "-xOf", tarball, "manifest.json",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil
+	}
+
+	var manifest []struct {
+		RepoTags []string
+	}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &manifest); err != nil {
+		return nil
+	}
+
+	var tags []string
+	for _, m := range manifest {
+		tags = append(tags, m.RepoTags...)
+	}
+	return tags
+}
+
+// hasImages returns true if all the images are included in the list of current images;
+// images are compared also considering the docker.io/library/ prefix that
+// some container runtimes add to the image names
+func hasImages(current, images []string) bool {
+	known := map[string]bool{}
+	for _, c := range current {
+		known[strings.TrimPrefix(strings.TrimPrefix(c, "docker.io/"), "library/")] = true
+	}
+	for _, i := range images {
+		if !known[strings.TrimPrefix(strings.TrimPrefix(i, "docker.io/"), "library/")] {
+			return false
+		}
+	}
+	return true
+}
+
+// RestartStaticPods restarts the containers of the given static pods in the selected container runtime that exists inside a kind(er) node
+func (h *ActionHelper) RestartStaticPods(n *status.Node, pods ...string) error {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.RestartStaticPods(n, pods...)
+	case status.CRIORuntime:
+		return crio.RestartStaticPods(n, pods...)
+	case status.DockerRuntime:
+		return docker.RestartStaticPods(n, pods...)
+	}
+	return errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// GetRunningContainers returns the Kubernetes containers running in the selected container runtime that exists inside a kind(er) node
+func (h *ActionHelper) GetRunningContainers(n *status.Node) ([]string, error) {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.GetRunningContainers(n)
+	case status.CRIORuntime:
+		return crio.GetRunningContainers(n)
+	case status.DockerRuntime:
+		return docker.GetRunningContainers(n)
+	}
+	return nil, errors.Errorf("unknown cri: %s", h.cri)
+}
+
 // GetImages prints the images available in the node
 func (h *ActionHelper) GetImages(n *status.Node) ([]string, error) {
 	switch h.cri {
 	case status.ContainerdRuntime:
 		return containerd.GetImages(n)
+	case status.CRIORuntime:
+		return crio.GetImages(n)
 	case status.DockerRuntime:
 		return docker.GetImages(n)
 	}
 	return nil, errors.Errorf("unknown cri: %s", h.cri)
 }
+
+// ConfigureRegistryMirrors configures the selected container runtime that exists inside a kind(er) node
+// for pulling images from the given registries through mirrors, and then restarts the container runtime
+func (h *ActionHelper) ConfigureRegistryMirrors(n *status.Node, mirrors map[string][]string) error {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.ConfigureRegistryMirrors(n, mirrors)
+	case status.CRIORuntime:
+		return crio.ConfigureRegistryMirrors(n, mirrors)
+	case status.DockerRuntime:
+		return docker.ConfigureRegistryMirrors(n, mirrors)
+	}
+	return errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// ConfigureInsecureRegistry configures the selected container runtime that exists inside a kind(er) node
+// for pulling images of a registry, e.g. localhost:5000, from an endpoint using http, e.g. the kinder registry,
+// and then restarts the container runtime
+func (h *ActionHelper) ConfigureInsecureRegistry(n *status.Node, registry, endpoint string) error {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.ConfigureInsecureRegistry(n, registry, endpoint)
+	case status.CRIORuntime:
+		return crio.ConfigureInsecureRegistry(n, registry, endpoint)
+	case status.DockerRuntime:
+		return docker.ConfigureInsecureRegistry(n, registry, endpoint)
+	}
+	return errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// ConfigureCgroupDriver configures the selected container runtime that exists inside a kind(er) node
+// for using the given cgroup driver, and then restarts the container runtime
+func (h *ActionHelper) ConfigureCgroupDriver(n *status.Node, driver status.CgroupDriver) error {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.ConfigureCgroupDriver(n, driver)
+	case status.CRIORuntime:
+		return crio.ConfigureCgroupDriver(n, driver)
+	case status.DockerRuntime:
+		return docker.ConfigureCgroupDriver(n, driver)
+	}
+	return errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// GetCgroupDriver returns the cgroup driver used by the selected container runtime that exists inside a kind(er) node
+func (h *ActionHelper) GetCgroupDriver(n *status.Node) (status.CgroupDriver, error) {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.GetCgroupDriver(n)
+	case status.CRIORuntime:
+		return crio.GetCgroupDriver(n)
+	case status.DockerRuntime:
+		return docker.GetCgroupDriver(n)
+	}
+	return "", errors.Errorf("unknown cri: %s", h.cri)
+}
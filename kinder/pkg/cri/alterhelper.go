@@ -22,6 +22,7 @@ import (
 	"k8s.io/kubeadm/kinder/pkg/build/bits"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/cri/containerd"
+	"k8s.io/kubeadm/kinder/pkg/cri/crio"
 	"k8s.io/kubeadm/kinder/pkg/cri/docker"
 )
 
@@ -46,6 +47,8 @@ func (h *AlterHelper) PreLoadInitImages(bc *bits.BuildContext) error {
 	switch h.cri {
 	case status.ContainerdRuntime:
 		return containerd.PreLoadInitImages(bc)
+	case status.CRIORuntime:
+		return crio.PreLoadInitImages(bc)
 	case status.DockerRuntime:
 		return docker.PreLoadInitImages(bc)
 	}
@@ -57,6 +60,8 @@ func (h *AlterHelper) Commit(containerID, targetImage string) error {
 	switch h.cri {
 	case status.ContainerdRuntime:
 		return containerd.Commit(containerID, targetImage)
+	case status.CRIORuntime:
+		return crio.Commit(containerID, targetImage)
 	case status.DockerRuntime:
 		return docker.Commit(containerID, targetImage)
 	}
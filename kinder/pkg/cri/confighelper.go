@@ -21,6 +21,7 @@ import (
 
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/kubeadm"
 )
 
@@ -36,15 +37,25 @@ func NewConfigHelper(cri status.ContainerRuntime) (*ConfigHelper, error) {
 	}, nil
 }
 
-// GetKubeadmConfigPatches returns kustomize patches for configuring the kubeadm config file for using the selected container runtime
-func (h *ConfigHelper) GetKubeadmConfigPatches(kubeadmVersion *K8sVersion.Version, controlPlane bool) ([]string, error) {
+// GetCRISocket returns the CRI socket kubeadm should use for the selected container runtime
+func (h *ConfigHelper) GetCRISocket() (string, error) {
 	switch h.cri {
 	case status.ContainerdRuntime:
-		// since we are using kind library for generating the kubeadm-config file, and kind uses by default containerd, no
-		// additional patches are required in this case
-		return []string{}, nil
+		return constants.ContainerdCRISocket, nil
+	case status.CRIORuntime:
+		return constants.CRIOCRISocket, nil
 	case status.DockerRuntime:
-		return kubeadm.GetDockerPatch(kubeadmVersion, controlPlane)
+		return constants.DockerCRISocket, nil
+	}
+	return "", errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// GetKubeadmConfigPatches returns the kubeadm config patches that will instruct kubeadm
+// to use the CRI socket of the selected container runtime
+func (h *ConfigHelper) GetKubeadmConfigPatches(kubeadmVersion *K8sVersion.Version, controlPlane bool) ([]string, error) {
+	criSocket, err := h.GetCRISocket()
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.Errorf("unknown cri: %s", h.cri)
+	return kubeadm.GetCRISocketPatch(kubeadmVersion, criSocket)
 }
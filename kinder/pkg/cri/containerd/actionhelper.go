@@ -17,9 +17,15 @@ limitations under the License.
 package containerd
 
 import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri/util"
 )
 
 // PreLoadUpgradeImages preload images required by kubeadm-upgrade into the containerd runtime that exists inside a kind(er) node
@@ -31,6 +37,40 @@ func PreLoadUpgradeImages(n *status.Node, srcFolder string) error {
 	).Silent().Run()
 }
 
+// LoadImage loads an image tarball into the containerd runtime that exists inside a kind(er) node
+func LoadImage(n *status.Node, tarball string) error {
+	return n.Command(
+		"ctr", "--namespace=k8s.io", "images", "import", "--no-unpack", tarball,
+	).Silent().Run()
+}
+
+// RestartStaticPods restarts the containers of the given static pods in the containerd runtime that exists inside a kind(er) node;
+// containers are stopped, and then restarted by the kubelet
+func RestartStaticPods(n *status.Node, pods ...string) error {
+	for _, p := range pods {
+		if err := n.Command(
+			"bash", "-c",
+			fmt.Sprintf("crictl ps -q --name '^%s$' | xargs -r crictl stop", p),
+		).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to restart %s on %s", p, n.Name())
+		}
+	}
+	return nil
+}
+
+// GetRunningContainers returns the IDs of the Kubernetes containers running in the containerd runtime that exists inside a kind(er) node
+func GetRunningContainers(n *status.Node) ([]string, error) {
+	containers, err := n.Command(
+		"crictl", "ps", "-q",
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read running containers from %s", n.Name())
+	}
+
+	return containers, nil
+}
+
 // GetImages returns the list of images available in the node
 func GetImages(n *status.Node) ([]string, error) {
 	current, err := n.Command(
@@ -43,3 +83,227 @@ func GetImages(n *status.Node) ([]string, error) {
 
 	return current, nil
 }
+
+// GetImageID returns the ID of an image available in the node, that is the digest of the image config
+func GetImageID(n *status.Node, image string) (string, error) {
+	lines, err := n.Command(
+		"crictl", "inspecti", "-o", "json", image,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to inspect image %s on %s", image, n.Name())
+	}
+
+	var inspect struct {
+		Status struct {
+			ID string `json:"id"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &inspect); err != nil {
+		return "", errors.Wrapf(err, "failed to parse the inspect output of image %s on %s", image, n.Name())
+	}
+	return inspect.Status.ID, nil
+}
+
+// containerdRegistryConfigPath is the folder where containerd reads the hosts.toml file for each registry
+const containerdRegistryConfigPath = "/etc/containerd/certs.d"
+
+// ConfigureRegistryMirrors configures the containerd runtime that exists inside a kind(er) node for pulling
+// images through mirrors, writing a hosts.toml file for each registry, and then restarts containerd;
+// with containerd older than v1.5 or with configs already setting registry mirrors, mirrors are added
+// to the registry mirrors in the containerd config instead
+func ConfigureRegistryMirrors(n *status.Node, mirrors map[string][]string) error {
+	useHosts, err := registryHostsSupported(n)
+	if err != nil {
+		return err
+	}
+	if !useHosts {
+		// nb. the upstream registry is added as a last endpoint, like the server in hosts.toml files
+		endpoints := map[string][]string{}
+		for r, m := range mirrors {
+			endpoints[r] = append(append([]string{}, m...), util.RegistryServer(r))
+		}
+		if err := addConfigRegistryMirrors(n, endpoints); err != nil {
+			return errors.Wrapf(err, "failed to configure registry mirrors on node %s", n.Name())
+		}
+		return nil
+	}
+
+	for _, r := range util.SortedRegistries(mirrors) {
+		hosts := fmt.Sprintf("server = %q\n", util.RegistryServer(r))
+		for _, m := range mirrors[r] {
+			hosts += fmt.Sprintf("\n[host.%q]\n  capabilities = [\"pull\", \"resolve\"]\n", m)
+		}
+
+		dir := path.Join(containerdRegistryConfigPath, r)
+		if err := n.Command("mkdir", "-p", dir).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to create %s on node %s", dir, n.Name())
+		}
+		if err := n.WriteFile(path.Join(dir, "hosts.toml"), []byte(hosts)); err != nil {
+			return err
+		}
+	}
+
+	if err := enableRegistryConfigPath(n); err != nil {
+		return errors.Wrapf(err, "failed to configure registry mirrors on node %s", n.Name())
+	}
+	return nil
+}
+
+// ConfigureInsecureRegistry configures the containerd runtime that exists inside a kind(er) node for pulling
+// images of a registry through a mirror endpoint using http, writing the hosts.toml file for the registry, and
+// then restarts containerd; with containerd older than v1.5 or with configs already setting registry mirrors,
+// the endpoint is added to the registry mirrors in the containerd config instead
+func ConfigureInsecureRegistry(n *status.Node, registry, endpoint string) error {
+	server := "http://" + registry
+	mirror := "http://" + endpoint
+
+	useHosts, err := registryHostsSupported(n)
+	if err != nil {
+		return err
+	}
+	if !useHosts {
+		// nb. mirrors set by a previous run are left in place, because the endpoint does not change
+		if n.Command("grep", "-qF", fmt.Sprintf("registry.mirrors.%q]", registry), "/etc/containerd/config.toml").Silent().Run() == nil {
+			return nil
+		}
+		if err := addConfigRegistryMirrors(n, map[string][]string{registry: {mirror}}); err != nil {
+			return errors.Wrapf(err, "failed to configure registry %s on node %s", registry, n.Name())
+		}
+		return nil
+	}
+
+	hosts := fmt.Sprintf("server = %q\n\n[host.%q]\n  capabilities = [\"pull\", \"resolve\"]\n", server, mirror)
+
+	dir := path.Join(containerdRegistryConfigPath, registry)
+	if err := n.Command("mkdir", "-p", dir).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s on node %s", dir, n.Name())
+	}
+	if err := n.WriteFile(path.Join(dir, "hosts.toml"), []byte(hosts)); err != nil {
+		return err
+	}
+
+	if err := enableRegistryConfigPath(n); err != nil {
+		return errors.Wrapf(err, "failed to configure registry %s on node %s", registry, n.Name())
+	}
+	return nil
+}
+
+// enableRegistryConfigPath sets the config_path in the containerd config, if not already set, and restarts containerd
+func enableRegistryConfigPath(n *status.Node) error {
+	script := fmt.Sprintf(`set -e
+if ! grep -q config_path /etc/containerd/config.toml; then
+  if grep -q '^\[plugins."io.containerd.grpc.v1.cri".registry\]' /etc/containerd/config.toml; then
+    sed -i '/^\[plugins."io.containerd.grpc.v1.cri".registry\]/a \  config_path = "%[1]s"' /etc/containerd/config.toml
+  else
+    printf '\n[plugins."io.containerd.grpc.v1.cri".registry]\n  config_path = "%[1]s"\n' >> /etc/containerd/config.toml
+  fi
+fi
+systemctl restart containerd`, containerdRegistryConfigPath)
+	return n.Command("bash", "-c", script).Silent().Run()
+}
+
+// addConfigRegistryMirrors adds a registry.mirrors table with the given endpoints for each registry to the containerd
+// config, using the plugin name of the config version, and restarts containerd; it fails if the config already
+// defines mirrors for one of the registries, instead of adding a duplicated TOML table
+func addConfigRegistryMirrors(n *status.Node, endpoints map[string][]string) error {
+	script := `set -e
+config=/etc/containerd/config.toml
+plugin=cri
+if grep -qE '^ *version *= *2' $config; then plugin='"io.containerd.grpc.v1.cri"'; fi
+`
+	for _, r := range util.SortedRegistries(endpoints) {
+		quoted := make([]string, 0, len(endpoints[r]))
+		for _, e := range endpoints[r] {
+			quoted = append(quoted, fmt.Sprintf("%q", e))
+		}
+		script += fmt.Sprintf(`if grep -qF 'registry.mirrors."%[1]s"]' $config; then
+  echo "registry mirrors for %[1]s are already defined in $config" >&2
+  exit 1
+fi
+printf '\n[plugins.%%s.registry.mirrors."%[1]s"]\n  endpoint = [%[2]s]\n' "$plugin" >> $config
+`, r, strings.Join(quoted, ", "))
+	}
+	script += "systemctl restart containerd"
+	return n.Command("bash", "-c", script).Silent().Run()
+}
+
+// ConfigureCgroupDriver configures the containerd runtime that exists inside a kind(er) node for using the
+// given cgroup driver, setting SystemdCgroup in the runc options of the containerd config, and then restarts containerd.
+// Runtime options require containerd v1.3 or greater and a runc runtime (io.containerd.runc.v1 or io.containerd.runc.v2),
+// because the legacy io.containerd.runtime.v1.linux runtime ignores them; the effective setting is checked after restart
+func ConfigureCgroupDriver(n *status.Node, driver status.CgroupDriver) error {
+	current, err := GetCgroupDriver(n)
+	if err != nil {
+		return err
+	}
+	if current == driver {
+		return nil
+	}
+
+	version, err := containerdVersion(n)
+	if err != nil {
+		return err
+	}
+	if version.LessThan(v1_3) {
+		return errors.Errorf("the %s cgroup driver can't be configured on node %s, it requires containerd v1.3 or greater, got %s", driver, n.Name(), version)
+	}
+	config, err := effectiveCRIConfig(n)
+	if err != nil {
+		return err
+	}
+	if runtimeType := config.defaultRuntime().RuntimeType; runtimeType != "io.containerd.runc.v1" && runtimeType != "io.containerd.runc.v2" {
+		return errors.Errorf("the %s cgroup driver can't be configured on node %s, it requires the io.containerd.runc.v1 or io.containerd.runc.v2 runtime, got %q", driver, n.Name(), runtimeType)
+	}
+
+	// sets SystemdCgroup in the existing runc options table, using the plugin name of the config version
+	// (plugins."io.containerd.grpc.v1.cri" with version 2, plugins.cri otherwise); a new table is added only
+	// if the file does not define it. Before restarting, the config is validated and restored in case of errors
+	script := fmt.Sprintf(`set -e
+config=/etc/containerd/config.toml
+cp $config $config.kinder-backup
+options='^ *\[plugins\.("io\.containerd\.grpc\.v1\.cri"|cri)\.containerd\.runtimes\.runc\.options\]'
+if grep -q 'SystemdCgroup' $config; then
+  sed -i 's/SystemdCgroup *=.*/SystemdCgroup = %[1]t/' $config
+elif grep -qE "$options" $config; then
+  sed -i -E "/$options/a \  SystemdCgroup = %[1]t" $config
+elif grep -qE '^ *version *= *2' $config; then
+  printf '\n[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc.options]\n  SystemdCgroup = %[1]t\n' >> $config
+else
+  printf '\n[plugins.cri.containerd.runtimes.runc.options]\n  SystemdCgroup = %[1]t\n' >> $config
+fi
+if ! containerd config dump >/dev/null; then
+  mv $config.kinder-backup $config
+  exit 1
+fi
+rm -f $config.kinder-backup
+systemctl restart containerd`, driver == status.SystemdCgroupDriver)
+	if err := n.Command("bash", "-c", script).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to configure the %s cgroup driver on node %s", driver, n.Name())
+	}
+
+	current, err = GetCgroupDriver(n)
+	if err != nil {
+		return err
+	}
+	if current != driver {
+		return errors.Errorf("failed to configure the %s cgroup driver on node %s, containerd uses the %s cgroup driver", driver, n.Name(), current)
+	}
+	return nil
+}
+
+// GetCgroupDriver returns the cgroup driver used by the containerd runtime that exists inside a kind(er) node,
+// reading the effective CRI config; containerd uses the cgroupfs driver unless SystemdCgroup is set to true
+// in the options of the default runtime or, for the legacy io.containerd.runtime.v1.linux runtime, systemd_cgroup is set
+func GetCgroupDriver(n *status.Node) (status.CgroupDriver, error) {
+	config, err := effectiveCRIConfig(n)
+	if err != nil {
+		return "", err
+	}
+	if systemd, ok := config.defaultRuntime().Options["SystemdCgroup"].(bool); ok && systemd {
+		return status.SystemdCgroupDriver, nil
+	}
+	if config.SystemdCgroup {
+		return status.SystemdCgroupDriver, nil
+	}
+	return status.CgroupfsCgroupDriver, nil
+}
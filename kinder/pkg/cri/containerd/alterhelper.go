@@ -26,6 +26,8 @@ import (
 // PreLoadInitImages preload images required by kubeadm-init into the containerd runtime installed that exists inside a kind(er) node
 func PreLoadInitImages(bc *bits.BuildContext) error {
 	// NB. this code is an extract from "sigs.k8s.io/kind/pkg/build/node"
+	// images imported at alter time are committed into the node image, and docker copies them into the
+	// /var/lib/containerd volume when nodes are created, so they are not imported again at create time
 
 	return bc.RunInContainer(
 		"bash", "-c",
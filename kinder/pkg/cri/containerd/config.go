@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerd
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+var (
+	// v1_3 is the first containerd version supporting runtime options, e.g. SystemdCgroup
+	v1_3 = K8sVersion.MustParseGeneric("v1.3.0")
+
+	// v1_5 is the first containerd version supporting the registry config_path, that is hosts.toml files
+	v1_5 = K8sVersion.MustParseGeneric("v1.5.0")
+)
+
+// containerdVersion returns the version of the containerd binary installed in a kind(er) node
+func containerdVersion(n *status.Node) (*K8sVersion.Version, error) {
+	lines, err := n.Command(
+		"containerd", "--version",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the containerd version on node %s", n.Name())
+	}
+
+	// the output is in the form containerd github.com/containerd/containerd v1.6.8 9cd3357b7fd7218e4aec3eae239db1f68a5a6ec6
+	if len(lines) == 1 {
+		for _, f := range strings.Fields(lines[0]) {
+			if v, err := K8sVersion.ParseGeneric(f); err == nil {
+				return v, nil
+			}
+		}
+	}
+	return nil, errors.Errorf("failed to parse the containerd version on node %s: %s", n.Name(), strings.Join(lines, "\n"))
+}
+
+// criRuntime defines the settings of a runtime in the effective CRI plugin config
+type criRuntime struct {
+	RuntimeType string                 `json:"runtimeType"`
+	Options     map[string]interface{} `json:"options"`
+}
+
+// criConfig defines the settings of the effective CRI plugin config used by kinder, as reported by crictl info
+type criConfig struct {
+	Containerd struct {
+		DefaultRuntimeName string                `json:"defaultRuntimeName"`
+		DefaultRuntime     criRuntime            `json:"defaultRuntime"`
+		Runtimes           map[string]criRuntime `json:"runtimes"`
+	} `json:"containerd"`
+	SystemdCgroup bool `json:"systemdCgroup"`
+	Registry      struct {
+		ConfigPath string                 `json:"configPath"`
+		Mirrors    map[string]interface{} `json:"mirrors"`
+	} `json:"registry"`
+}
+
+// defaultRuntime returns the default runtime in the effective CRI plugin config
+func (c *criConfig) defaultRuntime() criRuntime {
+	if r, ok := c.Containerd.Runtimes[c.Containerd.DefaultRuntimeName]; ok && c.Containerd.DefaultRuntimeName != "" {
+		return r
+	}
+	return c.Containerd.DefaultRuntime
+}
+
+// effectiveCRIConfig returns the effective CRI plugin config of the containerd runtime that exists inside a kind(er) node,
+// as reported by crictl info; it retries until the container runtime is ready
+func effectiveCRIConfig(n *status.Node) (*criConfig, error) {
+	var lines []string
+	var err error
+	for until := time.Now().Add(30 * time.Second); ; time.Sleep(time.Second) {
+		if lines, err = n.Command("crictl", "info").Silent().RunAndCapture(); err == nil || time.Now().After(until) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the containerd CRI config on node %s", n.Name())
+	}
+
+	var info struct {
+		Config criConfig `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &info); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the containerd CRI config on node %s", n.Name())
+	}
+	return &info.Config, nil
+}
+
+// registryHostsSupported returns true if the containerd runtime that exists inside a kind(er) node supports
+// hosts.toml files, that requires containerd v1.5 or greater and a config without registry mirrors, because
+// containerd refuses to start if both config_path and mirrors are set
+func registryHostsSupported(n *status.Node) (bool, error) {
+	version, err := containerdVersion(n)
+	if err != nil {
+		return false, err
+	}
+	if version.LessThan(v1_5) {
+		return false, nil
+	}
+
+	config, err := effectiveCRIConfig(n)
+	if err != nil {
+		return false, err
+	}
+	return len(config.Registry.Mirrors) == 0, nil
+}
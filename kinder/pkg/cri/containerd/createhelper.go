@@ -22,13 +22,13 @@ import (
 )
 
 // CreateNode creates a container that internally hosts the containerd cri runtime
-func CreateNode(cluster, name, image, role string, volumes []string) error {
+func CreateNode(cluster, name, image, role string, volumes []string, ipv6 bool, apiServerPort int32, resources util.NodeResources) error {
 	args, err := util.CommonArgs(cluster, name, role)
 	if err != nil {
 		return err
 	}
 
-	args, err = util.RunArgsForNode(role, volumes, args)
+	args, err = util.RunArgsForNode(role, volumes, ipv6, apiServerPort, resources, args)
 	if err != nil {
 		return err
 	}
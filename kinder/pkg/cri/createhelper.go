@@ -17,15 +17,20 @@ limitations under the License.
 package cri
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/cri/containerd"
+	"k8s.io/kubeadm/kinder/pkg/cri/crio"
 	"k8s.io/kubeadm/kinder/pkg/cri/docker"
 	"k8s.io/kubeadm/kinder/pkg/cri/util"
 	"k8s.io/kubeadm/kinder/pkg/exec"
 	"k8s.io/kubeadm/kinder/third_party/kind/loadbalancer"
+	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
 )
 
 // CreateHelper provides CRI specific methods for node create
@@ -40,13 +45,17 @@ func NewCreateHelper(cri status.ContainerRuntime) (*CreateHelper, error) {
 	}, nil
 }
 
-// CreateNode creates a container that internally hosts the selected cri runtime
-func (h *CreateHelper) CreateNode(cluster, name, image, role string, volumes []string) error {
+// CreateNode creates a container that internally hosts the selected cri runtime;
+// if ipv6 is set, IPv6 is enabled in the container. For control-plane nodes, apiServerPort is the port on the host
+// where the API server is exposed; if 0, a free port is used. Resources are the CPU and memory limits of the container
+func (h *CreateHelper) CreateNode(cluster, name, image, role string, volumes []string, ipv6 bool, apiServerPort int32, resources util.NodeResources) error {
 	switch h.cri {
 	case status.ContainerdRuntime:
-		return containerd.CreateNode(cluster, name, image, role, volumes)
+		return containerd.CreateNode(cluster, name, image, role, volumes, ipv6, apiServerPort, resources)
+	case status.CRIORuntime:
+		return crio.CreateNode(cluster, name, image, role, volumes, ipv6, apiServerPort, resources)
 	case status.DockerRuntime:
-		return docker.CreateNode(cluster, name, image, role, volumes)
+		return docker.CreateNode(cluster, name, image, role, volumes, ipv6, apiServerPort, resources)
 	}
 	return errors.Errorf("unknown cri: %s", h.cri)
 }
@@ -71,15 +80,67 @@ func (h *CreateHelper) CreateExternalEtcd(cluster, name, image string) error {
 	return exec.NewHostCmd("docker", args...).Run()
 }
 
-// CreateExternalLoadBalancer creates a container hosting an external load balancer
-func (h *CreateHelper) CreateExternalLoadBalancer(cluster, name string) error {
+// CreateExternalEtcdCluster creates containers hosting the members of a multi-member, insecure, external etcd cluster
+func (h *CreateHelper) CreateExternalEtcdCluster(cluster string, names []string, image string) error {
+	// creates the containers; etcd will start only after the etcd args are written into each container
+	for _, name := range names {
+		args, err := util.CommonArgs(cluster, name, constants.ExternalEtcdNodeRoleValue)
+		if err != nil {
+			return err
+		}
+
+		// Add etcd member run args
+		args = util.RunArgsForExternalEtcd(args)
+		args = util.RunArgsForExternalEtcdMember(args)
+
+		// Specify the image to run
+		args = append(args, image)
+
+		// Add container args for waiting for the etcd args
+		args = util.ContainerArgsForExternalEtcdMember(args)
+
+		if err := exec.NewHostCmd("docker", args...).Run(); err != nil {
+			return err
+		}
+	}
+
+	// gets the member IPs, that are required for building the list of peers in the etcd cluster
+	ips := map[string]string{}
+	var initialCluster []string
+	for _, name := range names {
+		lines, err := kinddocker.Inspect(name, "{{range .NetworkSettings.Networks}}{{.IPAddress}}{{end}}")
+		if err != nil {
+			return errors.Wrapf(err, "failed to get IP for node: %s", name)
+		}
+		if len(lines) != 1 || lines[0] == "" {
+			return errors.Errorf("failed to get IP for node: %s", name)
+		}
+		ips[name] = lines[0]
+		initialCluster = append(initialCluster, fmt.Sprintf("%s=http://%s:2380", name, lines[0]))
+	}
+
+	// writes the etcd args into each container, thus starting the etcd members
+	for _, name := range names {
+		etcdArgs := util.EtcdArgsForExternalEtcdMember(cluster, name, ips[name], initialCluster)
+		script := fmt.Sprintf("echo '%[1]s' > %[2]s.tmp && mv %[2]s.tmp %[2]s", strings.Join(etcdArgs, " "), util.ExternalEtcdMemberArgsFile)
+		if err := exec.NewHostCmd("docker", "exec", name, "sh", "-c", script).Run(); err != nil {
+			return errors.Wrapf(err, "failed to start etcd on node: %s", name)
+		}
+	}
+
+	return nil
+}
+
+// CreateExternalLoadBalancer creates a container hosting an external load balancer; hostPort is the port on
+// the host where the control-plane endpoint is exposed, if 0, a free port is used
+func (h *CreateHelper) CreateExternalLoadBalancer(cluster, name string, hostPort int32) error {
 	args, err := util.CommonArgs(cluster, name, constants.ExternalLoadBalancerNodeRoleValue)
 	if err != nil {
 		return err
 	}
 
 	// Add load balancer run args
-	args, err = util.RunArgsForExternalLoadBalancer(args)
+	args, err = util.RunArgsForExternalLoadBalancer(hostPort, args)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,201 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crio
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri/util"
+)
+
+// PreLoadUpgradeImages preload images required by kubeadm-upgrade into the CRI-O runtime that exists inside a kind(er) node
+func PreLoadUpgradeImages(n *status.Node, srcFolder string) error {
+	// NB. podman shares the image store with CRI-O
+	return n.Command(
+		"/bin/bash", "-c",
+		`find `+srcFolder+` -name *.tar -print0 | xargs -0 -n 1 -P $(nproc) podman load -i`,
+	).Silent().Run()
+}
+
+// LoadImage loads an image tarball into the CRI-O runtime that exists inside a kind(er) node
+func LoadImage(n *status.Node, tarball string) error {
+	// NB. podman shares the image store with CRI-O
+	return n.Command(
+		"podman", "load", "-i", tarball,
+	).Silent().Run()
+}
+
+// RestartStaticPods restarts the containers of the given static pods in the CRI-O runtime that exists inside a kind(er) node;
+// containers are stopped, and then restarted by the kubelet
+func RestartStaticPods(n *status.Node, pods ...string) error {
+	for _, p := range pods {
+		if err := n.Command(
+			"bash", "-c",
+			fmt.Sprintf("crictl ps -q --name '^%s$' | xargs -r crictl stop", p),
+		).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to restart %s on %s", p, n.Name())
+		}
+	}
+	return nil
+}
+
+// GetRunningContainers returns the IDs of the Kubernetes containers running in the CRI-O runtime that exists inside a kind(er) node
+func GetRunningContainers(n *status.Node) ([]string, error) {
+	containers, err := n.Command(
+		"crictl", "ps", "-q",
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read running containers from %s", n.Name())
+	}
+
+	return containers, nil
+}
+
+// GetImages returns the list of images available in the node
+func GetImages(n *status.Node) ([]string, error) {
+	current, err := n.Command(
+		"podman", "images", `--format="{{.Repository}}:{{.Tag}}"`,
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read current images from %s", n.Name())
+	}
+
+	for i := range current {
+		current[i], _ = strconv.Unquote(current[i])
+	}
+
+	return current, nil
+}
+
+// GetImageID returns the ID of an image available in the node, that is the digest of the image config
+func GetImageID(n *status.Node, image string) (string, error) {
+	lines, err := n.Command(
+		"crictl", "inspecti", "-o", "json", image,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to inspect image %s on %s", image, n.Name())
+	}
+
+	var inspect struct {
+		Status struct {
+			ID string `json:"id"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &inspect); err != nil {
+		return "", errors.Wrapf(err, "failed to parse the inspect output of image %s on %s", image, n.Name())
+	}
+	return inspect.Status.ID, nil
+}
+
+// crioRegistryMirrorsConfig is the registries.conf drop-in file where kinder writes registry mirrors
+const crioRegistryMirrorsConfig = "/etc/containers/registries.conf.d/99-kinder-mirrors.conf"
+
+// ConfigureRegistryMirrors configures the CRI-O runtime that exists inside a kind(er) node for pulling
+// images through mirrors, writing a registries.conf drop-in file, and then restarts CRI-O
+func ConfigureRegistryMirrors(n *status.Node, mirrors map[string][]string) error {
+	config := ""
+	for _, r := range util.SortedRegistries(mirrors) {
+		location, _ := util.MirrorHost(util.RegistryServer(r))
+		config += fmt.Sprintf("[[registry]]\nprefix = %q\nlocation = %q\n", r, location)
+		for _, m := range mirrors[r] {
+			host, insecure := util.MirrorHost(m)
+			config += fmt.Sprintf("\n[[registry.mirror]]\nlocation = %q\ninsecure = %t\n", host, insecure)
+		}
+		config += "\n"
+	}
+
+	if err := n.Command("mkdir", "-p", path.Dir(crioRegistryMirrorsConfig)).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s on node %s", path.Dir(crioRegistryMirrorsConfig), n.Name())
+	}
+	if err := n.WriteFile(crioRegistryMirrorsConfig, []byte(config)); err != nil {
+		return err
+	}
+	if err := n.Command("systemctl", "restart", "crio").Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to restart crio on node %s", n.Name())
+	}
+	return nil
+}
+
+// crioInsecureRegistryConfig is the registries.conf drop-in file where kinder writes the insecure registry
+const crioInsecureRegistryConfig = "/etc/containers/registries.conf.d/98-kinder-registry.conf"
+
+// ConfigureInsecureRegistry configures the CRI-O runtime that exists inside a kind(er) node for pulling
+// images of a registry from an endpoint using http, writing a registries.conf drop-in file, and then restarts CRI-O
+func ConfigureInsecureRegistry(n *status.Node, registry, endpoint string) error {
+	config := fmt.Sprintf("[[registry]]\nprefix = %q\nlocation = %q\ninsecure = true\n", registry, endpoint)
+
+	if err := n.Command("mkdir", "-p", path.Dir(crioInsecureRegistryConfig)).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s on node %s", path.Dir(crioInsecureRegistryConfig), n.Name())
+	}
+	if err := n.WriteFile(crioInsecureRegistryConfig, []byte(config)); err != nil {
+		return err
+	}
+	if err := n.Command("systemctl", "restart", "crio").Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to restart crio on node %s", n.Name())
+	}
+	return nil
+}
+
+// crioCgroupDriverConfig is the crio.conf drop-in file where kinder writes the cgroup driver
+const crioCgroupDriverConfig = "/etc/crio/crio.conf.d/99-kinder-cgroup-driver.conf"
+
+// ConfigureCgroupDriver configures the CRI-O runtime that exists inside a kind(er) node for using the
+// given cgroup driver, writing a crio.conf drop-in file, and then restarts CRI-O
+func ConfigureCgroupDriver(n *status.Node, driver status.CgroupDriver) error {
+	// nb. with cgroupfs, conmon can't be placed in a systemd slice
+	conmonCgroup := "system.slice"
+	if driver == status.CgroupfsCgroupDriver {
+		conmonCgroup = "pod"
+	}
+	config := fmt.Sprintf("[crio.runtime]\ncgroup_manager = %q\nconmon_cgroup = %q\n", driver, conmonCgroup)
+
+	if err := n.Command("mkdir", "-p", path.Dir(crioCgroupDriverConfig)).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s on node %s", path.Dir(crioCgroupDriverConfig), n.Name())
+	}
+	if err := n.WriteFile(crioCgroupDriverConfig, []byte(config)); err != nil {
+		return err
+	}
+	if err := n.Command("systemctl", "restart", "crio").Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to restart crio on node %s", n.Name())
+	}
+	return nil
+}
+
+// GetCgroupDriver returns the cgroup driver used by the CRI-O runtime that exists inside a kind(er) node,
+// that is the last cgroup_manager set in crio.conf and in the drop-in files; CRI-O defaults to systemd
+func GetCgroupDriver(n *status.Node) (status.CgroupDriver, error) {
+	lines, err := n.Command(
+		"sh", "-c", "cat /etc/crio/crio.conf /etc/crio/crio.conf.d/*.conf 2>/dev/null | grep -E '^ *cgroup_manager *=' || true",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the crio config on node %s", n.Name())
+	}
+	if len(lines) == 0 {
+		return status.SystemdCgroupDriver, nil
+	}
+	value := strings.TrimSpace(strings.SplitN(lines[len(lines)-1], "=", 2)[1])
+	return status.CgroupDriver(strings.Trim(value, `"'`)), nil
+}
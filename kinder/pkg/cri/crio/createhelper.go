@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crio
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cri/util"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
+)
+
+// CreateNode creates a container that internally hosts the CRI-O cri runtime
+func CreateNode(cluster, name, image, role string, volumes []string, ipv6 bool, apiServerPort int32, resources util.NodeResources) error {
+	args, err := util.CommonArgs(cluster, name, role)
+	if err != nil {
+		return err
+	}
+
+	args, err = util.RunArgsForNode(role, volumes, ipv6, apiServerPort, resources, args)
+	if err != nil {
+		return err
+	}
+
+	// Add run args for CRI-O
+	args = append(args, "--entrypoint=/usr/local/bin/entrypoint")
+
+	// Specify the image to run
+	args = append(args, image)
+
+	// Add container args for CRI-O
+	args = append(args, "/sbin/init")
+
+	// creates the container
+	if err := exec.NewHostCmd("docker", args...).Run(); err != nil {
+		return err
+	}
+
+	// Deletes the machine-id embedded in the node image and regenerate a new one.
+	// This is necessary because both kubelet and other components like weave net
+	// use machine-id internally to distinguish nodes.
+	if err := fixMachineID(name); err != nil {
+		return err
+	}
+
+	// we need to change a few mounts once we have the container
+	if err := fixMounts(name); err != nil {
+		return err
+	}
+
+	// signal the node container entrypoint to continue booting into systemd
+	if err := kinddocker.Kill("SIGUSR1", name); err != nil {
+		return err
+	}
+
+	// wait for CRI-O to be ready
+	if !waitForCRIO(name, time.Now().Add(time.Second*30)) {
+		return errors.Errorf("timed out waiting for CRI-O to be ready on node %s", name)
+	}
+
+	return nil
+}
+
+func fixMachineID(name string) error {
+	if err := exec.NewNodeCmd(name, "rm", "-f", "/etc/machine-id").Silent().Run(); err != nil {
+		return errors.Wrap(err, "machine-id-setup error")
+	}
+	if err := exec.NewNodeCmd(name, "systemd-machine-id-setup").Silent().Run(); err != nil {
+		return errors.Wrap(err, "machine-id-setup error")
+	}
+	return nil
+}
+
+// fixMounts will correct mounts in the node container to meet the right
+// sharing and permissions for systemd and CRI-O / Kubernetes
+func fixMounts(name string) error {
+	// systemd-in-a-container should have read only /sys
+	// https://www.freedesktop.org/wiki/Software/systemd/ContainerInterface/
+	if err := exec.NewNodeCmd(name, "mount", "-o", "remount,ro", "/sys").Silent().Run(); err != nil {
+		return err
+	}
+	// kubernetes needs shared mount propagation
+	for _, m := range []string{"/", "/run", "/var/lib/containers"} {
+		if err := exec.NewNodeCmd(name, "mount", "--make-shared", m).Silent().Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForCRIO waits for CRI-O to be ready on the node
+// it returns true on success, and false on a timeout
+func waitForCRIO(name string, until time.Time) bool {
+	for until.After(time.Now()) {
+		out, err := exec.NewNodeCmd(name, "systemctl", "is-active", "crio").Silent().RunAndCapture()
+		if err == nil && len(out) == 1 && out[0] == "active" {
+			return true
+		}
+		time.Sleep(time.Second)
+	}
+	return false
+}
@@ -17,11 +17,16 @@ limitations under the License.
 package docker
 
 import (
+	"encoding/json"
+	"fmt"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri/util"
 )
 
 // PreLoadUpgradeImages preload images required by kubeadm-upgrade into the docker runtime that exists inside a kind(er) node
@@ -32,6 +37,40 @@ func PreLoadUpgradeImages(n *status.Node, srcFolder string) error {
 	).Silent().Run()
 }
 
+// LoadImage loads an image tarball into the docker runtime that exists inside a kind(er) node
+func LoadImage(n *status.Node, tarball string) error {
+	return n.Command(
+		"docker", "load", "-i", tarball,
+	).Silent().Run()
+}
+
+// RestartStaticPods restarts the containers of the given static pods in the docker runtime that exists inside a kind(er) node;
+// containers are killed, and then restarted by the kubelet
+func RestartStaticPods(n *status.Node, pods ...string) error {
+	for _, p := range pods {
+		if err := n.Command(
+			"/bin/bash", "-c",
+			fmt.Sprintf("docker ps -q --filter label=io.kubernetes.container.name=%s | xargs -r docker kill", p),
+		).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to restart %s on %s", p, n.Name())
+		}
+	}
+	return nil
+}
+
+// GetRunningContainers returns the names of the Kubernetes containers running in the docker runtime that exists inside a kind(er) node
+func GetRunningContainers(n *status.Node) ([]string, error) {
+	containers, err := n.Command(
+		"docker", "ps", "--filter", "label=io.kubernetes.pod.name", "--format", "{{.Names}}",
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read running containers from %s", n.Name())
+	}
+
+	return containers, nil
+}
+
 // GetImages returns the list of images available in the node
 func GetImages(n *status.Node) ([]string, error) {
 	current, err := n.Command(
@@ -48,3 +87,139 @@ func GetImages(n *status.Node) ([]string, error) {
 
 	return current, nil
 }
+
+// GetImageID returns the ID of an image available in the node, that is the digest of the image config
+func GetImageID(n *status.Node, image string) (string, error) {
+	lines, err := n.Command(
+		"docker", "image", "inspect", "--format={{.Id}}", image,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to inspect image %s on %s", image, n.Name())
+	}
+	if len(lines) != 1 {
+		return "", errors.Errorf("failed to inspect image %s on %s, unexpected output: %s", image, n.Name(), strings.Join(lines, "\n"))
+	}
+	return lines[0], nil
+}
+
+// dockerDaemonConfig is the docker daemon configuration file inside a kind(er) node
+const dockerDaemonConfig = "/etc/docker/daemon.json"
+
+// ConfigureRegistryMirrors configures the docker runtime that exists inside a kind(er) node for pulling
+// images through mirrors, setting registry-mirrors in the daemon.json file, and then restarts docker.
+// Please note that docker supports mirrors only for Docker Hub
+func ConfigureRegistryMirrors(n *status.Node, mirrors map[string][]string) error {
+	for _, r := range util.SortedRegistries(mirrors) {
+		if r != util.DockerHubRegistry {
+			return errors.Errorf("docker supports registry mirrors only for %s, got %s", util.DockerHubRegistry, r)
+		}
+	}
+
+	config, err := readDaemonConfig(n)
+	if err != nil {
+		return err
+	}
+	config["registry-mirrors"] = mirrors[util.DockerHubRegistry]
+
+	// nb. mirrors using http must be listed as insecure registries
+	var insecure []string
+	for _, m := range mirrors[util.DockerHubRegistry] {
+		if host, ok := util.MirrorHost(m); ok {
+			insecure = append(insecure, host)
+		}
+	}
+	if len(insecure) > 0 {
+		config["insecure-registries"] = insecure
+	}
+
+	return writeDaemonConfigAndRestart(n, config)
+}
+
+// ConfigureInsecureRegistry configures the docker runtime that exists inside a kind(er) node for pulling
+// images from an endpoint using http, adding it to insecure-registries in the daemon.json file, and then restarts docker.
+// Please note that docker supports mirrors only for Docker Hub, so images of the registry can't be pulled through
+// the endpoint, and images must be pulled from the endpoint directly
+func ConfigureInsecureRegistry(n *status.Node, registry, endpoint string) error {
+	config, err := readDaemonConfig(n)
+	if err != nil {
+		return err
+	}
+
+	// preserves insecure registries already configured, e.g. for registry mirrors
+	insecure := []interface{}{}
+	if current, ok := config["insecure-registries"].([]interface{}); ok {
+		insecure = current
+	}
+	for _, r := range insecure {
+		if r == endpoint {
+			return nil
+		}
+	}
+	config["insecure-registries"] = append(insecure, endpoint)
+
+	return writeDaemonConfigAndRestart(n, config)
+}
+
+// readDaemonConfig reads the existing docker daemon configuration on a node, if any
+func readDaemonConfig(n *status.Node) (map[string]interface{}, error) {
+	config := map[string]interface{}{}
+	if lines, err := n.Command("cat", dockerDaemonConfig).Silent().RunAndCapture(); err == nil && len(lines) > 0 {
+		if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &config); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s on node %s", dockerDaemonConfig, n.Name())
+		}
+	}
+	return config, nil
+}
+
+// writeDaemonConfigAndRestart writes the docker daemon configuration on a node, and then restarts docker
+func writeDaemonConfigAndRestart(n *status.Node, config map[string]interface{}) error {
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode %s", dockerDaemonConfig)
+	}
+	if err := n.Command("mkdir", "-p", path.Dir(dockerDaemonConfig)).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s on node %s", path.Dir(dockerDaemonConfig), n.Name())
+	}
+	if err := n.WriteFile(dockerDaemonConfig, content); err != nil {
+		return err
+	}
+	if err := n.Command("systemctl", "restart", "docker").Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to restart docker on node %s", n.Name())
+	}
+	return nil
+}
+
+// ConfigureCgroupDriver configures the docker runtime that exists inside a kind(er) node for using the
+// given cgroup driver, setting native.cgroupdriver in exec-opts in the daemon.json file, and then restarts docker
+func ConfigureCgroupDriver(n *status.Node, driver status.CgroupDriver) error {
+	config, err := readDaemonConfig(n)
+	if err != nil {
+		return err
+	}
+
+	// preserves exec-opts other than the cgroup driver, if any
+	execOpts := []interface{}{}
+	if current, ok := config["exec-opts"].([]interface{}); ok {
+		for _, o := range current {
+			if s, ok := o.(string); ok && strings.HasPrefix(s, "native.cgroupdriver=") {
+				continue
+			}
+			execOpts = append(execOpts, o)
+		}
+	}
+	config["exec-opts"] = append(execOpts, fmt.Sprintf("native.cgroupdriver=%s", driver))
+
+	return writeDaemonConfigAndRestart(n, config)
+}
+
+// GetCgroupDriver returns the cgroup driver used by the docker runtime that exists inside a kind(er) node
+func GetCgroupDriver(n *status.Node) (status.CgroupDriver, error) {
+	lines, err := n.Command("docker", "info", "--format", "{{.CgroupDriver}}").Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the docker cgroup driver on node %s", n.Name())
+	}
+	if len(lines) != 1 {
+		return "", errors.Errorf("docker cgroup driver should only be one line, got %d lines", len(lines))
+	}
+	return status.CgroupDriver(strings.TrimSpace(lines[0])), nil
+}
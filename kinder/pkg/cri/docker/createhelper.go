@@ -20,7 +20,6 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 
 	"k8s.io/kubeadm/kinder/pkg/cri/util"
 	"k8s.io/kubeadm/kinder/pkg/exec"
@@ -28,13 +27,13 @@ import (
 )
 
 // CreateNode creates a container that internally hosts the docker cri runtime
-func CreateNode(cluster, name, image, role string, volumes []string) error {
+func CreateNode(cluster, name, image, role string, volumes []string, ipv6 bool, apiServerPort int32, resources util.NodeResources) error {
 	args, err := util.CommonArgs(cluster, name, role)
 	if err != nil {
 		return err
 	}
 
-	args, err = util.RunArgsForNode(role, volumes, args)
+	args, err = util.RunArgsForNode(role, volumes, ipv6, apiServerPort, resources, args)
 	if err != nil {
 		return err
 	}
@@ -77,9 +76,6 @@ func CreateNode(cluster, name, image, role string, volumes []string) error {
 		return errors.Errorf("timed out waiting for docker to be ready on node %s", name)
 	}
 
-	// load the docker image artifacts into the docker daemon
-	loadImages(name)
-
 	return nil
 }
 
@@ -173,16 +169,3 @@ func tryUntil(until time.Time, try func() bool) bool {
 	}
 	return false
 }
-
-// loadImages loads image tarballs stored on the node into docker on the node
-func loadImages(name string) {
-	// load images cached on the node into docker
-	if err := exec.NewNodeCmd(name,
-		"/bin/bash", "-c",
-		// use xargs to load images in parallel
-		`find /kind/images -name *.tar -print0 | xargs -0 -n 1 -P $(nproc) docker load -i`,
-	).Silent().Run(); err != nil {
-		log.Warningf("Failed to preload docker images: %v", err)
-		return
-	}
-}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cri
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ImagesInArchive returns the images stored in an image tarball created with docker save, that is a map
+// of image tag to image ID; the image ID is the digest of the image config, that is the same ID reported
+// by container runtimes for loaded images, so it can be used for detecting images rebuilt with the same tag
+func ImagesInArchive(tarball string) (map[string]string, error) {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open image archive %s", tarball)
+	}
+	defer f.Close()
+
+	r := tar.NewReader(f)
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			return nil, errors.Errorf("manifest.json not found in image archive %s", tarball)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read image archive %s", tarball)
+		}
+		if path.Clean(h.Name) != "manifest.json" {
+			continue
+		}
+
+		var manifest []struct {
+			Config   string
+			RepoTags []string
+		}
+		if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse manifest.json in image archive %s", tarball)
+		}
+
+		images := map[string]string{}
+		for _, m := range manifest {
+			// the image config is stored as <digest>.json by docker save, or as blobs/sha256/<digest>
+			// in archives using the OCI layout
+			id := "sha256:" + strings.TrimSuffix(path.Base(m.Config), ".json")
+			for _, t := range m.RepoTags {
+				images[t] = id
+			}
+		}
+		return images, nil
+	}
+}
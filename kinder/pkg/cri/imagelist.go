@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cri
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
+)
+
+// ImagesInImage returns the container runtime installed in a node image and the sorted list of images bundled
+// into the node image, including both image tarballs in the /kind/images folder and images already imported
+// into the container runtime store (e.g. for node images built with kind)
+func ImagesInImage(image string) (status.ContainerRuntime, []string, error) {
+	// creates a temporary container from the node image;
+	// NB. the container is privileged, because the container runtime is started for reading the image store
+	id := "kinder-images-" + uuid.New().String()
+	if err := kinddocker.Run(
+		image,
+		kinddocker.WithRunArgs(
+			"-d", // make the client exit while the container continues to run
+			"--privileged",
+			"--entrypoint=sleep",
+			"--name="+id,
+		),
+		kinddocker.WithContainerArgs(
+			"infinity", // sleep infinitely to keep the container around
+		),
+	); err != nil {
+		return "", nil, errors.Wrap(err, "error creating a temporary container for reading images")
+	}
+	defer func() {
+		exec.NewHostCmd("docker", "rm", "-f", id).Run()
+	}()
+
+	runtime, err := status.InspectCRIinContainer(id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	n, err := status.NewNode(id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	images := map[string]bool{}
+
+	// gets images in the container runtime store
+	stored, err := imagesInStore(n, runtime)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, i := range stored {
+		images[i] = true
+	}
+
+	// gets images in image tarballs
+	tarballs, err := imageTarballs(n, "/kind/images")
+	if err != nil {
+		return "", nil, err
+	}
+	for _, t := range tarballs {
+		for _, i := range imageTagsInTarball(n, t) {
+			images[i] = true
+		}
+	}
+
+	var list []string
+	for i := range images {
+		list = append(list, i)
+	}
+	sort.Strings(list)
+
+	return runtime, list, nil
+}
+
+// imagesInStore returns the images in the container runtime store of a stopped node
+func imagesInStore(n *status.Node, runtime status.ContainerRuntime) ([]string, error) {
+	switch runtime {
+	case status.ContainerdRuntime:
+		// starts containerd for reading the image store, retrying until it is ready
+		lines, err := n.Command(
+			"bash", "-c",
+			`containerd >/dev/null 2>&1 & for i in $(seq 10); do ctr --namespace=k8s.io images ls -q 2>/dev/null && break; sleep 1; done; kill %1`,
+		).Silent().RunAndCapture()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read images from the containerd image store")
+		}
+		var images []string
+		for _, l := range lines {
+			// skips references by digest, that are duplicates of references by tag
+			if !strings.HasPrefix(l, "sha256:") {
+				images = append(images, l)
+			}
+		}
+		return images, nil
+	case status.DockerRuntime, status.CRIORuntime:
+		// the image store is a volume, so images are never stored in the node image
+		return nil, nil
+	}
+	return nil, errors.Errorf("unknown cri: %s", runtime)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DockerHubRegistry is the name used for referencing the Docker Hub registry in registry mirrors configuration
+const DockerHubRegistry = "docker.io"
+
+// SortedRegistries returns the registries in a registry mirrors configuration in a stable order
+func SortedRegistries(mirrors map[string][]string) []string {
+	registries := make([]string, 0, len(mirrors))
+	for r := range mirrors {
+		registries = append(registries, r)
+	}
+	sort.Strings(registries)
+	return registries
+}
+
+// RegistryServer returns the URL of the upstream registry server for a registry name
+func RegistryServer(registry string) string {
+	if registry == DockerHubRegistry {
+		return "https://registry-1.docker.io"
+	}
+	return "https://" + registry
+}
+
+// MirrorHost returns the host (and path) of a mirror URL, and if the mirror is insecure, that is using http
+func MirrorHost(mirror string) (string, bool) {
+	u, err := url.Parse(mirror)
+	if err != nil || u.Host == "" {
+		return mirror, false
+	}
+	return u.Host + strings.TrimSuffix(u.Path, "/"), u.Scheme == "http"
+}
@@ -45,9 +45,6 @@ func CommonArgs(cluster, name, role string) ([]string, error) {
 		"--label", fmt.Sprintf("%s=%s", constants.NodeRoleKey, role),
 	}
 
-	// TODO: enable IPv6 if necessary
-	// args = append(args, "--sysctl=net.ipv6.conf.all.disable_ipv6=0", "--sysctl=net.ipv6.conf.all.forwarding=1")
-
 	// pass proxy environment variables
 	proxyEnv, err := getProxyEnvs()
 	if err != nil {
@@ -109,6 +106,16 @@ func getProxyEnvs() (map[string]string, error) {
 	return envs, nil
 }
 
+// IPv6Enabled checks if IPv6 is enabled on the docker network used by node containers
+func IPv6Enabled() (bool, error) {
+	cmd := exec.NewHostCmd("docker", "network", "inspect", "-f", "{{.EnableIPv6}}", defaultNetwork)
+	lines, err := cmd.RunAndCapture()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to inspect the %s docker network", defaultNetwork)
+	}
+	return len(lines) > 0 && strings.TrimSpace(lines[0]) == "true", nil
+}
+
 func getSubnets(networkName string) ([]string, error) {
 	format := `{{range (index (index . "IPAM") "Config")}}{{index . "Subnet"}} {{end}}`
 	cmd := exec.NewHostCmd("docker", "network", "inspect", "-f", format, networkName)
@@ -134,8 +141,16 @@ func usernsRemap() bool {
 	return false
 }
 
-// RunArgsForNode computes docker run arguments that apply to containers that should host K8s nodes
-func RunArgsForNode(role string, volumes []string, args []string) ([]string, error) {
+// NodeResources defines the resource limits of a node container, in the format of the docker run --cpus
+// and --memory flags, e.g. 1.5 and 2g; empty values mean no limit
+type NodeResources struct {
+	CPU    string
+	Memory string
+}
+
+// RunArgsForNode computes docker run arguments that apply to containers that should host K8s nodes;
+// apiServerPort is the port on the host where the API server of control-plane nodes is exposed, if 0, a free port is used
+func RunArgsForNode(role string, volumes []string, ipv6 bool, apiServerPort int32, resources NodeResources, args []string) ([]string, error) {
 	args = append(args,
 		// running containers in a container requires privileged
 		// NOTE: we could try to replicate this with --cap-add, and use less
@@ -161,9 +176,24 @@ func RunArgsForNode(role string, volumes []string, args []string) ([]string, err
 		args = append(args, "--volume", v)
 	}
 
+	// set resource limits if necessary
+	// nb. swap is disabled by setting the memory+swap limit equal to the memory limit, so the
+	// memory pressure on the node is predictable
+	if resources.CPU != "" {
+		args = append(args, "--cpus", resources.CPU)
+	}
+	if resources.Memory != "" {
+		args = append(args, "--memory", resources.Memory, "--memory-swap", resources.Memory)
+	}
+
+	// enable IPv6 if necessary
+	if ipv6 {
+		args = append(args, "--sysctl=net.ipv6.conf.all.disable_ipv6=0", "--sysctl=net.ipv6.conf.all.forwarding=1")
+	}
+
 	if role == constants.ControlPlaneNodeRoleValue {
 		// API server port mapping
-		hostPort, err := getPort()
+		hostPort, err := hostPortOrFree(apiServerPort)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to get host port for the API server address")
 		}
@@ -184,10 +214,40 @@ func getPort() (int32, error) {
 	return int32(port), nil
 }
 
-// RunArgsForExternalLoadBalancer computes docker run arguments that apply to containers that should host external load balancers
-func RunArgsForExternalLoadBalancer(args []string) ([]string, error) {
+// hostPortOrFree returns the given port, or a free TCP port if port is 0
+func hostPortOrFree(port int32) (int32, error) {
+	if port != 0 {
+		return port, nil
+	}
+	return getPort()
+}
+
+// CheckHostPort checks a port on the host is available for exposing the API server, that is
+// it is not published by another container and it is not in use by another process
+func CheckHostPort(port int32) error {
+	lines, err := exec.NewHostCmd("docker", "ps",
+		"--filter", fmt.Sprintf("publish=%d", port),
+		"--format", "{{.Names}}",
+	).SetSilent(true).RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list containers publishing port %d", port)
+	}
+	if len(lines) > 0 {
+		return errors.Errorf("port %d is already published by container %s", port, strings.Join(lines, ", "))
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return errors.Wrapf(err, "port %d is already in use", port)
+	}
+	return listener.Close()
+}
+
+// RunArgsForExternalLoadBalancer computes docker run arguments that apply to containers that should host external load balancers;
+// port is the port on the host where the load balancer endpoint is exposed, if 0, a free port is used
+func RunArgsForExternalLoadBalancer(port int32, args []string) ([]string, error) {
 	// load balancer port mapping
-	hostPort, err := getPort()
+	hostPort, err := hostPortOrFree(port)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get host port for the load balancer endpoint")
 	}
@@ -201,6 +261,41 @@ func RunArgsForExternalEtcd(args []string) []string {
 	return args
 }
 
+// ExternalEtcdMemberArgsFile is the file where the args for an external etcd member are written once
+// all the members of the external etcd cluster are created; see ContainerArgsForExternalEtcdMember
+const ExternalEtcdMemberArgsFile = "/kinder-etcd-args"
+
+// RunArgsForExternalEtcdMember computes docker run arguments that apply to containers that should host
+// a member of a multi-member external etcd cluster
+func RunArgsForExternalEtcdMember(args []string) []string {
+	return append(args, "--entrypoint", "sh")
+}
+
+// ContainerArgsForExternalEtcdMember computes arguments to pass to the entry point of a container hosting
+// a member of a multi-member external etcd cluster. Because member IPs are known only after containers
+// are created, etcd starts only when the ExternalEtcdMemberArgsFile (that contains the etcd args) exists
+func ContainerArgsForExternalEtcdMember(args []string) []string {
+	return append(args,
+		"-c",
+		fmt.Sprintf("while [ ! -f %[1]s ]; do sleep 1; done; exec etcd $(cat %[1]s)", ExternalEtcdMemberArgsFile),
+	)
+}
+
+// EtcdArgsForExternalEtcdMember computes the etcd args for a member of an insecure, multi-member external etcd
+// cluster (not exposed to the host machine); initialCluster is the list of name=peerURL for all the members
+func EtcdArgsForExternalEtcdMember(cluster, name, ip string, initialCluster []string) []string {
+	return []string{
+		"--name", name,
+		"--initial-advertise-peer-urls", fmt.Sprintf("http://%s:2380", ip),
+		"--listen-peer-urls", "http://0.0.0.0:2380",
+		"--advertise-client-urls", fmt.Sprintf("http://%s:2379", ip),
+		"--listen-client-urls", "http://0.0.0.0:2379",
+		"--initial-cluster", strings.Join(initialCluster, ","),
+		"--initial-cluster-token", fmt.Sprintf("%s-etcd", cluster),
+		"--initial-cluster-state", "new",
+	}
+}
+
 // ContainerArgsForExternalEtcd computes arguments to pass to the external etcd container's entry point
 func ContainerArgsForExternalEtcd(name string, args []string) []string {
 	args = append(args,
@@ -19,20 +19,36 @@ package exec
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"os/exec"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
+// ErrTimeout is returned (wrapped, use errors.Cause for checking it) when a command does not complete within the timeout set with SetTimeout
+var ErrTimeout = errors.New("command timed out")
+
+// terminationGracePeriod defines how long to wait for a command to exit after SIGTERM before sending SIGKILL
+const terminationGracePeriod = 10 * time.Second
+
 // HostCmd allows to run a command on the host
 // By default, when the command is run it does not print any output generated during execution.
-// See Silent, Stdin, RunWithEcho, RunAndCapture, Skip and DryRun for possible variations to the default behavior.
+// See Silent, Stdin, RunWithEcho, RunAndCapture, RunWithOutput, Skip and DryRun for possible variations to the default behavior.
 type HostCmd struct {
+	ctx     context.Context
 	command string
 	args    []string
 	env     []string
+	dryRun  bool
+	silent  bool
+	timeout time.Duration
 	stdin   io.Reader
 	stdout  io.Writer
 	stderr  io.Writer
@@ -40,7 +56,15 @@ type HostCmd struct {
 
 // NewHostCmd returns a new HostCmd to run a command on a host
 func NewHostCmd(command string, args ...string) *HostCmd {
+	return NewHostCmdContext(context.Background(), command, args...)
+}
+
+// NewHostCmdContext returns a new HostCmd to run a command on a host bound to a context;
+// when the context is done before the command completes, the command is first terminated
+// with SIGTERM and then, if it does not exit within a grace period, killed with SIGKILL
+func NewHostCmdContext(ctx context.Context, command string, args ...string) *HostCmd {
 	return &HostCmd{
+		ctx:     ctx,
 		command: command,
 		args:    args,
 	}
@@ -51,6 +75,26 @@ func (c *HostCmd) Run() error {
 	return c.runInnnerCommand()
 }
 
+// RunWithRetry executes the inner command on the host, retrying up to `attempts` times in case of failures;
+// the wait time between attempts starts from `backoff` and doubles at every retry.
+// NB. the stdin io.Reader, if any, is not rewinded between attempts
+func (c *HostCmd) RunWithRetry(attempts int, backoff time.Duration) (err error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	for i := 1; i <= attempts; i++ {
+		if err = c.runInnnerCommand(); err == nil {
+			return nil
+		}
+		if i < attempts {
+			log.Infof("%s %s failed (attempt %d/%d), retrying in %s: %v", c.command, strings.Join(c.args, " "), i, attempts, backoff, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
+
 // RunWithEcho execute the inner command on a kind(er) node and echoes the command output to screen
 func (c *HostCmd) RunWithEcho() error {
 	c.stdout = os.Stderr
@@ -58,6 +102,15 @@ func (c *HostCmd) RunWithEcho() error {
 	return c.runInnnerCommand()
 }
 
+// RunInteractive executes the command attached to the stdin, stdout and stderr of the current process;
+// this allows e.g. to run interactive commands with a TTY
+func (c *HostCmd) RunInteractive() error {
+	c.stdin = os.Stdin
+	c.stdout = os.Stdout
+	c.stderr = os.Stderr
+	return c.runInnnerCommand()
+}
+
 // RunAndCapture executes the inner command on a kind(er) node and return the output captured during execution
 func (c *HostCmd) RunAndCapture() (lines []string, err error) {
 	var buff bytes.Buffer
@@ -73,8 +126,24 @@ func (c *HostCmd) RunAndCapture() (lines []string, err error) {
 	return lines, err
 }
 
+// RunWithOutput executes the inner command on the host and returns stdout and stderr captured during execution
+func (c *HostCmd) RunWithOutput() (stdout string, stderr string, err error) {
+	var outBuff, errBuff bytes.Buffer
+	c.stdout = &outBuff
+	c.stderr = &errBuff
+	err = c.runInnnerCommand()
+	return outBuff.String(), errBuff.String(), err
+}
+
 // Stdin sets an io.Reader to be used for streaming data in input to the inner command
 func (c *HostCmd) Stdin(in io.Reader) *HostCmd {
+	return c.SetStdin(in)
+}
+
+// SetStdin sets an io.Reader to be used for streaming data in input to the inner command,
+// e.g. for piping a config file to the command without writing it to disk first.
+// It can be combined with any of Run, RunWithEcho, RunAndCapture and RunWithOutput
+func (c *HostCmd) SetStdin(in io.Reader) *HostCmd {
 	c.stdin = in
 	return c
 }
@@ -85,6 +154,72 @@ func (c *HostCmd) SetEnv(env ...string) *HostCmd {
 	return c
 }
 
+// SetEnvOverride sets env variables to be used when running the inner command, starting from
+// the environment of the current process and then replacing variables with the values in `kv`,
+// so the resulting environment never contains duplicated variables
+func (c *HostCmd) SetEnvOverride(kv map[string]string) *HostCmd {
+	env := []string{}
+	for _, e := range os.Environ() {
+		if i := strings.Index(e, "="); i >= 0 {
+			if _, ok := kv[e[:i]]; ok {
+				continue
+			}
+		}
+		env = append(env, e)
+	}
+
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+kv[k])
+	}
+
+	c.env = env
+	return c
+}
+
+// SetTimeout sets the maximum duration for running the inner command; when the timeout expires,
+// the command and all its child processes are killed, and an error wrapping ErrTimeout is returned
+func (c *HostCmd) SetTimeout(timeout time.Duration) *HostCmd {
+	c.timeout = timeout
+	return c
+}
+
+// SetSilent instructs the host command to not log the "Running: ..." line before execution at debug level
+// (the line is logged at trace level instead); errors returned by silent commands include the command text,
+// so debugging is still possible. Please note that this does not change where stdout/stderr are written
+func (c *HostCmd) SetSilent(silent bool) *HostCmd {
+	c.silent = silent
+	return c
+}
+
+// DryRun instruct the host command to print the command text instead of running it.
+func (c *HostCmd) DryRun() *HostCmd {
+	c.dryRun = true
+	return c
+}
+
+// commandLine returns the command text, prefixed by env variables that are not inherited from the current process
+func (c *HostCmd) commandLine() string {
+	inherited := map[string]bool{}
+	for _, e := range os.Environ() {
+		inherited[e] = true
+	}
+
+	var parts []string
+	for _, e := range c.env {
+		if !inherited[e] {
+			parts = append(parts, e)
+		}
+	}
+	parts = append(parts, c.command)
+	parts = append(parts, c.args...)
+	return strings.Join(parts, " ")
+}
+
 func (c *HostCmd) runInnnerCommand() error {
 	// create the commands
 	cmd := exec.Command(c.command, c.args...)
@@ -104,7 +239,76 @@ func (c *HostCmd) runInnnerCommand() error {
 		cmd.Env = c.env
 	}
 
-	// eventually print the proxy command, and then run the command to be executed
-	log.Debugf("Running: %v", cmd.Args)
-	return cmd.Run()
+	// if we are dry running, print the command and then exit
+	if c.dryRun {
+		log.Infof("Dry-running: %s", c.commandLine())
+		return nil
+	}
+
+	// if a timeout is set, run the command in its own process group, so it is possible to kill the command
+	// and all its child processes on timeout
+	var timeout <-chan time.Time
+	if c.timeout > 0 {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	// eventually print the command, including env variables not inherited from the current process,
+	// and then run the command to be executed
+	// NB. silent commands are logged only at trace level, so they don't flood the debug output
+	if c.silent {
+		log.Tracef("Running: %s", c.commandLine())
+	} else {
+		log.Debugf("Running: %s", c.commandLine())
+	}
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		log.Debugf("Failed to start: %v: %v", cmd.Args, err)
+		return c.wrapError(cmd, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		// nb. failures are logged at debug level also for silent commands
+		if err != nil {
+			log.Debugf("Failed after %s: %v: %v", time.Since(start), cmd.Args, err)
+		} else if c.silent {
+			log.Tracef("Completed in %s: %v", time.Since(start), cmd.Args)
+		} else {
+			log.Debugf("Completed in %s: %v", time.Since(start), cmd.Args)
+		}
+		return c.wrapError(cmd, err)
+	case <-timeout:
+		// kill the whole process group
+		log.Debugf("Timed out: %v", cmd.Args)
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return errors.Wrapf(ErrTimeout, "%v did not complete within %s", cmd.Args, c.timeout)
+	case <-c.ctx.Done():
+		// gracefully terminate the command, and then kill it if it does not exit in time
+		log.Debugf("Terminating: %v", cmd.Args)
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-done:
+		case <-time.After(terminationGracePeriod):
+			_ = cmd.Process.Kill()
+			<-done
+		}
+		return c.ctx.Err()
+	}
+}
+
+// wrapError adds the command text to errors returned by silent commands
+func (c *HostCmd) wrapError(cmd *exec.Cmd, err error) error {
+	if err == nil || !c.silent {
+		return err
+	}
+	return errors.Wrapf(err, "%v", cmd.Args)
 }
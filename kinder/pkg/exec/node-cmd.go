@@ -24,9 +24,12 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"k8s.io/kubeadm/kinder/pkg/audit"
 	"k8s.io/kubeadm/kinder/pkg/exec/colors"
 )
 
@@ -145,6 +148,13 @@ func (c *NodeCmd) runInnnerCommand() error {
 		cmd.Stderr = c.stderr
 	}
 
+	// if the command should be recorded in the audit log, captures also stdout and stderr separately
+	audited := !c.dryRun && audit.IsAudited(c.command)
+	var auditStdout, auditStderr bytes.Buffer
+	if audited {
+		cmd.Stdout, cmd.Stderr = auditWriters(c.stdout, c.stderr, &auditStdout, &auditStderr)
+	}
+
 	// if not silent, prints the screen echo for the command to be executed
 	if !c.silent {
 		prompt := colors.Prompt(fmt.Sprintf("%s:$ ", c.node))
@@ -153,12 +163,81 @@ func (c *NodeCmd) runInnnerCommand() error {
 	}
 
 	// if we are dry running, eventually print the proxy command and then exit
+	logger := log.WithField("node", c.node)
 	if c.dryRun {
-		log.Debugf("Dry-running: %v", cmd.Args)
+		logger.Debugf("Dry-running: %v", cmd.Args)
 		return nil
 	}
 
 	// eventually print the proxy command, and then run the command to be executed
-	log.Debugf("Running: %v", cmd.Args)
-	return cmd.Run()
+	// NB. silent commands are logged only at trace level, unless they fail
+	logf := logger.Debugf
+	if c.silent {
+		logf = logger.Tracef
+	}
+	logf("Running: %v", cmd.Args)
+	start := time.Now()
+	err := cmd.Run()
+	if audited {
+		audit.Record(audit.Entry{
+			Time:            start,
+			Node:            c.node,
+			Command:         c.command,
+			Args:            c.args,
+			Stdout:          auditStdout.String(),
+			Stderr:          auditStderr.String(),
+			ExitCode:        exitCode(err),
+			DurationSeconds: time.Since(start).Seconds(),
+		})
+	}
+	if err != nil {
+		logger.Debugf("Failed after %s: %v: %v", time.Since(start), cmd.Args, err)
+		return err
+	}
+	logf("Completed in %s: %v", time.Since(start), cmd.Args)
+	return nil
+}
+
+// auditWriters returns the writers for stdout and stderr of a command recorded in the audit log, that write both
+// on the given stdout and stderr, if any, and on the audit buffers.
+// NB. when stdout and stderr are the same writer, e.g. for RunAndCapture, writes are serialized, because it
+// is not safe to write on the same writer from the go routines copying stdout and stderr
+func auditWriters(stdout, stderr io.Writer, auditStdout, auditStderr *bytes.Buffer) (io.Writer, io.Writer) {
+	if stdout != nil && stdout == stderr {
+		w := &lockedWriter{w: stdout}
+		return io.MultiWriter(w, auditStdout), io.MultiWriter(w, auditStderr)
+	}
+
+	outW, errW := io.Writer(auditStdout), io.Writer(auditStderr)
+	if stdout != nil {
+		outW = io.MultiWriter(stdout, auditStdout)
+	}
+	if stderr != nil {
+		errW = io.MultiWriter(stderr, auditStderr)
+	}
+	return outW, errW
+}
+
+// lockedWriter is an io.Writer safe for concurrent use
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// exitCode returns the exit code of a command given the error returned by Run; -1 is returned if
+// the command did not exit, e.g. because it was not started
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return -1
 }
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultCacheTTL is the default time after which cached artifacts are downloaded again
+const DefaultCacheTTL = 24 * time.Hour
+
+// DefaultCacheDir returns the default folder where downloaded artifacts are cached,
+// ~/.kinder/cache, or an empty string if the home folder cannot be determined
+func DefaultCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kinder", "cache")
+}
+
+// cachedFile returns the path where the artifact downloaded from src is cached.
+// The cache path includes the build repository, the Kubernetes version and the arch of the artifact, e.g.
+// ~/.kinder/cache/storage.googleapis.com/kubernetes-release/release/v1.14.0/bin/linux/amd64/kubeadm
+func (e *Extractor) cachedFile(src string) (string, bool) {
+	if e.noCache || e.cacheDir == "" {
+		return "", false
+	}
+	return filepath.Join(e.cacheDir, filepath.FromSlash(strings.TrimPrefix(src, "https://"))), true
+}
+
+// isFresh returns true if the cached file exists and it is not expired
+func (e *Extractor) isFresh(cached string) bool {
+	info, err := os.Stat(cached)
+	if err != nil {
+		return false
+	}
+	// nb. a TTL of zero means cached files never expire
+	return e.cacheTTL <= 0 || time.Since(info.ModTime()) < e.cacheTTL
+}
+
+// downloadWithCache gets the artifact of a Kubernetes build at src into dst, using the cache if possible
+func (e *Extractor) downloadWithCache(src, dst string, verifyChecksum bool) error {
+	cached, ok := e.cachedFile(src)
+	if !ok {
+		return download(src, dst, verifyChecksum)
+	}
+
+	if e.isFresh(cached) {
+		log.Infof("Cache hit for %s, using %s", src, cached)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(cached), 0777); err != nil {
+			return errors.Wrapf(err, "failed to make %s dir", filepath.Dir(cached))
+		}
+
+		// nb. the artifact is downloaded into a temporary file so the cache never contains
+		// incomplete or not verified artifacts
+		tmp := cached + ".download"
+		if err := download(src, tmp, verifyChecksum); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, cached); err != nil {
+			return errors.Wrapf(err, "failed to add %s to the cache", src)
+		}
+		log.Debugf("%s saved into the cache at %s", src, cached)
+	}
+
+	if err := linkOrCopy(cached, dst); err != nil {
+		return errors.Wrapf(err, "failed to copy %s to %s", cached, dst)
+	}
+	return nil
+}
+
+// download gets the artifact at src into dst, verifying its checksum if required
+func download(src, dst string, verifyChecksum bool) error {
+	if err := copyFromURI(src, dst); err != nil {
+		return errors.Wrapf(err, "failed to copy %s to %s", src, dst)
+	}
+	if verifyChecksum {
+		if err := verifySHA256(src, dst); err != nil {
+			// nb. the downloaded file is removed, so it will be downloaded again next time
+			os.Remove(dst)
+			return err
+		}
+	}
+	return nil
+}
@@ -25,6 +25,8 @@ package extract
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -42,6 +44,7 @@ import (
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kindfs "sigs.k8s.io/kind/pkg/fs"
+	"sigs.k8s.io/kind/pkg/util"
 )
 
 const (
@@ -78,6 +81,12 @@ const (
 
 	// LocalRepositorySource describe a src that is hosted in local repository
 	LocalRepositorySource
+
+	// KubernetesSourceTreeSource describe a src that is a local kubernetes/kubernetes source tree
+	KubernetesSourceTreeSource
+
+	// GCSBuildSource describe a src that is a Kubernetes build hosted in a GCS bucket
+	GCSBuildSource
 )
 
 // GetSourceType returns the src type descriptor
@@ -88,6 +97,8 @@ func GetSourceType(src string) SourceType {
 		return ReleaseLabelOrVersionSource
 	} else if strings.HasPrefix(src, "ci/") {
 		return CILabelOrVersionSource
+	} else if strings.HasPrefix(src, "gs://") {
+		return GCSBuildSource
 	} else if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
 		return RemoteRepositorySource
 	} else if v, err := K8sVersion.ParseSemantic(src); err == nil {
@@ -95,6 +106,8 @@ func GetSourceType(src string) SourceType {
 			return CILabelOrVersionSource
 		}
 		return ReleaseLabelOrVersionSource
+	} else if isKubernetesSourceTree(src) {
+		return KubernetesSourceTreeSource
 	}
 	return LocalRepositorySource
 }
@@ -160,6 +173,47 @@ func WithVersionFolder(versionFolder bool) Option {
 	}
 }
 
+// WithArch option instructs the Extractor to retrieve artifacts for the given arch.
+// NB. this setting is respected only by release or ci builds and by local Kubernetes source trees
+func WithArch(arch string) Option {
+	return func(b *Extractor) {
+		b.arch = arch
+	}
+}
+
+// SkipChecksum option instructs the Extractor to skip the verification of SHA256 checksums
+// for artifacts downloaded from release or ci builds
+func SkipChecksum(skipChecksum bool) Option {
+	return func(b *Extractor) {
+		b.skipChecksum = skipChecksum
+	}
+}
+
+// WithCacheDir option instructs the Extractor to cache artifacts downloaded from release or ci builds
+// into the given folder
+func WithCacheDir(cacheDir string) Option {
+	return func(b *Extractor) {
+		if cacheDir != "" {
+			b.cacheDir = cacheDir
+		}
+	}
+}
+
+// WithCacheTTL option instructs the Extractor to download again cached artifacts older than the given TTL;
+// a TTL of zero means cached artifacts never expire
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(b *Extractor) {
+		b.cacheTTL = ttl
+	}
+}
+
+// NoCache option instructs the Extractor to bypass the cache of downloaded artifacts
+func NoCache(noCache bool) Option {
+	return func(b *Extractor) {
+		b.noCache = noCache
+	}
+}
+
 // Extractor defines attributes for a Kubernetes artifact extractor
 type Extractor struct {
 	// src is the source from where to extract file
@@ -172,6 +226,16 @@ type Extractor struct {
 	dstMutator fileNameMutator
 	// add version file to dst
 	addVersionFileToDst bool
+	// arch of the artifacts to extract
+	arch string
+	// skip checksum verification of downloaded artifacts
+	skipChecksum bool
+	// folder where downloaded artifacts are cached
+	cacheDir string
+	// time after which cached artifacts are downloaded again
+	cacheTTL time.Duration
+	// bypass the cache of downloaded artifacts
+	noCache bool
 }
 
 // NewExtractor returns a new extractor configured with the given options
@@ -182,6 +246,9 @@ func NewExtractor(src, dst string, options ...Option) (extractor *Extractor) {
 		dst:                 dst,
 		dstMutator:          fileNameMutator{},
 		addVersionFileToDst: true,
+		arch:                util.GetArch(),
+		cacheDir:            DefaultCacheDir(),
+		cacheTTL:            DefaultCacheTTL,
 	}
 
 	// apply user options
@@ -203,13 +270,17 @@ func (e *Extractor) Extract() (paths map[string]string, err error) {
 
 	switch GetSourceType(e.src) {
 	case ReleaseLabelOrVersionSource:
-		f = extractFromReleaseBuild
+		f = e.extractFromReleaseBuild
 	case CILabelOrVersionSource:
-		f = extractFromCIBuild
+		f = e.extractFromCIBuild
 	case RemoteRepositorySource:
-		f = extractFromHTTP
+		f = e.extractFromHTTP
 	case LocalRepositorySource:
 		f = extractFromLocalDir
+	case KubernetesSourceTreeSource:
+		f = e.extractFromSourceTree
+	case GCSBuildSource:
+		f = e.extractFromGCSBuild
 	default:
 		return nil, errors.Errorf("source %s did not resolve to a valid source type", e.src)
 	}
@@ -220,14 +291,18 @@ func (e *Extractor) Extract() (paths map[string]string, err error) {
 // extractFunc define a function that implements an extractor method
 type extractFunc func(string, []string, string, fileNameMutator, bool) (map[string]string, error)
 
-func extractFromCIBuild(src string, files []string, dst string, m fileNameMutator, addVersionFileToDst bool) (paths map[string]string, err error) {
+func (e *Extractor) extractFromCIBuild(src string, files []string, dst string, m fileNameMutator, addVersionFileToDst bool) (paths map[string]string, err error) {
 	// cleanup the src from the prefix, if any
 	src = strings.TrimPrefix(src, "ci/")
 
 	// gets the Kubernetes version from the src
 	version, err := K8sVersion.ParseSemantic(src)
 	if err != nil {
-		version, err = resolveLabel(ciBuildRepository, src)
+		if isCommitSHA(src) {
+			version, err = resolveCommit(ciBuildRepository, src)
+		} else {
+			version, err = resolveLabel(ciBuildRepository, src)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -243,14 +318,11 @@ func extractFromCIBuild(src string, files []string, dst string, m fileNameMutato
 	// nb. this will allow to save extracted files into a version folder
 	m.SetPrependVersionFolder(version)
 
-	// sets the url for downloading the requested ci version
-	src = fmt.Sprintf("%s/v%s", ciBuildRepository, version)
-
-	// read from the src via http, taking care of setting addVersionFileToDst (because it was already saved above)
-	return extractFromHTTP(src, files, dst, m, false)
+	// read from the build for the requested ci version
+	return e.extractFromBuild(fmt.Sprintf("%s/v%s", ciBuildRepository, version), files, dst, m)
 }
 
-func extractFromReleaseBuild(src string, files []string, dst string, m fileNameMutator, addVersionFileToDst bool) (paths map[string]string, err error) {
+func (e *Extractor) extractFromReleaseBuild(src string, files []string, dst string, m fileNameMutator, addVersionFileToDst bool) (paths map[string]string, err error) {
 	// cleanup the source src the prefix, if any
 	src = strings.TrimPrefix(src, "release/")
 
@@ -273,14 +345,24 @@ func extractFromReleaseBuild(src string, files []string, dst string, m fileNameM
 	// nb. this will allow to save extracted files into a version folder
 	m.SetPrependVersionFolder(version)
 
-	// sets the url for downloading the requested release version
-	src = fmt.Sprintf("%s/v%s", releaseBuildURepository, version)
+	// read from the build for the requested release version
+	return e.extractFromBuild(fmt.Sprintf("%s/v%s", releaseBuildURepository, version), files, dst, m)
+}
 
-	// read from the src via http, taking care of setting addVersionFileToDst (because it was already saved above)
-	return extractFromHTTP(src, files, dst, m, false)
+func (e *Extractor) extractFromHTTP(src string, files []string, dst string, m fileNameMutator, addVersionFileToDst bool) (paths map[string]string, err error) {
+	return e.downloadFiles(src, files, dst, m, addVersionFileToDst, false)
 }
 
-func extractFromHTTP(src string, files []string, dst string, m fileNameMutator, addVersionFileToDst bool) (paths map[string]string, err error) {
+// extractFromBuild reads artifacts for the selected arch from a Kubernetes build at buildURL;
+// the version file is not added to dst, because it is expected to be created from the build version
+func (e *Extractor) extractFromBuild(buildURL string, files []string, dst string, m fileNameMutator) (paths map[string]string, err error) {
+	return e.downloadFiles(fmt.Sprintf("%s/bin/linux/%s", buildURL, e.arch), files, dst, m, false, true)
+}
+
+// downloadFiles downloads files from src via http;
+// Kubernetes builds publish a SHA256 checksum for each artifact, so for builds checksums are verified
+// and downloaded artifacts are cached
+func (e *Extractor) downloadFiles(src string, files []string, dst string, m fileNameMutator, addVersionFileToDst, build bool) (paths map[string]string, err error) {
 	dst, _ = filepath.Abs(dst)
 	if _, err := os.Stat(dst); os.IsNotExist(err) {
 		return nil, errors.Errorf("destination path %s does not exists", dst)
@@ -298,19 +380,19 @@ func extractFromHTTP(src string, files []string, dst string, m fileNameMutator,
 		files = append(files, "version")
 	}
 
-	// in case the source is a Kubernetes build, add bin/OS/ARCH to the src uri
-	if strings.HasPrefix(src, releaseBuildURepository) || strings.HasPrefix(src, ciBuildRepository) {
-		src = fmt.Sprintf("%s/bin/linux/amd64", src)
-	}
-
 	// Download the files.
 	paths = map[string]string{}
 	for _, f := range files {
 		srcFilePath := fmt.Sprintf("%s/%s", src, f)
 		log.Infof("Downloading %s\n", srcFilePath)
 		dstFilePath := path.Join(dst, m.Mutate(f))
-		if err := copyFromURI(srcFilePath, dstFilePath); err != nil {
-			return nil, errors.Wrapf(err, "failed to copy %s to %s", srcFilePath, dstFilePath)
+		if build {
+			err = e.downloadWithCache(srcFilePath, dstFilePath, !e.skipChecksum && f != "version")
+		} else {
+			err = download(srcFilePath, dstFilePath, false)
+		}
+		if err != nil {
+			return nil, err
 		}
 		if f == kubeadmBinary || f == kubeletBinary || f == kubectlBinary {
 			os.Chmod(dstFilePath, 0755)
@@ -494,54 +576,220 @@ var httpGetBackoff = wait.Backoff{
 }
 
 func httpGet(uri string) (int64, io.ReadCloser, error) {
+	resp, err := httpGetFrom(uri, 0)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return resp.ContentLength, resp.Body, nil
+}
+
+// httpGetFrom does an HTTP GET of uri; if offset is greater than zero, only the content starting from offset
+// is requested, and responses for servers not supporting range requests are accepted as well
+func httpGetFrom(uri string, offset int64) (*http.Response, error) {
 	var lastError error
 	var resp *http.Response
 	err := wait.ExponentialBackoff(httpGetBackoff, func() (bool, error) {
-		var err error
-		resp, err = http.Get(uri)
+		req, err := http.NewRequest(http.MethodGet, uri, nil)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid request for %s", uri)
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		resp, err = http.DefaultClient.Do(req)
 		if err != nil {
 			log.Warnf("HTTP GET %s failed. Retry in few seconds", uri)
 			lastError = errors.Wrapf(err, "HTTP GET %s failed", uri)
 			return false, nil
 		}
-		if resp.StatusCode != http.StatusOK {
-			log.Warnf("HTTP GET %s failed: %s. Retry in few seconds", uri, resp.Status)
-			lastError = errors.Errorf("HTTP GET %s failed: %s", uri, resp.Status)
-			return false, nil
+		if resp.StatusCode == http.StatusOK {
+			return true, nil
+		}
+		if offset > 0 && (resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable) {
+			return true, nil
 		}
-		return true, nil
+		resp.Body.Close()
+		log.Warnf("HTTP GET %s failed: %s. Retry in few seconds", uri, resp.Status)
+		lastError = errors.Errorf("HTTP GET %s failed: %s", uri, resp.Status)
+		return false, nil
 	})
 	if err != nil {
-		return 0, nil, lastError
+		if lastError == nil {
+			lastError = err
+		}
+		return nil, lastError
 	}
 
-	return resp.ContentLength, resp.Body, nil
+	return resp, nil
+}
+
+// downloadAttempts defines how many times an interrupted download is resumed
+const downloadAttempts = 3
+
+// copyFromURI downloads src into dst; the content is downloaded into a dst.partial file, so
+// in case the download is interrupted it can be resumed from where it left off
+func copyFromURI(src, dst string) (err error) {
+	for i := 1; i <= downloadAttempts; i++ {
+		if err = copyFromURIOnce(src, dst); err == nil || errors.Cause(err) != errInterrupted {
+			return err
+		}
+		if i < downloadAttempts {
+			log.Warnf("Download of %s interrupted, resuming", src)
+		}
+	}
+	return err
 }
 
-func copyFromURI(src, dst string) error {
-	size, r, err := httpGet(src)
+// errInterrupted is returned when a download is interrupted and it can be resumed
+var errInterrupted = errors.New("download interrupted")
+
+func copyFromURIOnce(src, dst string) error {
+	partial := dst + ".partial"
+	var offset int64
+	if f, err := os.Stat(partial); err == nil {
+		offset = f.Size()
+	}
+
+	resp, err := httpGetFrom(src, offset)
 	if err != nil {
 		return errors.Wrapf(err, "error getting reader for %s", src)
 	}
-	defer r.Close()
+	defer resp.Body.Close()
+
+	// size is the expected size of the complete file, or -1 if unknown
+	var size int64 = -1
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		log.Infof("Resuming download of %s from byte %d", src, offset)
+		flags |= os.O_APPEND
+		if resp.ContentLength >= 0 {
+			size = offset + resp.ContentLength
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial file does not match the remote content anymore; download it again
+		log.Infof("Unable to resume download of %s, downloading it again", src)
+		if err := os.Remove(partial); err != nil {
+			return errors.Wrapf(err, "error removing %s", partial)
+		}
+		return copyFromURIOnce(src, dst)
+	default:
+		if offset > 0 {
+			log.Infof("%s does not support resuming downloads, downloading it again", src)
+		}
+		flags |= os.O_TRUNC
+		size = resp.ContentLength
+
+		// If the file already exists and has the same size as the remote
+		// content then do not redownload it.
+		if f, err := os.Stat(dst); err == nil && offset == 0 {
+			if size == f.Size() {
+				return nil
+			}
+		}
+	}
+
+	w, err := os.OpenFile(partial, flags, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "error creating %s", partial)
+	}
+	_, err = io.Copy(w, resp.Body)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Debugf("error copying %s to %s: %v", src, partial, err)
+		return errors.Wrapf(errInterrupted, "error copying %s to %s", src, partial)
+	}
+
+	// validates the final size of the downloaded file
+	if f, err := os.Stat(partial); err != nil {
+		return errors.Wrapf(err, "error reading %s", partial)
+	} else if size >= 0 && f.Size() != size {
+		return errors.Wrapf(errInterrupted, "incomplete download of %s: expected %d bytes, got %d", src, size, f.Size())
+	}
+
+	if err := os.Rename(partial, dst); err != nil {
+		return errors.Wrapf(err, "error moving %s to %s", partial, dst)
+	}
+
+	return nil
+}
 
-	// If the file already exists and has the same size as the remote
-	// content then do not redownload it.
-	if f, err := os.Stat(dst); err == nil {
-		if size == f.Size() {
-			return nil
+// verifySHA256 checks that the SHA256 checksum of the dst file matches the checksum
+// published at src + ".sha256"
+func verifySHA256(src, dst string) error {
+	checksumURI := src + ".sha256"
+	if err := checkSHA256(src, checksumURI, dst); err != nil {
+		if errors.Cause(err) == errChecksumNotAvailable {
+			return errors.Wrap(err, "use --skip-checksum to skip checksum verification")
 		}
+		return err
 	}
+	return nil
+}
+
+// errChecksumNotAvailable is returned when the published checksum of an artifact can't be read
+var errChecksumNotAvailable = errors.New("checksum not available")
 
-	w, err := os.Create(dst)
+// Download downloads the artifact at src into dst, and checks that its SHA256 checksum matches the checksum
+// published at checksumURI, e.g. src + ".sha256" or src + ".sha256sum" for GitHub releases; if checksumURI
+// is empty, the checksum is not verified. In case of checksum errors dst is removed
+func Download(src, checksumURI, dst string) error {
+	if err := copyFromURI(src, dst); err != nil {
+		return errors.Wrapf(err, "failed to copy %s to %s", src, dst)
+	}
+	if checksumURI == "" {
+		return nil
+	}
+	if err := checkSHA256(src, checksumURI, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// checkSHA256 checks that the SHA256 checksum of the dst file downloaded from src matches the checksum
+// published at checksumURI
+func checkSHA256(src, checksumURI, dst string) error {
+	resp, err := http.Get(checksumURI)
 	if err != nil {
-		return errors.Wrapf(err, "error creating %s", dst)
+		return errors.Wrapf(errChecksumNotAvailable, "HTTP GET %s failed: %v", checksumURI, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Wrapf(errChecksumNotAvailable, "HTTP GET %s failed: %s", checksumURI, resp.Status)
 	}
-	defer w.Close()
 
-	if _, err := io.Copy(w, r); err != nil {
-		return errors.Wrapf(err, "error copying %s to %s", src, dst)
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "error reading checksum from %s", checksumURI)
+	}
+	// nb. checksum file could be in the "<checksum>  <file name>" format
+	fields := strings.Fields(string(buf))
+	if len(fields) == 0 {
+		return errors.Errorf("invalid checksum file %s", checksumURI)
+	}
+	expected := strings.ToLower(fields[0])
+
+	f, err := os.Open(dst)
+	if err != nil {
+		return errors.Wrapf(err, "error opening %s", dst)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return errors.Wrapf(err, "error computing checksum of %s", dst)
+	}
+	actual := hex.EncodeToString(h.Sum(nil))
+
+	if actual != expected {
+		return errors.Errorf("checksum mismatch for %s: expected %s, actual %s", src, expected, actual)
 	}
+	log.Debugf("Checksum verified for %s", dst)
 
 	return nil
 }
@@ -618,7 +866,12 @@ func ResolveLabel(src string) (version string, err error) {
 		return "", errors.Errorf("source %s did not resolve to a valid label", src)
 	}
 
-	v, err := resolveLabel(repository, src)
+	var v *K8sVersion.Version
+	if repository == ciBuildRepository && isCommitSHA(src) {
+		v, err = resolveCommit(repository, src)
+	} else {
+		v, err = resolveLabel(repository, src)
+	}
 	if err != nil {
 		return "", err
 	}
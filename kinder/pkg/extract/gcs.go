@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	gcsURL    = "https://storage.googleapis.com"
+	gcsAPIURL = "https://storage.googleapis.com/storage/v1"
+)
+
+// commitSHARegexp matches full or abbreviated git commit SHAs
+var commitSHARegexp = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// isCommitSHA returns true if the src is a git commit SHA
+func isCommitSHA(src string) bool {
+	return commitSHARegexp.MatchString(src)
+}
+
+// resolveCommit returns the version of the build for the given commit SHA; this is done by
+// looking for a build folder with a version having the commit SHA as a build metadata,
+// e.g. v1.15.0-alpha.0.100+78573805a7292a
+func resolveCommit(repository, sha string) (version *K8sVersion.Version, err error) {
+	bucket, prefix, err := splitGCSURL(repository)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Resolving commit %s in %s\n", sha, repository)
+
+	var matches []*K8sVersion.Version
+	pageToken := ""
+	for {
+		folders, nextPageToken, err := listGCSFolders(bucket, prefix+"/v", pageToken)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error resolving commit %s", sha)
+		}
+
+		for _, f := range folders {
+			v, err := K8sVersion.ParseSemantic(path.Base(f))
+			if err != nil {
+				continue
+			}
+			// nb. build metadata are abbreviated commit SHAs, while sha could be a full commit SHA
+			metadata := v.BuildMetadata()
+			if metadata != "" && (strings.HasPrefix(metadata, sha) || strings.HasPrefix(sha, metadata)) {
+				matches = append(matches, v)
+			}
+		}
+
+		if nextPageToken == "" {
+			break
+		}
+		pageToken = nextPageToken
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.Errorf("no build artifacts published in %s for commit %s", repository, sha)
+	case 1:
+		log.Debugf("Commit %s resolves to v%s\n", sha, matches[0])
+		return matches[0], nil
+	default:
+		var versions []string
+		for _, v := range matches {
+			versions = append(versions, fmt.Sprintf("v%s", v))
+		}
+		return nil, errors.Errorf("commit %s is ambiguous, it matches builds %s; please use a longer commit SHA or a version", sha, strings.Join(versions, ", "))
+	}
+}
+
+// listGCSFolders returns a page of folders in a GCS bucket with the given prefix
+func listGCSFolders(bucket, prefix, pageToken string) (folders []string, nextPageToken string, err error) {
+	query := url.Values{}
+	query.Set("delimiter", "/")
+	query.Set("prefix", prefix)
+	query.Set("fields", "prefixes,nextPageToken")
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+	uri := fmt.Sprintf("%s/b/%s/o?%s", gcsAPIURL, bucket, query.Encode())
+
+	_, r, err := httpGet(uri)
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Close()
+
+	var list struct {
+		Prefixes      []string `json:"prefixes"`
+		NextPageToken string   `json:"nextPageToken"`
+	}
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, "", errors.Wrapf(err, "error reading the list of folders from %s", uri)
+	}
+
+	for _, p := range list.Prefixes {
+		folders = append(folders, strings.TrimSuffix(p, "/"))
+	}
+	return folders, list.NextPageToken, nil
+}
+
+// splitGCSURL splits an https://storage.googleapis.com/bucket/path URL in bucket and path
+func splitGCSURL(uri string) (bucket, prefix string, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(uri, gcsURL+"/"), "/", 2)
+	if !strings.HasPrefix(uri, gcsURL+"/") || len(parts) != 2 {
+		return "", "", errors.Errorf("%s is not a valid GCS folder", uri)
+	}
+	return parts[0], parts[1], nil
+}
+
+// extractFromGCSBuild reads artifacts from a Kubernetes build hosted in a GCS bucket,
+// e.g. gs://kubernetes-release-dev/ci/v1.15.0-alpha.0.100+78573805a7292a
+func (e *Extractor) extractFromGCSBuild(src string, files []string, dst string, m fileNameMutator, addVersionFileToDst bool) (paths map[string]string, err error) {
+	buildURL := fmt.Sprintf("%s/%s", gcsURL, strings.TrimSuffix(strings.TrimPrefix(src, "gs://"), "/"))
+
+	// gets the Kubernetes version from the build folder name
+	version, err := K8sVersion.ParseSemantic(path.Base(buildURL))
+	if err != nil {
+		return nil, errors.Errorf("%s is not a valid GCS build path; the path should end with the build version, e.g. gs://kubernetes-release-dev/ci/v1.15.0-alpha.0.100+78573805a7292a", src)
+	}
+
+	// saves the version file (if requested)
+	// nb. version file is created so the target folder can be eventually used as a source
+	if err := saveVersionFile(addVersionFileToDst, dst, version, m); err != nil {
+		return nil, errors.Wrapf(err, "error creating version file in %s", dst)
+	}
+
+	// pass the version to the file name mutator
+	// nb. this will allow to save extracted files into a version folder
+	m.SetPrependVersionFolder(version)
+
+	return e.extractFromBuild(buildURL, files, dst, m)
+}
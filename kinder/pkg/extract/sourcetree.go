@@ -0,0 +1,193 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	kindfs "sigs.k8s.io/kind/pkg/fs"
+)
+
+// buildStampFile defines the file, under the _output folder of a Kubernetes source tree, where
+// kinder records the revision/arch of the last build, so build outputs can be reused across runs
+const buildStampFile = "kinder-build-stamp"
+
+// isKubernetesSourceTree returns true if src is a local kubernetes/kubernetes source tree
+func isKubernetesSourceTree(src string) bool {
+	for _, f := range []string{
+		filepath.Join("build", "run.sh"),
+		filepath.Join("hack", "print-workspace-status.sh"),
+	} {
+		if _, err := os.Stat(filepath.Join(src, f)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// extractFromSourceTree builds Kubernetes binaries & images from a local kubernetes/kubernetes
+// source tree, and then extracts them like from a local repository
+func (e *Extractor) extractFromSourceTree(src string, files []string, dst string, m fileNameMutator, addVersionFileToDst bool) (paths map[string]string, err error) {
+	kubeRoot, err := filepath.Abs(src)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid Kubernetes source tree %s", src)
+	}
+
+	// build binaries & images, if the build outputs of a previous run can't be reused
+	stamp, cacheable := buildStamp(kubeRoot, e.arch)
+	stampFile := filepath.Join(kubeRoot, "_output", buildStampFile)
+	if cached, err := ioutil.ReadFile(stampFile); cacheable && err == nil && string(cached) == stamp {
+		log.Infof("Reusing build outputs for %s in %s", stamp, kubeRoot)
+	} else {
+		if err := buildSourceTree(kubeRoot, e.arch); err != nil {
+			return nil, err
+		}
+		if cacheable {
+			if err := ioutil.WriteFile(stampFile, []byte(stamp), 0644); err != nil {
+				return nil, errors.Wrap(err, "failed to write build stamp file")
+			}
+		}
+	}
+
+	// collects build outputs into a local repository
+	repo, err := stageSourceTreeOutputs(kubeRoot, e.arch)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractFromLocalDir(repo, files, dst, m, addVersionFileToDst)
+}
+
+// buildStamp returns a stamp identifying the current revision of the source tree and the target arch;
+// if the source tree has uncommitted changes the build outputs can't be reused
+func buildStamp(kubeRoot, arch string) (stamp string, cacheable bool) {
+	revision, err := exec.NewHostCmd("git", "-C", kubeRoot, "rev-parse", "HEAD").RunAndCapture()
+	if err != nil || len(revision) != 1 {
+		return "", false
+	}
+	changes, err := exec.NewHostCmd("git", "-C", kubeRoot, "status", "--porcelain").RunAndCapture()
+	if err != nil || len(changes) > 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%s linux/%s", revision[0], arch), true
+}
+
+// buildSourceTree builds Kubernetes binaries & images using the dockerized build
+// NB. this code mimics "sigs.k8s.io/kind/pkg/build/kube"
+func buildSourceTree(kubeRoot, arch string) error {
+	platform := fmt.Sprintf("KUBE_BUILD_PLATFORMS=linux/%s", arch)
+
+	log.Infof("Building Kubernetes binaries in %s ...", kubeRoot)
+	if err := exec.NewHostCmd(
+		filepath.Join(kubeRoot, "build", "run.sh"),
+		"make", "all",
+		"WHAT="+strings.Join([]string{"cmd/kubeadm", "cmd/kubectl", "cmd/kubelet"}, " "),
+		platform,
+		// ensure the build isn't especially noisy..
+		"KUBE_VERBOSE=0",
+	).RunWithEcho(); err != nil {
+		return errors.Wrap(err, "failed to build Kubernetes binaries")
+	}
+
+	log.Infof("Building Kubernetes images in %s ...", kubeRoot)
+	if err := exec.NewHostCmd(
+		"make", "-C", kubeRoot,
+		"quick-release-images",
+		platform,
+		// we don't want to build these images as we don't use them...
+		"KUBE_BUILD_HYPERKUBE=n",
+		"KUBE_BUILD_CONFORMANCE=n",
+	).RunWithEcho(); err != nil {
+		return errors.Wrap(err, "failed to build Kubernetes images")
+	}
+
+	return nil
+}
+
+// stageSourceTreeOutputs collects binaries & images built from a source tree, together with
+// the version file, into a folder that can be used as a local repository
+func stageSourceTreeOutputs(kubeRoot, arch string) (string, error) {
+	binDir := filepath.Join(kubeRoot, "_output", "dockerized", "bin", "linux", arch)
+	imageDir := filepath.Join(kubeRoot, "_output", "release-images", arch)
+	repo := filepath.Join(kubeRoot, "_output", "kinder", "linux", arch)
+
+	if err := os.MkdirAll(repo, 0777); err != nil {
+		return "", errors.Wrapf(err, "failed to make %s dir", repo)
+	}
+
+	stage := func(src string) error {
+		return linkOrCopy(src, filepath.Join(repo, filepath.Base(src)))
+	}
+
+	for _, b := range allKubernetesBinaries {
+		if err := stage(filepath.Join(binDir, b)); err != nil {
+			return "", errors.Wrapf(err, "failed to stage %s", b)
+		}
+	}
+	for _, i := range AllKubernetesImages {
+		if err := stage(filepath.Join(imageDir, i)); err != nil {
+			return "", errors.Wrapf(err, "failed to stage %s", i)
+		}
+	}
+
+	// write the version file
+	version, err := sourceTreeVersion(kubeRoot)
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(repo, "version"), []byte(version), 0644); err != nil {
+		return "", errors.Wrap(err, "failed to write version file")
+	}
+
+	return repo, nil
+}
+
+// sourceTreeVersion returns the Kubernetes version of the source tree
+func sourceTreeVersion(kubeRoot string) (string, error) {
+	lines, err := exec.NewHostCmd(filepath.Join(kubeRoot, "hack", "print-workspace-status.sh")).RunAndCapture()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the Kubernetes version")
+	}
+
+	for _, line := range lines {
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) == 2 && parts[0] == "gitVersion" {
+			return parts[1], nil
+		}
+	}
+	return "", errors.Errorf("could not obtain the Kubernetes version from output: %s", strings.Join(lines, "\n"))
+}
+
+// linkOrCopy makes dst a hard link to src, or a copy of src when hard links are not possible;
+// hard links are used for avoiding to copy large files
+func linkOrCopy(src, dst string) error {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return kindfs.CopyFile(src, dst)
+}
@@ -23,12 +23,11 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
-	"k8s.io/kubeadm/kinder/pkg/constants"
 )
 
 // GetAutomaticCopyCertsPatches returns the kubeadm config patch that will instruct kubeadm
-// to use a well known certificate key for init/join.
-func GetAutomaticCopyCertsPatches(kubeadmVersion *K8sVersion.Version) ([]string, error) {
+// to use the given certificate key for init/join.
+func GetAutomaticCopyCertsPatches(kubeadmVersion *K8sVersion.Version, certificateKey string) ([]string, error) {
 	// gets the config version corresponding to a kubeadm version
 	kubeadmConfigVersion, err := getKubeadmConfigVersion(kubeadmVersion)
 	if err != nil {
@@ -41,8 +40,8 @@ func GetAutomaticCopyCertsPatches(kubeadmVersion *K8sVersion.Version) ([]string,
 	switch kubeadmConfigVersion {
 	case "v1beta2":
 		return []string{
-			fmt.Sprintf(automaticCopyCertsInitv1beta2, constants.CertificateKey),
-			fmt.Sprintf(automaticCopyCertsJoinv1beta2, constants.CertificateKey),
+			fmt.Sprintf(automaticCopyCertsInitv1beta2, certificateKey),
+			fmt.Sprintf(automaticCopyCertsJoinv1beta2, certificateKey),
 		}, nil
 	case "v1beta1":
 		fallthrough
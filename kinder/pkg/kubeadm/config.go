@@ -18,6 +18,9 @@ package kubeadm
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"text/template"
 
 	"github.com/pkg/errors"
@@ -35,31 +38,26 @@ import (
 type ConfigData kindinternalkubeadm.ConfigData
 
 // Config returns a kubeadm generated using the config API version corresponding
-// to the kubeadmVersion and with the customizable settings based on data
-func Config(kubeadmVersion *K8sVersion.Version, data ConfigData) (config string, err error) {
+// to the kubeadmVersion and with the customizable settings based on data; if templatesDir is set
+// and it contains a template for the config API version, e.g. v1beta2.yaml, this template is used
+// instead of the embedded one.
+func Config(kubeadmVersion *K8sVersion.Version, data ConfigData, templatesDir string) (config string, err error) {
 	// gets the config version corresponding to a kubeadm version
 	kubeadmConfigVersion, err := getKubeadmConfigVersion(kubeadmVersion)
 	if err != nil {
 		return "", err
 	}
 
-	// select the patches for the kubeadm config version
+	// select the template for the kubeadm config version
 	log.Debugf("Preparing kubeadm config %s (kubeadm version %s)", kubeadmConfigVersion, kubeadmVersion)
-	var templateSource string
-	switch kubeadmConfigVersion {
-	case "v1beta2":
-		templateSource = kindinternalkubeadm.ConfigTemplateBetaV2
-	case "v1beta1":
-		templateSource = kindinternalkubeadm.ConfigTemplateBetaV1
-	case "v1alpha3":
-		templateSource = kindinternalkubeadm.ConfigTemplateAlphaV3
-	default:
-		return "", errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
+	templateName, templateSource, err := configTemplate(kubeadmConfigVersion, templatesDir)
+	if err != nil {
+		return "", err
 	}
 
 	t, err := template.New("kubeadm-config").Parse(templateSource)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to parse config template")
+		return "", errors.Wrapf(err, "failed to parse config template %s", templateName)
 	}
 
 	// derive any automatic fields if not supplied
@@ -71,12 +69,65 @@ func Config(kubeadmVersion *K8sVersion.Version, data ConfigData) (config string,
 	var buff bytes.Buffer
 	err = t.Execute(&buff, internalData)
 	if err != nil {
-		return "", errors.Wrap(err, "error executing config template")
+		return "", errors.Wrapf(err, "error executing config template %s", templateName)
 	}
 
 	return buff.String(), nil
 }
 
+// embeddedConfigTemplates defines the kubeadm config templates embedded in kinder for each config API version
+var embeddedConfigTemplates = map[string]string{
+	"v1beta2":  kindinternalkubeadm.ConfigTemplateBetaV2,
+	"v1beta1":  kindinternalkubeadm.ConfigTemplateBetaV1,
+	"v1alpha3": kindinternalkubeadm.ConfigTemplateAlphaV3,
+}
+
+// configTemplate returns the name and the source of the kubeadm config template for a config API version;
+// the template is read from a <version>.yaml file in templatesDir, if any, otherwise the embedded template is used
+func configTemplate(kubeadmConfigVersion, templatesDir string) (name string, source string, err error) {
+	embedded, ok := embeddedConfigTemplates[kubeadmConfigVersion]
+	if !ok {
+		return "", "", errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
+	}
+
+	if templatesDir != "" {
+		path := filepath.Join(templatesDir, kubeadmConfigVersion+".yaml")
+		content, err := ioutil.ReadFile(path)
+		if err == nil {
+			log.Debugf("Using kubeadm config template %s", path)
+			return path, string(content), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", errors.Wrapf(err, "failed to read config template %s", path)
+		}
+	}
+
+	return "embedded " + kubeadmConfigVersion, embedded, nil
+}
+
+// ValidateConfigTemplates checks that templatesDir exists and that all the kubeadm config templates in it are
+// well formed; config API versions without a template in templatesDir fall back to the embedded templates.
+func ValidateConfigTemplates(templatesDir string) error {
+	info, err := os.Stat(templatesDir)
+	if err != nil {
+		return errors.Wrapf(err, "invalid config templates dir")
+	}
+	if !info.IsDir() {
+		return errors.Errorf("invalid config templates dir %s, it must be a directory", templatesDir)
+	}
+
+	for version := range embeddedConfigTemplates {
+		name, source, err := configTemplate(version, templatesDir)
+		if err != nil {
+			return err
+		}
+		if _, err := template.New("kubeadm-config").Parse(source); err != nil {
+			return errors.Wrapf(err, "failed to parse config template %s", name)
+		}
+	}
+	return nil
+}
+
 // getKubeadmConfigVersion returns the kubeadm config version corresponding to a Kubernetes kubeadmVersion
 func getKubeadmConfigVersion(kubeadmVersion *K8sVersion.Version) (string, error) {
 	// returns the corresponding config version
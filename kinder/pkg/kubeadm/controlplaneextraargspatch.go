@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// GetControlPlaneExtraArgsPatch returns the kubeadm config patch that will instruct kubeadm
+// to set the given extra args for the kube-apiserver, the kube-controller-manager and the kube-scheduler.
+// NB. this is a strategic merge patch, so extra args already present in the ClusterConfiguration
+// (e.g. enable-hostpath-provisioner for the controller-manager) are preserved, unless overridden.
+func GetControlPlaneExtraArgsPatch(kubeadmVersion *K8sVersion.Version, apiServerExtraArgs, controllerManagerExtraArgs, schedulerExtraArgs map[string]string) (string, error) {
+	// gets the config version corresponding to a kubeadm version
+	kubeadmConfigVersion, err := getKubeadmConfigVersion(kubeadmVersion)
+	if err != nil {
+		return "", err
+	}
+
+	// v1alpha3 uses top level fields, while v1beta1 and later use a field nested in each component
+	components := []struct {
+		field     string
+		extraArgs map[string]string
+	}{
+		{"apiServer", apiServerExtraArgs},
+		{"controllerManager", controllerManagerExtraArgs},
+		{"scheduler", schedulerExtraArgs},
+	}
+
+	log.Debugf("Preparing ControlPlaneExtraArgsPatch for kubeadm config %s (kubeadm version %s)", kubeadmConfigVersion, kubeadmVersion)
+	var b strings.Builder
+	fmt.Fprintf(&b, controlPlaneExtraArgsPatch, kubeadmConfigVersion)
+	for _, c := range components {
+		if len(c.extraArgs) == 0 {
+			continue
+		}
+
+		indent := "  "
+		if kubeadmConfigVersion == "v1alpha3" {
+			fmt.Fprintf(&b, "\n%sExtraArgs:", c.field)
+		} else {
+			fmt.Fprintf(&b, "\n%s:\n  extraArgs:", c.field)
+			indent = "    "
+		}
+
+		// sorts args so the generated patch is stable
+		names := make([]string, 0, len(c.extraArgs))
+		for name := range c.extraArgs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "\n%s%s: %q", indent, name, c.extraArgs[name])
+		}
+	}
+
+	return b.String(), nil
+}
+
+const controlPlaneExtraArgsPatch = `apiVersion: kubeadm.k8s.io/%s
+kind: ClusterConfiguration
+metadata:
+  name: config`
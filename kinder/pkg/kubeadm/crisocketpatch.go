@@ -25,9 +25,9 @@ import (
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 )
 
-// GetDockerPatch returns the kubeadm config patch that will instruct kubeadm
-// to setup user docker CRI defaults.
-func GetDockerPatch(kubeadmVersion *K8sVersion.Version, ControlPlane bool) ([]string, error) {
+// GetCRISocketPatch returns the kubeadm config patch that will instruct kubeadm
+// to use the given CRI socket.
+func GetCRISocketPatch(kubeadmVersion *K8sVersion.Version, criSocket string) ([]string, error) {
 	// gets the config version corresponding to a kubeadm version
 	kubeadmConfigVersion, err := getKubeadmConfigVersion(kubeadmVersion)
 	if err != nil {
@@ -35,16 +35,10 @@ func GetDockerPatch(kubeadmVersion *K8sVersion.Version, ControlPlane bool) ([]st
 	}
 
 	// select the patches for the kubeadm config version
-	log.Debugf("Preparing dockerPatch for kubeadm config %s (kubeadm version %s)", kubeadmConfigVersion, kubeadmVersion)
+	log.Debugf("Preparing criSocketPatch for kubeadm config %s (kubeadm version %s)", kubeadmConfigVersion, kubeadmVersion)
 
-	var basePatch string
 	switch kubeadmConfigVersion {
-	case "v1beta2":
-		basePatch = dockerPatchv1beta2
-	case "v1beta1":
-		basePatch = dockerPatchv1beta1
-	case "v1alpha3":
-		basePatch = dockerPatchv1alpha3
+	case "v1beta2", "v1beta1", "v1alpha3":
 	default:
 		return nil, errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
 	}
@@ -52,28 +46,14 @@ func GetDockerPatch(kubeadmVersion *K8sVersion.Version, ControlPlane bool) ([]st
 	// kind kubeadm config template for v1alpha3, v1beta1,v1beta2 returns both InitConfiguration and JoinConfiguration
 	// so we should create two patches
 	return []string{
-		fmt.Sprintf(basePatch, "InitConfiguration"),
-		fmt.Sprintf(basePatch, "JoinConfiguration"),
+		fmt.Sprintf(criSocketPatch, kubeadmConfigVersion, "InitConfiguration", criSocket),
+		fmt.Sprintf(criSocketPatch, kubeadmConfigVersion, "JoinConfiguration", criSocket),
 	}, nil
 }
 
-const dockerPatchv1beta2 = `apiVersion: kubeadm.k8s.io/v1beta2
+const criSocketPatch = `apiVersion: kubeadm.k8s.io/%s
 kind: %s
 metadata:
   name: config
 nodeRegistration:
-  criSocket: /var/run/dockershim.sock`
-
-const dockerPatchv1beta1 = `apiVersion: kubeadm.k8s.io/v1beta1
-kind: %s
-metadata:
-  name: config
-nodeRegistration:
-  criSocket: /var/run/dockershim.sock`
-
-const dockerPatchv1alpha3 = `apiVersion: kubeadm.k8s.io/v1alpha3
-kind: %s
-metadata:
-  name: config
-nodeRegistration:
-  criSocket: /var/run/dockershim.sock`
+  criSocket: %s`
@@ -18,6 +18,7 @@ package kubeadm
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -26,8 +27,8 @@ import (
 )
 
 // GetExternalEtcdPatch returns the kubeadm config patch that will instruct kubeadm
-// to use external etcd.
-func GetExternalEtcdPatch(kubeadmVersion *K8sVersion.Version, etcdIP string) (string, error) {
+// to use external etcd with the given member IPs.
+func GetExternalEtcdPatch(kubeadmVersion *K8sVersion.Version, etcdIPs []string) (string, error) {
 	// gets the config version corresponding to a kubeadm version
 	kubeadmConfigVersion, err := getKubeadmConfigVersion(kubeadmVersion)
 	if err != nil {
@@ -49,7 +50,12 @@ func GetExternalEtcdPatch(kubeadmVersion *K8sVersion.Version, etcdIP string) (st
 		return "", errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
 	}
 
-	return fmt.Sprintf(externalEtcdPatch, etcdIP), nil
+	var endpoints []string
+	for _, ip := range etcdIPs {
+		endpoints = append(endpoints, fmt.Sprintf("    - http://%s:2379", ip))
+	}
+
+	return fmt.Sprintf(externalEtcdPatch, strings.Join(endpoints, "\n")), nil
 }
 
 const externalEtcdPatchv1beta2 = `apiVersion: kubeadm.k8s.io/v1beta2
@@ -59,7 +65,7 @@ metadata:
 etcd:
   external:
     endpoints:
-    - http://%s:2379`
+%s`
 
 const externalEtcdPatchv1beta1 = `apiVersion: kubeadm.k8s.io/v1beta1
 kind: ClusterConfiguration
@@ -68,7 +74,7 @@ metadata:
 etcd:
   external:
     endpoints:
-    - http://%s:2379`
+%s`
 
 const externalEtcdPatchv1alpha3 = `apiVersion: kubeadm.k8s.io/v1alpha3
 kind: ClusterConfiguration
@@ -77,4 +83,4 @@ metadata:
 etcd:
   external:
     endpoints:
-    - http://%s:2379`
+%s`
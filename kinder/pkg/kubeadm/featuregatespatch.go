@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// GetFeatureGatesPatch returns the kubeadm config patch that will instruct kubeadm
+// to use the given feature gates.
+// NB. this is a strategic merge patch, so gates already present in the ClusterConfiguration
+// (e.g. CoreDNS: false when using kube-dns with v1alpha3) are preserved.
+func GetFeatureGatesPatch(kubeadmVersion *K8sVersion.Version, featureGates map[string]bool) (string, error) {
+	// gets the config version corresponding to a kubeadm version
+	kubeadmConfigVersion, err := getKubeadmConfigVersion(kubeadmVersion)
+	if err != nil {
+		return "", err
+	}
+
+	// sorts gates so the generated patch is stable
+	names := make([]string, 0, len(featureGates))
+	for name := range featureGates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	log.Debugf("Preparing FeatureGatesPatch for kubeadm config %s (kubeadm version %s)", kubeadmConfigVersion, kubeadmVersion)
+	var b strings.Builder
+	fmt.Fprintf(&b, featureGatesPatch, kubeadmConfigVersion)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n  %s: %t", name, featureGates[name])
+	}
+
+	return b.String(), nil
+}
+
+const featureGatesPatch = `apiVersion: kubeadm.k8s.io/%s
+kind: ClusterConfiguration
+metadata:
+  name: config
+featureGates:`
@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// GetLocalEtcdPatch returns the kubeadm config patch that will instruct kubeadm
+// to use the given data dir and extra args for the local etcd; empty values are not set.
+func GetLocalEtcdPatch(kubeadmVersion *K8sVersion.Version, dataDir string, extraArgs map[string]string) (string, error) {
+	// gets the config version corresponding to a kubeadm version
+	kubeadmConfigVersion, err := getKubeadmConfigVersion(kubeadmVersion)
+	if err != nil {
+		return "", err
+	}
+
+	// nb. the local etcd fields are the same in all the supported kubeadm config versions
+	log.Debugf("Preparing localEtcdPatch for kubeadm config %s (kubeadm version %s)", kubeadmConfigVersion, kubeadmVersion)
+	var b strings.Builder
+	fmt.Fprintf(&b, localEtcdPatch, kubeadmConfigVersion)
+	if dataDir != "" {
+		fmt.Fprintf(&b, "\n    dataDir: %q", dataDir)
+	}
+	if len(extraArgs) > 0 {
+		fmt.Fprint(&b, "\n    extraArgs:")
+
+		// sorts args so the generated patch is stable
+		names := make([]string, 0, len(extraArgs))
+		for name := range extraArgs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "\n      %s: %q", name, extraArgs[name])
+		}
+	}
+
+	return b.String(), nil
+}
+
+const localEtcdPatch = `apiVersion: kubeadm.k8s.io/%s
+kind: ClusterConfiguration
+metadata:
+  name: config
+etcd:
+  local:`
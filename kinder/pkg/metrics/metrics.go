@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics implements a minimal recorder of kinder operation durations, that are
+// written in the Prometheus text exposition format at the end of a kinder run
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the duration metrics recorded by kinder
+const (
+	// BuildSeconds is the duration of kinder build node-image-variant
+	BuildSeconds = "kinder_build_seconds"
+	// InitSeconds is the duration of kubeadm init on the bootstrap control-plane node
+	InitSeconds = "kinder_init_seconds"
+	// JoinSeconds is the duration of kubeadm join on a node
+	JoinSeconds = "kinder_join_seconds"
+	// ActionSeconds is the duration of a kinder do action
+	ActionSeconds = "kinder_action_seconds"
+)
+
+// sample is a duration recorded for a metric with a set of labels
+type sample struct {
+	labels  string
+	seconds float64
+}
+
+var (
+	mu      sync.Mutex
+	path    string
+	samples = map[string][]sample{}
+)
+
+// SetOutput sets the path of the file where metrics are written by Flush;
+// if the path is empty, metrics are not written
+func SetOutput(p string) {
+	mu.Lock()
+	defer mu.Unlock()
+	path = p
+}
+
+// Observe records the duration for the metric with the given name; labels are
+// passed as a list of key, value pairs, e.g. "node", "kind-control-plane"
+func Observe(name string, d time.Duration, labels ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	samples[name] = append(samples[name], sample{
+		labels:  formatLabels(labels),
+		seconds: d.Seconds(),
+	})
+}
+
+// Since records the time elapsed since start for the metric with the given name
+func Since(name string, start time.Time, labels ...string) {
+	Observe(name, time.Since(start), labels...)
+}
+
+// Flush writes all the recorded metrics to the file set with SetOutput, if any
+func Flush() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if path == "" {
+		return nil
+	}
+
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		for _, s := range samples[name] {
+			fmt.Fprintf(&b, "%s%s %.3f\n", name, s.labels, s.seconds)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the folder for metrics file %s", path)
+	}
+	if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write metrics file %s", path)
+	}
+	return nil
+}
+
+// formatLabels formats a list of key, value pairs as Prometheus labels, e.g. {node="kind-control-plane"}
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := []string{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], escaper.Replace(labels[i+1])))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ","))
+}
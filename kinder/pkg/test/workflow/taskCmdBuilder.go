@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 	"text/template"
@@ -37,6 +39,7 @@ type taskCmd struct {
 	*Task
 	Cmd     *exec.Cmd
 	CmdText string
+	Skip    bool
 }
 
 // taskCmdBuilder provide support for creating taskCmd, taking care of the context
@@ -62,13 +65,32 @@ func newTaskCmdBuilder(w *Workflow) (c *taskCmdBuilder, err error) {
 	}
 
 	// process vars defined in the workflow
-	if w.Vars != nil {
-		for n, v := range w.Vars {
-			c.vars[n], err = c.expand(v)
+	// nb. vars can reference other vars, so vars that can't be expanded are retried
+	// until there are no more vars that can be expanded
+	pending := map[string]string{}
+	for n, v := range w.Vars {
+		pending[n] = v
+	}
+	for len(pending) > 0 {
+		errs := map[string]error{}
+		for n, v := range pending {
+			value, err := c.expand(v)
 			if err != nil {
+				errs[n] = err
+				continue
+			}
+			c.vars[n] = value
+		}
+		if len(errs) == len(pending) {
+			for n, err := range errs {
 				return nil, errors.Wrapf(err, "error expanding the %q var", n)
 			}
 		}
+		for n := range pending {
+			if _, ok := errs[n]; !ok {
+				delete(pending, n)
+			}
+		}
 	}
 
 	// process additional environment variables defined in the workflow
@@ -89,9 +111,39 @@ var funcMap = template.FuncMap{
 	"resolve": extract.ResolveLabel, // e.g. used in templates >> stable: '{{ resolve "release/stable" }}' or {{ "ci/latest" | resolve }}
 }
 
-// expand takes a string that might contain a golang template and process it
+// matches ${KEY} references to vars; references prefixed by \ are preserved as they are,
+// e.g. for shell variables in scripts
+var varRefRE = regexp.MustCompile(`\\?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// substitute replaces ${KEY} references with the corresponding vars
+func (c *taskCmdBuilder) substitute(text string) (string, error) {
+	var undefined []string
+	text = varRefRE.ReplaceAllStringFunc(text, func(ref string) string {
+		if strings.HasPrefix(ref, "\\") {
+			return ref
+		}
+		key := varRefRE.FindStringSubmatch(ref)[1]
+		v, ok := c.vars[key]
+		if !ok {
+			undefined = append(undefined, key)
+			return ref
+		}
+		return v
+	})
+	if len(undefined) > 0 {
+		return "", errors.Errorf("%q references undefined vars: %s", text, strings.Join(undefined, ", "))
+	}
+	return text, nil
+}
+
+// expand takes a string that might contain ${KEY} references to vars or a golang template and process it
 // using Vars and Env variables as a context
 func (c *taskCmdBuilder) expand(text string) (string, error) {
+	text, err := c.substitute(text)
+	if err != nil {
+		return "", err
+	}
+
 	templ, err := template.New("").Option("missingkey=error").Funcs(funcMap).Parse(text)
 	if err != nil {
 		return "", errors.Wrapf(err, "%q is not a valid expression", text)
@@ -102,11 +154,56 @@ func (c *taskCmdBuilder) expand(text string) (string, error) {
 		"env":  c.env,
 		"vars": c.vars,
 	}); err != nil {
+		if strings.Contains(err.Error(), "map has no entry for key") {
+			return "", errors.Wrapf(err, "expression %q references an undefined var or env var", text)
+		}
 		return "", errors.Wrapf(err, "expression %q returned an error", text)
 	}
 	return b.String(), nil
 }
 
+// buildArtifactsStage returns a copy of the artifacts stage with golang templates expanded
+func (c *taskCmdBuilder) buildArtifactsStage(s *ArtifactsStage) (*ArtifactsStage, error) {
+	cluster, err := c.expand(s.Cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "error expanding cluster for the artifacts stage")
+	}
+
+	dir := s.Dir
+	if dir == "" {
+		dir = "{{ .env.ARTIFACTS }}/cluster"
+	}
+	dir, err = c.expand(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "error expanding dir for the artifacts stage")
+	}
+
+	return &ArtifactsStage{
+		Cluster:     cluster,
+		Dir:         dir,
+		Timeout:     s.Timeout,
+		IgnoreError: s.IgnoreError,
+	}, nil
+}
+
+// Settings returns a textual representation of the taskCmd settings to be used in the execution plan
+func (t *taskCmd) Settings() string {
+	settings := []string{fmt.Sprintf("timeout %s", t.Timeout)}
+	if t.Dir != "" {
+		settings = append(settings, fmt.Sprintf("dir %s", t.Dir))
+	}
+	if t.Force {
+		settings = append(settings, "force")
+	}
+	if t.IgnoreError {
+		settings = append(settings, "ignoreError")
+	}
+	if t.Retries > 0 {
+		settings = append(settings, fmt.Sprintf("retries %d (delay %s)", t.Retries, t.RetryDelay))
+	}
+	return strings.Join(settings, ", ")
+}
+
 // build creates a taskCmd
 func (c *taskCmdBuilder) build(t *Task, verbose bool) (tcmd *taskCmd, err error) {
 	// expand golang templates that might exists in the cmd and/or into the args
@@ -132,11 +229,35 @@ func (c *taskCmdBuilder) build(t *Task, verbose bool) (tcmd *taskCmd, err error)
 		cmd.Dir = t.Dir
 	}
 
-	// set the environment variables for the command
+	// set the environment variables for the command, adding task env variables
+	// to the workflow env variables (task env variables take precedence)
+	env := map[string]string{}
 	for k, v := range c.env {
+		env[k] = v
+	}
+	for k, v := range t.Env {
+		env[k], err = c.expand(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error expanding the %q env var for task %q", k, t.Name)
+		}
+	}
+	for k, v := range env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	// evaluate the skip condition, if any
+	skip := false
+	if t.SkipIf != "" {
+		value, err := c.expand(t.SkipIf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error expanding skipIf for task %q", t.Name)
+		}
+		skip, err = strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid skipIf for task %q: %q does not evaluate to true or false", t.Name, value)
+		}
+	}
+
 	// sets the command in order to have a gid that will allows to identify
 	// all the child process eventually created by the testCmd
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -145,5 +266,6 @@ func (c *taskCmdBuilder) build(t *Task, verbose bool) (tcmd *taskCmd, err error)
 		Task:    t,
 		Cmd:     cmd,
 		CmdText: cmdText,
+		Skip:    skip,
 	}, nil
 }
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"testing"
+)
+
+func TestSubstitute(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputVars     map[string]string
+		inputText     string
+		expectedText  string
+		expectedError bool
+	}{
+		{
+			name:         "valid: no references",
+			inputVars:    map[string]string{"foo": "bar"},
+			inputText:    "kinder create cluster",
+			expectedText: "kinder create cluster",
+		},
+		{
+			name:         "valid: single reference",
+			inputVars:    map[string]string{"cluster": "kinder-test"},
+			inputText:    "--name=${cluster}",
+			expectedText: "--name=kinder-test",
+		},
+		{
+			name:         "valid: multiple references",
+			inputVars:    map[string]string{"a": "x", "b_2": "y"},
+			inputText:    "${a}-${b_2}-${a}",
+			expectedText: "x-y-x",
+		},
+		{
+			name:         "valid: escaped reference is preserved",
+			inputVars:    map[string]string{"HOME": "/root"},
+			inputText:    `echo \${HOME} ${HOME}`,
+			expectedText: `echo \${HOME} /root`,
+		},
+		{
+			name:         "valid: references not matching the var name syntax are preserved",
+			inputVars:    map[string]string{},
+			inputText:    "${1foo} $foo ${}",
+			expectedText: "${1foo} $foo ${}",
+		},
+		{
+			name:         "valid: template expressions are preserved",
+			inputVars:    map[string]string{"foo": "bar"},
+			inputText:    "{{ .vars.foo }}",
+			expectedText: "{{ .vars.foo }}",
+		},
+		{
+			name:          "invalid: undefined var",
+			inputVars:     map[string]string{"foo": "bar"},
+			inputText:     "${foo} ${missing}",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &taskCmdBuilder{vars: test.inputVars}
+			text, err := c.substitute(test.inputText)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if text != test.expectedText {
+				t.Fatalf("expected text: %q, found %q", test.expectedText, text)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -29,8 +30,14 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 )
 
+// artifactsStageName defines the name of the artifacts stage in the workflow output and in the junit report
+const artifactsStageName = "artifacts"
+
 // taskCmdRunner defines all the info of a runner responsible for executing as
 // sequence of taskCmd, handling failure, cancellation, timeouts and for generating
 // and/or collecting all the workflow artifacts (junit_runner.xml, task logs, etc)
@@ -53,12 +60,26 @@ type junitTestSuite struct {
 
 // junitTestCase implements junit TestCase standard object
 type junitTestCase struct {
-	XMLName   xml.Name `xml:"testcase"`
-	ClassName string   `xml:"classname,attr"`
-	Name      string   `xml:"name,attr"`
-	Time      float64  `xml:"time,attr"`
-	Failure   string   `xml:"failure,omitempty"`
-	Skipped   string   `xml:"skipped,omitempty"`
+	XMLName   xml.Name       `xml:"testcase"`
+	ClassName string         `xml:"classname,attr"`
+	Name      string         `xml:"name,attr"`
+	Time      float64        `xml:"time,attr"`
+	Failure   *junitFailure  `xml:"failure,omitempty"`
+	Skipped   string         `xml:"skipped,omitempty"`
+	Retries   []junitFailure `xml:"rerunFailure,omitempty"`
+	SystemOut *junitOutput   `xml:"system-out,omitempty"`
+}
+
+// junitFailure implements junit Failure standard object; the type is the failure reason, e.g. preflight or timeout
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Output  string `xml:",cdata"`
+}
+
+// junitOutput implements junit SystemOut standard object
+type junitOutput struct {
+	Output string `xml:",cdata"`
 }
 
 // newTaskCmdRunner returns a new taskCmdRunner
@@ -69,7 +90,8 @@ func newTaskCmdRunner() *taskCmdRunner {
 	}
 }
 
-// Run a taskCmd
+// Run a taskCmd; if the taskCmd fails or timeouts, it is executed again up to the number of retries
+// defined for the task
 func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 	start := time.Now()
 
@@ -101,14 +123,6 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 		return errors.Wrapf(err, "error creating %q log file", taskLog)
 	}
 
-	t.Cmd.Stdout = writer
-	t.Cmd.Stderr = writer
-
-	if verbose {
-		t.Cmd.Stdout = io.MultiWriter(writer, os.Stdout)
-		t.Cmd.Stderr = io.MultiWriter(writer, os.Stderr)
-	}
-
 	// outputs a command overview before executing it
 	writer.WriteString(fmt.Sprintf("%s\n", strings.Repeat("-", 80)))
 	writer.WriteString(fmt.Sprintf("%s\n", t.Name))
@@ -118,15 +132,71 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 	writer.WriteString(fmt.Sprintf("command : %s\n", t.CmdText))
 	writer.WriteString(fmt.Sprintf("timeout : %s\n", t.Timeout))
 	writer.WriteString(fmt.Sprintf("force   : %v\n", t.Force))
+	if t.Retries > 0 {
+		writer.WriteString(fmt.Sprintf("retries : %d (delay %s)\n", t.Retries, t.RetryDelay))
+	}
 	writer.WriteString(fmt.Sprintf("%s\n\n", strings.Repeat("-", 80)))
 
+	var retries []junitFailure
+	for attempt := 0; ; attempt++ {
+		// nb. an exec.Cmd can't be reused, so a new one is created for each retry
+		if attempt > 0 {
+			t.Cmd = cloneCmd(t.Cmd)
+		}
+
+		t.Cmd.Stdout = writer
+		t.Cmd.Stderr = writer
+
+		if verbose {
+			t.Cmd.Stdout = io.MultiWriter(writer, os.Stdout)
+			t.Cmd.Stderr = io.MultiWriter(writer, os.Stderr)
+		}
+
+		retryable, err := c.runAttempt(t, cancel)
+
+		// if the command completed without an error, record the test case success and exit
+		if err == nil {
+			return c.registerTestCase(t.Name,
+				withDuration(time.Since(start)),
+				withOutput(readOutput(writer, taskLog)),
+				withRetries(retries),
+			)
+		}
+
+		// if the command failed or timed out, retry it until there are retries left
+		reason := failureReason(err)
+		if retryable && attempt < t.Retries {
+			retries = append(retries, junitFailure{Message: err.Error(), Type: string(reason)})
+
+			message := fmt.Sprintf("attempt %d of %d failed: %s; retrying in %s", attempt+1, t.Retries+1, err, t.RetryDelay)
+			writer.WriteString(fmt.Sprintf("\n%s\n\n", message))
+			fmt.Printf(" %s\n", message)
+
+			time.Sleep(t.RetryDelay)
+			continue
+		}
+
+		// otherwise record test case failure and exits with error
+		return c.registerTestCase(t.Name,
+			withFailure(err.Error(), reason),
+			withDuration(time.Since(start)),
+			withOutput(readOutput(writer, taskLog)),
+			withRetries(retries),
+		)
+	}
+}
+
+// runAttempt executes the taskCmd once, and returns if the failure, if any, allows the taskCmd to be retried
+// and the failure itself; preflight and version skew failures of kinder actions are never retried, because they
+// are not going to change on the next attempt
+func (c *taskCmdRunner) runAttempt(t *taskCmd, cancel chan os.Signal) (retryable bool, failure error) {
 	// starts the command
 	if err := t.Cmd.Start(); err != nil {
 		// keeps track of this failure type to block execution of following TestCmd
 		c.failed = true
 
-		// record test case timeout and exits with error
-		return c.registerTestCase(t.Name, withFailure(err.Error()), withDuration(time.Since(start)))
+		// record test case failure to start
+		return false, err
 	}
 
 	// starts a go ruting responsible for waiting the command completes
@@ -141,12 +211,12 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 	// - the timeout is reached
 	select {
 	case err := <-result:
-		// if the command completed without an error or if we are ignoring errors, record the test case success and exit
+		// if the command completed without an error or if we are ignoring errors, record the test case success
 		if err == nil || t.IgnoreError {
-			// record test case timeout as success
-			return c.registerTestCase(t.Name,
-				withDuration(time.Since(start)),
-			)
+			// nb. if retrying, this resets a failure state eventually recorded by a previous attempt
+			c.failed = false
+			c.timedOut = false
+			return false, nil
 		}
 		// keeps track of this failure type to block execution of following TestCmd
 		c.failed = true
@@ -154,11 +224,13 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 		// cleanup command process and its child, if any
 		cleanup(t.Cmd)
 
-		// otherwise record test case failure and exits with error
-		return c.registerTestCase(t.Name,
-			withFailure(err.Error()),
-			withDuration(time.Since(start)),
-		)
+		// otherwise record test case failure
+		failure = taskError(t, err)
+		switch failureReason(failure) {
+		case actions.PreflightFailure, actions.VersionSkewFailure:
+			return false, failure
+		}
+		return true, failure
 
 	case <-cancel:
 		// keeps track of this failure type to block execution of following TestCmd
@@ -167,11 +239,8 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 		// cleanup command process and its child, if any
 		cleanup(t.Cmd)
 
-		// record test case cancellation and exits with error
-		return c.registerTestCase(t.Name,
-			withFailure("task was canceled by the user"),
-			withDuration(time.Since(start)),
-		)
+		// record test case cancellation; nb. a canceled task is never retried
+		return false, errors.New("task was canceled by the user")
 
 	case <-time.After(t.Timeout):
 		// keeps track of this failure type to block execution of following TestCmd
@@ -180,14 +249,88 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 		// cleanup command process and its child, if any
 		cleanup(t.Cmd)
 
-		// record test case timeout and exits with error
-		return c.registerTestCase(t.Name,
-			withFailure(fmt.Sprintf("timeout. task did not completed in less than %s as expected", t.Timeout)),
+		// record test case timeout
+		return true, &actions.ErrTimeout{Err: errors.Errorf("timeout. task did not completed in less than %s as expected", t.Timeout)}
+	}
+}
+
+// taskError returns the action error corresponding to the exit code of a kinder command, e.g. ErrPreflight;
+// errors of other commands are returned as is
+func taskError(t *taskCmd, err error) error {
+	exitErr, ok := errors.Cause(err).(*exec.ExitError)
+	if filepath.Base(t.Cmd.Args[0]) != "kinder" || !ok {
+		return err
+	}
+	return actions.ErrorFromExitCode(exitErr.ExitCode(), err)
+}
+
+// failureReason classifies a task failure, that is reported as failure type in the junit report
+func failureReason(err error) actions.FailureReason {
+	return actions.Reason(err)
+}
+
+// cloneCmd returns a new exec.Cmd with the same settings of cmd, that can be used
+// for executing the command once again
+func cloneCmd(cmd *exec.Cmd) *exec.Cmd {
+	clone := exec.Command(cmd.Path, cmd.Args[1:]...)
+	clone.Dir = cmd.Dir
+	clone.Env = cmd.Env
+	clone.SysProcAttr = cmd.SysProcAttr
+	return clone
+}
+
+// readOutput closes the task log file and returns its content, that is the task output captured so far
+func readOutput(writer *os.File, taskLog string) string {
+	writer.Close()
+	data, err := ioutil.ReadFile(taskLog)
+	if err != nil {
+		return fmt.Sprintf("error reading %q log file: %v", taskLog, err)
+	}
+	return string(data)
+}
+
+// RunArtifactsStage collects artifacts from the nodes of the cluster; nb. the artifacts stage is
+// always executed, no matter of the result of previous taskCmd
+func (c *taskCmdRunner) RunArtifactsStage(s *ArtifactsStage) error {
+	start := time.Now()
+
+	result := make(chan error, 1)
+	go func() {
+		cluster, err := status.FromDocker(s.Cluster)
+		if err != nil {
+			result <- err
+			return
+		}
+		if len(cluster.AllNodes()) == 0 {
+			result <- errors.Errorf("cluster %s does not exist or it does not have nodes", s.Cluster)
+			return
+		}
+		result <- actions.CollectArtifacts(cluster, s.Dir)
+	}()
+
+	select {
+	case err := <-result:
+		if err == nil || s.IgnoreError {
+			return c.registerTestCase(artifactsStageName, withDuration(time.Since(start)))
+		}
+		return c.registerTestCase(artifactsStageName,
+			withFailure(err.Error(), actions.Reason(err)),
+			withDuration(time.Since(start)),
+		)
+	case <-time.After(s.Timeout):
+		return c.registerTestCase(artifactsStageName,
+			withFailure(fmt.Sprintf("timeout. artifacts stage did not completed in less than %s as expected", s.Timeout), actions.TimeoutFailure),
 			withDuration(time.Since(start)),
 		)
 	}
 }
 
+// Skip records a taskCmd as skipped because its skipIf condition is true;
+// nb. skipping a task in this way does not block execution of following taskCmd
+func (c *taskCmdRunner) Skip(t *taskCmd) {
+	c.registerTestCase(t.Name, withSkipped("skipping because the skipIf condition is true"))
+}
+
 // ReportSummary prints a summary of executed task
 func (c *taskCmdRunner) ReportSummary() {
 	total := c.suite.Tests
@@ -210,7 +353,7 @@ func (c *taskCmdRunner) ReportSummary() {
 }
 
 // DumpJUnitRunner writes a report of executed tasks as a junit file
-func (c *taskCmdRunner) DumpJUnitRunner(artifacts string) error {
+func (c *taskCmdRunner) DumpJUnitRunner(file string) error {
 	// sets test suite duration
 	c.suite.Time = time.Since(c.start).Seconds()
 
@@ -219,7 +362,9 @@ func (c *taskCmdRunner) DumpJUnitRunner(artifacts string) error {
 	if err != nil {
 		return errors.Wrapf(err, "error marshaling test suite results")
 	}
-	file := filepath.Join(artifacts, "junit_runner.xml")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return errors.Wrapf(err, "error creating %s", filepath.Dir(file))
+	}
 	f, err := os.Create(file)
 	if err != nil {
 		return errors.Wrapf(err, "error creating %s", file)
@@ -242,9 +387,25 @@ func withDuration(duration time.Duration) testCaseOption {
 		t.Time = duration.Seconds()
 	}
 }
-func withFailure(message string) testCaseOption {
+
+// withFailure records the test case as failed; nb. if the test case output is recorded,
+// it is reported in the failure instead of system-out
+func withFailure(message string, reason actions.FailureReason) testCaseOption {
 	return func(t *junitTestCase) {
-		t.Failure = message
+		t.Failure = &junitFailure{Message: message, Type: string(reason)}
+	}
+}
+
+// withRetries records the failures of previous attempts of executing the test case
+func withRetries(retries []junitFailure) testCaseOption {
+	return func(t *junitTestCase) {
+		t.Retries = retries
+	}
+}
+
+func withOutput(output string) testCaseOption {
+	return func(t *junitTestCase) {
+		t.SystemOut = &junitOutput{Output: output}
 	}
 }
 
@@ -265,11 +426,16 @@ func (c *taskCmdRunner) registerTestCase(name string, options ...testCaseOption)
 		option(tc)
 	}
 
+	if tc.Failure != nil && tc.SystemOut != nil {
+		tc.Failure.Output = tc.SystemOut.Output
+		tc.SystemOut = nil
+	}
+
 	c.suite.Cases = append(c.suite.Cases, *tc)
 	c.suite.Tests++
-	if tc.Failure != "" {
+	if tc.Failure != nil {
 		c.suite.Failures++
-		return errors.New(tc.Failure)
+		return errors.New(tc.Failure.Message)
 	}
 
 	if tc.Skipped != "" {
@@ -22,7 +22,7 @@ can be any command, thus including also any kinder commands invoked via CLI.
 
 Tasks will be executed in order; in case of errors the workflow will stop and the remaining tasks
 will be skipped with the only exception of tasks specifically marked to be executed in any case
-(e.g. cleanup tasks).
+(e.g. cleanup tasks). Tasks can also be skipped conditionally, using a skipIf expression.
 */
 package workflow
 
@@ -31,6 +31,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"time"
@@ -38,6 +39,8 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	yaml "gopkg.in/yaml.v2"
+
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
 // Workflow represents a list of tasks to be executed during test workflow and related context
@@ -53,7 +56,8 @@ type Workflow struct {
 	// Vars defines a set of variables used for golang template expansion.
 	// Variables are processed in order, and OS environment variables and already known Vars
 	// can be used in templates for other vars.
-	// Vars will be accessible as {{ .vars.KEY }}
+	// Vars will be accessible as {{ .vars.KEY }} or using the ${KEY} shortcut, and they can be
+	// overridden from the command line
 	Vars map[string]string
 
 	// Env defines a list of env variables to be passed to the workflow CMD (in addition to OS env variables);
@@ -67,6 +71,28 @@ type Workflow struct {
 
 	// Tasks defines the list of tasks to be executed during test workflow
 	Tasks Tasks
+
+	// Artifacts defines a stage for collecting logs and other artifacts from the nodes of a cluster
+	Artifacts *ArtifactsStage
+}
+
+// ArtifactsStage defines a stage for collecting logs and other artifacts from all the nodes of a cluster.
+// The stage is executed when all the tasks are completed, no matter of the result of the previous tasks, but
+// before the cleanup tasks at the end of the workflow (the trailing tasks marked with force), so it is possible
+// to collect artifacts before the cluster is deleted
+type ArtifactsStage struct {
+	// Cluster defines the name of the cluster; it can be a literal or a template
+	Cluster string
+
+	// Dir defines the folder where artifacts should be stored, one sub folder for each node;
+	// it can be a literal or a template, and it defaults to {{ .env.ARTIFACTS }}/cluster
+	Dir string
+
+	// Timeout for the artifacts stage, 5m by default
+	Timeout time.Duration
+
+	// IgnoreError sets the artifacts stage to be recorded as successful even if it is actually failed
+	IgnoreError bool `yaml:"ignoreError"`
 }
 
 // Tasks represents a list of tasks to be executed during test workflow.
@@ -91,9 +117,21 @@ type Task struct {
 	// Import defines a path of a workflow file to import into the current workflow
 	Import string
 
+	// Include defines a path of a file containing a list of tasks to include into the current workflow;
+	// differently from Import, the included file is a fragment of a workflow without vars, env and version
+	Include string
+
 	// Args allows to set Cmd arguments; args can be a literal or a template
 	Args []string
 
+	// Env defines a list of env variables to be passed to the task Cmd in addition to the workflow env variables,
+	// eventually overriding them; envs can be a literal or a template
+	Env map[string]string
+
+	// SkipIf defines a template that is expanded before executing the task; if the resulting value is true,
+	// the task is skipped, e.g. '{{ eq .vars.upgrade "false" }}'
+	SkipIf string `yaml:"skipIf"`
+
 	// Force sets a task to be executed no matter of the result of the previous task.
 	// This allows e.g. to define cleanup tasks to be always executed
 	Force bool
@@ -103,6 +141,12 @@ type Task struct {
 
 	// IgnoreError sets a task to be recorded as successful even if it is actually failed
 	IgnoreError bool `yaml:"ignoreError"`
+
+	// Retries defines how many times a task should be executed again if it fails or timeouts, 0 by default
+	Retries int
+
+	// RetryDelay defines how long to wait before executing a task again, 10s by default
+	RetryDelay time.Duration `yaml:"retryDelay"`
 }
 
 // NewWorkflow creates a new workflow as defined in a workflow file
@@ -135,11 +179,39 @@ func NewWorkflow(file string) (*Workflow, error) {
 		return nil, errors.Errorf("invalid taskfile %s: at least one task should be defined", file)
 	}
 
+	// if the artifacts stage is defined, checks it is properly configured
+	if w.Artifacts != nil {
+		if w.Artifacts.Cluster == "" {
+			return nil, errors.Errorf("invalid taskfile %s: the artifacts stage does not define a cluster", file)
+		}
+		if w.Artifacts.Timeout == 0 {
+			w.Artifacts.Timeout = time.Duration(5 * time.Minute)
+		}
+	}
+
+	// Detect and resolve includes by expanding included tasks into the workflow
+	w.Tasks, err = expandIncludes(file, w.Tasks, []string{file})
+	if err != nil {
+		return nil, err
+	}
+
 	// Detect and resolve imports by expanding imported workflows into the top level workflow
 	if err := w.expandImports(file); err != nil {
 		return nil, err
 	}
 
+	// check task names are unique
+	names := map[string]bool{}
+	for _, t := range w.Tasks {
+		if t.Name == "" {
+			continue
+		}
+		if names[t.Name] {
+			return nil, errors.Errorf("invalid taskfile %s: task name %q is used more than once", file, t.Name)
+		}
+		names[t.Name] = true
+	}
+
 	// For each task
 	for i, t := range w.Tasks {
 		// if a task name is not defined, assign a default task name
@@ -156,6 +228,14 @@ func NewWorkflow(file string) (*Workflow, error) {
 			t.Timeout = time.Duration(5 * time.Minute)
 		}
 
+		// if retries are defined without a retry delay, assign a default one
+		if t.Retries < 0 {
+			return nil, errors.Errorf("invalid taskfile %s: task %q retries can't be a negative number", file, t.Name)
+		}
+		if t.Retries > 0 && t.RetryDelay == 0 {
+			t.RetryDelay = time.Duration(10 * time.Second)
+		}
+
 		// check if the task defines a cmd
 		if t.Cmd == "" {
 			return nil, errors.Errorf("invalid taskfile %s: task %q does not define a cmd", file, t.Name)
@@ -178,23 +258,8 @@ func (w *Workflow) expandImports(file string) error {
 
 		// otherwise it is an import task
 		// ensure the import task does not have other settings
-		if t.Dir != "" {
-			return errors.Errorf("invalid workflow file %s: task #%d - dir setting can't be combined with import directive", file, i+1)
-		}
-		if t.Cmd != "" {
-			return errors.Errorf("invalid workflow file %s: task #%d - cmd setting can't be combined with import directive", file, i+1)
-		}
-		if len(t.Args) != 0 {
-			return errors.Errorf("invalid workflow file %s: task #%d - args setting can't be combined with import directive", file, i+1)
-		}
-		if t.Force {
-			return errors.Errorf("invalid workflow file %s: task #%d - force setting can't be combined with import directive", file, i+1)
-		}
-		if t.Timeout != 0 {
-			return errors.Errorf("invalid workflow file %s: task #%d - timeout setting can't be combined with import directive", file, i+1)
-		}
-		if t.IgnoreError {
-			return errors.Errorf("invalid workflow file %s: task #%d - ignoreError setting can't be combined with import directive", file, i+1)
+		if err := validateDirectiveTask(file, i, "import", t); err != nil {
+			return err
 		}
 
 		// reads the Import file
@@ -230,6 +295,15 @@ func (w *Workflow) expandImports(file string) error {
 			log.Debugf("env var %s in workflow file %s is shadowed by env var %[1]s in parent workflow file %[3]s", k, path, file)
 		}
 
+		// import the artifacts stage from the import file, if not already defined in the parent file
+		if wx.Artifacts != nil {
+			if w.Artifacts == nil {
+				w.Artifacts = wx.Artifacts
+			} else {
+				log.Debugf("artifacts stage in workflow file %s is shadowed by the artifacts stage in parent workflow file %s", path, file)
+			}
+		}
+
 		// import all tasks from the import file into the parent file, removing task name prefix
 		re := regexp.MustCompile(`^task\-\d{2}\-?`)
 		for _, tx := range wx.Tasks {
@@ -241,8 +315,108 @@ func (w *Workflow) expandImports(file string) error {
 	return nil
 }
 
-// Run executes a workflow
-func (w *Workflow) Run(out io.Writer, dryRun, verbose, exitOnError bool, artifacts string) (err error) {
+// expandIncludes replaces tasks with an include directive with the tasks defined in the included file;
+// includedBy tracks the chain of files being included for detecting circular includes
+func expandIncludes(file string, tasks Tasks, includedBy []string) (Tasks, error) {
+	expanded := Tasks{}
+	for i, t := range tasks {
+		// check if the task does not defines an include, preserve it as it is
+		if t.Include == "" {
+			expanded = append(expanded, t)
+			continue
+		}
+
+		// otherwise it is an include task
+		// ensure the include task does not have other settings
+		if err := validateDirectiveTask(file, i, "include", t); err != nil {
+			return nil, err
+		}
+
+		// reads the include file
+		// if path are relative, consider as a base path the folder where the including file is located.
+		path := t.Include
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		for _, f := range includedBy {
+			if filepath.Clean(f) == filepath.Clean(path) {
+				return nil, errors.Errorf("invalid workflow file %s: task #%d - circular include of %s", file, i+1, path)
+			}
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading include file %s", path)
+		}
+		var tx Tasks
+		if err := yaml.UnmarshalStrict(data, &tx); err != nil {
+			return nil, errors.Wrapf(err, "error unmarshalling include file %s; it should contain a list of tasks", path)
+		}
+
+		// imports in the included file are relative to the included file too
+		for _, t := range tx {
+			if t.Import != "" && !filepath.IsAbs(t.Import) {
+				t.Import = filepath.Join(filepath.Dir(path), t.Import)
+			}
+		}
+
+		// expand nested includes, if any
+		tx, err = expandIncludes(path, tx, append(includedBy, path))
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, tx...)
+	}
+	return expanded, nil
+}
+
+// SetVars overrides vars defined in the workflow with the given values, e.g. the ones passed from the command line
+func (w *Workflow) SetVars(vars map[string]string) {
+	if w.Vars == nil {
+		w.Vars = map[string]string{}
+	}
+	for k, v := range vars {
+		w.Vars[k] = v
+	}
+}
+
+// validateDirectiveTask ensures a task defining an import or include directive does not have other settings
+func validateDirectiveTask(file string, i int, directive string, t *Task) error {
+	if t.Import != "" && t.Include != "" {
+		return errors.Errorf("invalid workflow file %s: task #%d - import and include directives can't be combined", file, i+1)
+	}
+	if t.Dir != "" {
+		return errors.Errorf("invalid workflow file %s: task #%d - dir setting can't be combined with %s directive", file, i+1, directive)
+	}
+	if t.Cmd != "" {
+		return errors.Errorf("invalid workflow file %s: task #%d - cmd setting can't be combined with %s directive", file, i+1, directive)
+	}
+	if len(t.Args) != 0 {
+		return errors.Errorf("invalid workflow file %s: task #%d - args setting can't be combined with %s directive", file, i+1, directive)
+	}
+	if t.Force {
+		return errors.Errorf("invalid workflow file %s: task #%d - force setting can't be combined with %s directive", file, i+1, directive)
+	}
+	if t.Timeout != 0 {
+		return errors.Errorf("invalid workflow file %s: task #%d - timeout setting can't be combined with %s directive", file, i+1, directive)
+	}
+	if t.IgnoreError {
+		return errors.Errorf("invalid workflow file %s: task #%d - ignoreError setting can't be combined with %s directive", file, i+1, directive)
+	}
+	if t.Retries != 0 || t.RetryDelay != 0 {
+		return errors.Errorf("invalid workflow file %s: task #%d - retries settings can't be combined with %s directive", file, i+1, directive)
+	}
+	if len(t.Env) != 0 {
+		return errors.Errorf("invalid workflow file %s: task #%d - env setting can't be combined with %s directive", file, i+1, directive)
+	}
+	if t.SkipIf != "" {
+		return errors.Errorf("invalid workflow file %s: task #%d - skipIf setting can't be combined with %s directive", file, i+1, directive)
+	}
+	return nil
+}
+
+// Run executes a workflow; if junit is not set, the junit report is written into the artifacts folder
+func (w *Workflow) Run(out io.Writer, dryRun, verbose, exitOnError bool, artifacts, junit string) (err error) {
 
 	// get a new taskCmdBuilder, responsible for creating taskCmd commands
 	taskCmdBuilder, err := newTaskCmdBuilder(w)
@@ -285,6 +459,10 @@ func (w *Workflow) Run(out io.Writer, dryRun, verbose, exitOnError bool, artifac
 	// to make this value available for cmd and args expansion
 	taskCmdBuilder.env["ARTIFACTS"] = artifacts
 
+	if junit == "" {
+		junit = filepath.Join(artifacts, "junit_runner.xml")
+	}
+
 	// Gets a taskCmdRunner, responsible for executing taskCmd,
 	// handling failure, cancellation, timeouts and for generating or collecting
 	// all the workflow artifacts (junit_runner.xml, task logs, etc)
@@ -294,23 +472,82 @@ func (w *Workflow) Run(out io.Writer, dryRun, verbose, exitOnError bool, artifac
 	// and create the corresponding taskCmd
 	// Nb. we are splitting this step from actual execution of task for ensuring
 	// that all the formal error are detected before starting any real activity
+	// Nb. when dry running, all the errors are collected in order to provide complete feedback at once
 	var tcmds []*taskCmd
+	var errs []error
 	for _, t := range w.Tasks {
 
 		tcmd, err := taskCmdBuilder.build(t, verbose)
 		if err != nil {
-			return err
+			if !dryRun {
+				return err
+			}
+			errs = append(errs, err)
+			continue
 		}
 
 		tcmds = append(tcmds, tcmd)
 	}
+	if len(errs) > 0 {
+		return kerrors.NewAggregate(errs)
+	}
+
+	// Process the artifacts stage, if defined, and find where it should be executed,
+	// that is before cleanup tasks at the end of the workflow
+	var stage *ArtifactsStage
+	stageIndex := len(tcmds)
+	if w.Artifacts != nil {
+		stage, err = taskCmdBuilder.buildArtifactsStage(w.Artifacts)
+		if err != nil {
+			return err
+		}
+		for stageIndex > 0 && tcmds[stageIndex-1].Force {
+			stageIndex--
+		}
+	}
+
+	if dryRun {
+		fmt.Fprintf(out, "Execution plan (%d tasks):\n\n", len(tcmds))
+	}
 
 	foundError := false
+	runStage := func() {
+		fmt.Fprintf(out, "# %s\n", artifactsStageName)
+		fmt.Fprintf(out, "collect artifacts from cluster %s into %s\n\n", stage.Cluster, stage.Dir)
+		if dryRun {
+			return
+		}
+		if err := taskCmdRunner.RunArtifactsStage(stage); err != nil {
+			foundError = true
+			fmt.Fprintf(out, " %v\n\n", err)
+			return
+		}
+		fmt.Fprintf(out, " completed!\n\n")
+	}
+
 	// Executes taskCmds
-	for _, tcmd := range tcmds {
+	for i, tcmd := range tcmds {
+		if stage != nil && i == stageIndex {
+			runStage()
+		}
+
 		fmt.Fprintf(out, "# %s\n", tcmd.Name)
+		if dryRun {
+			fmt.Fprintf(out, "# %s\n", tcmd.Settings())
+			if _, err := exec.LookPath(tcmd.Cmd.Path); err != nil {
+				fmt.Fprintf(out, "# WARNING: command %s not found\n", tcmd.Cmd.Path)
+			}
+		}
 		fmt.Fprintf(out, "%s\n\n", tcmd.CmdText)
 
+		if tcmd.Skip {
+			if !dryRun {
+				taskCmdRunner.Skip(tcmd)
+			}
+			fmt.Fprintf(out, " skipped because the skipIf condition is true\n\n")
+			continue
+		}
+
 		if !dryRun {
 			err := taskCmdRunner.Run(tcmd, artifacts, verbose)
 			if err != nil {
@@ -318,6 +555,10 @@ func (w *Workflow) Run(out io.Writer, dryRun, verbose, exitOnError bool, artifac
 				fmt.Fprintf(out, " %v\n\n", err)
 
 				if exitOnError {
+					// nb. artifacts are collected also when exiting on errors
+					if stage != nil && i < stageIndex {
+						runStage()
+					}
 					return err
 				}
 
@@ -327,16 +568,19 @@ func (w *Workflow) Run(out io.Writer, dryRun, verbose, exitOnError bool, artifac
 			fmt.Fprintf(out, " completed!\n\n")
 		}
 	}
+	if stage != nil && stageIndex == len(tcmds) {
+		runStage()
+	}
 
 	// If not dry running, prints task summary and dumps the junit_runner.xml file
 	if !dryRun {
 		taskCmdRunner.ReportSummary()
 
-		if err := taskCmdRunner.DumpJUnitRunner(artifacts); err != nil {
+		if err := taskCmdRunner.DumpJUnitRunner(junit); err != nil {
 			fmt.Fprintf(out, "%v\n", err)
 			return err
 		}
-		fmt.Fprintf(out, "see junit-runner.xml and task logs files for more details\n\n")
+		fmt.Fprintf(out, "see %s and task logs files for more details\n\n", junit)
 	}
 
 	if foundError {
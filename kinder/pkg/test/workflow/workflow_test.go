@@ -0,0 +1,230 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles writes the given files into a temporary folder, and returns the folder
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "kinder-workflow")
+	if err != nil {
+		t.Fatalf("failed to create a temporary folder: %v", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create folder for %s: %v", name, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestExpandIncludes(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputFiles    map[string]string
+		inputTasks    Tasks
+		expectedNames []string
+		expectedError bool
+	}{
+		{
+			name: "valid: no includes",
+			inputTasks: Tasks{
+				{Name: "a", Cmd: "echo"},
+				{Name: "b", Cmd: "echo"},
+			},
+			expectedNames: []string{"a", "b"},
+		},
+		{
+			name: "valid: include",
+			inputFiles: map[string]string{
+				"included.yaml": "- name: b\n  cmd: echo\n- name: c\n  cmd: echo\n",
+			},
+			inputTasks: Tasks{
+				{Name: "a", Cmd: "echo"},
+				{Include: "included.yaml"},
+				{Name: "d", Cmd: "echo"},
+			},
+			expectedNames: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "valid: nested include relative to the included file",
+			inputFiles: map[string]string{
+				"sub/included.yaml": "- name: b\n  cmd: echo\n- include: nested.yaml\n",
+				"sub/nested.yaml":   "- name: c\n  cmd: echo\n",
+			},
+			inputTasks: Tasks{
+				{Include: "sub/included.yaml"},
+			},
+			expectedNames: []string{"b", "c"},
+		},
+		{
+			name: "valid: the same file included twice",
+			inputFiles: map[string]string{
+				"included.yaml": "- cmd: echo\n",
+			},
+			inputTasks: Tasks{
+				{Include: "included.yaml"},
+				{Include: "included.yaml"},
+			},
+			expectedNames: []string{"", ""},
+		},
+		{
+			name: "invalid: missing include file",
+			inputTasks: Tasks{
+				{Include: "missing.yaml"},
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid: include file is not a list of tasks",
+			inputFiles: map[string]string{
+				"included.yaml": "version: 1\ntasks:\n- cmd: echo\n",
+			},
+			inputTasks: Tasks{
+				{Include: "included.yaml"},
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid: include combined with cmd",
+			inputFiles: map[string]string{
+				"included.yaml": "- cmd: echo\n",
+			},
+			inputTasks: Tasks{
+				{Include: "included.yaml", Cmd: "echo"},
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid: file including itself",
+			inputFiles: map[string]string{
+				"workflow.yaml": "",
+			},
+			inputTasks: Tasks{
+				{Include: "workflow.yaml"},
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid: circular include",
+			inputFiles: map[string]string{
+				"a.yaml": "- include: b.yaml\n",
+				"b.yaml": "- include: a.yaml\n",
+			},
+			inputTasks: Tasks{
+				{Include: "a.yaml"},
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeFiles(t, test.inputFiles)
+			defer os.RemoveAll(dir)
+
+			file := filepath.Join(dir, "workflow.yaml")
+			tasks, err := expandIncludes(file, test.inputTasks, []string{file})
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			names := []string{}
+			for _, task := range tasks {
+				names = append(names, task.Name)
+			}
+			if !reflect.DeepEqual(names, test.expectedNames) {
+				t.Fatalf("expected tasks: %v, found %v", test.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestNewWorkflowTaskNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputFiles    map[string]string
+		expectedNames []string
+		expectedError bool
+	}{
+		{
+			name: "valid: unique names",
+			inputFiles: map[string]string{
+				"workflow.yaml": "version: 1\ntasks:\n- name: a\n  cmd: echo\n- cmd: echo\n- include: included.yaml\n",
+				"included.yaml": "- name: b\n  cmd: echo\n",
+			},
+			expectedNames: []string{"task-00-a", "task-01", "task-02-b"},
+		},
+		{
+			name: "invalid: duplicate names",
+			inputFiles: map[string]string{
+				"workflow.yaml": "version: 1\ntasks:\n- name: a\n  cmd: echo\n- name: a\n  cmd: echo\n",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid: duplicate names across included files",
+			inputFiles: map[string]string{
+				"workflow.yaml": "version: 1\ntasks:\n- name: a\n  cmd: echo\n- include: included.yaml\n",
+				"included.yaml": "- name: a\n  cmd: echo\n",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid: included file without cmd",
+			inputFiles: map[string]string{
+				"workflow.yaml": "version: 1\ntasks:\n- include: included.yaml\n",
+				"included.yaml": "- name: a\n",
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeFiles(t, test.inputFiles)
+			defer os.RemoveAll(dir)
+
+			w, err := NewWorkflow(filepath.Join(dir, "workflow.yaml"))
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			names := []string{}
+			for _, task := range w.Tasks {
+				names = append(names, task.Name)
+			}
+			if !reflect.DeepEqual(names, test.expectedNames) {
+				t.Fatalf("expected tasks: %v, found %v", test.expectedNames, names)
+			}
+		})
+	}
+}